@@ -0,0 +1,337 @@
+// Package commands cmd/apps/skychat/protocol.go
+package commands
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Every frame starts with a header made of frameMagic, the protocol version and the big-endian
+// uint32 length of the frame payload. The payload of version 1 is:
+//
+//	type (1) | part (2) | parts (2) | unix nano timestamp (8) | id length (1) | id | content type length (1) | content type | body
+//
+// frameMagic never occurs in UTF-8 text, which tells framed peers apart from the older ones sending raw text.
+const (
+	frameMagic      = 0xff
+	protocolVersion = 1
+	frameHeaderSize = 6
+	// maxFrameSize is the biggest payload accepted, frames of newer versions are skipped up to it.
+	maxFrameSize = 64 * 1024
+	// maxPartSize is the biggest body sent within a single frame, longer bodies are split into parts.
+	maxPartSize = 32 * 1024
+	// maxMessageSize is the biggest body of a multi-part message.
+	maxMessageSize = 4 * 1024 * 1024
+	// maxPartialPackets is the number of multi-part messages being reassembled at a time per conn.
+	maxPartialPackets = 16
+	// legacyReadSize is the size of a single read of raw text sent by older peers.
+	legacyReadSize = 32 * 1024
+
+	// contentTypeText is the content type of plain text messages.
+	contentTypeText = "text/plain; charset=utf-8"
+)
+
+var (
+	errFrameTooBig   = errors.New("frame too big")
+	errMessageTooBig = errors.New("message too big")
+	errInvalidFrame  = errors.New("invalid frame")
+	// errLegacyContentType is returned on sending anything but text to older peers.
+	errLegacyContentType = errors.New("peer supports text messages only")
+	// errLegacyRooms is returned on sending room events to older peers.
+	errLegacyRooms = errors.New("peer does not support rooms")
+)
+
+// packetType is the type of a packet exchanged between skychat peers.
+type packetType uint8
+
+const (
+	// packetMessage carries a chat message.
+	packetMessage packetType = iota + 1
+	// packetDelivered acknowledges that the message of the packet ID was stored by the recipient.
+	packetDelivered
+	// packetRead acknowledges that the message of the packet ID was shown to the recipient.
+	packetRead
+	// packetRoom carries a JSON encoded event of a group room.
+	packetRoom
+	// packetHello announces that the peer sends frames. It is sent by the accepting peer and answered by the dialing one.
+	packetHello
+)
+
+func (t packetType) String() string {
+	switch t {
+	case packetMessage:
+		return "message"
+	case packetDelivered:
+		return "delivered"
+	case packetRead:
+		return "read"
+	case packetRoom:
+		return "room"
+	case packetHello:
+		return "hello"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(t))
+	}
+}
+
+// packet is everything sent over a skychat conn.
+type packet struct {
+	Type        packetType
+	ID          string
+	Timestamp   time.Time
+	ContentType string
+	Body        []byte
+}
+
+// isText checks whether the content type holds human readable text.
+func isText(contentType string) bool {
+	return contentType == "" || strings.HasPrefix(contentType, "text/")
+}
+
+// encodeBody returns the wire representation of a message body.
+func encodeBody(contentType, body string) ([]byte, error) {
+	if isText(contentType) {
+		return []byte(body), nil
+	}
+	return base64.StdEncoding.DecodeString(body)
+}
+
+// decodeBody returns the message body of the wire representation `b`.
+func decodeBody(contentType string, b []byte) string {
+	if isText(contentType) {
+		return string(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// writePacket writes `p` to `w`, splitting its body into as many frames as needed.
+func writePacket(w io.Writer, p packet) error {
+	if len(p.Body) > maxMessageSize {
+		return errMessageTooBig
+	}
+
+	parts := (len(p.Body) + maxPartSize - 1) / maxPartSize
+	if parts == 0 {
+		parts = 1
+	}
+	for part := 0; part < parts; part++ {
+		body := p.Body[part*maxPartSize:]
+		if len(body) > maxPartSize {
+			body = body[:maxPartSize]
+		}
+
+		frame, err := encodeFrame(p, uint16(part), uint16(parts), body)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(frame); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeFrame(p packet, part, parts uint16, body []byte) ([]byte, error) {
+	if len(p.ID) > 255 || len(p.ContentType) > 255 {
+		return nil, errInvalidFrame
+	}
+
+	payloadSize := 1 + 2 + 2 + 8 + 1 + len(p.ID) + 1 + len(p.ContentType) + len(body)
+	if payloadSize > maxFrameSize {
+		return nil, errFrameTooBig
+	}
+
+	frame := make([]byte, 0, frameHeaderSize+payloadSize)
+	frame = append(frame, frameMagic, protocolVersion)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(payloadSize))
+	frame = append(frame, byte(p.Type))
+	frame = binary.BigEndian.AppendUint16(frame, part)
+	frame = binary.BigEndian.AppendUint16(frame, parts)
+	frame = binary.BigEndian.AppendUint64(frame, uint64(p.Timestamp.UnixNano()))
+	frame = append(frame, byte(len(p.ID)))
+	frame = append(frame, p.ID...)
+	frame = append(frame, byte(len(p.ContentType)))
+	frame = append(frame, p.ContentType...)
+	frame = append(frame, body...)
+
+	return frame, nil
+}
+
+// partialPacket is a multi-part message being reassembled.
+type partialPacket struct {
+	packet
+	chunks   [][]byte
+	received int
+	size     int
+}
+
+// packetReader reads the packets sent by a peer.
+type packetReader struct {
+	r       *bufio.Reader
+	partial map[string]*partialPacket
+}
+
+func newPacketReader(r io.Reader) *packetReader {
+	return &packetReader{
+		r:       bufio.NewReaderSize(r, frameHeaderSize+maxFrameSize),
+		partial: make(map[string]*partialPacket),
+	}
+}
+
+// readPacket returns the next complete packet. Raw text of older peers is turned into a message
+// packet and reported as legacy, so no receipts are sent back.
+func (pr *packetReader) readPacket() (p packet, legacy bool, err error) {
+	for {
+		first, err := pr.r.Peek(1)
+		if err != nil {
+			return packet{}, false, err
+		}
+		if first[0] != frameMagic {
+			return pr.readLegacy()
+		}
+
+		p, complete, err := pr.readFrame()
+		if err != nil {
+			return packet{}, false, err
+		}
+		if complete {
+			return p, false, nil
+		}
+	}
+}
+
+func (pr *packetReader) readLegacy() (packet, bool, error) {
+	buf := make([]byte, legacyReadSize)
+	n, err := pr.r.Read(buf)
+	if err != nil {
+		return packet{}, false, err
+	}
+
+	return packet{
+		Type:        packetMessage,
+		ID:          newMessageID(),
+		Timestamp:   time.Now(),
+		ContentType: contentTypeText,
+		Body:        buf[:n],
+	}, true, nil
+}
+
+// readFrame reads a single frame. Returns false if the frame doesn't complete a packet.
+func (pr *packetReader) readFrame() (packet, bool, error) {
+	var hdr [frameHeaderSize]byte
+	if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+		return packet{}, false, err
+	}
+	version := hdr[1]
+	size := binary.BigEndian.Uint32(hdr[2:])
+	if size > maxFrameSize {
+		return packet{}, false, errFrameTooBig
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(pr.r, payload); err != nil {
+		return packet{}, false, err
+	}
+	if version != protocolVersion {
+		fmt.Printf("Skipping frame of unsupported protocol version %d\n", version)
+		return packet{}, false, nil
+	}
+
+	p, part, parts, err := decodePayload(payload)
+	if err != nil {
+		return packet{}, false, err
+	}
+	if parts == 1 {
+		return p, true, nil
+	}
+
+	return pr.addPart(p, part, parts)
+}
+
+func decodePayload(payload []byte) (p packet, part, parts uint16, err error) {
+	const fixedSize = 1 + 2 + 2 + 8 + 1
+	if len(payload) < fixedSize {
+		return p, 0, 0, errInvalidFrame
+	}
+
+	p.Type = packetType(payload[0])
+	part = binary.BigEndian.Uint16(payload[1:])
+	parts = binary.BigEndian.Uint16(payload[3:])
+	p.Timestamp = time.Unix(0, int64(binary.BigEndian.Uint64(payload[5:])))
+	rest := payload[fixedSize-1:]
+
+	var field []byte
+	if field, rest, err = readField(rest); err != nil {
+		return p, 0, 0, err
+	}
+	p.ID = string(field)
+	if field, rest, err = readField(rest); err != nil {
+		return p, 0, 0, err
+	}
+	p.ContentType = string(field)
+	p.Body = rest
+
+	if p.ID == "" || parts == 0 || part >= parts || int(parts) > maxMessageSize/maxPartSize {
+		return p, 0, 0, errInvalidFrame
+	}
+
+	return p, part, parts, nil
+}
+
+// readField reads a field prefixed by its uint8 length.
+func readField(b []byte) (field, rest []byte, err error) {
+	if len(b) < 1 || len(b) < 1+int(b[0]) {
+		return nil, nil, errInvalidFrame
+	}
+	n := int(b[0])
+	return b[1 : 1+n], b[1+n:], nil
+}
+
+func (pr *packetReader) addPart(p packet, part, parts uint16) (packet, bool, error) {
+	pp, ok := pr.partial[p.ID]
+	if !ok {
+		if len(pr.partial) >= maxPartialPackets {
+			// the peer doesn't complete its messages, drop the unfinished ones
+			pr.partial = make(map[string]*partialPacket)
+		}
+		pp = &partialPacket{
+			packet: p,
+			chunks: make([][]byte, parts),
+		}
+		pr.partial[p.ID] = pp
+	}
+	if len(pp.chunks) != int(parts) {
+		delete(pr.partial, p.ID)
+		return packet{}, false, errInvalidFrame
+	}
+	if pp.chunks[part] != nil {
+		return packet{}, false, nil
+	}
+
+	pp.size += len(p.Body)
+	if pp.size > maxMessageSize {
+		delete(pr.partial, p.ID)
+		return packet{}, false, errMessageTooBig
+	}
+	pp.chunks[part] = append([]byte{}, p.Body...)
+	pp.received++
+	if pp.received < int(parts) {
+		return packet{}, false, nil
+	}
+
+	delete(pr.partial, p.ID)
+	body := make([]byte, 0, pp.size)
+	for _, chunk := range pp.chunks {
+		body = append(body, chunk...)
+	}
+	pp.packet.Body = body
+
+	return pp.packet, true, nil
+}
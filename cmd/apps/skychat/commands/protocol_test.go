@@ -0,0 +1,122 @@
+// Package commands cmd/apps/skychat/protocol_test.go
+package commands
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPacket_roundTrip(t *testing.T) {
+	ts := time.Unix(0, time.Now().UnixNano())
+
+	tests := []struct {
+		name string
+		p    packet
+	}{
+		{
+			name: "message",
+			p:    packet{Type: packetMessage, ID: "id-1", Timestamp: ts, ContentType: contentTypeText, Body: []byte("hello")},
+		},
+		{
+			name: "receipt",
+			p:    packet{Type: packetDelivered, ID: "id-2", Timestamp: ts, Body: []byte{}},
+		},
+		{
+			name: "multi-part",
+			p: packet{Type: packetMessage, ID: "id-3", Timestamp: ts, ContentType: "image/png",
+				Body: bytes.Repeat([]byte{0, 1, 2, 0xff}, maxPartSize)},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, writePacket(&buf, tc.p))
+
+			p, legacy, err := newPacketReader(&buf).readPacket()
+			require.NoError(t, err)
+			require.False(t, legacy)
+			require.Equal(t, tc.p, p)
+		})
+	}
+}
+
+func TestPacketReader_interleaved(t *testing.T) {
+	long := packet{Type: packetMessage, ID: "long", ContentType: contentTypeText, Body: bytes.Repeat([]byte("a"), maxPartSize+1)}
+	short := packet{Type: packetRead, ID: "short", Body: []byte{}}
+
+	first, err := encodeFrame(long, 0, 2, long.Body[:maxPartSize])
+	require.NoError(t, err)
+	between, err := encodeFrame(short, 0, 1, short.Body)
+	require.NoError(t, err)
+	second, err := encodeFrame(long, 1, 2, long.Body[maxPartSize:])
+	require.NoError(t, err)
+
+	pr := newPacketReader(bytes.NewReader(append(append(first, between...), second...)))
+
+	p, _, err := pr.readPacket()
+	require.NoError(t, err)
+	require.Equal(t, short.ID, p.ID)
+
+	p, _, err = pr.readPacket()
+	require.NoError(t, err)
+	require.Equal(t, long.ID, p.ID)
+	require.Equal(t, long.Body, p.Body)
+}
+
+func TestPacketReader_legacy(t *testing.T) {
+	p, legacy, err := newPacketReader(bytes.NewReader([]byte("raw text"))).readPacket()
+	require.NoError(t, err)
+	require.True(t, legacy)
+	require.Equal(t, packetMessage, p.Type)
+	require.Equal(t, []byte("raw text"), p.Body)
+	require.NotEmpty(t, p.ID)
+}
+
+func TestPacketReader_skipsNewerVersions(t *testing.T) {
+	hello := packet{Type: packetHello, ID: "hello", Body: []byte{}}
+	frame, err := encodeFrame(hello, 0, 1, hello.Body)
+	require.NoError(t, err)
+
+	newer := append([]byte{}, frame...)
+	newer[1] = protocolVersion + 1
+
+	pr := newPacketReader(bytes.NewReader(append(newer, frame...)))
+	p, _, err := pr.readPacket()
+	require.NoError(t, err)
+	require.Equal(t, packetHello, p.Type)
+
+	_, _, err = pr.readPacket()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestPacketReader_invalid(t *testing.T) {
+	tooBig := []byte{frameMagic, protocolVersion, 0xff, 0xff, 0xff, 0xff}
+	_, _, err := newPacketReader(bytes.NewReader(tooBig)).readPacket()
+	require.Equal(t, errFrameTooBig, err)
+
+	noID, err := encodeFrame(packet{Type: packetMessage}, 0, 1, nil)
+	require.NoError(t, err)
+	_, _, err = newPacketReader(bytes.NewReader(noID)).readPacket()
+	require.Equal(t, errInvalidFrame, err)
+
+	require.Equal(t, errMessageTooBig, writePacket(io.Discard, packet{ID: "id", Body: make([]byte, maxMessageSize+1)}))
+}
+
+func TestEncodeBody(t *testing.T) {
+	b, err := encodeBody("image/png", "AAH/")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 1, 0xff}, b)
+	require.Equal(t, "AAH/", decodeBody("image/png", b))
+
+	b, err = encodeBody(contentTypeText, "héllo")
+	require.NoError(t, err)
+	require.Equal(t, "héllo", decodeBody(contentTypeText, b))
+
+	_, err = encodeBody("image/png", "not base64!")
+	require.Error(t, err)
+}
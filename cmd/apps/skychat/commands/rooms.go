@@ -0,0 +1,755 @@
+// Package commands cmd/apps/skychat/rooms.go
+package commands
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+// Rooms are hosted by a single visor, which keeps the membership and the history of the room and fans
+// the messages out to the members over their skychat conns. The room events are JSON encoded within
+// packets of the packetRoom type.
+const (
+	// roomsBucket keeps the rooms hosted by this visor.
+	roomsBucket = "_rooms"
+	// joinedRoomsBucket keeps the rooms of other hosts this visor is a member of.
+	joinedRoomsBucket = "_joined_rooms"
+	// roomRequestTimeout is the time to wait for a reply of a room host.
+	roomRequestTimeout = 10 * time.Second
+	// roomHistorySize is the number of the latest messages returned by the host to a member.
+	roomHistorySize = 100
+	// contentTypeJSON is the content type of room events.
+	contentTypeJSON = "application/json"
+)
+
+var (
+	errRoomNotFound = errors.New("room not found")
+	errNotMember    = errors.New("not a member of the room")
+	errNotAdmin     = errors.New("not an admin of the room")
+	errRoomClosed   = errors.New("room is closed, ask an admin to add you")
+)
+
+// roomOp is the operation of a room event.
+type roomOp string
+
+const (
+	// Requests of members to the host.
+	roomOpList    roomOp = "list"
+	roomOpJoin    roomOp = "join"
+	roomOpLeave   roomOp = "leave"
+	roomOpSend    roomOp = "send"
+	roomOpHistory roomOp = "history"
+	roomOpAdd     roomOp = "add"
+	roomOpRemove  roomOp = "remove"
+	roomOpPromote roomOp = "promote"
+
+	// Replies of the host.
+	roomOpOK       roomOp = "ok"
+	roomOpInfo     roomOp = "info"
+	roomOpRooms    roomOp = "rooms"
+	roomOpMessages roomOp = "messages"
+	roomOpError    roomOp = "error"
+
+	// Notifications of the host to members.
+	roomOpMessage roomOp = "message"
+	roomOpAdded   roomOp = "added"
+	roomOpRemoved roomOp = "removed"
+)
+
+func (op roomOp) isReply() bool {
+	switch op {
+	case roomOpOK, roomOpInfo, roomOpRooms, roomOpMessages, roomOpError:
+		return true
+	default:
+		return false
+	}
+}
+
+// roomEvent is exchanged between the members and the host of a room.
+type roomEvent struct {
+	Op       roomOp        `json:"op"`
+	Room     string        `json:"room,omitempty"`
+	Member   cipher.PubKey `json:"member,omitempty"`
+	Message  *message      `json:"message,omitempty"`
+	Rooms    []roomInfo    `json:"rooms,omitempty"`
+	Messages []message     `json:"messages,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// roomInfo describes a room.
+type roomInfo struct {
+	Host    cipher.PubKey   `json:"host"`
+	ID      string          `json:"id"`
+	Name    string          `json:"name"`
+	Open    bool            `json:"open"`
+	Admins  []cipher.PubKey `json:"admins"`
+	Members []cipher.PubKey `json:"members"`
+}
+
+func (r roomInfo) isMember(pk cipher.PubKey) bool {
+	return containsPK(r.Members, pk)
+}
+
+func (r roomInfo) isAdmin(pk cipher.PubKey) bool {
+	return containsPK(r.Admins, pk)
+}
+
+// listedTo returns the room as listed to `pk`, the members of closed rooms are only listed to the members.
+func (r roomInfo) listedTo(pk cipher.PubKey) roomInfo {
+	if !r.Open && !r.isMember(pk) {
+		r.Admins, r.Members = nil, nil
+	}
+	return r
+}
+
+func containsPK(pks []cipher.PubKey, pk cipher.PubKey) bool {
+	for _, p := range pks {
+		if p == pk {
+			return true
+		}
+	}
+	return false
+}
+
+func removePK(pks []cipher.PubKey, pk cipher.PubKey) []cipher.PubKey {
+	out := pks[:0]
+	for _, p := range pks {
+		if p != pk {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// roomBucket returns the name of the bucket keeping the history of a room.
+func roomBucket(host cipher.PubKey, room string) []byte {
+	return []byte("room:" + host.Hex() + ":" + room)
+}
+
+func newRoomID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Errorf("failed to generate room ID: %w", err))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+var (
+	roomsMu sync.Mutex // serializes the changes of the hosted rooms
+
+	pendingMu sync.Mutex
+	pending   = make(map[string]pendingRoomRequest) // room requests waiting for the host reply, by packet ID
+)
+
+type pendingRoomRequest struct {
+	host cipher.PubKey
+	ch   chan roomEvent
+}
+
+func localPK() cipher.PubKey {
+	return appCl.Config().VisorPK
+}
+
+/*
+	<<< HOST SIDE >>>
+*/
+
+// hostedRoom gets the room hosted by this visor.
+func hostedRoom(id string) (r roomInfo, err error) {
+	err = store.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(roomsBucket))
+		if b == nil {
+			return errRoomNotFound
+		}
+		raw := b.Get([]byte(id))
+		if raw == nil {
+			return errRoomNotFound
+		}
+		return json.Unmarshal(raw, &r)
+	})
+	return r, err
+}
+
+func hostedRooms() ([]roomInfo, error) {
+	rooms := make([]roomInfo, 0)
+	err := store.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(roomsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, raw []byte) error {
+			var r roomInfo
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return err
+			}
+			rooms = append(rooms, r)
+			return nil
+		})
+	})
+	return rooms, err
+}
+
+func putRoom(bucket string, r roomInfo) error {
+	return store.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		key := r.ID
+		if bucket == joinedRoomsBucket {
+			key = r.Host.Hex() + ":" + r.ID
+		}
+		return b.Put([]byte(key), raw)
+	})
+}
+
+func deleteJoinedRoom(host cipher.PubKey, id string) error {
+	return store.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(joinedRoomsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(host.Hex() + ":" + id))
+	})
+}
+
+func joinedRooms() ([]roomInfo, error) {
+	rooms := make([]roomInfo, 0)
+	err := store.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(joinedRoomsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, raw []byte) error {
+			var r roomInfo
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return err
+			}
+			rooms = append(rooms, r)
+			return nil
+		})
+	})
+	return rooms, err
+}
+
+// createRoom creates a room hosted by this visor, which is its first admin and member.
+func createRoom(name string, open bool) (roomInfo, error) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	r := roomInfo{
+		Host:    localPK(),
+		ID:      newRoomID(),
+		Name:    name,
+		Open:    open,
+		Admins:  []cipher.PubKey{localPK()},
+		Members: []cipher.PubKey{localPK()},
+	}
+	if err := putRoom(roomsBucket, r); err != nil {
+		return roomInfo{}, err
+	}
+	return r, putRoom(joinedRoomsBucket, r)
+}
+
+// handleRoomRequest serves the request of the member `from` to a room hosted by this visor.
+func handleRoomRequest(from cipher.PubKey, ev roomEvent) roomEvent {
+	if ev.Op == roomOpList {
+		rooms, err := hostedRooms()
+		if err != nil {
+			return roomErrorEvent(err)
+		}
+		for i, r := range rooms {
+			rooms[i] = r.listedTo(from)
+		}
+		return roomEvent{Op: roomOpRooms, Rooms: rooms}
+	}
+
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	r, err := hostedRoom(ev.Room)
+	if err != nil {
+		return roomErrorEvent(err)
+	}
+
+	switch ev.Op {
+	case roomOpJoin:
+		if !r.Open && !r.isMember(from) {
+			return roomErrorEvent(errRoomClosed)
+		}
+		if !r.isMember(from) {
+			r.Members = append(r.Members, from)
+		}
+	case roomOpLeave:
+		if from == r.Host {
+			return roomErrorEvent(errors.New("the host can't leave its room"))
+		}
+		r.Members = removePK(r.Members, from)
+		r.Admins = removePK(r.Admins, from)
+	case roomOpSend:
+		if !r.isMember(from) {
+			return roomErrorEvent(errNotMember)
+		}
+		if ev.Message == nil {
+			return roomErrorEvent(errors.New("no message"))
+		}
+		// the ID is assigned by the host, members can't overwrite the messages of others
+		m := *ev.Message
+		m.ID, m.ClientID = newMessageID(), ev.Message.ID
+		m.Peer, m.Room, m.Sender = r.Host, r.ID, from.Hex()
+		m.Outgoing = false
+		m.Status = statusReceived
+		m.Timestamp = time.Now()
+		if err := putRoomMessage(m); err != nil {
+			return roomErrorEvent(err)
+		}
+		go fanOut(r, from, m)
+		return roomEvent{Op: roomOpOK, Message: &m}
+	case roomOpHistory:
+		if !r.isMember(from) {
+			return roomErrorEvent(errNotMember)
+		}
+		msgs, err := roomHistory(r.Host, r.ID)
+		if err != nil {
+			return roomErrorEvent(err)
+		}
+		if len(msgs) > roomHistorySize {
+			msgs = msgs[len(msgs)-roomHistorySize:]
+		}
+		return roomEvent{Op: roomOpMessages, Messages: msgs}
+	case roomOpAdd, roomOpRemove, roomOpPromote:
+		if !r.isAdmin(from) {
+			return roomErrorEvent(errNotAdmin)
+		}
+		switch ev.Op {
+		case roomOpAdd:
+			if !r.isMember(ev.Member) {
+				r.Members = append(r.Members, ev.Member)
+			}
+		case roomOpRemove:
+			if ev.Member == r.Host {
+				return roomErrorEvent(errors.New("the host can't be removed"))
+			}
+			r.Members = removePK(r.Members, ev.Member)
+			r.Admins = removePK(r.Admins, ev.Member)
+		case roomOpPromote:
+			if !r.isMember(ev.Member) {
+				return roomErrorEvent(errNotMember)
+			}
+			if !r.isAdmin(ev.Member) {
+				r.Admins = append(r.Admins, ev.Member)
+			}
+		}
+	default:
+		return roomErrorEvent(fmt.Errorf("unknown room operation %q", ev.Op))
+	}
+
+	if err := putRoom(roomsBucket, r); err != nil {
+		return roomErrorEvent(err)
+	}
+	switch ev.Op {
+	case roomOpAdd:
+		go notifyMember(ev.Member, roomEvent{Op: roomOpAdded, Rooms: []roomInfo{r}})
+	case roomOpRemove:
+		go notifyMember(ev.Member, roomEvent{Op: roomOpRemoved, Room: r.ID})
+	}
+
+	return roomEvent{Op: roomOpInfo, Rooms: []roomInfo{r}}
+}
+
+func roomErrorEvent(err error) roomEvent {
+	return roomEvent{Op: roomOpError, Error: err.Error()}
+}
+
+// fanOut sends the message `m` of `sender` to the other members of the room.
+func fanOut(r roomInfo, sender cipher.PubKey, m message) {
+	for _, member := range r.Members {
+		if member == sender {
+			continue
+		}
+		notifyMember(member, roomEvent{Op: roomOpMessage, Room: r.ID, Message: &m})
+	}
+}
+
+// notifyMember sends the event of a hosted room to `member`, offline members fetch the history later.
+func notifyMember(member cipher.PubKey, ev roomEvent) {
+	if member == localPK() {
+		handleRoomNotification(member, ev)
+		return
+	}
+
+	conn, err := getConn(context.Background(), member, false)
+	if err != nil {
+		fmt.Printf("Room member %s is offline: %v\n", member, err)
+		return
+	}
+	if isLegacyPeer(member) {
+		return
+	}
+	if err := writeRoomEvent(conn, newMessageID(), ev); err != nil {
+		print(fmt.Sprintf("Failed to send room event to %s: %v\n", member, err))
+	}
+}
+
+/*
+	<<< MEMBER SIDE >>>
+*/
+
+// handleRoomPacket handles a room event received from `from`.
+func handleRoomPacket(conn net.Conn, from cipher.PubKey, p packet) {
+	var ev roomEvent
+	if err := json.Unmarshal(p.Body, &ev); err != nil {
+		print(fmt.Sprintf("Failed to decode room event from %s: %v\n", from, err))
+		return
+	}
+
+	switch {
+	case ev.Op.isReply():
+		// the request is done on the first reply, so that duplicates never block the read loop
+		pendingMu.Lock()
+		req, ok := pending[p.ID]
+		if ok && req.host == from {
+			delete(pending, p.ID)
+		}
+		pendingMu.Unlock()
+		if !ok || req.host != from {
+			fmt.Printf("Got unexpected room reply %s from %s\n", p.ID, from)
+			return
+		}
+		req.ch <- ev
+	case ev.Op == roomOpMessage || ev.Op == roomOpAdded || ev.Op == roomOpRemoved:
+		handleRoomNotification(from, ev)
+	default:
+		if err := writeRoomEvent(conn, p.ID, handleRoomRequest(from, ev)); err != nil {
+			print(fmt.Sprintf("Failed to reply to room request of %s: %v\n", from, err))
+		}
+	}
+}
+
+// handleRoomNotification handles the notification of the room host `host`.
+func handleRoomNotification(host cipher.PubKey, ev roomEvent) {
+	switch ev.Op {
+	case roomOpMessage:
+		if ev.Message == nil || ev.Message.Peer != host {
+			return
+		}
+		m := *ev.Message
+		if host != localPK() {
+			if err := putRoomMessage(m); err != nil {
+				print(fmt.Sprintf("Failed to store room message: %v\n", err))
+			}
+		}
+		sendRoomMessageToUI(m)
+	case roomOpAdded:
+		for _, r := range ev.Rooms {
+			if r.Host != host {
+				continue
+			}
+			if err := putRoom(joinedRoomsBucket, r); err != nil {
+				print(fmt.Sprintf("Failed to store joined room: %v\n", err))
+			}
+		}
+	case roomOpRemoved:
+		if err := deleteJoinedRoom(host, ev.Room); err != nil {
+			print(fmt.Sprintf("Failed to remove joined room: %v\n", err))
+		}
+	}
+}
+
+func sendRoomMessageToUI(m message) {
+	clientMsg, err := json.Marshal(m)
+	if err != nil {
+		print(fmt.Sprintf("Failed to marshal json: %v\n", err))
+		return
+	}
+	select {
+	case clientCh <- uiEvent{name: "room", data: string(clientMsg)}:
+	default:
+	}
+}
+
+func writeRoomEvent(conn net.Conn, id string, ev roomEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return writePacket(conn, packet{
+		Type:        packetRoom,
+		ID:          id,
+		Timestamp:   time.Now(),
+		ContentType: contentTypeJSON,
+		Body:        body,
+	})
+}
+
+// roomRequest sends the request `ev` to the room host and waits for the reply.
+func roomRequest(ctx context.Context, host cipher.PubKey, ev roomEvent) (roomEvent, error) {
+	var reply roomEvent
+	if host == localPK() {
+		reply = handleRoomRequest(host, ev)
+	} else {
+		conn, err := getConn(ctx, host, true)
+		if err != nil {
+			return roomEvent{}, err
+		}
+		if isLegacyPeer(host) {
+			return roomEvent{}, errLegacyRooms
+		}
+
+		id := newMessageID()
+		ch := make(chan roomEvent, 1)
+		pendingMu.Lock()
+		pending[id] = pendingRoomRequest{host: host, ch: ch}
+		pendingMu.Unlock()
+		defer func() {
+			pendingMu.Lock()
+			delete(pending, id)
+			pendingMu.Unlock()
+		}()
+
+		if err := writeRoomEvent(conn, id, ev); err != nil {
+			return roomEvent{}, err
+		}
+
+		select {
+		case reply = <-ch:
+		case <-time.After(roomRequestTimeout):
+			return roomEvent{}, errors.New("room host did not reply")
+		case <-ctx.Done():
+			return roomEvent{}, ctx.Err()
+		}
+	}
+
+	if reply.Op == roomOpError {
+		return reply, errors.New(reply.Error)
+	}
+	return reply, nil
+}
+
+func putRoomMessage(m message) error {
+	return store.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(roomBucket(m.Peer, m.Room))
+		if err != nil {
+			return err
+		}
+		raw, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(m.ID), raw)
+	})
+}
+
+func roomHistory(host cipher.PubKey, room string) ([]message, error) {
+	return store.bucketMessages(roomBucket(host, room))
+}
+
+/*
+	<<< HTTP HANDLERS >>>
+*/
+
+type roomRequestBody struct {
+	Host        cipher.PubKey `json:"host"`
+	Room        string        `json:"room"`
+	Name        string        `json:"name"`
+	Open        bool          `json:"open"`
+	Member      cipher.PubKey `json:"member"`
+	Op          roomOp        `json:"op"`
+	Message     string        `json:"message"`
+	ContentType string        `json:"content_type"`
+}
+
+// roomsHandler lists the rooms of the host `host` on GET and creates a room hosted by this visor on POST.
+func roomsHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			host := localPK()
+			if q := req.URL.Query().Get("host"); q != "" {
+				if err := host.UnmarshalText([]byte(q)); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			reply, err := roomRequest(ctx, host, roomEvent{Op: roomOpList})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, reply.Rooms)
+		case http.MethodPost:
+			var body roomRequestBody
+			if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r, err := createRoom(body.Name, body.Open)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// joinedRoomsHandler lists the rooms this visor is a member of.
+func joinedRoomsHandler(w http.ResponseWriter, _ *http.Request) {
+	rooms, err := joinedRooms()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, rooms)
+}
+
+// roomOpHandler sends the request of operation `op` to the room host. If more operations are
+// given, the operation is taken from the request body.
+func roomOpHandler(ctx context.Context, ops ...roomOp) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body roomRequestBody
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if body.Host.Null() {
+			body.Host = localPK()
+		}
+
+		op := ops[0]
+		if len(ops) > 1 {
+			op = ""
+			for _, o := range ops {
+				if o == body.Op {
+					op = o
+				}
+			}
+			if op == "" {
+				http.Error(w, fmt.Sprintf("unknown operation %q", body.Op), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ev := roomEvent{Op: op, Room: body.Room, Member: body.Member}
+		switch op {
+		case roomOpSend:
+			m := message{
+				ID:          newMessageID(),
+				Peer:        body.Host,
+				Room:        body.Room,
+				Sender:      localPK().Hex(),
+				Outgoing:    true,
+				Body:        body.Message,
+				ContentType: body.ContentType,
+				Timestamp:   time.Now(),
+				Status:      statusSent,
+			}
+			if m.ContentType == "" {
+				m.ContentType = contentTypeText
+			}
+			if _, err := encodeBody(m.ContentType, m.Body); err != nil {
+				http.Error(w, fmt.Sprintf("invalid base64 body: %v", err), http.StatusBadRequest)
+				return
+			}
+			ev.Message = &m
+		}
+
+		reply, err := roomRequest(ctx, body.Host, ev)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		switch op {
+		case roomOpJoin:
+			for _, r := range reply.Rooms {
+				if err := putRoom(joinedRoomsBucket, r); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+		case roomOpLeave:
+			if err := deleteJoinedRoom(body.Host, body.Room); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		case roomOpSend:
+			if reply.Message == nil || reply.Message.ClientID != ev.Message.ID {
+				http.Error(w, "room host did not return the message ID", http.StatusBadGateway)
+				return
+			}
+			ev.Message.ID, ev.Message.Timestamp = reply.Message.ID, reply.Message.Timestamp
+			if body.Host != localPK() {
+				if err := putRoomMessage(*ev.Message); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+			writeJSON(w, ev.Message)
+			return
+		}
+
+		writeJSON(w, reply)
+	}
+}
+
+// roomHistoryHandler returns the latest messages of a room, fetched from the host if it's online.
+func roomHistoryHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var host cipher.PubKey
+		if err := host.UnmarshalText([]byte(req.URL.Query().Get("host"))); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		room := req.URL.Query().Get("room")
+
+		if host != localPK() {
+			reply, err := roomRequest(ctx, host, roomEvent{Op: roomOpHistory, Room: room})
+			if err != nil {
+				fmt.Printf("Failed to fetch history of room %s from %s, using the local one: %v\n", room, host, err)
+			}
+			for _, m := range reply.Messages {
+				if m.Peer != host || m.Room != room {
+					continue
+				}
+				if err := putRoomMessage(m); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+
+		msgs, err := roomHistory(host, room)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, msgs)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		print(fmt.Sprintf("Failed to write response: %v\n", err))
+	}
+}
@@ -0,0 +1,139 @@
+// Package commands cmd/apps/skychat/rooms_test.go
+package commands
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+func TestHandleRoomRequest_list(t *testing.T) {
+	store = openTestStore(t)
+	host, _ := cipher.GenerateKeyPair()
+	member, _ := cipher.GenerateKeyPair()
+	stranger, _ := cipher.GenerateKeyPair()
+
+	open := roomInfo{Host: host, ID: "open", Open: true, Admins: []cipher.PubKey{host}, Members: []cipher.PubKey{host, member}}
+	closed := roomInfo{Host: host, ID: "closed", Admins: []cipher.PubKey{host}, Members: []cipher.PubKey{host, member}}
+	require.NoError(t, putRoom(roomsBucket, open))
+	require.NoError(t, putRoom(roomsBucket, closed))
+
+	rooms := func(from cipher.PubKey) map[string]roomInfo {
+		ev := handleRoomRequest(from, roomEvent{Op: roomOpList})
+		require.Equal(t, roomOpRooms, ev.Op)
+		out := make(map[string]roomInfo)
+		for _, r := range ev.Rooms {
+			out[r.ID] = r
+		}
+		return out
+	}
+
+	got := rooms(member)
+	require.Equal(t, open, got["open"])
+	require.Equal(t, closed, got["closed"])
+
+	got = rooms(stranger)
+	require.Equal(t, open, got["open"])
+	require.Empty(t, got["closed"].Members)
+	require.Empty(t, got["closed"].Admins)
+	require.Equal(t, closed.ID, got["closed"].ID)
+}
+
+func TestHandleRoomRequest_membership(t *testing.T) {
+	store = openTestStore(t)
+	host, _ := cipher.GenerateKeyPair()
+	member, _ := cipher.GenerateKeyPair()
+
+	r := roomInfo{Host: host, ID: "room", Admins: []cipher.PubKey{host}, Members: []cipher.PubKey{host}}
+	require.NoError(t, putRoom(roomsBucket, r))
+
+	ev := handleRoomRequest(member, roomEvent{Op: roomOpJoin, Room: r.ID})
+	require.Equal(t, roomOpError, ev.Op)
+	require.Equal(t, errRoomClosed.Error(), ev.Error)
+
+	ev = handleRoomRequest(member, roomEvent{Op: roomOpHistory, Room: r.ID})
+	require.Equal(t, errNotMember.Error(), ev.Error)
+
+	ev = handleRoomRequest(member, roomEvent{Op: roomOpPromote, Room: r.ID, Member: member})
+	require.Equal(t, errNotAdmin.Error(), ev.Error)
+
+	ev = handleRoomRequest(host, roomEvent{Op: roomOpLeave, Room: r.ID})
+	require.Equal(t, roomOpError, ev.Op)
+
+	ev = handleRoomRequest(member, roomEvent{Op: roomOpJoin, Room: "unknown"})
+	require.Equal(t, errRoomNotFound.Error(), ev.Error)
+}
+
+func TestHandleRoomRequest_send(t *testing.T) {
+	store = openTestStore(t)
+	host, _ := cipher.GenerateKeyPair()
+	member, _ := cipher.GenerateKeyPair()
+
+	r := roomInfo{Host: host, ID: "room", Admins: []cipher.PubKey{host}, Members: []cipher.PubKey{host}}
+	require.NoError(t, putRoom(roomsBucket, r))
+
+	ev := handleRoomRequest(member, roomEvent{Op: roomOpSend, Room: r.ID, Message: &message{ID: "id", Body: "hi"}})
+	require.Equal(t, errNotMember.Error(), ev.Error)
+
+	// a member reusing the ID of another message doesn't overwrite it
+	ids := make(map[string]bool)
+	for _, body := range []string{"first", "second"} {
+		ev = handleRoomRequest(host, roomEvent{Op: roomOpSend, Room: r.ID, Message: &message{ID: "id", Body: body}})
+		require.Equal(t, roomOpOK, ev.Op)
+		require.NotNil(t, ev.Message)
+		require.NotEqual(t, "id", ev.Message.ID)
+		require.Equal(t, "id", ev.Message.ClientID)
+		ids[ev.Message.ID] = true
+	}
+	require.Len(t, ids, 2)
+
+	msgs, err := roomHistory(host, r.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, "first", msgs[0].Body)
+	require.Equal(t, "second", msgs[1].Body)
+}
+
+func TestHandleRoomPacket_reply(t *testing.T) {
+	host, _ := cipher.GenerateKeyPair()
+	other, _ := cipher.GenerateKeyPair()
+
+	id := newMessageID()
+	ch := make(chan roomEvent, 1)
+	pendingMu.Lock()
+	pending[id] = pendingRoomRequest{host: host, ch: ch}
+	pendingMu.Unlock()
+
+	body, err := json.Marshal(roomEvent{Op: roomOpOK})
+	require.NoError(t, err)
+	p := packet{Type: packetRoom, ID: id, ContentType: contentTypeJSON, Body: body}
+
+	conn, _ := net.Pipe()
+
+	// replies of other peers are ignored
+	handleRoomPacket(conn, other, p)
+	require.Len(t, ch, 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handleRoomPacket(conn, host, p)
+		// a duplicate reply does not block
+		handleRoomPacket(conn, host, p)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handling a duplicate room reply blocked")
+	}
+
+	require.Equal(t, roomOpOK, (<-ch).Op)
+	pendingMu.Lock()
+	require.NotContains(t, pending, id)
+	pendingMu.Unlock()
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"time"
@@ -32,6 +33,17 @@ import (
 const (
 	netType = appnet.TypeSkynet
 	port    = routing.Port(1)
+	// queueRetryInterval is the interval of retrying to send queued messages to offline peers.
+	queueRetryInterval = 30 * time.Second
+	// ackTimeout is the time after which a sent message with no delivery receipt is sent again.
+	ackTimeout = 2 * time.Minute
+)
+
+var (
+	// helloTimeout is how long the dialing peer waits for the hello, the peers which don't send it are treated as legacy.
+	helloTimeout = 10 * time.Second
+	// helloDelay is how long the accepting peer waits for the raw text of a legacy peer before sending the hello.
+	helloDelay = 2 * time.Second
 )
 
 // var addr = flag.String("addr", ":8001", "address to bind, put an * before the port if you want to be able to access outside localhost")
@@ -40,9 +52,15 @@ var r = netutil.NewRetrier(nil, 50*time.Millisecond, netutil.DefaultMaxBackoff,
 var (
 	addr     string
 	appCl    *app.Client
-	clientCh chan string
+	clientCh chan uiEvent
 	conns    map[cipher.PubKey]net.Conn // Chat connections
 	connsMu  sync.Mutex
+	store    *messageStore
+	flushMu  sync.Mutex // ensures queued messages are sent once
+	// peers running older skychat versions, they send and receive raw text only - protected by 'connsMu'
+	legacyPeers map[cipher.PubKey]struct{}
+	// protocol version negotiations of the chat connections - protected by 'connsMu'
+	negotiations map[net.Conn]*negotiation
 )
 
 // the go embed static points to skywire/cmd/apps/skychat/static
@@ -78,10 +96,25 @@ var RootCmd = &cobra.Command{
 
 		fmt.Println("Successfully started skychat.")
 
-		clientCh = make(chan string)
+		var err error
+		store, err = openMessageStore(filepath.Join(appCl.Config().ProcWorkDir, historyFileName))
+		if err != nil {
+			print(fmt.Sprintf("Error opening message store: %v\n", err))
+			setAppError(appCl, err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := store.Close(); err != nil {
+				print(fmt.Sprintf("Error closing message store: %v\n", err))
+			}
+		}()
+
+		clientCh = make(chan uiEvent)
 		defer close(clientCh)
 
 		conns = make(map[cipher.PubKey]net.Conn)
+		legacyPeers = make(map[cipher.PubKey]struct{})
+		negotiations = make(map[net.Conn]*negotiation)
 		go listenLoop()
 
 		if runtime.GOOS == "windows" {
@@ -96,8 +129,18 @@ var RootCmd = &cobra.Command{
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 
+		go retryQueueLoop(ctx)
+
 		http.Handle("/", http.FileServer(getFileSystem()))
 		http.HandleFunc("/message", messageHandler(ctx))
+		http.HandleFunc("/history", historyHandler)
+		http.HandleFunc("/rooms", roomsHandler(ctx))
+		http.HandleFunc("/rooms/joined", joinedRoomsHandler)
+		http.HandleFunc("/rooms/join", roomOpHandler(ctx, roomOpJoin))
+		http.HandleFunc("/rooms/leave", roomOpHandler(ctx, roomOpLeave))
+		http.HandleFunc("/rooms/members", roomOpHandler(ctx, roomOpAdd, roomOpRemove, roomOpPromote))
+		http.HandleFunc("/rooms/message", roomOpHandler(ctx, roomOpSend))
+		http.HandleFunc("/rooms/history", roomHistoryHandler(ctx))
 		http.HandleFunc("/sse", sseHandler)
 
 		url := ""
@@ -131,7 +174,7 @@ var RootCmd = &cobra.Command{
 			ReadTimeout:  5 * time.Second,
 			WriteTimeout: 10 * time.Second,
 		}
-		err := srv.ListenAndServe()
+		err = srv.ListenAndServe()
 		if err != nil {
 			print(err.Error())
 			setAppError(appCl, err)
@@ -168,38 +211,349 @@ func listenLoop() {
 		fmt.Println("Accepted skychat conn")
 
 		raddr := conn.RemoteAddr().(appnet.Addr)
-		connsMu.Lock()
-		conns[raddr.PubKey] = conn
-		connsMu.Unlock()
+		addConn(raddr.PubKey, conn)
+		go announceHello(conn, raddr.PubKey)
 		fmt.Printf("Accepted skychat conn on %s from %s\n", conn.LocalAddr(), raddr.PubKey)
-
-		go handleConn(conn)
 	}
 }
 
 func handleConn(conn net.Conn) {
 	raddr := conn.RemoteAddr().(appnet.Addr)
+
+	// the peer is online, so the messages queued for it may be sent right away
+	go flushQueue(context.Background(), &raddr.PubKey)
+
+	pr := newPacketReader(conn)
 	for {
-		buf := make([]byte, 32*1024)
-		n, err := conn.Read(buf)
+		p, legacy, err := pr.readPacket()
 		if err != nil {
 			fmt.Println("Failed to read packet:", err)
 			raddr := conn.RemoteAddr().(appnet.Addr)
 			connsMu.Lock()
-			delete(conns, raddr.PubKey)
+			if conns[raddr.PubKey] == conn {
+				delete(conns, raddr.PubKey)
+			}
+			if n, ok := negotiations[conn]; ok {
+				n.finish()
+				delete(negotiations, conn)
+			}
 			connsMu.Unlock()
 			return
 		}
 
-		clientMsg, err := json.Marshal(map[string]string{"sender": raddr.PubKey.Hex(), "message": string(buf[:n])})
-		if err != nil {
-			print(fmt.Sprintf("Failed to marshal json: %v\n", err))
+		setVersion(conn, raddr.PubKey, legacy)
+		handlePacket(conn, raddr.PubKey, p, legacy)
+	}
+}
+
+func handlePacket(conn net.Conn, pk cipher.PubKey, p packet, legacy bool) {
+	switch p.Type {
+	case packetHello:
+		sendHello(conn)
+	case packetDelivered:
+		updateStatus(pk, p.ID, statusDelivered)
+	case packetRead:
+		updateStatus(pk, p.ID, statusRead)
+	case packetMessage:
+		if store.has(pk, p.ID) {
+			// the peer resent a message it got no receipt for
+			sendReceipt(conn, packetDelivered, p.ID)
+			return
+		}
+
+		m := message{
+			ID:          p.ID,
+			Peer:        pk,
+			Body:        decodeBody(p.ContentType, p.Body),
+			ContentType: p.ContentType,
+			Timestamp:   p.Timestamp,
+			Status:      statusReceived,
+			Legacy:      legacy,
+		}
+		if m.Timestamp.IsZero() {
+			m.Timestamp = time.Now()
+		}
+		if err := store.put(m); err != nil {
+			print(fmt.Sprintf("Failed to store message from %s: %v\n", pk, err))
+		} else if !legacy {
+			sendReceipt(conn, packetDelivered, m.ID)
+		}
+
+		if sendToUI(m) {
+			updateStatus(pk, m.ID, statusRead)
+			if !legacy {
+				sendReceipt(conn, packetRead, m.ID)
+			}
+		}
+	case packetRoom:
+		if legacy {
+			return
 		}
+		handleRoomPacket(conn, pk, p)
+	default:
+		fmt.Printf("Received packet of unknown type %s from %s\n", p.Type, pk)
+	}
+}
+
+// sendToUI passes the incoming message to the UI. Returns false if the UI is not attached.
+func sendToUI(m message) bool {
+	clientMsg, err := json.Marshal(map[string]string{
+		"sender":       m.Peer.Hex(),
+		"message":      m.Body,
+		"content_type": m.ContentType,
+		"id":           m.ID,
+		"timestamp":    m.Timestamp.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		print(fmt.Sprintf("Failed to marshal json: %v\n", err))
+	}
+	select {
+	case clientCh <- uiEvent{data: string(clientMsg)}:
+		fmt.Printf("Received and sent to ui: %s\n", clientMsg)
+		return true
+	default:
+		fmt.Printf("Received and stored: %s\n", clientMsg)
+		return false
+	}
+}
+
+func updateStatus(pk cipher.PubKey, id string, status messageStatus) {
+	if _, ok, err := store.setStatus(pk, id, status); err != nil {
+		print(fmt.Sprintf("Failed to set status of message %s to %s: %v\n", id, status, err))
+	} else if !ok {
+		fmt.Printf("Got %s receipt of unknown message %s from %s\n", status, id, pk)
+	}
+}
+
+func sendReceipt(conn net.Conn, t packetType, id string) {
+	if err := writePacket(conn, packet{Type: t, ID: id, Timestamp: time.Now()}); err != nil {
+		print(fmt.Sprintf("Failed to send %s receipt of message %s: %v\n", t, id, err))
+	}
+}
+
+// getConn returns the conn to `pk`, dialing the peer if there is none. It returns once the protocol
+// version of the peer is known.
+func getConn(ctx context.Context, pk cipher.PubKey, retry bool) (net.Conn, error) {
+	connsMu.Lock()
+	conn, ok := conns[pk]
+	connsMu.Unlock()
+	if ok {
+		return conn, waitVersion(ctx, conn, pk)
+	}
+
+	addr := appnet.Addr{
+		Net:    netType,
+		PubKey: pk,
+		Port:   port,
+	}
+
+	var err error
+	if retry {
+		err = r.Do(ctx, func() error {
+			conn, err = appCl.Dial(addr)
+			return err
+		})
+	} else {
+		conn, err = appCl.Dial(addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	addConn(pk, conn)
+
+	return conn, waitVersion(ctx, conn, pk)
+}
+
+// negotiation tells the protocol version of a conn. Older peers send raw text as soon as they dial, so the
+// accepting peer sends a hello frame unless it got raw text first, and the dialing peer answers it. The dialing
+// peer sends nothing before the hello, the peers which don't send it are older ones and never get frames.
+type negotiation struct {
+	done  chan struct{}
+	once  sync.Once
+	hello sync.Once
+}
+
+func (n *negotiation) finish() {
+	n.once.Do(func() { close(n.done) })
+}
+
+// addConn stores the conn to `pk` and starts reading from it.
+func addConn(pk cipher.PubKey, conn net.Conn) {
+	connsMu.Lock()
+	conns[pk] = conn
+	negotiations[conn] = &negotiation{done: make(chan struct{})}
+	connsMu.Unlock()
+
+	go handleConn(conn)
+}
+
+func getNegotiation(conn net.Conn) *negotiation {
+	connsMu.Lock()
+	defer connsMu.Unlock()
+	return negotiations[conn]
+}
+
+// sendHello sends the hello frame over `conn` unless it was sent already.
+func sendHello(conn net.Conn) {
+	n := getNegotiation(conn)
+	if n == nil {
+		return
+	}
+	n.hello.Do(func() {
+		if err := writePacket(conn, packet{Type: packetHello, ID: newMessageID(), Timestamp: time.Now()}); err != nil {
+			print(fmt.Sprintf("Failed to send hello: %v\n", err))
+		}
+	})
+}
+
+// announceHello sends the hello over the accepted `conn` unless the peer `pk` sent raw text in the meantime.
+func announceHello(conn net.Conn, pk cipher.PubKey) {
+	n := getNegotiation(conn)
+	if n == nil {
+		return
+	}
+
+	timer := time.NewTimer(helloDelay)
+	defer timer.Stop()
+
+	select {
+	case <-n.done:
+		if isLegacyPeer(pk) {
+			return
+		}
+	case <-timer.C:
+	}
+	sendHello(conn)
+}
+
+// setVersion records whether the peer `pk` of `conn` is legacy.
+func setVersion(conn net.Conn, pk cipher.PubKey, legacy bool) {
+	connsMu.Lock()
+	defer connsMu.Unlock()
+	if legacy {
+		legacyPeers[pk] = struct{}{}
+	} else {
+		delete(legacyPeers, pk)
+	}
+	if n, ok := negotiations[conn]; ok {
+		n.finish()
+	}
+}
+
+// waitVersion waits until the protocol version of the peer `pk` of `conn` is known. Peers which don't
+// send the hello in time are treated as legacy.
+func waitVersion(ctx context.Context, conn net.Conn, pk cipher.PubKey) error {
+	n := getNegotiation(conn)
+	if n == nil {
+		// the conn is closed, writing to it fails
+		return nil
+	}
+
+	timer := time.NewTimer(helloTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-n.done:
+	case <-timer.C:
+		fmt.Printf("No hello from %s, treating it as a legacy peer\n", pk)
+		setVersion(conn, pk, true)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// deliver sends the outgoing message `m` and marks it as sent.
+func deliver(ctx context.Context, m message, retry bool) (message, error) {
+	conn, err := getConn(ctx, m.Peer, retry)
+	if err != nil {
+		return m, err
+	}
+
+	body, err := encodeBody(m.ContentType, m.Body)
+	if err != nil {
+		return m, err
+	}
+
+	legacy := isLegacyPeer(m.Peer)
+	if legacy {
+		if !isText(m.ContentType) {
+			return m, errLegacyContentType
+		}
+		_, err = conn.Write(body)
+	} else {
+		err = writePacket(conn, packet{
+			Type:        packetMessage,
+			ID:          m.ID,
+			Timestamp:   m.Timestamp,
+			ContentType: m.ContentType,
+			Body:        body,
+		})
+	}
+	if err != nil {
+		connsMu.Lock()
+		if conns[m.Peer] == conn {
+			delete(conns, m.Peer)
+		}
+		connsMu.Unlock()
+
+		return m, err
+	}
+
+	// legacy peers send no receipts, so their messages are not resent
+	var sentAt time.Time
+	if !legacy {
+		sentAt = time.Now()
+	}
+	return store.markSent(m.Peer, m.ID, sentAt)
+}
+
+func isLegacyPeer(pk cipher.PubKey) bool {
+	connsMu.Lock()
+	defer connsMu.Unlock()
+	_, ok := legacyPeers[pk]
+	return ok
+}
+
+// flushQueue sends the queued messages and resends the unacknowledged ones, only the ones of `peer` if it's not nil.
+func flushQueue(ctx context.Context, peer *cipher.PubKey) {
+	flushMu.Lock()
+	defer flushMu.Unlock()
+
+	msgs, err := store.pending(time.Now().Add(-ackTimeout))
+	if err != nil {
+		print(fmt.Sprintf("Failed to get queued messages: %v\n", err))
+		return
+	}
+
+	offline := make(map[cipher.PubKey]struct{})
+	for _, m := range msgs {
+		if peer != nil && m.Peer != *peer {
+			continue
+		}
+		if _, ok := offline[m.Peer]; ok {
+			continue
+		}
+		if _, err := deliver(ctx, m, false); err != nil {
+			// keep the order of the messages, the rest is sent once the peer is back
+			offline[m.Peer] = struct{}{}
+			continue
+		}
+		fmt.Printf("Sent queued message %s to %s\n", m.ID, m.Peer)
+	}
+}
+
+func retryQueueLoop(ctx context.Context) {
+	ticker := time.NewTicker(queueRetryInterval)
+	defer ticker.Stop()
+
+	for {
 		select {
-		case clientCh <- string(clientMsg):
-			fmt.Printf("Received and sent to ui: %s\n", clientMsg)
-		default:
-			fmt.Printf("Received and trashed: %s\n", clientMsg)
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			flushQueue(ctx, nil)
 		}
 	}
 }
@@ -219,44 +573,85 @@ func messageHandler(ctx context.Context) func(w http.ResponseWriter, rreq *http.
 			return
 		}
 
-		addr := appnet.Addr{
-			Net:    netType,
-			PubKey: pk,
-			Port:   1,
+		m := message{
+			ID:          newMessageID(),
+			Peer:        pk,
+			Outgoing:    true,
+			Body:        data["message"],
+			ContentType: data["content_type"],
+			Timestamp:   time.Now(),
+			Status:      statusQueued,
+		}
+		if m.ContentType == "" {
+			m.ContentType = contentTypeText
+		}
+		body, err := encodeBody(m.ContentType, m.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid base64 body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxMessageSize {
+			http.Error(w, errMessageTooBig.Error(), http.StatusBadRequest)
+			return
+		}
+		if !isText(m.ContentType) && isLegacyPeer(pk) {
+			http.Error(w, errLegacyContentType.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := store.put(m); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-		connsMu.Lock()
-		conn, ok := conns[pk]
-		connsMu.Unlock()
-
-		if !ok {
-			var err error
-			err = r.Do(ctx, func() error {
-				conn, err = appCl.Dial(addr)
-				return err
-			})
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
 
-			connsMu.Lock()
-			conns[pk] = conn
-			connsMu.Unlock()
+		sent, err := deliver(ctx, m, true)
+		if err != nil {
+			fmt.Printf("Queued message %s to offline peer %s: %v\n", m.ID, pk, err)
+		}
 
-			go handleConn(conn)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(sent); err != nil {
+			print(fmt.Sprintf("Failed to write response: %v\n", err))
 		}
+	}
+}
 
-		_, err := conn.Write([]byte(data["message"]))
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+// historyHandler returns the messages exchanged with the peer `pk`, the incoming ones are marked as read.
+func historyHandler(w http.ResponseWriter, req *http.Request) {
+	pk := cipher.PubKey{}
+	if err := pk.UnmarshalText([]byte(req.URL.Query().Get("pk"))); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-			connsMu.Lock()
-			delete(conns, pk)
-			connsMu.Unlock()
+	msgs, err := store.history(pk)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-			return
+	connsMu.Lock()
+	conn, online := conns[pk]
+	connsMu.Unlock()
+	for _, m := range msgs {
+		if m.Outgoing || m.Status != statusReceived {
+			continue
+		}
+		updateStatus(pk, m.ID, statusRead)
+		if online && !m.Legacy {
+			sendReceipt(conn, packetRead, m.ID)
 		}
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(msgs); err != nil {
+		print(fmt.Sprintf("Failed to write response: %v\n", err))
+	}
+}
+
+// uiEvent is an event sent to the UI, the events without name are the incoming 1:1 messages.
+type uiEvent struct {
+	name string
+	data string
 }
 
 func sseHandler(w http.ResponseWriter, req *http.Request) {
@@ -277,7 +672,10 @@ func sseHandler(w http.ResponseWriter, req *http.Request) {
 			if !ok {
 				return
 			}
-			_, _ = fmt.Fprintf(w, "data: %s\n\n", msg)
+			if msg.name != "" {
+				_, _ = fmt.Fprintf(w, "event: %s\n", msg.name)
+			}
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", msg.data)
 			f.Flush()
 
 		case <-req.Context().Done():
@@ -0,0 +1,79 @@
+// Package commands cmd/apps/skychat/skychat_test.go
+package commands
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/app/appnet"
+)
+
+// pipeConn is a net.Pipe conn to the peer `remote`.
+type pipeConn struct {
+	net.Conn
+	remote appnet.Addr
+}
+
+func (c pipeConn) RemoteAddr() net.Addr { return c.remote }
+
+// legacyPeerConn returns the conn to a legacy peer `pk`, which is read from the returned one.
+func legacyPeerConn(t *testing.T, pk cipher.PubKey) (net.Conn, net.Conn) {
+	store = openTestStore(t)
+	conns = make(map[cipher.PubKey]net.Conn)
+	legacyPeers = make(map[cipher.PubKey]struct{})
+	negotiations = make(map[net.Conn]*negotiation)
+
+	local, remote := net.Pipe()
+	t.Cleanup(func() {
+		require.NoError(t, remote.Close())
+		require.NoError(t, local.Close())
+	})
+	return pipeConn{Conn: local, remote: appnet.Addr{Net: netType, PubKey: pk, Port: port}}, remote
+}
+
+func setHelloTimeouts(t *testing.T, timeout, delay time.Duration) {
+	oldTimeout, oldDelay := helloTimeout, helloDelay
+	helloTimeout, helloDelay = timeout, delay
+	t.Cleanup(func() { helloTimeout, helloDelay = oldTimeout, oldDelay })
+}
+
+func TestNegotiation_legacyAcceptor(t *testing.T) {
+	setHelloTimeouts(t, 100*time.Millisecond, 100*time.Millisecond)
+	pk, _ := cipher.GenerateKeyPair()
+	conn, legacy := legacyPeerConn(t, pk)
+
+	m := message{ID: newMessageID(), Peer: pk, Outgoing: true, Body: "hi", ContentType: contentTypeText, Timestamp: time.Now(), Status: statusQueued}
+	require.NoError(t, store.put(m))
+
+	// the dialing peer sends the queued message once the hello timed out, and nothing before it
+	addConn(pk, conn)
+
+	buf := make([]byte, 64)
+	require.NoError(t, legacy.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, err := legacy.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "hi", string(buf[:n]))
+	require.True(t, isLegacyPeer(pk))
+}
+
+func TestNegotiation_legacyDialer(t *testing.T) {
+	setHelloTimeouts(t, time.Second, time.Second)
+	pk, _ := cipher.GenerateKeyPair()
+	conn, legacy := legacyPeerConn(t, pk)
+
+	// the accepting peer sends no hello to the peers sending raw text right away
+	addConn(pk, conn)
+	go announceHello(conn, pk)
+	_, err := legacy.Write([]byte("hi"))
+	require.NoError(t, err)
+
+	require.NoError(t, legacy.SetReadDeadline(time.Now().Add(2*helloDelay)))
+	_, err = legacy.Read(make([]byte, 64))
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.True(t, isLegacyPeer(pk))
+}
@@ -0,0 +1,251 @@
+// Package commands cmd/apps/skychat/store.go
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+// historyFileName is the name of the message store within the skychat work dir.
+const historyFileName = "history.db"
+
+// messageStatus is the delivery status of a message.
+type messageStatus string
+
+const (
+	// statusQueued is set on outgoing messages waiting for the peer to come online.
+	statusQueued messageStatus = "queued"
+	// statusSent is set on outgoing messages written to the peer's conn.
+	statusSent messageStatus = "sent"
+	// statusDelivered is set on outgoing messages acknowledged by the peer.
+	statusDelivered messageStatus = "delivered"
+	// statusReceived is set on incoming messages not yet shown in the UI.
+	statusReceived messageStatus = "received"
+	// statusRead is set on messages shown in the UI of the recipient.
+	statusRead messageStatus = "read"
+)
+
+// rank orders the statuses, so a late receipt never downgrades a message.
+func (s messageStatus) rank() int {
+	switch s {
+	case statusQueued:
+		return 0
+	case statusSent, statusReceived:
+		return 1
+	case statusDelivered:
+		return 2
+	case statusRead:
+		return 3
+	default:
+		return -1
+	}
+}
+
+// message is a chat message stored in the history.
+type message struct {
+	ID       string        `json:"id"`
+	Peer     cipher.PubKey `json:"peer"`
+	Outgoing bool          `json:"outgoing"`
+	// Body is text, or base64 encoded data of other content types.
+	Body        string        `json:"message"`
+	ContentType string        `json:"content_type,omitempty"`
+	Timestamp   time.Time     `json:"timestamp"`
+	Status      messageStatus `json:"status"`
+	// SentAt is the time an outgoing message was last sent at, it is resent if not acknowledged in time.
+	// It is not set on the messages sent to legacy peers, which never acknowledge them.
+	SentAt time.Time `json:"sent_at,omitempty"`
+	// Legacy is set on incoming messages of peers not supporting receipts.
+	Legacy bool `json:"legacy,omitempty"`
+	// Room is set on messages of group rooms, Peer is the room host then.
+	Room string `json:"room,omitempty"`
+	// Sender is the public key of the author of a room message.
+	Sender string `json:"sender,omitempty"`
+	// ClientID is the ID given to a room message by its sender, the host assigns the stored ID.
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// newMessageID generates an ID ordered by the creation time of the message.
+func newMessageID() string {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Errorf("failed to generate message ID: %w", err))
+	}
+	return fmt.Sprintf("%016x%s", time.Now().UnixNano(), hex.EncodeToString(b[:]))
+}
+
+// messageStore keeps the messages of every peer in a separate bbolt bucket.
+type messageStore struct {
+	*bbolt.DB
+}
+
+func openMessageStore(path string) (*messageStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message store %s: %w", path, err)
+	}
+
+	return &messageStore{DB: db}, nil
+}
+
+// put stores `m`, replacing the message of the same ID.
+func (s *messageStore) put(m message) error {
+	return s.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(m.Peer.Hex()))
+		if err != nil {
+			return err
+		}
+
+		raw, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(m.ID), raw)
+	})
+}
+
+// setStatus updates the status of the message `id` exchanged with `peer`, unless it already has a later one.
+// Returns false if there is no such message.
+func (s *messageStore) setStatus(peer cipher.PubKey, id string, status messageStatus) (m message, ok bool, err error) {
+	err = s.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(peer.Hex()))
+		if b == nil {
+			return nil
+		}
+		raw := b.Get([]byte(id))
+		if raw == nil {
+			return nil
+		}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		ok = true
+		if status.rank() <= m.Status.rank() {
+			return nil
+		}
+
+		m.Status = status
+		raw, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), raw)
+	})
+
+	return m, ok, err
+}
+
+// markSent marks the message `id` sent to `peer` as sent at `at`, unless it was already acknowledged.
+// A zero `at` marks a message which is never resent.
+func (s *messageStore) markSent(peer cipher.PubKey, id string, at time.Time) (m message, err error) {
+	err = s.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(peer.Hex()))
+		if b == nil {
+			return fmt.Errorf("no message %s of %s", id, peer)
+		}
+		raw := b.Get([]byte(id))
+		if raw == nil {
+			return fmt.Errorf("no message %s of %s", id, peer)
+		}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return err
+		}
+		if m.Status.rank() > statusSent.rank() {
+			return nil
+		}
+
+		m.Status = statusSent
+		m.SentAt = at
+		raw, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), raw)
+	})
+
+	return m, err
+}
+
+// history returns the messages exchanged with `peer` ordered by their timestamps.
+func (s *messageStore) history(peer cipher.PubKey) ([]message, error) {
+	return s.bucketMessages([]byte(peer.Hex()))
+}
+
+// bucketMessages returns the messages of the bucket `name` ordered by their timestamps.
+func (s *messageStore) bucketMessages(name []byte) ([]message, error) {
+	msgs := make([]message, 0)
+	err := s.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(name)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, raw []byte) error {
+			var m message
+			if err := json.Unmarshal(raw, &m); err != nil {
+				return err
+			}
+			msgs = append(msgs, m)
+			return nil
+		})
+	})
+	sort.SliceStable(msgs, func(i, j int) bool {
+		return msgs[i].Timestamp.Before(msgs[j].Timestamp)
+	})
+
+	return msgs, err
+}
+
+// pending returns the outgoing messages to send, ordered by creation time: the queued ones, and the ones
+// sent before `resendBefore` which were not acknowledged, i.e. lost when the conn to the peer broke.
+func (s *messageStore) pending(resendBefore time.Time) ([]message, error) {
+	var msgs []message
+	err := s.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bbolt.Bucket) error {
+			if len(name) != 2*len(cipher.PubKey{}) {
+				// not a bucket of a peer, i.e. rooms
+				return nil
+			}
+			return b.ForEach(func(_, raw []byte) error {
+				var m message
+				if err := json.Unmarshal(raw, &m); err != nil {
+					return err
+				}
+				if !m.Outgoing {
+					return nil
+				}
+				if m.Status == statusQueued ||
+					(m.Status == statusSent && !m.SentAt.IsZero() && m.SentAt.Before(resendBefore)) {
+					msgs = append(msgs, m)
+				}
+				return nil
+			})
+		})
+	})
+
+	return msgs, err
+}
+
+// has checks whether the message `id` exchanged with `peer` is stored.
+func (s *messageStore) has(peer cipher.PubKey, id string) (found bool) {
+	_ = s.View(func(tx *bbolt.Tx) error { //nolint:errcheck
+		if b := tx.Bucket([]byte(peer.Hex())); b != nil {
+			found = b.Get([]byte(id)) != nil
+		}
+		return nil
+	})
+	return found
+}
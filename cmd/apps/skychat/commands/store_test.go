@@ -0,0 +1,94 @@
+// Package commands cmd/apps/skychat/store_test.go
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+func openTestStore(t *testing.T) *messageStore {
+	s, err := openMessageStore(filepath.Join(t.TempDir(), historyFileName))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+	return s
+}
+
+func TestMessageStore_pending(t *testing.T) {
+	s := openTestStore(t)
+	peer, _ := cipher.GenerateKeyPair()
+	now := time.Now()
+
+	newMsg := func(status messageStatus) message {
+		m := message{ID: newMessageID(), Peer: peer, Outgoing: true, Body: "hi", Timestamp: now, Status: status}
+		require.NoError(t, s.put(m))
+		return m
+	}
+	queued := newMsg(statusQueued)
+	sent := newMsg(statusQueued)
+	legacy := newMsg(statusQueued)
+	delivered := newMsg(statusQueued)
+	require.NoError(t, s.put(message{ID: newMessageID(), Peer: peer, Body: "incoming", Status: statusReceived}))
+
+	_, err := s.markSent(peer, sent.ID, now)
+	require.NoError(t, err)
+	_, err = s.markSent(peer, legacy.ID, time.Time{})
+	require.NoError(t, err)
+	_, err = s.markSent(peer, delivered.ID, now)
+	require.NoError(t, err)
+	_, _, err = s.setStatus(peer, delivered.ID, statusDelivered)
+	require.NoError(t, err)
+
+	// the sent messages are not resent before the ack timeout
+	msgs, err := s.pending(now.Add(-ackTimeout))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, queued.ID, msgs[0].ID)
+
+	// the unacknowledged messages are resent after it, except the ones of legacy peers
+	msgs, err = s.pending(now.Add(time.Second))
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, queued.ID, msgs[0].ID)
+	require.Equal(t, sent.ID, msgs[1].ID)
+
+	// a late resend never downgrades an acknowledged message
+	m, err := s.markSent(peer, delivered.ID, now)
+	require.NoError(t, err)
+	require.Equal(t, statusDelivered, m.Status)
+
+	_, err = s.markSent(peer, "unknown", now)
+	require.Error(t, err)
+}
+
+func TestMessageStore_setStatus(t *testing.T) {
+	s := openTestStore(t)
+	peer, _ := cipher.GenerateKeyPair()
+
+	m := message{ID: newMessageID(), Peer: peer, Outgoing: true, Timestamp: time.Now(), Status: statusSent}
+	require.NoError(t, s.put(m))
+	require.True(t, s.has(peer, m.ID))
+
+	got, ok, err := s.setStatus(peer, m.ID, statusRead)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, statusRead, got.Status)
+
+	// a late delivery receipt does not downgrade the status
+	got, ok, err = s.setStatus(peer, m.ID, statusDelivered)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, statusRead, got.Status)
+
+	_, ok, err = s.setStatus(peer, "unknown", statusRead)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	history, err := s.history(peer)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+}
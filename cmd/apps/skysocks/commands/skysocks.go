@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 
 	ipc "github.com/james-barrow/golang-ipc"
@@ -14,6 +15,7 @@ import (
 	"github.com/skycoin/skywire-utilities/pkg/buildinfo"
 	"github.com/skycoin/skywire/internal/skysocks"
 	"github.com/skycoin/skywire/pkg/app"
+	"github.com/skycoin/skywire/pkg/app/appacl"
 	"github.com/skycoin/skywire/pkg/app/appnet"
 	"github.com/skycoin/skywire/pkg/app/appserver"
 	"github.com/skycoin/skywire/pkg/routing"
@@ -52,7 +54,14 @@ var RootCmd = &cobra.Command{
 			print(fmt.Sprintf("Failed to output build info: %v", err))
 		}
 
-		srv, err := skysocks.NewServer(passcode, appCl)
+		acl, err := appacl.New(filepath.Join(appCl.Config().ProcWorkDir, appacl.FileName))
+		if err != nil {
+			setAppError(appCl, err)
+			print(fmt.Sprintf("Failed to load ACL: %v\n", err))
+			os.Exit(1)
+		}
+
+		srv, err := skysocks.NewServer(passcode, acl, appCl)
 		if err != nil {
 			setAppError(appCl, err)
 			print(fmt.Sprintf("Failed to create a new server: %v\n", err))
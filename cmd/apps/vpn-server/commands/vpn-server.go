@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"syscall"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
 	"github.com/skycoin/skywire/internal/vpn"
 	"github.com/skycoin/skywire/pkg/app"
+	"github.com/skycoin/skywire/pkg/app/appacl"
 	"github.com/skycoin/skywire/pkg/app/appnet"
 	"github.com/skycoin/skywire/pkg/app/appserver"
 	"github.com/skycoin/skywire/pkg/routing"
@@ -33,6 +35,9 @@ var (
 	passcode   string
 	networkIfc string
 	secure     bool
+	ipv6       bool
+	rateLimit  uint64
+	monthlyCap uint64
 )
 
 func init() {
@@ -41,6 +46,9 @@ func init() {
 	RootCmd.Flags().StringVar(&passcode, "passcode", "", "passcode to authenticate connecting users")
 	RootCmd.Flags().StringVar(&networkIfc, "netifc", "", "Default network interface for multiple available interfaces")
 	RootCmd.Flags().BoolVar(&secure, "secure", true, "Forbid connections from clients to server local network")
+	RootCmd.Flags().BoolVar(&ipv6, "ipv6", true, "Tunnel IPv6 traffic of clients supporting it")
+	RootCmd.Flags().Uint64Var(&rateLimit, "ratelimit", 0, "Per-client rate limit in KB/s for each direction, 0 is unlimited")
+	RootCmd.Flags().Uint64Var(&monthlyCap, "monthlycap", 0, "Per-client monthly transfer cap in MB, 0 is unlimited")
 }
 
 // RootCmd is the root command for skywire-cli
@@ -105,10 +113,22 @@ var RootCmd = &cobra.Command{
 		setAppPort(appCl, vpnPort)
 		fmt.Printf("Got app listener, bound to %d\n", vpnPort)
 
+		acl, err := appacl.New(filepath.Join(appCl.Config().ProcWorkDir, appacl.FileName))
+		if err != nil {
+			print(fmt.Sprintf("Error loading ACL: %v\n", err))
+			setAppErr(appCl, err)
+			os.Exit(1)
+		}
+
 		srvCfg := vpn.ServerConfig{
 			Passcode:         passcode,
 			Secure:           secure,
 			NetworkInterface: networkIfc,
+			IPv6:             ipv6,
+			ACL:              acl,
+			RateLimit:        rateLimit * 1024,
+			MonthlyCap:       monthlyCap * 1024 * 1024,
+			UsagePath:        filepath.Join(appCl.Config().ProcWorkDir, vpn.UsageFileName),
 		}
 		srv, err := vpn.NewServer(srvCfg, appCl)
 		if err != nil {
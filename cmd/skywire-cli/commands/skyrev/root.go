@@ -14,6 +14,7 @@ import (
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
 	clirpc "github.com/skycoin/skywire/cmd/skywire-cli/commands/rpc"
 	"github.com/skycoin/skywire/cmd/skywire-cli/internal"
+	"github.com/skycoin/skywire/pkg/app/appnet"
 )
 
 var (
@@ -22,6 +23,7 @@ var (
 	localPort  int
 	lsPorts    bool
 	disconnect string
+	fwdType    string
 )
 
 func init() {
@@ -30,6 +32,7 @@ func init() {
 	RootCmd.Flags().IntVarP(&localPort, "port", "p", 0, "local port to reverse proxy")
 	RootCmd.Flags().BoolVarP(&lsPorts, "ls", "l", false, "list configured connections")
 	RootCmd.Flags().StringVarP(&disconnect, "stop", "d", "", "disconnect from specified <id>")
+	RootCmd.Flags().StringVarP(&fwdType, "type", "t", string(appnet.ForwardHTTP), "forwarding type: http, tcp or udp")
 }
 
 // RootCmd contains commands that interact with the skyforwarding
@@ -51,12 +54,12 @@ var RootCmd = &cobra.Command{
 
 			var b bytes.Buffer
 			w := tabwriter.NewWriter(&b, 0, 0, 3, ' ', tabwriter.TabIndent)
-			_, err = fmt.Fprintln(w, "id\tlocal_port\tremote_port")
+			_, err = fmt.Fprintln(w, "id\ttype\tlocal_port\tremote_port\tsent\treceived")
 			internal.Catch(cmd.Flags(), err)
 
 			for _, forwardConn := range forwardConns {
-				_, err = fmt.Fprintf(w, "%s\t%s\t%s\n", forwardConn.ID, strconv.Itoa(int(forwardConn.LocalPort)),
-					strconv.Itoa(int(forwardConn.RemotePort)))
+				_, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\n", forwardConn.ID, forwardConn.Type, strconv.Itoa(int(forwardConn.LocalPort)),
+					strconv.Itoa(int(forwardConn.RemotePort)), forwardConn.BytesSent, forwardConn.BytesReceived)
 				internal.Catch(cmd.Flags(), err)
 			}
 			internal.Catch(cmd.Flags(), w.Flush())
@@ -97,7 +100,10 @@ var RootCmd = &cobra.Command{
 			internal.PrintFatalError(cmd.Flags(), fmt.Errorf("port cannot be greater than 65535"))
 		}
 
-		id, err := rpcClient.Connect(remotePK, remotePort, localPort)
+		fType, err := appnet.ParseForwardType(fwdType)
+		internal.Catch(cmd.Flags(), err)
+
+		id, err := rpcClient.Connect(remotePK, remotePort, localPort, fType)
 		internal.Catch(cmd.Flags(), err)
 		internal.PrintOutput(cmd.Flags(), id, fmt.Sprintln(id))
 	},
@@ -71,7 +71,7 @@ var lsAppsCmd = &cobra.Command{
 		internal.Catch(cmd.Flags(), err)
 		var b bytes.Buffer
 		w := tabwriter.NewWriter(&b, 0, 0, 5, ' ', tabwriter.TabIndent)
-		_, err = fmt.Fprintln(w, "app\tport\tauto_start\tstatus\tdetailed_status")
+		_, err = fmt.Fprintln(w, "app\tport\tauto_start\tstatus\tcrashes\tdetailed_status")
 		internal.Catch(cmd.Flags(), err)
 
 		type appState struct {
@@ -79,6 +79,8 @@ var lsAppsCmd = &cobra.Command{
 			Port           int    `json:"port"`
 			AutoStart      bool   `json:"auto_start"`
 			Status         string `json:"status"`
+			Crashes        int    `json:"crashes"`
+			ExitCode       *int   `json:"exit_code,omitempty"`
 			DetailedStatus string `json:"detailed_status"`
 		}
 
@@ -91,14 +93,16 @@ var lsAppsCmd = &cobra.Command{
 			if state.Status == appserver.AppStatusErrored {
 				status = "errored"
 			}
-			_, err = fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", state.Name, strconv.Itoa(int(state.Port)),
-				state.AutoStart, status, state.DetailedStatus)
+			_, err = fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%d\t%s\n", state.Name, strconv.Itoa(int(state.Port)),
+				state.AutoStart, status, state.Crashes, state.DetailedStatus)
 			internal.Catch(cmd.Flags(), err)
 			s := appState{
 				App:            state.Name,
 				Port:           int(state.Port),
 				AutoStart:      state.AutoStart,
 				Status:         status,
+				Crashes:        state.Crashes,
+				ExitCode:       state.ExitCode,
 				DetailedStatus: state.DetailedStatus,
 			}
 			appStates = append(appStates, s)
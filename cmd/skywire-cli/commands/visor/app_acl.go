@@ -0,0 +1,119 @@
+// Package clivisor app_acl.go
+package clivisor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	clirpc "github.com/skycoin/skywire/cmd/skywire-cli/commands/rpc"
+	"github.com/skycoin/skywire/cmd/skywire-cli/internal"
+	"github.com/skycoin/skywire/pkg/app/appacl"
+)
+
+var aclExpire time.Duration
+
+func init() {
+	argCmd.AddCommand(appACLCmd)
+	appACLCmd.AddCommand(
+		lsAppACLCmd,
+		allowAppACLCmd,
+		denyAppACLCmd,
+		rmAppACLCmd,
+	)
+	for _, c := range []*cobra.Command{allowAppACLCmd, denyAppACLCmd} {
+		c.Flags().DurationVarP(&aclExpire, "expire", "e", 0, "remove the entry after duration, 0 never expires")
+	}
+}
+
+var appACLCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "Per-client access control of vpn-server and skysocks",
+	Long:  "\n  Per-client access control of vpn-server and skysocks.\n\r\n\r  A client with a deny entry is rejected. If there are any allow entries, only those clients are allowed.\n\r  Changes apply to the running app without restart.",
+}
+
+var lsAppACLCmd = &cobra.Command{
+	Use:   "ls <name>",
+	Short: "List app ACL entries",
+	Long:  "\n  List app ACL entries",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		rpcClient, err := clirpc.Client(cmd.Flags())
+		if err != nil {
+			os.Exit(1)
+		}
+		entries, err := rpcClient.GetAppACL(args[0])
+		internal.Catch(cmd.Flags(), err)
+
+		var b bytes.Buffer
+		w := tabwriter.NewWriter(&b, 0, 0, 5, ' ', tabwriter.TabIndent)
+		_, err = fmt.Fprintln(w, "pk\taction\texpires")
+		internal.Catch(cmd.Flags(), err)
+		for _, e := range entries {
+			expires := "never"
+			if !e.ExpiresAt.IsZero() {
+				expires = e.ExpiresAt.Format(time.RFC3339)
+			}
+			_, err = fmt.Fprintf(w, "%s\t%s\t%s\n", e.PK, e.Action, expires)
+			internal.Catch(cmd.Flags(), err)
+		}
+		internal.Catch(cmd.Flags(), w.Flush())
+		internal.PrintOutput(cmd.Flags(), entries, b.String())
+	},
+}
+
+var allowAppACLCmd = &cobra.Command{
+	Use:   "allow <name> <pk>",
+	Short: "Allow client to connect to app",
+	Long:  "\n  Allow client to connect to app",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		setAppACLEntry(cmd, args, appacl.Allow)
+	},
+}
+
+var denyAppACLCmd = &cobra.Command{
+	Use:   "deny <name> <pk>",
+	Short: "Deny client to connect to app",
+	Long:  "\n  Deny client to connect to app",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		setAppACLEntry(cmd, args, appacl.Deny)
+	},
+}
+
+var rmAppACLCmd = &cobra.Command{
+	Use:   "rm <name> <pk>",
+	Short: "Remove app ACL entry",
+	Long:  "\n  Remove app ACL entry",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		pk := internal.ParsePK(cmd.Flags(), "pk", args[1])
+		rpcClient, err := clirpc.Client(cmd.Flags())
+		if err != nil {
+			os.Exit(1)
+		}
+		internal.Catch(cmd.Flags(), rpcClient.RemoveAppACLEntry(args[0], pk))
+		internal.PrintOutput(cmd.Flags(), "OK", "OK\n")
+	},
+}
+
+func setAppACLEntry(cmd *cobra.Command, args []string, action appacl.Action) {
+	entry := appacl.Entry{
+		PK:     internal.ParsePK(cmd.Flags(), "pk", args[1]),
+		Action: action,
+	}
+	if aclExpire > 0 {
+		entry.ExpiresAt = time.Now().Add(aclExpire)
+	}
+	rpcClient, err := clirpc.Client(cmd.Flags())
+	if err != nil {
+		os.Exit(1)
+	}
+	internal.Catch(cmd.Flags(), rpcClient.SetAppACLEntry(args[0], entry))
+	internal.PrintOutput(cmd.Flags(), "OK", "OK\n")
+}
@@ -0,0 +1,76 @@
+// Package clivisor cmd/skywire-cli/commands/visor/events.go
+package clivisor
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	clirpc "github.com/skycoin/skywire/cmd/skywire-cli/commands/rpc"
+	"github.com/skycoin/skywire/cmd/skywire-cli/internal"
+	"github.com/skycoin/skywire/pkg/visor"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
+)
+
+var (
+	followEvents bool
+	eventsSince  uint64
+	eventTypes   []string
+)
+
+func init() {
+	RootCmd.AddCommand(eventsCmd)
+	eventsCmd.Flags().BoolVarP(&followEvents, "follow", "f", false, "wait for and display new events")
+	eventsCmd.Flags().Uint64Var(&eventsSince, "since", 0, "display the events after this sequence number")
+	eventsCmd.Flags().StringSliceVarP(&eventTypes, "type", "t", nil, "display only events of these types")
+}
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Display visor events",
+	Long: "\n  Display the recent events of the visor\n\n  event types: " + strings.Join([]string{
+		visorevent.TransportUp, visorevent.TransportDown,
+		visorevent.RouteGroupCreated, visorevent.RouteGroupClosed,
+		visorevent.AppStarted, visorevent.AppStopped, visorevent.AppCrashed,
+		visorevent.DmsgSessionUp, visorevent.DmsgSessionDown,
+		visorevent.ConfigReloaded,
+	}, ", "),
+	Run: func(cmd *cobra.Command, _ []string) {
+		types := make(map[string]bool, len(eventTypes))
+		for _, t := range eventTypes {
+			if !visorevent.AllTypes()[t] {
+				internal.PrintFatalError(cmd.Flags(), fmt.Errorf("unknown event type %q", t))
+			}
+			types[t] = true
+		}
+
+		rpcClient, err := clirpc.Client(cmd.Flags())
+		if err != nil {
+			os.Exit(1)
+		}
+
+		since := eventsSince
+		var wait time.Duration
+		for {
+			events, err := rpcClient.Events(since, wait)
+			internal.Catch(cmd.Flags(), err)
+
+			for _, e := range events {
+				// sequence numbers are reset when the visor restarts, the visor then returns all of its events
+				since = e.Seq
+				if len(types) != 0 && !types[e.Type] {
+					continue
+				}
+				internal.PrintOutput(cmd.Flags(), e, fmt.Sprintf("%d\t%s\t%s\t%s\n", e.Seq, e.Time.Local().Format(time.RFC3339), e.Type, string(e.Data)))
+			}
+
+			if !followEvents {
+				return
+			}
+			wait = visor.MaxEventsWait
+		}
+	},
+}
@@ -0,0 +1,212 @@
+// Package clivisor cmd/skywire-cli/commands/visor/hv_token.go
+package clivisor
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/cmd/skywire-cli/internal"
+	"github.com/skycoin/skywire/pkg/visor/usermanager"
+)
+
+var (
+	hvURL        string
+	hvUser       string
+	hvInsecure   bool
+	tokenName    string
+	tokenScope   string
+	tokenExpires string
+	tokenVisors  []string
+)
+
+const (
+	// hvRequestTimeout is the timeout of the requests to the hypervisor API.
+	hvRequestTimeout = 30 * time.Second
+	// hvPassEnv is the environment variable holding the hypervisor password, it's prompted for otherwise.
+	hvPassEnv = "SKYWIRE_HV_PASSWORD"
+)
+
+func init() {
+	hvCmd.AddCommand(hvTokenCmd)
+	hvTokenCmd.PersistentFlags().StringVar(&hvURL, "url", "", "hypervisor URL, the local hypervisor by default")
+	hvTokenCmd.PersistentFlags().StringVarP(&hvUser, "user", "u", usermanager.AdminName, "hypervisor user")
+	hvTokenCmd.PersistentFlags().BoolVarP(&hvInsecure, "insecure", "k", false, "skip verification of the hypervisor TLS certificate")
+	hvTokenCmd.AddCommand(hvTokenAddCmd, hvTokenLsCmd, hvTokenRmCmd)
+	hvTokenAddCmd.Flags().StringVarP(&tokenName, "name", "n", "", "name of the token")
+	hvTokenAddCmd.Flags().StringVarP(&tokenScope, "scope", "s", "", "scope of the token: admin, operator or read-only; the role of the user by default")
+	hvTokenAddCmd.Flags().StringVarP(&tokenExpires, "expires", "e", "", "lifetime of the token e.g. 720h, never expires by default")
+	hvTokenAddCmd.Flags().StringSliceVarP(&tokenVisors, "visors", "v", nil, "public keys of the visors the token is restricted to, comma-separated")
+}
+
+var hvTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Hypervisor API tokens",
+	Long:  "\n  Hypervisor API tokens\n\n  Manage the bearer tokens giving scripted access to the hypervisor API\n  requires the hypervisor authentication to be enabled\n\n  the password is read from the " + hvPassEnv + " environment variable, or prompted for",
+}
+
+var hvTokenAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Create an API token",
+	Long:  "\n  Create an API token\n\n  the token is only displayed once",
+	Run: func(cmd *cobra.Command, _ []string) {
+		if tokenName == "" {
+			internal.PrintFatalError(cmd.Flags(), errors.New("the name of the token is required"))
+		}
+		rb := struct {
+			Name      string          `json:"name"`
+			Scope     string          `json:"scope,omitempty"`
+			Visors    []cipher.PubKey `json:"visors,omitempty"`
+			ExpiresIn string          `json:"expires_in,omitempty"`
+		}{
+			Name:      tokenName,
+			Scope:     tokenScope,
+			ExpiresIn: tokenExpires,
+		}
+		for _, v := range tokenVisors {
+			rb.Visors = append(rb.Visors, internal.ParsePK(cmd.Flags(), "visors", v))
+		}
+
+		var token usermanager.TokenSummary
+		internal.Catch(cmd.Flags(), hvRequest(cmd.Flags(), http.MethodPost, "/api/tokens", rb, &token))
+		internal.PrintOutput(cmd.Flags(), token, token.Token+"\n")
+	},
+}
+
+var hvTokenLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List API tokens",
+	Long:  "\n  List API tokens\n\n  lists the tokens of the user, or of all users for admins",
+	Run: func(cmd *cobra.Command, _ []string) {
+		var tokens []usermanager.TokenSummary
+		internal.Catch(cmd.Flags(), hvRequest(cmd.Flags(), http.MethodGet, "/api/tokens", nil, &tokens))
+
+		var b bytes.Buffer
+		w := tabwriter.NewWriter(&b, 0, 0, 5, ' ', tabwriter.TabIndent)
+		_, err := fmt.Fprintln(w, "id\tname\tuser\tscope\texpiry")
+		internal.Catch(cmd.Flags(), err)
+		for _, t := range tokens {
+			expiry := "never"
+			if t.Expiry != nil {
+				expiry = t.Expiry.Format(time.RFC3339)
+			}
+			_, err = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.User, t.Scope, expiry)
+			internal.Catch(cmd.Flags(), err)
+		}
+		internal.Catch(cmd.Flags(), w.Flush())
+		internal.PrintOutput(cmd.Flags(), tokens, b.String())
+	},
+}
+
+var hvTokenRmCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "Revoke an API token",
+	Long:  "\n  Revoke an API token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := internal.ParseUUID(cmd.Flags(), "id", args[0])
+		internal.Catch(cmd.Flags(), hvRequest(cmd.Flags(), http.MethodDelete, "/api/tokens/"+id.String(), nil, nil))
+		internal.PrintOutput(cmd.Flags(), "OK", "OK\n")
+	},
+}
+
+// hvRequest logs into the hypervisor and performs a request to its API.
+func hvRequest(cmdFlags *pflag.FlagSet, method, path string, in, out interface{}) error {
+	base := hvURL
+	if base == "" {
+		base = "http://127.0.0.1" + HypervisorPort(cmdFlags)
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Jar:     jar,
+		Timeout: hvRequestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: hvInsecure}, //nolint:gosec
+		},
+	}
+
+	pass, err := hvPassword()
+	if err != nil {
+		return err
+	}
+	login := map[string]string{"username": hvUser, "password": pass}
+	if err := hvDo(client, http.MethodPost, base+"/api/login", login, nil); err != nil {
+		return fmt.Errorf("failed to login to hypervisor: %w", err)
+	}
+
+	return hvDo(client, method, base+path, in, out)
+}
+
+// hvPassword returns the hypervisor password of hvPassEnv, or prompts for it.
+func hvPassword() (string, error) {
+	if pass, ok := os.LookupEnv(hvPassEnv); ok {
+		return pass, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("no terminal to prompt for the hypervisor password, set %s", hvPassEnv)
+	}
+	fmt.Fprint(os.Stderr, "Hypervisor password: ") //nolint:errcheck
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr) //nolint:errcheck
+	if err != nil {
+		return "", fmt.Errorf("failed to read the hypervisor password: %w", err)
+	}
+
+	return string(pass), nil
+}
+
+func hvDo(client *http.Client, method, url string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+			return fmt.Errorf("hypervisor responded with %s", resp.Status)
+		}
+		return errors.New(e.Error)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -2,15 +2,13 @@
 package clivisor
 
 import (
-	"context"
 	"fmt"
 	"os"
 	"os/user"
-	"time"
+	"strings"
 
 	"github.com/bitfield/script"
 	"github.com/spf13/cobra"
-	"golang.org/x/sync/errgroup"
 
 	clirpc "github.com/skycoin/skywire/cmd/skywire-cli/commands/rpc"
 	"github.com/skycoin/skywire/cmd/skywire-cli/internal"
@@ -58,18 +56,10 @@ var startCmd = &cobra.Command{
 	},
 }
 
-func gort(ctx context.Context, fn func() error) error {
-	errs, _ := errgroup.WithContext(ctx)
-	errs.Go(func() error {
-		return fn()
-	})
-	// Wait for completion and return the first error (if any)
-	return errs.Wait()
-}
-
 var reloadCmd = &cobra.Command{
 	Use:    "reload",
 	Short:  "reload visor",
+	Long:   "\n  Reload the config file of the visor\n\n  The changed apps, persistent transports, hypervisors and log level\n  are applied at once, the other changed sections require a restart of the visor",
 	Hidden: true,
 	Run: func(cmd *cobra.Command, args []string) {
 		rpcClient, err := clirpc.Client(cmd.Flags())
@@ -77,16 +67,17 @@ var reloadCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		go func() {
-			err = gort(context.Background(), rpcClient.Reload)
-			if err != nil {
-				internal.PrintFatalError(cmd.Flags(), fmt.Errorf("error reloading visor"))
-			}
-		}()
-
-		<-time.After(1 * time.Second)
-		internal.PrintOutput(cmd.Flags(), "Visor reloaded", fmt.Sprintln("Visor reloaded"))
+		report, err := rpcClient.Reload()
+		if err != nil {
+			internal.PrintFatalError(cmd.Flags(), fmt.Errorf("error reloading visor: %w", err))
+		}
 
+		msg := "Visor reloaded, no config changes\n"
+		if len(report.Applied) > 0 || len(report.RestartRequired) > 0 {
+			msg = fmt.Sprintf("Visor reloaded\napplied: %s\nrestart required: %s\n",
+				strings.Join(report.Applied, ", "), strings.Join(report.RestartRequired, ", "))
+		}
+		internal.PrintOutput(cmd.Flags(), report, msg)
 	},
 }
 
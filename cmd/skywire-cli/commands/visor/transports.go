@@ -39,6 +39,8 @@ func init() {
 		addTpCmd,
 		rmTpCmd,
 		discTpCmd,
+		statsTpCmd,
+		lanTpCmd,
 	)
 }
 
@@ -55,7 +57,7 @@ var tpCmd = &cobra.Command{
 	and has a Transport Type that identifies
 	a specific implementation of the Transport.
 
-	Types: stcp stcpr sudph dmsg`,
+	Types: stcp stcpr sudph dmsg quic wss`,
 }
 
 var lsTypesCmd = &cobra.Command{
@@ -361,3 +363,90 @@ func (t *transportID) Set(s string) error {
 	*t = transportID(tID)
 	return nil
 }
+
+var (
+	statsSince   time.Duration
+	statsPK      string
+	statsType    string
+	statsGroupBy []string
+)
+
+func init() {
+	statsTpCmd.Flags().DurationVarP(&statsSince, "since", "s", 7*24*time.Hour, "aggregate traffic of the given duration, 0 for all of the logs")
+	statsTpCmd.Flags().StringVarP(&statsPK, "pk", "p", "", "traffic exchanged with public key")
+	statsTpCmd.Flags().StringVarP(&statsType, "type", "t", "", "traffic of transport type")
+	statsTpCmd.Flags().StringSliceVarP(&statsGroupBy, "group", "g", []string{string(transport.LogGroupByPeer)}, "group by peer, type, transport comma-separated")
+}
+
+var statsTpCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Transport traffic statistics",
+	Long:  "\n    Transport traffic statistics\n\n    aggregates the traffic recorded in the transport log store\n    requires the bbolt transport log store",
+	Run: func(cmd *cobra.Command, _ []string) {
+		q := transport.LogQuery{
+			Type: network.Type(statsType),
+		}
+		if statsSince > 0 {
+			q.From = time.Now().Add(-statsSince)
+		}
+		if statsPK != "" {
+			q.RemotePK = internal.ParsePK(cmd.Flags(), "pk", statsPK)
+		}
+		for _, g := range statsGroupBy {
+			q.GroupBy = append(q.GroupBy, transport.LogGroupBy(g))
+		}
+		rpcClient, err := clirpc.Client(cmd.Flags())
+		if err != nil {
+			os.Exit(1)
+		}
+		stats, err := rpcClient.TransportStats(q)
+		internal.Catch(cmd.Flags(), err)
+
+		var b bytes.Buffer
+		w := tabwriter.NewWriter(&b, 0, 0, 5, ' ', tabwriter.TabIndent)
+		_, err = fmt.Fprintln(w, "remote_pk\ttype\tid\trecv\tsent")
+		internal.Catch(cmd.Flags(), err)
+		orAll := func(s string, null bool) string {
+			if null {
+				return "*"
+			}
+			return s
+		}
+		for _, s := range stats {
+			_, err = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\n",
+				orAll(s.RemotePK.String(), s.RemotePK.Null()),
+				orAll(string(s.Type), s.Type == ""),
+				orAll(s.TpID.String(), s.TpID == uuid.Nil),
+				s.RecvBytes, s.SentBytes)
+			internal.Catch(cmd.Flags(), err)
+		}
+		internal.Catch(cmd.Flags(), w.Flush())
+		internal.PrintOutput(cmd.Flags(), stats, b.String())
+	},
+}
+
+var lanTpCmd = &cobra.Command{
+	Use:                   "lan",
+	Short:                 "Visors discovered on the local network",
+	Long:                  "\n    Visors discovered on the local network\n\n    lists the visors found by LAN discovery and used for stcp transports\n    requires lan_discovery in the skywire-tcp config",
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, _ []string) {
+		rpcClient, err := clirpc.Client(cmd.Flags())
+		if err != nil {
+			os.Exit(1)
+		}
+		peers, err := rpcClient.LANPeers()
+		internal.Catch(cmd.Flags(), err)
+
+		var b bytes.Buffer
+		w := tabwriter.NewWriter(&b, 0, 0, 5, ' ', tabwriter.TabIndent)
+		_, err = fmt.Fprintln(w, "remote_pk\taddr\tlast_seen")
+		internal.Catch(cmd.Flags(), err)
+		for _, p := range peers {
+			_, err = fmt.Fprintf(w, "%s\t%s\t%s\n", p.PK, p.Addr, p.LastSeen.Format(time.RFC3339))
+			internal.Catch(cmd.Flags(), err)
+		}
+		internal.Catch(cmd.Flags(), w.Flush())
+		internal.PrintOutput(cmd.Flags(), peers, b.String())
+	},
+}
@@ -26,6 +26,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/orandin/lumberjackrus v1.0.1
 	github.com/pterm/pterm v0.12.66
+	github.com/quic-go/quic-go v0.38.1
 	github.com/sirupsen/logrus v1.9.3
 	github.com/skycoin/dmsg v1.3.18-0.20240311074627-0ba753f65a88
 	github.com/skycoin/skycoin v0.27.1
@@ -46,7 +47,9 @@ require (
 	golang.org/x/net v0.14.0
 	golang.org/x/sync v0.3.0
 	golang.org/x/sys v0.15.0
+	golang.org/x/term v0.15.0
 	golang.zx2c4.com/wireguard v0.0.0-20230223181233-21636207a675
+	nhooyr.io/websocket v1.8.7
 )
 
 require (
@@ -128,7 +131,6 @@ require (
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/quic-go/qtls-go1-20 v0.3.3 // indirect
-	github.com/quic-go/quic-go v0.38.1 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/rs/cors v1.8.2 // indirect
 	github.com/skycoin/noise v0.0.0-20180327030543-2492fe189ae6 // indirect
@@ -147,7 +149,6 @@ require (
 	golang.org/x/crypto v0.17.0 // indirect
 	golang.org/x/exp v0.0.0-20230817173708-d852ddb80c63 // indirect
 	golang.org/x/mod v0.12.0 // indirect
-	golang.org/x/term v0.15.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	golang.org/x/tools v0.12.1-0.20230815132531-74c255bcf846 // indirect
 	golang.zx2c4.com/wintun v0.0.0-20230126152724-0fa3db229ce2 // indirect
@@ -159,7 +160,6 @@ require (
 	gorm.io/gorm v1.23.8 // indirect
 	howett.net/plist v1.0.0 // indirect
 	mvdan.cc/sh/v3 v3.7.0 // indirect
-	nhooyr.io/websocket v1.8.7 // indirect
 )
 
 // Uncomment for tests with alternate branches of 'dmsg'
@@ -7,27 +7,34 @@ import (
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/armon/go-socks5"
 	"github.com/hashicorp/yamux"
 	ipc "github.com/james-barrow/golang-ipc"
 
 	"github.com/skycoin/skywire/pkg/app"
+	"github.com/skycoin/skywire/pkg/app/appacl"
 	"github.com/skycoin/skywire/pkg/app/appserver"
 	"github.com/skycoin/skywire/pkg/skyenv"
 )
 
+// aclCheckInterval is the interval of closing the connections of clients no longer allowed by the ACL.
+const aclCheckInterval = 10 * time.Second
+
 // Server implements multiplexing proxy server using yamux.
 type Server struct {
 	appCl    *app.Client
+	acl      *appacl.ACL
 	sMu      sync.Mutex
 	socks    *socks5.Server
 	listener net.Listener
+	sessions map[net.Conn]*yamux.Session
 	closed   uint32
 }
 
-// NewServer constructs a new Server.
-func NewServer(passcode string, appCl *app.Client) (*Server, error) {
+// NewServer constructs a new Server. A nil `acl` allows every client.
+func NewServer(passcode string, acl *appacl.ACL, appCl *app.Client) (*Server, error) {
 	var credentials socks5.CredentialStore
 	if passcode != "" {
 		credentials = passcodeCredentials(passcode)
@@ -39,8 +46,10 @@ func NewServer(passcode string, appCl *app.Client) (*Server, error) {
 	}
 
 	server := &Server{
-		appCl: appCl,
-		socks: s,
+		appCl:    appCl,
+		acl:      acl,
+		socks:    s,
+		sessions: make(map[net.Conn]*yamux.Session),
 	}
 
 	return server, nil
@@ -57,6 +66,12 @@ func (s *Server) Serve(l net.Listener) error {
 		s.setAppStatus(appserver.AppDetailedStatusRunning)
 	}
 
+	if s.acl != nil {
+		aclDone := make(chan struct{})
+		defer close(aclDone)
+		go s.acl.Watch(aclDone, aclCheckInterval, s.closeRevokedSessions)
+	}
+
 	for {
 		if s.isClosed() {
 			return nil
@@ -74,6 +89,14 @@ func (s *Server) Serve(l net.Listener) error {
 			return fmt.Errorf("accept: %w", err)
 		}
 
+		if !s.acl.IsConnAllowed(conn) {
+			fmt.Printf("Rejected skysocks connection from %s: not allowed by the ACL\n", conn.RemoteAddr())
+			if err := conn.Close(); err != nil {
+				print(fmt.Sprintf("Error closing skysocks connection: %v\n", err))
+			}
+			continue
+		}
+
 		fmt.Println("Accepted new skysocks connection")
 
 		sessionCfg := yamux.DefaultConfig()
@@ -83,14 +106,37 @@ func (s *Server) Serve(l net.Listener) error {
 			return fmt.Errorf("yamux server failure: %w", err)
 		}
 
+		s.sMu.Lock()
+		s.sessions[conn] = session
+		s.sMu.Unlock()
+
 		go func() {
 			if err := s.socks.Serve(session); err != nil {
 				print(fmt.Sprintf("Failed to start SOCKS5 server: %v\n", err))
 			}
+
+			s.sMu.Lock()
+			delete(s.sessions, conn)
+			s.sMu.Unlock()
 		}()
 	}
 }
 
+func (s *Server) closeRevokedSessions() {
+	s.sMu.Lock()
+	defer s.sMu.Unlock()
+
+	for conn, session := range s.sessions {
+		if !s.acl.IsConnAllowed(conn) {
+			fmt.Printf("Client %s is not allowed anymore, closing connection\n", conn.RemoteAddr())
+			if err := session.Close(); err != nil {
+				print(fmt.Sprintf("Error closing skysocks session: %v\n", err))
+			}
+			delete(s.sessions, conn)
+		}
+	}
+}
+
 // ListenIPC starts named-pipe based connection server for windows or unix socket in Linux/Mac
 func (s *Server) ListenIPC(client *ipc.Client) {
 	listenIPC(client, skyenv.SkysocksName, func() {
@@ -29,7 +29,11 @@ import (
 const (
 	ipv4FirstHalfAddr      = "0.0.0.0/1"
 	ipv4SecondHalfAddr     = "128.0.0.0/1"
+	ipv6FirstHalfAddr      = "::/1"
+	ipv6SecondHalfAddr     = "8000::/1"
 	directRouteNetmaskCIDR = "/32"
+	// directRouteIPv6PrefixCIDR is the prefix length of the direct routes to IPv6 addresses.
+	directRouteIPv6PrefixCIDR = "/128"
 )
 
 // Client is a VPN client.
@@ -39,11 +43,15 @@ type Client struct {
 	directIPSMu    sync.Mutex
 	directIPs      []net.IP
 	defaultGateway net.IP
-	closeC         chan struct{}
-	closeOnce      sync.Once
+	// defaultGatewayIPv6 is nil if the host has no default IPv6 route.
+	defaultGatewayIPv6 *net.IPAddr
+	closeC             chan struct{}
+	closeOnce          sync.Once
 
-	prevTUNGateway   net.IP
-	prevTUNGatewayMu sync.Mutex
+	prevTUNGateway     net.IP
+	prevTUNGatewayIPv6 net.IP
+	ipv6Blocked        bool
+	prevTUNGatewayMu   sync.Mutex
 
 	suidMu sync.Mutex //nolint
 	suid   int        //nolint
@@ -121,12 +129,20 @@ func NewClient(cfg ClientConfig, appCl *app.Client) (*Client, error) {
 
 	fmt.Printf("Got default network gateway IP: %s\n", defaultGateway)
 
+	defaultGatewayIPv6, err := DefaultNetworkGatewayIPv6()
+	if err != nil {
+		fmt.Printf("No default IPv6 network gateway: %v\n", err)
+	} else {
+		fmt.Printf("Got default IPv6 network gateway IP: %s\n", defaultGatewayIPv6)
+	}
+
 	return &Client{
-		cfg:            cfg,
-		appCl:          appCl,
-		directIPs:      filterOutEqualIPs(directIPs),
-		defaultGateway: defaultGateway,
-		closeC:         make(chan struct{}),
+		cfg:                cfg,
+		appCl:              appCl,
+		directIPs:          filterOutEqualIPs(directIPs),
+		defaultGateway:     defaultGateway,
+		defaultGatewayIPv6: defaultGatewayIPv6,
+		closeC:             make(chan struct{}),
 	}, nil
 }
 
@@ -154,9 +170,15 @@ func (c *Client) Serve() error {
 				fmt.Printf("Routing traffic directly, previous TUN gateway: %s\n", c.prevTUNGateway.String())
 				c.routeTrafficDirectly(c.prevTUNGateway)
 			}
+			if len(c.prevTUNGatewayIPv6) > 0 {
+				fmt.Printf("Routing IPv6 traffic directly, previous TUN gateway: %s\n", c.prevTUNGatewayIPv6.String())
+				c.routeIPv6TrafficDirectly(c.prevTUNGatewayIPv6)
+			}
 			c.prevTUNGateway = nil
+			c.prevTUNGatewayIPv6 = nil
 			c.prevTUNGatewayMu.Unlock()
 		}
+		c.unblockIPv6()
 
 		if err := c.closeTUN(); err != nil {
 			print(fmt.Sprintf("Failed to close TUN: %v\n", err))
@@ -174,7 +196,7 @@ func (c *Client) Serve() error {
 
 	r := netutil.NewRetrier(nil, netutil.DefaultInitBackoff, netutil.DefaultMaxBackoff, 3, netutil.DefaultFactor).
 		WithErrWhitelist(errHandshakeStatusForbidden, errHandshakeStatusInternalError, errHandshakeNoFreeIPs,
-			errHandshakeStatusBadRequest, errNoTransportFound, errTransportNotFound, errErrSetupNode, errNotPermitted,
+			errHandshakeStatusBadRequest, errHandshakeStatusQuotaExceeded, errNoTransportFound, errTransportNotFound, errErrSetupNode, errNotPermitted,
 			errErrServerOffline)
 
 	err := r.Do(context.Background(), func() error {
@@ -185,7 +207,7 @@ func (c *Client) Serve() error {
 		if err := c.dialServeConn(); err != nil {
 			switch err {
 			case errHandshakeStatusForbidden, errHandshakeStatusInternalError, errHandshakeNoFreeIPs,
-				errHandshakeStatusBadRequest, errNoTransportFound, errTransportNotFound, errErrSetupNode, errNotPermitted,
+				errHandshakeStatusBadRequest, errHandshakeStatusQuotaExceeded, errNoTransportFound, errTransportNotFound, errErrSetupNode, errNotPermitted,
 				errErrServerOffline:
 				c.setAppError(err)
 				c.resetConnDuration()
@@ -342,16 +364,33 @@ func (c *Client) setupTUN(tunIP, tunGateway net.IP) error {
 	return c.SetupTUN(c.tun.Name(), tunIP.String()+TUNNetmaskCIDR, tunGateway.String(), TUNMTU)
 }
 
+func (c *Client) setupTUNv6(tunIP net.IP) error {
+	c.tunMu.Lock()
+	defer c.tunMu.Unlock()
+
+	if !c.tunCreated {
+		return errors.New("TUN is not created")
+	}
+
+	return c.SetupTUNv6(c.tun.Name(), tunIP.String()+TUNNetmaskCIDRv6)
+}
+
 func (c *Client) serveConn(conn net.Conn) error {
-	tunIP, tunGateway, err := c.shakeHands(conn)
+	sHello, err := c.shakeHands(conn)
 	if err != nil {
 		fmt.Printf("error during client/server handshake: %s\n", err)
 		return err
 	}
+	tunIP, tunGateway := sHello.TUNIP, sHello.TUNGateway
+	tunIPv6, tunGatewayIPv6 := sHello.TUNIPv6, sHello.TUNGatewayIPv6
 
 	fmt.Printf("Performed handshake with %s\n", conn.RemoteAddr())
 	fmt.Printf("Local TUN IP: %s\n", tunIP.String())
 	fmt.Printf("Local TUN gateway: %s\n", tunGateway.String())
+	if tunIPv6 != nil {
+		fmt.Printf("Local TUN IPv6: %s\n", tunIPv6.String())
+		fmt.Printf("Local TUN IPv6 gateway: %s\n", tunGatewayIPv6.String())
+	}
 
 	fmt.Println("CREATING TUN INTERFACE")
 	tun, err := c.createTUN()
@@ -379,15 +418,40 @@ func (c *Client) serveConn(conn net.Conn) error {
 		time.Sleep(13 * time.Second)
 	}
 
+	if tunIPv6 != nil {
+		fmt.Printf("Setting up TUN device with IPv6: %s\n", tunIPv6)
+		if err := c.setupTUNv6(tunIPv6); err != nil {
+			return fmt.Errorf("error setting up IPv6 on TUN %s: %w", tun.Name(), err)
+		}
+	}
+
 	fmt.Printf("TUN %s all sets\n", tunIP)
 
+	// the IPv6 direct routes can't be set up without a default IPv6 gateway, capturing the IPv6 traffic
+	// would capture them too, so it's left alone then
+	captureIPv6 := c.canCaptureIPv6()
+	if !captureIPv6 {
+		fmt.Println("Not capturing IPv6 traffic, there is no default IPv6 gateway to route the IPv6 direct routes through")
+		tunGatewayIPv6 = nil
+	}
+
 	isNewRoute := true
+	isNewIPv6Route := true
 	if c.cfg.Killswitch {
 		c.prevTUNGatewayMu.Lock()
 		if len(c.prevTUNGateway) > 0 {
 			isNewRoute = false
 		}
+		if len(c.prevTUNGatewayIPv6) > 0 {
+			if tunGatewayIPv6 == nil {
+				// server doesn't support IPv6 anymore, drop the previous IPv6 routes
+				c.routeIPv6TrafficDirectly(c.prevTUNGatewayIPv6)
+			} else {
+				isNewIPv6Route = false
+			}
+		}
 		c.prevTUNGateway = tunGateway
+		c.prevTUNGatewayIPv6 = tunGatewayIPv6
 		c.prevTUNGatewayMu.Unlock()
 	}
 
@@ -396,6 +460,24 @@ func (c *Client) serveConn(conn net.Conn) error {
 		return fmt.Errorf("error routing traffic through TUN %s: %w", tun.Name(), err)
 	}
 
+	// captured IPv6 traffic is either tunneled or blocked while connected, whether the killswitch is enabled
+	// or not, so that it never leaks outside the tunnel
+	ipv6Routed := false
+	if tunGatewayIPv6 != nil {
+		fmt.Printf("Routing all IPv6 traffic through TUN %s\n", tun.Name())
+		if err := c.routeIPv6TrafficThroughTUN(tunGatewayIPv6, isNewIPv6Route); err != nil {
+			print(fmt.Sprintf("Error routing IPv6 traffic through TUN %s: %v\n", tun.Name(), err))
+		} else {
+			ipv6Routed = true
+		}
+	}
+	if ipv6Routed || !captureIPv6 {
+		c.unblockIPv6()
+	} else {
+		// server doesn't tunnel IPv6, so it's blocked to prevent leaking traffic
+		c.blockIPv6()
+	}
+
 	c.setAppStatus(appserver.AppDetailedStatusRunning)
 	c.resetConnDuration()
 	t := time.NewTicker(time.Second)
@@ -404,6 +486,10 @@ func (c *Client) serveConn(conn net.Conn) error {
 		if !c.cfg.Killswitch {
 			fmt.Println("serveConn done, killswitch disabled, routing traffic directly")
 			c.routeTrafficDirectly(tunGateway)
+			if ipv6Routed {
+				c.routeIPv6TrafficDirectly(tunGatewayIPv6)
+			}
+			c.unblockIPv6()
 		}
 	}()
 
@@ -513,6 +599,59 @@ func (c *Client) routeTrafficDirectly(tunGateway net.IP) {
 	}
 }
 
+func (c *Client) routeIPv6TrafficThroughTUN(tunGateway net.IP, isNewRoute bool) error {
+	// route all IPv6 traffic through TUN gateway
+	for _, ipCIDR := range []string{ipv6FirstHalfAddr, ipv6SecondHalfAddr} {
+		if isNewRoute {
+			if err := c.AddRoute(ipCIDR, tunGateway.String()); err != nil {
+				return err
+			}
+		} else {
+			if err := c.ChangeRoute(ipCIDR, tunGateway.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) routeIPv6TrafficDirectly(tunGateway net.IP) {
+	fmt.Println("Routing all IPv6 traffic through default network gateway")
+
+	for _, ipCIDR := range []string{ipv6FirstHalfAddr, ipv6SecondHalfAddr} {
+		if err := c.DeleteRoute(ipCIDR, tunGateway.String()); err != nil {
+			print(fmt.Sprintf("Error routing IPv6 traffic through default network gateway: %v\n", err))
+		}
+	}
+}
+
+func (c *Client) blockIPv6() {
+	if c.ipv6Blocked {
+		return
+	}
+
+	fmt.Println("Blocking IPv6 traffic")
+	if err := c.BlockIPv6(); err != nil {
+		print(fmt.Sprintf("Error blocking IPv6 traffic: %v\n", err))
+		return
+	}
+	c.ipv6Blocked = true
+}
+
+func (c *Client) unblockIPv6() {
+	if !c.ipv6Blocked {
+		return
+	}
+
+	fmt.Println("Unblocking IPv6 traffic")
+	if err := c.UnblockIPv6(); err != nil {
+		print(fmt.Sprintf("Error unblocking IPv6 traffic: %v\n", err))
+		return
+	}
+	c.ipv6Blocked = false
+}
+
 func (c *Client) setupDirectRoutes() error {
 	c.directIPSMu.Lock()
 	defer c.directIPSMu.Unlock()
@@ -526,26 +665,68 @@ func (c *Client) setupDirectRoutes() error {
 	return nil
 }
 
-func (c *Client) setupDirectRoute(ip net.IP) error {
-	if !ip.IsLoopback() {
-		fmt.Printf("Adding direct route to %s, via %s\n", ip.String(), c.defaultGateway.String())
-		if err := c.AddRoute(ip.String()+directRouteNetmaskCIDR, c.defaultGateway.String()); err != nil {
-			return fmt.Errorf("error adding direct route to %s: %w", ip.String(), err)
+// directRoute returns the CIDR and the gateway of the direct route to `ip`. It returns false if there is no
+// gateway to route `ip` through, which is the case of IPv6 addresses on hosts with no default IPv6 route.
+func (c *Client) directRoute(ip net.IP) (ipCIDR, gateway string, ok bool) {
+	if ip.To4() != nil {
+		return ip.String() + directRouteNetmaskCIDR, c.defaultGateway.String(), true
+	}
+	if c.defaultGatewayIPv6 == nil {
+		return "", "", false
+	}
+	return ip.String() + directRouteIPv6PrefixCIDR, c.defaultGatewayIPv6.String(), true
+}
+
+// canCaptureIPv6 tells whether the IPv6 traffic may be routed through the TUN or blocked. It may not if
+// some direct routes are IPv6 ones and there is no default IPv6 gateway to route them through, they would
+// be captured by the VPN too otherwise.
+func (c *Client) canCaptureIPv6() bool {
+	if c.defaultGatewayIPv6 != nil {
+		return true
+	}
+
+	c.directIPSMu.Lock()
+	defer c.directIPSMu.Unlock()
+
+	for _, ip := range c.directIPs {
+		if ip.To4() == nil && !ip.IsLoopback() {
+			return false
 		}
 	}
+	return true
+}
+
+func (c *Client) setupDirectRoute(ip net.IP) error {
+	if ip.IsLoopback() {
+		return nil
+	}
+
+	ipCIDR, gateway, ok := c.directRoute(ip)
+	if !ok {
+		fmt.Printf("Skipping direct route to IPv6 address %s, there is no default IPv6 gateway\n", ip.String())
+		return nil
+	}
+
+	fmt.Printf("Adding direct route to %s, via %s\n", ip.String(), gateway)
+	if err := c.AddRoute(ipCIDR, gateway); err != nil {
+		return fmt.Errorf("error adding direct route to %s: %w", ip.String(), err)
+	}
 
 	return nil
 }
 
 func (c *Client) removeDirectRoute(ip net.IP) error {
-	if !ip.IsLoopback() {
-		fmt.Printf("Removing direct route to %s\n", ip.String())
-		if err := c.DeleteRoute(ip.String()+directRouteNetmaskCIDR, c.defaultGateway.String()); err != nil {
-			return err
-		}
+	if ip.IsLoopback() {
+		return nil
 	}
 
-	return nil
+	ipCIDR, gateway, ok := c.directRoute(ip)
+	if !ok {
+		return nil
+	}
+
+	fmt.Printf("Removing direct route to %s\n", ip.String())
+	return c.DeleteRoute(ipCIDR, gateway)
 }
 
 func (c *Client) removeDirectRoutes() {
@@ -663,10 +844,10 @@ func stcpEntitiesFromEnv() ([]net.IP, error) {
 	return stcpEntities, nil
 }
 
-func (c *Client) shakeHands(conn net.Conn) (TUNIP, TUNGateway net.IP, err error) {
+func (c *Client) shakeHands(conn net.Conn) (ServerHello, error) {
 	unavailableIPs, err := netutil.LocalNetworkInterfaceIPs()
 	if err != nil {
-		return nil, nil, fmt.Errorf("error getting unavailable private IPs: %w", err)
+		return ServerHello{}, fmt.Errorf("error getting unavailable private IPs: %w", err)
 	}
 
 	unavailableIPs = append(unavailableIPs, c.defaultGateway)
@@ -674,6 +855,7 @@ func (c *Client) shakeHands(conn net.Conn) (TUNIP, TUNGateway net.IP, err error)
 	cHello := ClientHello{
 		UnavailablePrivateIPs: unavailableIPs,
 		Passcode:              c.cfg.Passcode,
+		IPv6:                  true,
 	}
 
 	const handshakeTimeout = 5 * time.Second
@@ -681,7 +863,7 @@ func (c *Client) shakeHands(conn net.Conn) (TUNIP, TUNGateway net.IP, err error)
 	fmt.Printf("Sending client hello: %v\n", cHello)
 
 	if err := WriteJSONWithTimeout(conn, &cHello, handshakeTimeout); err != nil {
-		return nil, nil, fmt.Errorf("error sending client hello: %w", err)
+		return ServerHello{}, fmt.Errorf("error sending client hello: %w", err)
 	}
 
 	var sHello ServerHello
@@ -692,16 +874,16 @@ func (c *Client) shakeHands(conn net.Conn) (TUNIP, TUNGateway net.IP, err error)
 				Err: err.Error(),
 			}
 		}
-		return nil, nil, err
+		return ServerHello{}, err
 	}
 
 	fmt.Printf("Got server hello: %v", sHello)
 
 	if sHello.Status != HandshakeStatusOK {
-		return nil, nil, sHello.Status.getError()
+		return ServerHello{}, sHello.Status.getError()
 	}
 
-	return sHello.TUNIP, sHello.TUNGateway, nil
+	return sHello, nil
 }
 
 func (c *Client) dialServer(appCl *app.Client, pk cipher.PubKey) (net.Conn, error) {
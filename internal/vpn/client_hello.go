@@ -9,4 +9,6 @@ import (
 type ClientHello struct {
 	UnavailablePrivateIPs []net.IP `json:"unavailable_private_ips"`
 	Passcode              string   `json:"passcode"`
+	// IPv6 is set by clients able to set up a dual-stack TUN.
+	IPv6 bool `json:"ipv6,omitempty"`
 }
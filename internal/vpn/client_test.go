@@ -0,0 +1,33 @@
+// Package vpn internal/vpn/client_test.go
+package vpn
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_directRoute(t *testing.T) {
+	ipv4, ipv6 := net.ParseIP("1.2.3.4"), net.ParseIP("2001:db8::1")
+	c := &Client{defaultGateway: net.ParseIP("192.168.1.1"), directIPs: []net.IP{ipv4, net.IPv6loopback}}
+
+	ipCIDR, gateway, ok := c.directRoute(ipv4)
+	require.True(t, ok)
+	require.Equal(t, "1.2.3.4/32", ipCIDR)
+	require.Equal(t, "192.168.1.1", gateway)
+
+	// IPv6 traffic is captured as long as no IPv6 direct route is lost because of it
+	_, _, ok = c.directRoute(ipv6)
+	require.False(t, ok)
+	require.True(t, c.canCaptureIPv6())
+	c.directIPs = append(c.directIPs, ipv6)
+	require.False(t, c.canCaptureIPv6())
+
+	c.defaultGatewayIPv6 = &net.IPAddr{IP: net.ParseIP("fe80::1"), Zone: "eth0"}
+	ipCIDR, gateway, ok = c.directRoute(ipv6)
+	require.True(t, ok)
+	require.Equal(t, "2001:db8::1/128", ipCIDR)
+	require.Equal(t, "fe80::1%eth0", gateway)
+	require.True(t, c.canCaptureIPv6())
+}
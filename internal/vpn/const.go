@@ -4,6 +4,8 @@ package vpn
 const (
 	// TUNNetmaskCIDR is a general netmask used for all TUN interfaces in CIDR format (only suffix).
 	TUNNetmaskCIDR = "/29"
+	// TUNNetmaskCIDRv6 is a general netmask used for the IPv6 addresses of all TUN interfaces in CIDR format (only suffix).
+	TUNNetmaskCIDRv6 = "/64"
 	// TUNMTU is MTU value used for all TUN interfaces.
 	TUNMTU = 1500
 )
@@ -13,10 +13,12 @@ import (
 
 var (
 	errCouldFindDefaultNetworkGateway = errors.New("could not find default network gateway")
+	errCouldFindDefaultIPv6Gateway    = errors.New("could not find default IPv6 network gateway")
 	errHandshakeStatusForbidden       = errors.New("password didn't match")
 	errHandshakeStatusInternalError   = errors.New("internal server error")
 	errHandshakeNoFreeIPs             = errors.New("no free IPs left to serve")
 	errHandshakeStatusBadRequest      = errors.New("request was malformed")
+	errHandshakeStatusQuotaExceeded   = errors.New("monthly data cap exceeded")
 	errTimeout                        = errors.New("internal error: Timeout")
 	errNotPermitted                   = errors.New("ioctl: operation not permitted")
 	errVPNServerClosed                = errors.New("vpn-server closed")
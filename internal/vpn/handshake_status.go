@@ -19,6 +19,8 @@ const (
 	HandshakeStatusInternalError
 	// HandshakeStatusForbidden is returned if client had sent the wrong passcode.
 	HandshakeStatusForbidden
+	// HandshakeStatusQuotaExceeded is returned if client is over its monthly transfer cap.
+	HandshakeStatusQuotaExceeded
 )
 
 func (hs HandshakeStatus) String() string {
@@ -33,6 +35,8 @@ func (hs HandshakeStatus) String() string {
 		return "Internal server error"
 	case HandshakeStatusForbidden:
 		return "Forbidden"
+	case HandshakeStatusQuotaExceeded:
+		return "Monthly data cap exceeded"
 	default:
 		return "Unknown code"
 	}
@@ -50,6 +54,8 @@ func (hs HandshakeStatus) getError() error {
 		return errHandshakeStatusInternalError
 	case HandshakeStatusForbidden:
 		return errHandshakeStatusForbidden
+	case HandshakeStatusQuotaExceeded:
+		return errHandshakeStatusQuotaExceeded
 	default:
 		return errors.New("Unknown error code")
 	}
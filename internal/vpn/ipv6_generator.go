@@ -0,0 +1,104 @@
+// Package vpn internal/vpn/ipv6_generator.go
+package vpn
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// IPv6Generator is used to generate IPv6 subnets for TUN interfaces. Subnets are /64
+// ranges taken from a randomly generated unique local address (ULA) /48 prefix, as
+// described in RFC 4193.
+type IPv6Generator struct {
+	mx       sync.Mutex
+	prefix   [6]byte
+	current  uint16
+	reserved map[uint16]struct{}
+}
+
+// NewIPv6Generator creates IPv6 generator with a random ULA prefix.
+func NewIPv6Generator() (*IPv6Generator, error) {
+	var prefix [6]byte
+	prefix[0] = 0xfd
+	if _, err := rand.Read(prefix[1:]); err != nil {
+		return nil, fmt.Errorf("error generating ULA global ID: %w", err)
+	}
+
+	return newIPv6GeneratorWithPrefix(prefix), nil
+}
+
+func newIPv6GeneratorWithPrefix(prefix [6]byte) *IPv6Generator {
+	return &IPv6Generator{
+		prefix: prefix,
+		// subnet 0 is left unused so the generated prefixes never collide with the /48 itself.
+		reserved: map[uint16]struct{}{0: {}},
+	}
+}
+
+// Next gets next available /64 subnet.
+func (g *IPv6Generator) Next() (net.IP, error) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	for id := g.current + 1; id != g.current; id++ {
+		if _, ok := g.reserved[id]; ok {
+			continue
+		}
+
+		g.reserved[id] = struct{}{}
+		g.current = id
+
+		return g.subnetIP(id), nil
+	}
+
+	return nil, errors.New("no free IPv6 subnets left")
+}
+
+// Release makes the subnet of `ip` available for the IP generation again.
+func (g *IPv6Generator) Release(ip net.IP) {
+	id, ok := g.subnetID(ip)
+	if !ok || id == 0 {
+		return
+	}
+
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	delete(g.reserved, id)
+}
+
+func (g *IPv6Generator) subnetIP(id uint16) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, g.prefix[:])
+	ip[6] = byte(id >> 8)
+	ip[7] = byte(id)
+
+	return ip
+}
+
+func (g *IPv6Generator) subnetID(ip net.IP) (uint16, bool) {
+	ip = ip.To16()
+	if ip == nil || ip.To4() != nil {
+		return 0, false
+	}
+
+	for i := range g.prefix {
+		if ip[i] != g.prefix[i] {
+			return 0, false
+		}
+	}
+
+	return uint16(ip[6])<<8 | uint16(ip[7]), true
+}
+
+// ipv6WithHost returns the address with the interface identifier `host` in the /64 `subnet`.
+func ipv6WithHost(subnet net.IP, host byte) net.IP {
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, subnet.To16()[:8])
+	ip[15] = host
+
+	return ip
+}
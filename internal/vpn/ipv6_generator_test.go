@@ -0,0 +1,72 @@
+// Package vpn internal/vpn/ipv6_generator_test.go
+package vpn
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewIPv6Generator(t *testing.T) {
+	g, err := NewIPv6Generator()
+	require.NoError(t, err)
+
+	ip, err := g.Next()
+	require.NoError(t, err)
+	require.Len(t, ip, net.IPv6len)
+	require.Nil(t, ip.To4())
+	// unique local address
+	require.Equal(t, byte(0xfd), ip[0])
+}
+
+func TestIPv6Generator_Next(t *testing.T) {
+	prefix := [6]byte{0xfd, 1, 2, 3, 4, 5}
+	g := newIPv6GeneratorWithPrefix(prefix)
+
+	ip1, err := g.Next()
+	require.NoError(t, err)
+	require.Equal(t, net.ParseIP("fd01:203:405:1::"), ip1)
+
+	ip2, err := g.Next()
+	require.NoError(t, err)
+	require.Equal(t, net.ParseIP("fd01:203:405:2::"), ip2)
+
+	// released subnets are generated again once the others are taken
+	g.Release(ip1)
+	g.current = 0xffff
+	ip, err := g.Next()
+	require.NoError(t, err)
+	require.Equal(t, ip1, ip)
+
+	// addresses out of the prefix are ignored
+	g.Release(net.ParseIP("fd00::1"))
+	g.Release(net.ParseIP("10.0.0.1"))
+	require.Len(t, g.reserved, 3)
+}
+
+func TestIPv6Generator_exhausted(t *testing.T) {
+	g := newIPv6GeneratorWithPrefix([6]byte{0xfd})
+	for id := 0; id <= 0xffff; id++ {
+		g.reserved[uint16(id)] = struct{}{}
+	}
+
+	_, err := g.Next()
+	require.Error(t, err)
+
+	// subnet 0 is never generated
+	g.Release(g.subnetIP(0))
+	_, err = g.Next()
+	require.Error(t, err)
+
+	g.Release(g.subnetIP(7))
+	ip, err := g.Next()
+	require.NoError(t, err)
+	require.Equal(t, g.subnetIP(7), ip)
+}
+
+func TestIPv6WithHost(t *testing.T) {
+	subnet := net.ParseIP("fd01:203:405:1::")
+	require.Equal(t, net.ParseIP("fd01:203:405:1::1"), ipv6WithHost(subnet, 1))
+	require.Equal(t, net.ParseIP("fd01:203:405:1::2"), ipv6WithHost(subnet, 2))
+}
@@ -4,6 +4,7 @@ package vpn
 import (
 	"fmt"
 	"net"
+	"strconv"
 )
 
 func parseCIDR(ipCIDR string) (ipStr, netmask string, err error) { //nolint : actually used in os_windows.go
@@ -14,3 +15,18 @@ func parseCIDR(ipCIDR string) (ipStr, netmask string, err error) { //nolint : ac
 
 	return ip.String(), fmt.Sprintf("%d.%d.%d.%d", net.Mask[0], net.Mask[1], net.Mask[2], net.Mask[3]), nil
 }
+
+func parseCIDRv6(ipCIDR string) (ipStr, prefixLen string, err error) { //nolint : actually used in os_darwin.go
+	ip, net, err := net.ParseCIDR(ipCIDR)
+	if err != nil {
+		return "", "", err
+	}
+
+	ones, _ := net.Mask.Size()
+	return ip.String(), strconv.Itoa(ones), nil
+}
+
+func isIPv6CIDR(ipCIDR string) bool { //nolint : actually used in os_darwin.go and os_windows.go
+	ip, _, err := net.ParseCIDR(ipCIDR)
+	return err == nil && ip.To4() == nil
+}
@@ -12,7 +12,8 @@ import (
 )
 
 const (
-	defaultNetworkGatewayCMD = "netstat -rn | sed -n '/Internet/,/Internet6/p' | grep default | awk '{print $2}'"
+	defaultNetworkGatewayCMD     = "netstat -rn | sed -n '/Internet/,/Internet6/p' | grep default | awk '{print $2}'"
+	defaultNetworkGatewayIPv6CMD = "netstat -rn -f inet6 | grep default | awk '{print $2}'"
 )
 
 // DefaultNetworkGateway fetches system's default network gateway.
@@ -39,6 +40,28 @@ func DefaultNetworkGateway() (net.IP, error) {
 	return nil, errCouldFindDefaultNetworkGateway
 }
 
+// DefaultNetworkGatewayIPv6 fetches system's default IPv6 network gateway, zoned with its interface.
+func DefaultNetworkGatewayIPv6() (*net.IPAddr, error) {
+	outputBytes, err := osutil.RunWithResult("sh", "-c", defaultNetworkGatewayIPv6CMD)
+	if err != nil {
+		return nil, err
+	}
+
+	outputBytes = bytes.TrimRight(outputBytes, "\n")
+
+	lines := bytes.Split(outputBytes, []byte{'\n'})
+	for _, l := range lines {
+		ipStr, zone, _ := strings.Cut(string(l), "%")
+
+		ip := net.ParseIP(ipStr)
+		if ip != nil && ip.To4() == nil {
+			return &net.IPAddr{IP: ip, Zone: zone}, nil
+		}
+	}
+
+	return nil, errCouldFindDefaultIPv6Gateway
+}
+
 func setupClientSysPrivileges() (int, error) {
 	value, err := osutil.GainRoot()
 	if err != nil && strings.Contains(err.Error(), "operation not permitted") {
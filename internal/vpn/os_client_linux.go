@@ -15,7 +15,8 @@ import (
 )
 
 const (
-	defaultNetworkGatewayCMD = `ip r | grep "default via" | awk '{print $3}'`
+	defaultNetworkGatewayCMD     = `ip r | grep "default via" | awk '{print $3}'`
+	defaultNetworkGatewayIPv6CMD = `ip -6 r | grep "default via" | awk '{print $3" "$5}'`
 )
 
 // DefaultNetworkGateway fetches system's default network gateway.
@@ -44,6 +45,30 @@ func DefaultNetworkGateway() (net.IP, error) {
 	return nil, errCouldFindDefaultNetworkGateway
 }
 
+// DefaultNetworkGatewayIPv6 fetches system's default IPv6 network gateway, zoned with its interface.
+func DefaultNetworkGatewayIPv6() (*net.IPAddr, error) {
+	outBytes, err := osutil.RunWithResult("sh", "-c", defaultNetworkGatewayIPv6CMD)
+	if err != nil {
+		return nil, err
+	}
+
+	outBytes = bytes.TrimRight(outBytes, "\n")
+
+	for _, l := range bytes.Split(outBytes, []byte{'\n'}) {
+		fields := bytes.Fields(l)
+		if len(fields) != 2 {
+			continue
+		}
+
+		ip := net.ParseIP(string(fields[0]))
+		if ip != nil && ip.To4() == nil {
+			return &net.IPAddr{IP: ip, Zone: string(fields[1])}, nil
+		}
+	}
+
+	return nil, errCouldFindDefaultIPv6Gateway
+}
+
 func setupClientSysPrivileges() (int, error) {
 	var err error
 
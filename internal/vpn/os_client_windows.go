@@ -12,7 +12,8 @@ import (
 )
 
 const (
-	defaultNetworkGatewayCMD = "route PRINT"
+	defaultNetworkGatewayCMD     = "route PRINT"
+	defaultNetworkGatewayIPv6CMD = "route PRINT -6"
 )
 
 var redundantWhitespacesCleanupRegex = regexp.MustCompile(`[\s\p{Zs}]{2,}`)
@@ -51,6 +52,32 @@ func DefaultNetworkGateway() (net.IP, error) {
 	return nil, errCouldFindDefaultNetworkGateway
 }
 
+// DefaultNetworkGatewayIPv6 fetches system's default IPv6 network gateway, zoned with the index of its interface.
+func DefaultNetworkGatewayIPv6() (*net.IPAddr, error) {
+	outBytes, err := osutil.RunWithResult("cmd", "/C", defaultNetworkGatewayIPv6CMD)
+	if err != nil {
+		return nil, err
+	}
+
+	outBytes = bytes.TrimRight(outBytes, "\n\r")
+
+	lines := bytes.Split(outBytes, []byte{'\n'})
+	for _, line := range lines {
+		// If | Metric | Network Destination | Gateway
+		lineTokens := bytes.Fields(line)
+		if len(lineTokens) < 4 || string(lineTokens[2]) != "::/0" {
+			continue
+		}
+
+		ip := net.ParseIP(string(lineTokens[3]))
+		if ip != nil && ip.To4() == nil {
+			return &net.IPAddr{IP: ip, Zone: string(lineTokens[0])}, nil
+		}
+	}
+
+	return nil, errCouldFindDefaultIPv6Gateway
+}
+
 func setupClientSysPrivileges() (suid int, err error) { //nolint
 	return 0, nil
 }
@@ -30,6 +30,50 @@ func (c *Client) SetupTUN(ifcName, ipCIDR, gateway string, mtu int) error {
 	return osutil.Run("ifconfig", ifcName, ip, gateway, "mtu", strconv.Itoa(mtu), "netmask", netmask, "up")
 }
 
+// SetupTUNv6 assigns the IPv6 address `ipCIDR` to the allocated TUN interface.
+func (c *Client) SetupTUNv6(ifcName, ipCIDR string) error {
+	ip, prefixLen, err := parseCIDRv6(ipCIDR)
+	if err != nil {
+		return fmt.Errorf("error parsing IP CIDR: %w", err)
+	}
+	if err := c.setSysPrivileges(); err != nil {
+		print(fmt.Sprintf("Failed to setup system privileges for SetupTUNv6: %v\n", err))
+		return err
+	}
+	defer c.releaseSysPrivileges()
+	return osutil.Run("ifconfig", ifcName, "inet6", ip, "prefixlen", prefixLen, "alias")
+}
+
+// BlockIPv6 makes all the IPv6 destinations unreachable, so no IPv6 traffic leaks out of the VPN.
+func (c *Client) BlockIPv6() error {
+	if err := c.setSysPrivileges(); err != nil {
+		print(fmt.Sprintf("Failed to setup system privileges for BlockIPv6: %v\n", err))
+		return err
+	}
+	defer c.releaseSysPrivileges()
+	for _, ipCIDR := range []string{ipv6FirstHalfAddr, ipv6SecondHalfAddr} {
+		if err := osutil.Run("route", "add", "-inet6", "-net", ipCIDR, "::1", "-reject"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnblockIPv6 reverts BlockIPv6.
+func (c *Client) UnblockIPv6() error {
+	if err := c.setSysPrivileges(); err != nil {
+		print(fmt.Sprintf("Failed to setup system privileges for UnblockIPv6: %v\n", err))
+		return err
+	}
+	defer c.releaseSysPrivileges()
+	for _, ipCIDR := range []string{ipv6FirstHalfAddr, ipv6SecondHalfAddr} {
+		if err := osutil.Run("route", "delete", "-inet6", "-net", ipCIDR, "::1"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SetupDNS trying to set DNS server
 func (c *Client) SetupDNS() {
 	defaultDNSByte, _ := osutil.RunWithResult("networksetup", "-getdnsservers", "Wi-Fi") //nolint
@@ -77,6 +121,15 @@ func (c *Client) DeleteRoute(ipCIDR, gateway string) error {
 }
 
 func (c *Client) modifyRoutingTable(action, ipCIDR, gateway string) error {
+	if isIPv6CIDR(ipCIDR) {
+		if err := c.setSysPrivileges(); err != nil {
+			print(fmt.Sprintf("Failed to setup system privileges for %s: %v\n", action, err))
+			return err
+		}
+		defer c.releaseSysPrivileges()
+		return osutil.Run("route", action, "-inet6", "-net", ipCIDR, gateway)
+	}
+
 	ip, netmask, err := parseCIDR(ipCIDR)
 	if err != nil {
 		return fmt.Errorf("error parsing IP CIDR: %w", err)
@@ -99,3 +152,13 @@ func (s *Server) SetupTUN(ifcName, ipCIDR, gateway string, mtu int) error {
 
 	return osutil.Run("ifconfig", ifcName, ip, gateway, "mtu", strconv.Itoa(mtu), "netmask", netmask, "up")
 }
+
+// SetupTUNv6 assigns the IPv6 address `ipCIDR` to the allocated TUN interface.
+func (s *Server) SetupTUNv6(ifcName, ipCIDR string) error {
+	ip, prefixLen, err := parseCIDRv6(ipCIDR)
+	if err != nil {
+		return fmt.Errorf("error parsing IP CIDR: %w", err)
+	}
+
+	return osutil.Run("ifconfig", ifcName, "inet6", ip, "prefixlen", prefixLen, "alias")
+}
@@ -67,6 +67,54 @@ func (c *Client) SetupTUN(ifcName, ipCIDR, gateway string, mtu int) error {
 	return nil
 }
 
+// SetupTUNv6 assigns the IPv6 address `ipCIDR` to the allocated TUN interface.
+func (c *Client) SetupTUNv6(ifcName, ipCIDR string) error {
+	if err := c.setSysPrivileges(); err != nil {
+		print(fmt.Sprintf("Failed to setup system privileges for SetupTUNv6: %v\n", err))
+		return err
+	}
+	defer c.releaseSysPrivileges()
+	if err := osutil.Run("ip", "-6", "a", "add", ipCIDR, "dev", ifcName); err != nil {
+		return fmt.Errorf("error assigning IPv6: %w", err)
+	}
+	return nil
+}
+
+// BlockIPv6 makes all the IPv6 destinations unreachable, so no IPv6 traffic leaks out of the VPN.
+func (c *Client) BlockIPv6() error {
+	if err := c.setSysPrivileges(); err != nil {
+		print(fmt.Sprintf("Failed to setup system privileges for BlockIPv6: %v\n", err))
+		return err
+	}
+	defer c.releaseSysPrivileges()
+	for _, ipCIDR := range []string{ipv6FirstHalfAddr, ipv6SecondHalfAddr} {
+		err := osutil.Run("ip", "-6", "r", "add", "unreachable", ipCIDR)
+		var e *osutil.ErrorWithStderr
+		if errors.As(err, &e) && strings.Contains(string(e.Stderr), "File exists") {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnblockIPv6 reverts BlockIPv6.
+func (c *Client) UnblockIPv6() error {
+	if err := c.setSysPrivileges(); err != nil {
+		print(fmt.Sprintf("Failed to setup system privileges for UnblockIPv6: %v\n", err))
+		return err
+	}
+	defer c.releaseSysPrivileges()
+	for _, ipCIDR := range []string{ipv6FirstHalfAddr, ipv6SecondHalfAddr} {
+		if err := osutil.Run("ip", "-6", "r", "del", "unreachable", ipCIDR); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ChangeRoute changes current route to `ip` to go through the `gateway`
 // in the OS routing table.
 func (c *Client) ChangeRoute(ip, gateway string) error {
@@ -75,7 +123,7 @@ func (c *Client) ChangeRoute(ip, gateway string) error {
 		return err
 	}
 	defer c.releaseSysPrivileges()
-	return osutil.Run("ip", "r", "change", ip, "via", gateway)
+	return osutil.Run("ip", append([]string{"r", "change", ip}, viaGatewayArgs(gateway)...)...)
 }
 
 // AddRoute adds route to `ip` with `netmask` through the `gateway` to the OS routing table.
@@ -85,7 +133,7 @@ func (c *Client) AddRoute(ip, gateway string) error {
 		return err
 	}
 	defer c.releaseSysPrivileges()
-	err := osutil.Run("ip", "r", "add", ip, "via", gateway)
+	err := osutil.Run("ip", append([]string{"r", "add", ip}, viaGatewayArgs(gateway)...)...)
 
 	var e *osutil.ErrorWithStderr
 	if errors.As(err, &e) {
@@ -110,7 +158,16 @@ func (c *Client) DeleteRoute(ip, gateway string) error {
 		return err
 	}
 	defer c.releaseSysPrivileges()
-	return osutil.Run("ip", "r", "del", ip, "via", gateway)
+	return osutil.Run("ip", append([]string{"r", "del", ip}, viaGatewayArgs(gateway)...)...)
+}
+
+// viaGatewayArgs returns the `ip route` arguments routing through `gateway`, which is zoned with its
+// interface in case of link-local IPv6 gateways.
+func viaGatewayArgs(gateway string) []string {
+	if ip, zone, ok := strings.Cut(gateway, "%"); ok {
+		return []string{"via", ip, "dev", zone}
+	}
+	return []string{"via", gateway}
 }
 
 // SetupDNS set dns address for TUN device on tun0
@@ -143,6 +200,14 @@ func (c *Client) RevertDNS() {
 
 // Server
 
+// SetupTUNv6 assigns the IPv6 address `ipCIDR` to the allocated TUN interface.
+func (s *Server) SetupTUNv6(ifcName, ipCIDR string) error {
+	if err := osutil.Run("ip", "-6", "a", "add", ipCIDR, "dev", ifcName); err != nil {
+		return fmt.Errorf("error assigning IPv6: %w", err)
+	}
+	return nil
+}
+
 // SetupTUN sets the allocated TUN interface up, setting its IP, gateway, netmask and MTU.
 func (s *Server) SetupTUN(ifcName, ipCIDR, gateway string, mtu int) error {
 	if err := osutil.Run("ip", "a", "add", ipCIDR, "dev", ifcName); err != nil {
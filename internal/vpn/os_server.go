@@ -39,6 +39,33 @@ func BlockIPToLocalNetwork(_, _ net.IP) error {
 	return errServerMethodsNotSupported
 }
 
+// GetIP6TablesForwardPolicy gets current policy for ip6tables `forward` chain.
+func GetIP6TablesForwardPolicy() (string, error) {
+	return "", errServerMethodsNotSupported
+}
+
+// SetIP6TablesForwardPolicy sets `policy` for ip6tables `forward` chain.
+func SetIP6TablesForwardPolicy(_ string) error {
+	return errServerMethodsNotSupported
+}
+
+// SetIP6TablesForwardAcceptPolicy sets ACCEPT policy for ip6tables `forward` chain.
+func SetIP6TablesForwardAcceptPolicy() error {
+	return errServerMethodsNotSupported
+}
+
+// AllowIPv6ToLocalNetwork allows all the packets coming from `source`
+// to private and link-local IPv6 ranges.
+func AllowIPv6ToLocalNetwork(_ net.IP) error {
+	return errServerMethodsNotSupported
+}
+
+// BlockIPv6ToLocalNetwork blocks all the packets coming from `source`
+// to private and link-local IPv6 ranges.
+func BlockIPv6ToLocalNetwork(_ net.IP) error {
+	return errServerMethodsNotSupported
+}
+
 // GetIPv4ForwardingValue gets current value of IPv4 forwarding.
 func GetIPv4ForwardingValue() (string, error) {
 	return "", errServerMethodsNotSupported
@@ -78,3 +105,13 @@ func EnableIPMasquerading(_ string) error {
 func DisableIPMasquerading(_ string) error {
 	return errServerMethodsNotSupported
 }
+
+// EnableIPv6Masquerading enables IPv6 masquerading for the interface with name `ifcName`.
+func EnableIPv6Masquerading(_ string) error {
+	return errServerMethodsNotSupported
+}
+
+// DisableIPv6Masquerading disables IPv6 masquerading for the interface with name `ifcName`.
+func DisableIPv6Masquerading(_ string) error {
+	return errServerMethodsNotSupported
+}
@@ -23,6 +23,13 @@ const (
 	disableIPMasqueradingCMDFmt    = "iptables -t nat -D POSTROUTING -o %s -j MASQUERADE"
 	blockIPToLocalNetCMDFmt        = "iptables -I FORWARD -d 192.168.0.0/16,172.16.0.0/12,10.0.0.0/8 -s %s -j DROP && iptables -I INPUT -d 192.168.0.0/16,172.16.0.0/12,10.0.0.0/8 -s %s -j DROP"
 	allowIPToLocalNetCMDFmt        = "iptables -D FORWARD -d 192.168.0.0/16,172.16.0.0/12,10.0.0.0/8 -s %s -j DROP && iptables -D INPUT -d 192.168.0.0/16,172.16.0.0/12,10.0.0.0/8 -s %s -j DROP"
+
+	getIP6TablesForwardPolicyCMD    = "ip6tables -L | grep \"Chain FORWARD\" | tr -d '()' | awk '{print $4}'"
+	setIP6TablesForwardPolicyCMDFmt = "ip6tables --policy FORWARD %s"
+	enableIPv6MasqueradingCMDFmt    = "ip6tables -t nat -A POSTROUTING -o %s -j MASQUERADE"
+	disableIPv6MasqueradingCMDFmt   = "ip6tables -t nat -D POSTROUTING -o %s -j MASQUERADE"
+	blockIPv6ToLocalNetCMDFmt       = "ip6tables -I FORWARD -d fc00::/7,fe80::/10 -s %s -j DROP && ip6tables -I INPUT -d fc00::/7,fe80::/10 -s %s -j DROP"
+	allowIPv6ToLocalNetCMDFmt       = "ip6tables -D FORWARD -d fc00::/7,fe80::/10 -s %s -j DROP && ip6tables -D INPUT -d fc00::/7,fe80::/10 -s %s -j DROP"
 )
 
 // GetIPTablesForwardPolicy gets current policy for iptables `forward` chain.
@@ -63,6 +70,44 @@ func BlockIPToLocalNetwork(src, dst net.IP) error { //nolint:all
 	return osutil.Run("sh", "-c", cmd)
 }
 
+// GetIP6TablesForwardPolicy gets current policy for ip6tables `forward` chain.
+func GetIP6TablesForwardPolicy() (string, error) {
+	outputBytes, err := osutil.RunWithResult("sh", "-c", getIP6TablesForwardPolicyCMD)
+	if err != nil {
+		return "", err
+	}
+	if len(outputBytes) == 0 {
+		return "", errPermissionDenied
+	}
+	return strings.TrimRight(string(outputBytes), "\n"), nil
+}
+
+// SetIP6TablesForwardPolicy sets `policy` for ip6tables `forward` chain.
+func SetIP6TablesForwardPolicy(policy string) error {
+	cmd := fmt.Sprintf(setIP6TablesForwardPolicyCMDFmt, policy)
+	return osutil.Run("sh", "-c", cmd)
+}
+
+// SetIP6TablesForwardAcceptPolicy sets ACCEPT policy for ip6tables `forward` chain.
+func SetIP6TablesForwardAcceptPolicy() error {
+	const policy = "ACCEPT"
+	return SetIP6TablesForwardPolicy(policy)
+}
+
+// AllowIPv6ToLocalNetwork allows all the packets coming from `source`
+// to private and link-local IPv6 ranges.
+func AllowIPv6ToLocalNetwork(src net.IP) error {
+	cmd := fmt.Sprintf(allowIPv6ToLocalNetCMDFmt, src, src)
+	return osutil.Run("sh", "-c", cmd)
+}
+
+// BlockIPv6ToLocalNetwork blocks all the packets coming from `source`
+// to private and link-local IPv6 ranges.
+func BlockIPv6ToLocalNetwork(src net.IP) error {
+	cmd := fmt.Sprintf(blockIPv6ToLocalNetCMDFmt, src, src)
+	return osutil.Run("sh", "-c", cmd)
+}
+
 // GetIPv4ForwardingValue gets current value of IPv4 forwarding.
 func GetIPv4ForwardingValue() (string, error) {
 	return getIPForwardingValue(getIPv4ForwardingCMD)
@@ -121,6 +166,18 @@ func DisableIPMasquerading(ifcName string) error {
 	return osutil.Run("sh", "-c", cmd)
 }
 
+// EnableIPv6Masquerading enables IPv6 masquerading for the interface with name `ifcName`.
+func EnableIPv6Masquerading(ifcName string) error {
+	cmd := fmt.Sprintf(enableIPv6MasqueradingCMDFmt, ifcName)
+	return osutil.Run("sh", "-c", cmd)
+}
+
+// DisableIPv6Masquerading disables IPv6 masquerading for the interface with name `ifcName`.
+func DisableIPv6Masquerading(ifcName string) error {
+	cmd := fmt.Sprintf(disableIPv6MasqueradingCMDFmt, ifcName)
+	return osutil.Run("sh", "-c", cmd)
+}
+
 func getIPForwardingValue(cmd string) (string, error) {
 	outBytes, err := osutil.RunWithResult("sh", "-c", cmd)
 	if err != nil {
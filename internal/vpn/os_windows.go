@@ -4,7 +4,10 @@
 package vpn
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"strings"
 
 	"github.com/skycoin/skywire/pkg/util/osutil"
 )
@@ -14,6 +17,12 @@ const (
 	tunMTUSetupCMDFmt = "netsh interface ipv4 set subinterface \"%s\" mtu=%d"
 	tunDNSCMDFmt      = "netsh interface ip set dns \"%s\" static %s"
 	modifyRouteCMDFmt = "route %s %s mask %s %s"
+
+	tunSetupIPv6CMDFmt      = "netsh interface ipv6 add address \"%s\" %s"
+	modifyRouteIPv6Fmt      = "route %s %s %s"
+	modifyZonedRouteIPv6Fmt = "route %s %s %s IF %s"
+	blockIPv6RouteCMD       = "netsh interface ipv6 add route %s interface=%d store=active"
+	unblockIPv6RouteCMD     = "netsh interface ipv6 delete route %s interface=%d"
 )
 
 // SetupTUN sets the allocated TUN interface up, setting its IP, gateway, netmask and MTU.
@@ -40,6 +49,60 @@ func (c *Client) SetupTUN(ifcName, ipCIDR, gateway string, mtu int) error {
 	return nil
 }
 
+// SetupTUNv6 assigns the IPv6 address `ipCIDR` to the allocated TUN interface.
+func (c *Client) SetupTUNv6(ifcName, ipCIDR string) error {
+	setupCmd := fmt.Sprintf(tunSetupIPv6CMDFmt, ifcName, ipCIDR)
+	if err := osutil.Run("cmd", "/C", setupCmd); err != nil {
+		return fmt.Errorf("error running command %s: %w", setupCmd, err)
+	}
+	return nil
+}
+
+// BlockIPv6 routes all the IPv6 destinations to the loopback interface, so no IPv6 traffic
+// leaks out of the VPN.
+func (c *Client) BlockIPv6() error {
+	ifcIndex, err := loopbackInterfaceIndex()
+	if err != nil {
+		return err
+	}
+	for _, ipCIDR := range []string{ipv6FirstHalfAddr, ipv6SecondHalfAddr} {
+		cmd := fmt.Sprintf(blockIPv6RouteCMD, ipCIDR, ifcIndex)
+		if err := osutil.Run("cmd", "/C", cmd); err != nil {
+			return fmt.Errorf("error running command %s: %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+// UnblockIPv6 reverts BlockIPv6.
+func (c *Client) UnblockIPv6() error {
+	ifcIndex, err := loopbackInterfaceIndex()
+	if err != nil {
+		return err
+	}
+	for _, ipCIDR := range []string{ipv6FirstHalfAddr, ipv6SecondHalfAddr} {
+		cmd := fmt.Sprintf(unblockIPv6RouteCMD, ipCIDR, ifcIndex)
+		if err := osutil.Run("cmd", "/C", cmd); err != nil {
+			return fmt.Errorf("error running command %s: %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+// loopbackInterfaceIndex returns the index of the loopback interface, the blocked IPv6 traffic is routed to.
+func loopbackInterfaceIndex() (int, error) {
+	ifcs, err := net.Interfaces()
+	if err != nil {
+		return 0, fmt.Errorf("error listing network interfaces: %w", err)
+	}
+	for _, ifc := range ifcs {
+		if ifc.Flags&net.FlagLoopback != 0 {
+			return ifc.Index, nil
+		}
+	}
+	return 0, errors.New("no loopback interface found")
+}
+
 // SetupDNS trying to set DNS server
 func (c *Client) SetupDNS() {
 	dnsSetupCmd := fmt.Sprintf(tunDNSCMDFmt, c.tun.Name(), c.cfg.DNSAddr)
@@ -75,13 +138,22 @@ func (c *Client) DeleteRoute(ipCIDR, gateway string) error {
 }
 
 func modifyRoutingTable(action, ipCIDR, gateway string) error {
-	ip, netmask, err := parseCIDR(ipCIDR)
-	if err != nil {
-		return fmt.Errorf("error parsing IP CIDR: %w", err)
+	var cmd string
+	if isIPv6CIDR(ipCIDR) {
+		if ip, zone, ok := strings.Cut(gateway, "%"); ok {
+			cmd = fmt.Sprintf(modifyZonedRouteIPv6Fmt, action, ipCIDR, ip, zone)
+		} else {
+			cmd = fmt.Sprintf(modifyRouteIPv6Fmt, action, ipCIDR, gateway)
+		}
+	} else {
+		ip, netmask, err := parseCIDR(ipCIDR)
+		if err != nil {
+			return fmt.Errorf("error parsing IP CIDR: %w", err)
+		}
+		cmd = fmt.Sprintf(modifyRouteCMDFmt, action, ip, netmask, gateway)
 	}
 
-	cmd := fmt.Sprintf(modifyRouteCMDFmt, action, ip, netmask, gateway)
-	err = osutil.Run("cmd", "/C", cmd)
+	err := osutil.Run("cmd", "/C", cmd)
 	if err != nil {
 		return errPermissionDenied
 	}
@@ -107,3 +179,12 @@ func (s *Server) SetupTUN(ifcName, ipCIDR, gateway string, mtu int) error {
 
 	return nil
 }
+
+// SetupTUNv6 assigns the IPv6 address `ipCIDR` to the allocated TUN interface.
+func (s *Server) SetupTUNv6(ifcName, ipCIDR string) error {
+	setupCmd := fmt.Sprintf(tunSetupIPv6CMDFmt, ifcName, ipCIDR)
+	if err := osutil.Run("cmd", "/C", setupCmd); err != nil {
+		return fmt.Errorf("error running command %s: %w", setupCmd, err)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+// Package vpn internal/vpn/quota.go
+package vpn
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/app/appacl"
+	"github.com/skycoin/skywire/pkg/app/appserver"
+)
+
+const (
+	// UsageFileName is the name of the file persisting clients' data usage within the vpn-server work dir.
+	UsageFileName = "usage.json"
+	// usageReportInterval is the interval of persisting clients' data usage and reporting it to the visor.
+	usageReportInterval = 30 * time.Second
+	// usagePeriodLayout is the layout of the quota period, counters are reset every month.
+	usagePeriodLayout = "2006-01"
+)
+
+// usageCounters are the persisted data usage counters of a client.
+type usageCounters struct {
+	Sent     uint64 `json:"sent"`
+	Received uint64 `json:"received"`
+}
+
+// usageFile is the persisted data usage of all the clients.
+type usageFile struct {
+	Period  string                          `json:"period"`
+	Clients map[cipher.PubKey]usageCounters `json:"clients"`
+}
+
+// clientUsage is the data usage of a client shared between all of its connections.
+type clientUsage struct {
+	sent         uint64
+	received     uint64
+	sendLimiter  *rateLimiter
+	recvLimiter  *rateLimiter
+	monthlyCap   uint64
+	rateLimitBps uint64
+}
+
+func (u *clientUsage) exceeded() bool {
+	if u.monthlyCap == 0 {
+		return false
+	}
+	return atomic.LoadUint64(&u.sent)+atomic.LoadUint64(&u.received) >= u.monthlyCap
+}
+
+// usageTracker keeps the per-client rate limits and monthly transfer caps of the server.
+type usageTracker struct {
+	rateLimit  uint64
+	monthlyCap uint64
+	path       string
+	now        func() time.Time
+
+	month int64 // month of the period, read atomically by the accounting path
+
+	mx      sync.Mutex
+	period  string
+	clients map[cipher.PubKey]*clientUsage
+}
+
+// usageMonth returns the number of the month of `t`, counted from year 0.
+func usageMonth(t time.Time) int64 {
+	t = t.UTC()
+	return int64(t.Year())*12 + int64(t.Month()) - 1
+}
+
+func newUsageTracker(cfg ServerConfig) (*usageTracker, error) {
+	now := time.Now()
+	t := &usageTracker{
+		rateLimit:  cfg.RateLimit,
+		monthlyCap: cfg.MonthlyCap,
+		path:       cfg.UsagePath,
+		now:        time.Now,
+		month:      usageMonth(now),
+		period:     now.UTC().Format(usagePeriodLayout),
+		clients:    make(map[cipher.PubKey]*clientUsage),
+	}
+	if t.path == "" {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(t.path) //nolint:gosec
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+		return nil, fmt.Errorf("error reading usage file: %w", err)
+	}
+
+	var f usageFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error decoding usage file %s: %w", t.path, err)
+	}
+	if f.Period != t.period {
+		// counters of the previous months are not carried over
+		return t, nil
+	}
+	for pk, c := range f.Clients {
+		u := t.newClientUsage()
+		u.sent, u.received = c.Sent, c.Received
+		t.clients[pk] = u
+	}
+
+	return t, nil
+}
+
+func (t *usageTracker) newClientUsage() *clientUsage {
+	return &clientUsage{
+		sendLimiter:  newRateLimiter(t.rateLimit),
+		recvLimiter:  newRateLimiter(t.rateLimit),
+		monthlyCap:   t.monthlyCap,
+		rateLimitBps: t.rateLimit,
+	}
+}
+
+// rollover resets the counters of all clients once a new month starts. It is called on every
+// accounting, so that the clients idle at the start of the month are reset as well.
+func (t *usageTracker) rollover() {
+	now := t.now()
+	month := usageMonth(now)
+	if atomic.LoadInt64(&t.month) == month {
+		return
+	}
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if atomic.LoadInt64(&t.month) == month {
+		return
+	}
+	t.period = now.UTC().Format(usagePeriodLayout)
+	for _, u := range t.clients {
+		atomic.StoreUint64(&u.sent, 0)
+		atomic.StoreUint64(&u.received, 0)
+	}
+	atomic.StoreInt64(&t.month, month)
+}
+
+// client gets the usage of the client with `pk`.
+func (t *usageTracker) client(pk cipher.PubKey) *clientUsage {
+	t.rollover()
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	u, ok := t.clients[pk]
+	if !ok {
+		u = t.newClientUsage()
+		t.clients[pk] = u
+	}
+
+	return u
+}
+
+// exceeded checks whether the client with `pk` is over its monthly transfer cap.
+func (t *usageTracker) exceeded(pk cipher.PubKey) bool {
+	return t.client(pk).exceeded()
+}
+
+// wrapConn makes `conn` subject to the rate limit and monthly transfer cap of its client.
+func (t *usageTracker) wrapConn(conn net.Conn) net.Conn {
+	pk, ok := appacl.RemotePK(conn)
+	if !ok {
+		return conn
+	}
+
+	return &quotaConn{
+		Conn:    conn,
+		tracker: t,
+		usage:   t.client(pk),
+	}
+}
+
+func (t *usageTracker) summary() []appserver.ClientUsage {
+	t.rollover()
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	summary := make([]appserver.ClientUsage, 0, len(t.clients))
+	for pk, u := range t.clients {
+		summary = append(summary, appserver.ClientUsage{
+			PK:         pk,
+			Period:     t.period,
+			Sent:       atomic.LoadUint64(&u.sent),
+			Received:   atomic.LoadUint64(&u.received),
+			MonthlyCap: u.monthlyCap,
+			RateLimit:  u.rateLimitBps,
+		})
+	}
+
+	return summary
+}
+
+// save persists the counters, so they survive server restarts.
+func (t *usageTracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+	t.rollover()
+
+	t.mx.Lock()
+	f := usageFile{
+		Period:  t.period,
+		Clients: make(map[cipher.PubKey]usageCounters, len(t.clients)),
+	}
+	for pk, u := range t.clients {
+		f.Clients[pk] = usageCounters{
+			Sent:     atomic.LoadUint64(&u.sent),
+			Received: atomic.LoadUint64(&u.received),
+		}
+	}
+	t.mx.Unlock()
+
+	data, err := json.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil { //nolint
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, t.path)
+}
+
+// quotaConn counts the traffic of a client, throttles it and fails once the client is over its cap.
+type quotaConn struct {
+	net.Conn
+	tracker *usageTracker
+	usage   *clientUsage
+}
+
+func (c *quotaConn) Read(p []byte) (int, error) {
+	c.tracker.rollover()
+	if c.usage.exceeded() {
+		return 0, errHandshakeStatusQuotaExceeded
+	}
+
+	n, err := c.Conn.Read(p)
+	atomic.AddUint64(&c.usage.received, uint64(n))
+	c.usage.recvLimiter.wait(n)
+
+	return n, err
+}
+
+func (c *quotaConn) Write(p []byte) (int, error) {
+	c.tracker.rollover()
+	if c.usage.exceeded() {
+		return 0, errHandshakeStatusQuotaExceeded
+	}
+
+	c.usage.sendLimiter.wait(len(p))
+	n, err := c.Conn.Write(p)
+	atomic.AddUint64(&c.usage.sent, uint64(n))
+
+	return n, err
+}
+
+// rateLimiter is a token bucket allowing bursts of up to one second of traffic.
+type rateLimiter struct {
+	mx     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter of `rate` bytes per second, nil if the rate is unlimited.
+func newRateLimiter(rate uint64) *rateLimiter {
+	if rate == 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until `n` bytes may pass.
+func (l *rateLimiter) wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	l.mx.Lock()
+	now := time.Now()
+	l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mx.Unlock()
+
+	time.Sleep(delay)
+}
@@ -0,0 +1,108 @@
+// Package vpn internal/vpn/quota_test.go
+package vpn
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+func TestUsageTracker_exceeded(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+
+	tracker, err := newUsageTracker(ServerConfig{MonthlyCap: 10})
+	require.NoError(t, err)
+
+	u := tracker.client(pk)
+	require.False(t, tracker.exceeded(pk))
+	u.sent, u.received = 4, 6
+	require.True(t, tracker.exceeded(pk))
+
+	unlimited, err := newUsageTracker(ServerConfig{})
+	require.NoError(t, err)
+	unlimited.client(pk).sent = 1 << 40
+	require.False(t, unlimited.exceeded(pk))
+}
+
+func TestUsageTracker_rollover(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+
+	now := time.Date(2024, time.January, 31, 23, 0, 0, 0, time.UTC)
+	tracker, err := newUsageTracker(ServerConfig{MonthlyCap: 10})
+	require.NoError(t, err)
+	tracker.now = func() time.Time { return now }
+	tracker.month = usageMonth(now)
+
+	tracker.client(pk).sent = 10
+	require.True(t, tracker.exceeded(pk))
+
+	// an idle client is reset by the accounting of its connection once the month changes
+	conn := &quotaConn{Conn: nopConn{}, tracker: tracker, usage: tracker.clients[pk]}
+	now = now.Add(2 * time.Hour)
+	n, err := conn.Write([]byte("ab"))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.False(t, tracker.exceeded(pk))
+
+	summary := tracker.summary()
+	require.Len(t, summary, 1)
+	require.Equal(t, "2024-02", summary[0].Period)
+	require.Equal(t, uint64(2), summary[0].Sent)
+}
+
+func TestUsageTracker_save(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	path := filepath.Join(t.TempDir(), UsageFileName)
+
+	tracker, err := newUsageTracker(ServerConfig{UsagePath: path})
+	require.NoError(t, err)
+	u := tracker.client(pk)
+	u.sent, u.received = 3, 5
+	require.NoError(t, tracker.save())
+
+	loaded, err := newUsageTracker(ServerConfig{UsagePath: path})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), loaded.client(pk).sent)
+	require.Equal(t, uint64(5), loaded.client(pk).received)
+}
+
+func TestQuotaConn(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	tracker, err := newUsageTracker(ServerConfig{MonthlyCap: 4})
+	require.NoError(t, err)
+	conn := &quotaConn{Conn: nopConn{}, tracker: tracker, usage: tracker.client(pk)}
+
+	_, err = conn.Write([]byte("abc"))
+	require.NoError(t, err)
+	_, err = conn.Read(make([]byte, 1))
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("d"))
+	require.Equal(t, errHandshakeStatusQuotaExceeded, err)
+	_, err = conn.Read(make([]byte, 1))
+	require.Equal(t, errHandshakeStatusQuotaExceeded, err)
+}
+
+func TestRateLimiter(t *testing.T) {
+	var unlimited *rateLimiter
+	unlimited.wait(1 << 30)
+
+	l := newRateLimiter(1000)
+	start := time.Now()
+	l.wait(1000) // the burst passes at once
+	l.wait(100)
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+// nopConn reads and writes all the bytes.
+type nopConn struct {
+	net.Conn
+}
+
+func (nopConn) Read(p []byte) (int, error)  { return len(p), nil }
+func (nopConn) Write(p []byte) (int, error) { return len(p), nil }
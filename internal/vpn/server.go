@@ -8,12 +8,17 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/skycoin/skywire-utilities/pkg/netutil"
 	"github.com/skycoin/skywire/pkg/app"
+	"github.com/skycoin/skywire/pkg/app/appacl"
 	"github.com/skycoin/skywire/pkg/app/appserver"
 )
 
+// aclCheckInterval is the interval of closing the connections of clients no longer allowed by the ACL.
+const aclCheckInterval = 10 * time.Second
+
 // Server is a VPN server.
 type Server struct {
 	cfg                        ServerConfig
@@ -21,12 +26,25 @@ type Server struct {
 	lis                        net.Listener
 	serveOnce                  sync.Once
 	ipGen                      *IPGenerator
+	ipv6Gen                    *IPv6Generator
 	defaultNetworkInterface    string
 	defaultNetworkInterfaceIPs []net.IP
 	ipv4ForwardingVal          string
 	ipv6ForwardingVal          string
 	iptablesForwardPolicy      string
+	ip6tablesForwardPolicy     string
 	appCl                      *app.Client
+	connsMx                    sync.Mutex
+	conns                      map[net.Conn]struct{}
+	usage                      *usageTracker
+}
+
+// tunAddrs are the addresses assigned to one end of the VPN tunnel.
+type tunAddrs struct {
+	ip          net.IP
+	gateway     net.IP
+	ipv6        net.IP
+	gatewayIPv6 net.IP
 }
 
 // NewServer creates VPN server instance.
@@ -36,7 +54,14 @@ func NewServer(cfg ServerConfig, appCl *app.Client) (*Server, error) {
 		cfg:   cfg,
 		ipGen: NewIPGenerator(),
 		appCl: appCl,
+		conns: make(map[net.Conn]struct{}),
+	}
+
+	usage, err := newUsageTracker(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error loading clients usage: %w", err)
 	}
+	s.usage = usage
 
 	defaultNetworkIfcs, err := netutil.DefaultNetworkInterface()
 	if err != nil {
@@ -82,6 +107,19 @@ func NewServer(cfg ServerConfig, appCl *app.Client) (*Server, error) {
 
 	fmt.Printf("Old iptables forward policy: %s\n", iptablesForwardPolicy)
 
+	if cfg.IPv6 {
+		s.ip6tablesForwardPolicy, err = GetIP6TablesForwardPolicy()
+		if err == nil {
+			fmt.Printf("Old ip6tables forward policy: %s\n", s.ip6tablesForwardPolicy)
+			s.ipv6Gen, err = NewIPv6Generator()
+		}
+		if err != nil {
+			// IPv6 is optional, clients will get IPv4 only tunnels
+			print(fmt.Sprintf("Disabling IPv6 tunnels: %v\n", err))
+			s.ipv6Gen = nil
+		}
+	}
+
 	s.defaultNetworkInterface = defaultNetworkIfc
 	s.defaultNetworkInterfaceIPs = defaultNetworkIfcIPs
 	s.ipv4ForwardingVal = ipv4ForwardingVal
@@ -135,6 +173,28 @@ func (s *Server) Serve(l net.Listener) error {
 			s.restoreIPTablesForwardPolicy()
 		}()
 
+		if s.ipv6Gen != nil {
+			if err := s.enableIPv6Routing(); err != nil {
+				// IPv6 is optional, clients will get IPv4 only tunnels
+				print(fmt.Sprintf("Disabling IPv6 tunnels: %v\n", err))
+				s.ipv6Gen = nil
+			} else {
+				defer func() {
+					s.disableIPv6Routing()
+				}()
+			}
+		}
+
+		if s.cfg.ACL != nil {
+			aclDone := make(chan struct{})
+			defer close(aclDone)
+			go s.cfg.ACL.Watch(aclDone, aclCheckInterval, s.closeRevokedConns)
+		}
+
+		usageDone := make(chan struct{})
+		defer close(usageDone)
+		go s.reportUsage(usageDone)
+
 		s.lisMx.Lock()
 		s.lis = l
 		s.lisMx.Unlock()
@@ -163,6 +223,9 @@ func (s *Server) Close() error {
 	s.revertIPv6ForwardingValue()
 	s.disableIPMasquerading()
 	s.restoreIPTablesForwardPolicy()
+	if s.ipv6Gen != nil {
+		s.disableIPv6Routing()
+	}
 
 	if s.lis == nil {
 		return nil
@@ -206,21 +269,105 @@ func (s *Server) restoreIPTablesForwardPolicy() {
 	}
 }
 
+func (s *Server) enableIPv6Routing() error {
+	if err := EnableIPv6Masquerading(s.defaultNetworkInterface); err != nil {
+		return fmt.Errorf("error enabling IPv6 masquerading for %s: %w", s.defaultNetworkInterface, err)
+	}
+	fmt.Println("Enabled IPv6 masquerading")
+
+	if err := SetIP6TablesForwardAcceptPolicy(); err != nil {
+		if err := DisableIPv6Masquerading(s.defaultNetworkInterface); err != nil {
+			print(fmt.Sprintf("Error disabling IPv6 masquerading for %s: %v\n", s.defaultNetworkInterface, err))
+		}
+		return fmt.Errorf("error settings ip6tables forward policy to ACCEPT: %w", err)
+	}
+	fmt.Println("Set ip6tables forward policy to ACCEPT")
+
+	return nil
+}
+
+func (s *Server) disableIPv6Routing() {
+	if err := DisableIPv6Masquerading(s.defaultNetworkInterface); err != nil {
+		print(fmt.Sprintf("Error disabling IPv6 masquerading for %s: %v\n", s.defaultNetworkInterface, err))
+	} else {
+		fmt.Printf("Disabled IPv6 masquerading for %s\n", s.defaultNetworkInterface)
+	}
+
+	if err := SetIP6TablesForwardPolicy(s.ip6tablesForwardPolicy); err != nil {
+		print(fmt.Sprintf("Error restoring ip6tables forward policy to %s: %v\n", s.ip6tablesForwardPolicy, err))
+	} else {
+		fmt.Printf("Restored ip6tables forward policy to %s\n", s.ip6tablesForwardPolicy)
+	}
+}
+
 func (s *Server) closeConn(conn net.Conn) {
 	if err := conn.Close(); err != nil {
 		print(fmt.Sprintf("Error closing client %s connection: %v\n", conn.RemoteAddr(), err))
 	}
 }
 
+// reportUsage persists clients' data usage and reports it to the visor until `done` is closed.
+func (s *Server) reportUsage(done <-chan struct{}) {
+	ticker := time.NewTicker(usageReportInterval)
+	defer ticker.Stop()
+
+	report := func() {
+		if err := s.usage.save(); err != nil {
+			print(fmt.Sprintf("Error saving clients usage: %v\n", err))
+		}
+		if s.appCl == nil {
+			return
+		}
+		if err := s.appCl.SetClientsUsage(s.usage.summary()); err != nil {
+			print(fmt.Sprintf("Failed to set clients usage: %v\n", err))
+		}
+	}
+
+	for {
+		select {
+		case <-done:
+			report()
+			return
+		case <-ticker.C:
+			report()
+		}
+	}
+}
+
+func (s *Server) closeRevokedConns() {
+	s.connsMx.Lock()
+	defer s.connsMx.Unlock()
+
+	for conn := range s.conns {
+		if !s.cfg.ACL.IsConnAllowed(conn) {
+			fmt.Printf("Client %s is not allowed anymore, closing connection\n", conn.RemoteAddr())
+			s.closeConn(conn)
+			delete(s.conns, conn)
+		}
+	}
+}
+
 func (s *Server) serveConn(conn net.Conn) {
 	defer s.closeConn(conn)
 
-	tunIP, tunGateway, allowTrafficToLocalNet, err := s.shakeHands(conn)
+	s.connsMx.Lock()
+	s.conns[conn] = struct{}{}
+	s.connsMx.Unlock()
+	defer func() {
+		s.connsMx.Lock()
+		delete(s.conns, conn)
+		s.connsMx.Unlock()
+	}()
+
+	sTUN, allowTrafficToLocalNet, err := s.shakeHands(conn)
 	if err != nil {
 		print(fmt.Sprintf("Error negotiating with client %s: %v\n", conn.RemoteAddr(), err))
 		return
 	}
 	defer allowTrafficToLocalNet()
+	if sTUN.ipv6 != nil {
+		defer s.ipv6Gen.Release(sTUN.ipv6)
+	}
 
 	tun, err := newTUNDevice()
 	if err != nil {
@@ -235,17 +382,26 @@ func (s *Server) serveConn(conn net.Conn) {
 
 	fmt.Printf("Allocated TUN %s", tun.Name())
 
-	if err := s.SetupTUN(tun.Name(), tunIP.String()+TUNNetmaskCIDR, tunGateway.String(), TUNMTU); err != nil {
+	if err := s.SetupTUN(tun.Name(), sTUN.ip.String()+TUNNetmaskCIDR, sTUN.gateway.String(), TUNMTU); err != nil {
 		print(fmt.Sprintf("Error setting up TUN %s: %v", tun.Name(), err))
 		return
 	}
 
+	if sTUN.ipv6 != nil {
+		if err := s.SetupTUNv6(tun.Name(), sTUN.ipv6.String()+TUNNetmaskCIDRv6); err != nil {
+			print(fmt.Sprintf("Error setting up IPv6 on TUN %s: %v", tun.Name(), err))
+			return
+		}
+	}
+
+	clientConn := s.usage.wrapConn(conn)
+
 	connToTunDoneCh := make(chan struct{})
 	tunToConnCh := make(chan struct{})
 	go func() {
 		defer close(connToTunDoneCh)
 
-		if _, err := io.Copy(tun, conn); err != nil {
+		if _, err := io.Copy(tun, clientConn); err != nil {
 			// when the vpn-client is closed we get the error "EOF"
 			if err.Error() != io.EOF.Error() {
 				print(fmt.Sprintf("Error resending traffic from VPN client to TUN %s: %v\n", tun.Name(), err))
@@ -255,7 +411,7 @@ func (s *Server) serveConn(conn net.Conn) {
 	go func() {
 		defer close(tunToConnCh)
 
-		if _, err := io.Copy(conn, tun); err != nil {
+		if _, err := io.Copy(clientConn, tun); err != nil {
 			// when the vpn-client is closed we get the error "read tun: file already closed"
 			if err.Error() != "read tun: file already closed" {
 				print(fmt.Sprintf("Error resending traffic from TUN %s to VPN client: %v\n", tun.Name(), err))
@@ -270,10 +426,10 @@ func (s *Server) serveConn(conn net.Conn) {
 	}
 }
 
-func (s *Server) shakeHands(conn net.Conn) (tunIP, tunGateway net.IP, unsecureVPN func(), err error) {
+func (s *Server) shakeHands(conn net.Conn) (sTUN tunAddrs, unsecureVPN func(), err error) {
 	var cHello ClientHello
 	if err := ReadJSON(conn, &cHello); err != nil {
-		return nil, nil, nil, fmt.Errorf("error reading client hello: %w", err)
+		return tunAddrs{}, nil, fmt.Errorf("error reading client hello: %w", err)
 	}
 
 	// default value
@@ -283,27 +439,41 @@ func (s *Server) shakeHands(conn net.Conn) (tunIP, tunGateway net.IP, unsecureVP
 
 	if s.cfg.Passcode != "" && cHello.Passcode != s.cfg.Passcode {
 		s.sendServerErrHello(conn, HandshakeStatusForbidden)
-		return nil, nil, nil, errors.New("got wrong passcode from client")
+		return tunAddrs{}, nil, errors.New("got wrong passcode from client")
+	}
+
+	if !s.cfg.ACL.IsConnAllowed(conn) {
+		s.sendServerErrHello(conn, HandshakeStatusForbidden)
+		return tunAddrs{}, nil, errors.New("client is not allowed by the ACL")
+	}
+
+	if pk, ok := appacl.RemotePK(conn); ok && s.usage.exceeded(pk) {
+		s.sendServerErrHello(conn, HandshakeStatusQuotaExceeded)
+		return tunAddrs{}, nil, fmt.Errorf("client %s is over its monthly data cap", pk)
 	}
 
 	for _, ip := range cHello.UnavailablePrivateIPs {
+		if ip.To4() == nil {
+			// IPv6 subnets are generated within a random ULA prefix, no need to exclude client's IPs
+			continue
+		}
 		if err := s.ipGen.Reserve(ip); err != nil {
 			// this happens only on malformed IP
 			s.sendServerErrHello(conn, HandshakeStatusBadRequest)
-			return nil, nil, nil, fmt.Errorf("error reserving IP %s: %w", ip.String(), err)
+			return tunAddrs{}, nil, fmt.Errorf("error reserving IP %s: %w", ip.String(), err)
 		}
 	}
 
 	subnet, err := s.ipGen.Next()
 	if err != nil {
 		s.sendServerErrHello(conn, HandshakeNoFreeIPs)
-		return nil, nil, nil, fmt.Errorf("error getting free subnet IP: %w", err)
+		return tunAddrs{}, nil, fmt.Errorf("error getting free subnet IP: %w", err)
 	}
 
 	subnetOctets, err := fetchIPv4Octets(subnet)
 	if err != nil {
 		s.sendServerErrHello(conn, HandshakeStatusInternalError)
-		return nil, nil, nil, fmt.Errorf("error breaking IP into octets: %w", err)
+		return tunAddrs{}, nil, fmt.Errorf("error breaking IP into octets: %w", err)
 	}
 
 	// basically IP address comprised of `subnetOctets` items is the IP address of the subnet,
@@ -314,38 +484,80 @@ func (s *Server) shakeHands(conn net.Conn) (tunIP, tunGateway net.IP, unsecureVP
 	// - Client-side TUN gateway = subnet IP + 3
 	// - Client-site TUN IP = subnet IP + 4
 
-	sTUNIP := net.IPv4(subnetOctets[0], subnetOctets[1], subnetOctets[2], subnetOctets[3]+2)
-	sTUNGateway := net.IPv4(subnetOctets[0], subnetOctets[1], subnetOctets[2], subnetOctets[3]+1)
+	sTUN.ip = net.IPv4(subnetOctets[0], subnetOctets[1], subnetOctets[2], subnetOctets[3]+2)
+	sTUN.gateway = net.IPv4(subnetOctets[0], subnetOctets[1], subnetOctets[2], subnetOctets[3]+1)
+
+	var cTUN tunAddrs
+	cTUN.ip = net.IPv4(subnetOctets[0], subnetOctets[1], subnetOctets[2], subnetOctets[3]+4)
+	cTUN.gateway = net.IPv4(subnetOctets[0], subnetOctets[1], subnetOctets[2], subnetOctets[3]+3)
+
+	// IPv6 addresses follow the same layout within the /64 subnet given to the client.
+	if cHello.IPv6 && s.ipv6Gen != nil {
+		subnetv6, err := s.ipv6Gen.Next()
+		if err != nil {
+			s.sendServerErrHello(conn, HandshakeNoFreeIPs)
+			return tunAddrs{}, nil, fmt.Errorf("error getting free IPv6 subnet: %w", err)
+		}
+
+		sTUN.ipv6 = ipv6WithHost(subnetv6, 2)
+		sTUN.gatewayIPv6 = ipv6WithHost(subnetv6, 1)
+		cTUN.ipv6 = ipv6WithHost(subnetv6, 4)
+		cTUN.gatewayIPv6 = ipv6WithHost(subnetv6, 3)
+	}
 
-	cTUNIP := net.IPv4(subnetOctets[0], subnetOctets[1], subnetOctets[2], subnetOctets[3]+4)
-	cTUNGateway := net.IPv4(subnetOctets[0], subnetOctets[1], subnetOctets[2], subnetOctets[3]+3)
+	releaseIPv6 := func() {
+		if sTUN.ipv6 != nil {
+			s.ipv6Gen.Release(sTUN.ipv6)
+		}
+	}
 
 	if s.cfg.Secure {
-		if err := BlockIPToLocalNetwork(cTUNIP, sTUNIP); err != nil {
+		if err := BlockIPToLocalNetwork(cTUN.ip, sTUN.ip); err != nil {
 			s.sendServerErrHello(conn, HandshakeStatusInternalError)
-			return nil, nil, nil,
-				fmt.Errorf("error securing local network for IP %s: %w", cTUNIP, err)
+			releaseIPv6()
+			return tunAddrs{}, nil,
+				fmt.Errorf("error securing local network for IP %s: %w", cTUN.ip, err)
+		}
+
+		if cTUN.ipv6 != nil {
+			if err := BlockIPv6ToLocalNetwork(cTUN.ipv6); err != nil {
+				if err := AllowIPToLocalNetwork(cTUN.ip, sTUN.ip); err != nil {
+					print(fmt.Sprintf("Error allowing traffic to local network: %v\n", err))
+				}
+				s.sendServerErrHello(conn, HandshakeStatusInternalError)
+				releaseIPv6()
+				return tunAddrs{}, nil,
+					fmt.Errorf("error securing local network for IP %s: %w", cTUN.ipv6, err)
+			}
 		}
 
 		unsecureVPN = func() {
-			if err := AllowIPToLocalNetwork(cTUNIP, sTUNIP); err != nil {
+			if err := AllowIPToLocalNetwork(cTUN.ip, sTUN.ip); err != nil {
 				print(fmt.Sprintf("Error allowing traffic to local network: %v\n", err))
 			}
+			if cTUN.ipv6 != nil {
+				if err := AllowIPv6ToLocalNetwork(cTUN.ipv6); err != nil {
+					print(fmt.Sprintf("Error allowing IPv6 traffic to local network: %v\n", err))
+				}
+			}
 		}
 	}
 
 	sHello := ServerHello{
-		Status:     HandshakeStatusOK,
-		TUNIP:      cTUNIP,
-		TUNGateway: cTUNGateway,
+		Status:         HandshakeStatusOK,
+		TUNIP:          cTUN.ip,
+		TUNGateway:     cTUN.gateway,
+		TUNIPv6:        cTUN.ipv6,
+		TUNGatewayIPv6: cTUN.gatewayIPv6,
 	}
 
 	if err := WriteJSON(conn, &sHello); err != nil {
 		unsecureVPN()
-		return nil, nil, nil, fmt.Errorf("error finishing handshake: error sending server hello: %w", err)
+		releaseIPv6()
+		return tunAddrs{}, nil, fmt.Errorf("error finishing handshake: error sending server hello: %w", err)
 	}
 
-	return sTUNIP, sTUNGateway, unsecureVPN, nil
+	return sTUN, unsecureVPN, nil
 }
 
 func (s *Server) setAppStatus(status appserver.AppDetailedStatus) {
@@ -1,9 +1,20 @@
 // Package vpn internal/vpn/server_config.go
 package vpn
 
+import "github.com/skycoin/skywire/pkg/app/appacl"
+
 // ServerConfig is a configuration for VPN server.
 type ServerConfig struct {
 	Passcode         string
 	Secure           bool
 	NetworkInterface string
+	IPv6             bool
+	// ACL is the per-client access list, nil allows every client.
+	ACL *appacl.ACL
+	// RateLimit is the per-client rate limit in bytes per second for each direction, 0 is unlimited.
+	RateLimit uint64
+	// MonthlyCap is the per-client monthly transfer cap in bytes, 0 is unlimited.
+	MonthlyCap uint64
+	// UsagePath is the file persisting clients' data usage, empty disables persistence.
+	UsagePath string
 }
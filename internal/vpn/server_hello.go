@@ -7,7 +7,9 @@ import (
 
 // ServerHello is a message sent by server during the Client/Server handshake.
 type ServerHello struct {
-	Status     HandshakeStatus `json:"status"`
-	TUNIP      net.IP          `json:"tun_ip"`
-	TUNGateway net.IP          `json:"tun_gateway"`
+	Status         HandshakeStatus `json:"status"`
+	TUNIP          net.IP          `json:"tun_ip"`
+	TUNGateway     net.IP          `json:"tun_gateway"`
+	TUNIPv6        net.IP          `json:"tun_ipv6,omitempty"`
+	TUNGatewayIPv6 net.IP          `json:"tun_gateway_ipv6,omitempty"`
 }
@@ -0,0 +1,260 @@
+// Package appacl pkg/app/appacl/acl.go
+package appacl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/app/appnet"
+)
+
+// FileName is the name of the ACL file within the working directory of an app.
+const FileName = "acl.json"
+
+// Action is the decision taken for the remote visor of an Entry.
+type Action string
+
+const (
+	// Allow lets the remote visor connect.
+	Allow Action = "allow"
+	// Deny rejects the remote visor.
+	Deny Action = "deny"
+)
+
+// ErrInvalidAction is returned when an Entry has an unknown action.
+var ErrInvalidAction = errors.New("invalid ACL action")
+
+// Entry is a rule of the ACL for a single remote visor.
+type Entry struct {
+	PK     cipher.PubKey `json:"pk"`
+	Action Action        `json:"action"`
+	// ExpiresAt is the time after which the entry is ignored, zero value never expires.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired returns true if the entry is expired at `now`.
+func (e Entry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// List is a per-client access list of an app.
+// A remote visor is allowed to connect if it has no active deny entry and, when the list
+// contains any active allow entries, it has one of them.
+type List struct {
+	Entries []Entry `json:"entries"`
+}
+
+// IsAllowed checks whether the remote visor with `pk` is allowed at `now`.
+func (l List) IsAllowed(pk cipher.PubKey, now time.Time) bool {
+	var hasAllowList, allowed bool
+	for _, e := range l.Entries {
+		if e.Expired(now) {
+			continue
+		}
+		switch e.Action {
+		case Deny:
+			if e.PK == pk {
+				return false
+			}
+		case Allow:
+			hasAllowList = true
+			if e.PK == pk {
+				allowed = true
+			}
+		}
+	}
+
+	return !hasAllowList || allowed
+}
+
+// Set adds the entry, replacing the existing entry of the same remote visor.
+func (l *List) Set(entry Entry) error {
+	if entry.Action != Allow && entry.Action != Deny {
+		return fmt.Errorf("%w: %q", ErrInvalidAction, entry.Action)
+	}
+	for i, e := range l.Entries {
+		if e.PK == entry.PK {
+			l.Entries[i] = entry
+			return nil
+		}
+	}
+	l.Entries = append(l.Entries, entry)
+	return nil
+}
+
+// Remove removes the entry of the remote visor with `pk`. Returns false if there is no such entry.
+func (l *List) Remove(pk cipher.PubKey) bool {
+	for i, e := range l.Entries {
+		if e.PK == pk {
+			l.Entries = append(l.Entries[:i], l.Entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Prune removes the entries expired at `now`.
+func (l *List) Prune(now time.Time) {
+	entries := l.Entries[:0]
+	for _, e := range l.Entries {
+		if !e.Expired(now) {
+			entries = append(entries, e)
+		}
+	}
+	l.Entries = entries
+}
+
+// Load reads the list from `path`. A missing file results in an empty list.
+func Load(path string) (List, error) {
+	var l List
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return l, err
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return l, fmt.Errorf("failed to decode ACL %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Save writes the list to `path`, replacing the existing file atomically.
+func Save(path string, l List) error {
+	data, err := json.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil { //nolint
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// updateMu serializes the updates of the ACL files, so that concurrent updates do not lose changes.
+var updateMu sync.Mutex
+
+// Update loads the list from `path`, modifies it with `fn` and saves it, holding a lock over the whole update.
+// The list is not saved if `fn` returns an error.
+func Update(path string, fn func(l *List) error) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	l, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(&l); err != nil {
+		return err
+	}
+	return Save(path, l)
+}
+
+// ACL is a List kept in sync with its file, so it can be edited while the app runs.
+type ACL struct {
+	path    string
+	mu      sync.Mutex
+	list    List
+	modTime time.Time
+	size    int64
+}
+
+// New creates an ACL backed by the file at `path`.
+func New(path string) (*ACL, error) {
+	a := &ACL{path: path}
+	if err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload reads the file again if it was changed since the last read.
+func (a *ACL) Reload() error {
+	var modTime time.Time
+	var size int64
+	info, err := os.Stat(a.path)
+	switch {
+	case err == nil:
+		modTime, size = info.ModTime(), info.Size()
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if modTime.Equal(a.modTime) && size == a.size && !a.modTime.IsZero() {
+		return nil
+	}
+	l, err := Load(a.path)
+	if err != nil {
+		return err
+	}
+	a.list = l
+	a.modTime, a.size = modTime, size
+	return nil
+}
+
+// IsAllowed checks whether the remote visor with `pk` is allowed now.
+func (a *ACL) IsAllowed(pk cipher.PubKey) bool {
+	if a == nil {
+		return true
+	}
+	if err := a.Reload(); err != nil {
+		print(fmt.Sprintf("Failed to reload ACL %s: %v\n", a.path, err))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.list.IsAllowed(pk, time.Now())
+}
+
+// IsConnAllowed checks whether the remote visor of a skynet `conn` is allowed now.
+func (a *ACL) IsConnAllowed(conn net.Conn) bool {
+	pk, ok := RemotePK(conn)
+	if !ok {
+		return true
+	}
+	return a.IsAllowed(pk)
+}
+
+// RemotePK returns the public key of the remote visor of `conn`.
+func RemotePK(conn net.Conn) (cipher.PubKey, bool) {
+	addr, ok := conn.RemoteAddr().(appnet.Addr)
+	if !ok {
+		return cipher.PubKey{}, false
+	}
+	return addr.PubKey, true
+}
+
+// Watch reloads the ACL every `interval` and calls `fn`, so that connections of the
+// remote visors which are no longer allowed may be closed. It returns once `done` is closed.
+func (a *ACL) Watch(done <-chan struct{}, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := a.Reload(); err != nil {
+				print(fmt.Sprintf("Failed to reload ACL %s: %v\n", a.path, err))
+				continue
+			}
+			fn()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Package appacl pkg/app/appacl/acl_test.go
+package appacl
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+func TestList_IsAllowed(t *testing.T) {
+	pk1, _ := cipher.GenerateKeyPair()
+	pk2, _ := cipher.GenerateKeyPair()
+	now := time.Now()
+
+	var l List
+	require.True(t, l.IsAllowed(pk1, now))
+
+	require.NoError(t, l.Set(Entry{PK: pk1, Action: Deny}))
+	require.False(t, l.IsAllowed(pk1, now))
+	require.True(t, l.IsAllowed(pk2, now))
+
+	// an active allow entry turns the list into an allow list
+	require.NoError(t, l.Set(Entry{PK: pk1, Action: Allow}))
+	require.True(t, l.IsAllowed(pk1, now))
+	require.False(t, l.IsAllowed(pk2, now))
+
+	// expired entries are ignored
+	require.NoError(t, l.Set(Entry{PK: pk1, Action: Allow, ExpiresAt: now.Add(-time.Minute)}))
+	require.True(t, l.IsAllowed(pk1, now))
+	require.True(t, l.IsAllowed(pk2, now))
+
+	l.Prune(now)
+	require.Empty(t, l.Entries)
+
+	require.ErrorIs(t, l.Set(Entry{PK: pk1, Action: "maybe"}), ErrInvalidAction)
+}
+
+func TestACL_Reload(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	path := filepath.Join(t.TempDir(), FileName)
+
+	acl, err := New(path)
+	require.NoError(t, err)
+	require.True(t, acl.IsAllowed(pk))
+
+	l, err := Load(path)
+	require.NoError(t, err)
+	require.NoError(t, l.Set(Entry{PK: pk, Action: Deny}))
+	require.NoError(t, Save(path, l))
+	require.False(t, acl.IsAllowed(pk))
+
+	require.True(t, l.Remove(pk))
+	require.False(t, l.Remove(pk))
+	require.NoError(t, Save(path, l))
+	require.True(t, acl.IsAllowed(pk))
+}
+
+func TestUpdate_concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pk, _ := cipher.GenerateKeyPair()
+			require.NoError(t, Update(path, func(l *List) error {
+				return l.Set(Entry{PK: pk, Action: Allow})
+			}))
+		}()
+	}
+	wg.Wait()
+
+	l, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, l.Entries, n)
+}
@@ -81,6 +81,8 @@ type ProcConfig struct {
 	BinaryLoc    string        `json:"binary_loc"`
 	LogDBLoc     string        `json:"log_db_loc"`
 	LogStorePath string        `json:"log_store_path"`
+	// RoutePolicy constrains the routes dialed by the app, if not nil.
+	RoutePolicy *routing.RoutePolicy `json:"route_policy,omitempty"`
 }
 
 // ProcConfigFromEnv obtains a ProcConfig from the associated env variable, returning an error if any.
@@ -8,9 +8,11 @@ import (
 	"net"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hashicorp/yamux"
 
 	"github.com/skycoin/skywire-utilities/pkg/logging"
 )
@@ -36,12 +38,16 @@ func GetForwardConn(id uuid.UUID) *ForwardConn {
 	return forwardConns[id]
 }
 
-// GetAllForwardConns gets all ForwardConns
+// GetAllForwardConns gets a copy of all ForwardConns
 func GetAllForwardConns() map[uuid.UUID]*ForwardConn {
 	forwardConnsMu.Lock()
 	defer forwardConnsMu.Unlock()
 
-	return forwardConns
+	out := make(map[uuid.UUID]*ForwardConn, len(forwardConns))
+	for id, fwd := range forwardConns {
+		out[id] = fwd
+	}
+	return out
 }
 
 // RemoveForwardConn removes a ForwardConn by ID
@@ -51,57 +57,105 @@ func RemoveForwardConn(id uuid.UUID) {
 	delete(forwardConns, id)
 }
 
+// ForwardType is the kind of traffic carried by a ForwardConn.
+type ForwardType string
+
+const (
+	// ForwardHTTP replays local HTTP requests on the remote server.
+	ForwardHTTP ForwardType = "http"
+	// ForwardTCP splices raw TCP streams to the remote port.
+	ForwardTCP ForwardType = "tcp"
+	// ForwardUDP carries UDP datagrams to the remote port.
+	ForwardUDP ForwardType = "udp"
+)
+
+// ErrUnknownForwardType is returned when a forward type is not supported.
+var ErrUnknownForwardType = errors.New("unknown forward type")
+
+// ParseForwardType parses a forward type, an empty string defaults to ForwardHTTP.
+func ParseForwardType(s string) (ForwardType, error) {
+	switch t := ForwardType(s); t {
+	case "":
+		return ForwardHTTP, nil
+	case ForwardHTTP, ForwardTCP, ForwardUDP:
+		return t, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownForwardType, s)
+	}
+}
+
 // ForwardConn ...
 type ForwardConn struct {
-	ID         uuid.UUID
-	LocalPort  int
-	RemotePort int
-	remoteConn net.Conn
-	closeOnce  sync.Once
-	srv        *http.Server
-	closeChan  chan struct{}
-	log        *logging.Logger
+	// byte counters are kept first for 64-bit alignment of atomic operations
+	BytesSent     uint64
+	BytesReceived uint64
+	ID            uuid.UUID
+	Type          ForwardType
+	LocalPort     int
+	RemotePort    int
+	remoteConn    net.Conn
+	closeOnce     sync.Once
+	srv           *http.Server
+	listener      net.Listener
+	packetConn    net.PacketConn
+	session       *yamux.Session
+	closeChan     chan struct{}
+	log           *logging.Logger
 }
 
 // NewForwardConn creates a new forwarding conn
-func NewForwardConn(log *logging.Logger, remoteConn net.Conn, remotePort, localPort int) *ForwardConn {
-	closeChan := make(chan struct{})
-	var once sync.Once
-	handler := http.NewServeMux()
-	var lock sync.Mutex
-	handler.HandleFunc("/", handleFunc(remoteConn, log, closeChan, once, &lock))
-
-	srv := &http.Server{
-		Addr:           fmt.Sprintf(":%v", localPort),
-		Handler:        handler,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
-	}
+func NewForwardConn(log *logging.Logger, remoteConn net.Conn, remotePort, localPort int, fwdType ForwardType) *ForwardConn {
 	fwdConn := &ForwardConn{
 		ID:         uuid.New(),
-		remoteConn: remoteConn,
-		srv:        srv,
+		Type:       fwdType,
 		LocalPort:  localPort,
 		RemotePort: remotePort,
-		closeChan:  closeChan,
+		closeChan:  make(chan struct{}),
 		log:        log,
 	}
+	fwdConn.remoteConn = &countingConn{Conn: remoteConn, sent: &fwdConn.BytesSent, received: &fwdConn.BytesReceived}
+
+	if fwdType == ForwardHTTP {
+		handler := http.NewServeMux()
+		var once sync.Once
+		var lock sync.Mutex
+		handler.HandleFunc("/", handleFunc(fwdConn.remoteConn, log, fwdConn.closeChan, &once, &lock))
+
+		fwdConn.srv = &http.Server{
+			Addr:           fmt.Sprintf(":%v", localPort),
+			Handler:        handler,
+			ReadTimeout:    10 * time.Second,
+			WriteTimeout:   10 * time.Second,
+			MaxHeaderBytes: 1 << 20,
+		}
+	}
+
 	AddForwarding(fwdConn)
 	return fwdConn
 }
 
-// Serve serves a HTTP forward conn that accepts all requests and forwards them directly to the remote server over the specified net.Conn.
-func (f *ForwardConn) Serve() {
-	go func() {
-		err := f.srv.ListenAndServe()
-		if err != nil {
-			// don't print error if local server is closed
-			if !errors.Is(err, http.ErrServerClosed) {
-				f.log.WithError(err).Error("Error listening and serving app forwarding.")
-			}
+// Serve starts listening on the local port and forwards the accepted traffic to the remote server
+// over the specified net.Conn. HTTP forward conns replay each request on the remote server, TCP
+// and UDP forward conns multiplex local connections and datagrams over the remote conn.
+func (f *ForwardConn) Serve() error {
+	var err error
+	switch f.Type {
+	case ForwardHTTP:
+		err = f.serveHTTP()
+	case ForwardTCP:
+		err = f.serveTCP()
+	case ForwardUDP:
+		err = f.serveUDP()
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnknownForwardType, f.Type)
+	}
+	if err != nil {
+		if cErr := f.Close(); cErr != nil {
+			f.log.WithError(cErr).Warn("Failed to close forward conn.")
 		}
-	}()
+		return err
+	}
+
 	go func() {
 		<-f.closeChan
 		err := f.Close()
@@ -109,14 +163,60 @@ func (f *ForwardConn) Serve() {
 			f.log.Error(err)
 		}
 	}()
-	f.log.Debugf("Serving on localhost:%v", f.LocalPort)
+	f.log.Debugf("Serving %s on localhost:%v", f.Type, f.LocalPort)
+	return nil
+}
+
+func (f *ForwardConn) serveHTTP() error {
+	l, err := net.Listen("tcp", f.srv.Addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := f.srv.Serve(l)
+		if err != nil {
+			// don't print error if local server is closed
+			if !errors.Is(err, http.ErrServerClosed) {
+				f.log.WithError(err).Error("Error listening and serving app forwarding.")
+			}
+		}
+	}()
+	return nil
+}
+
+// Snapshot returns a copy of the exported fields of the ForwardConn, safe to be read
+// while the forward conn is in use.
+func (f *ForwardConn) Snapshot() *ForwardConn {
+	return &ForwardConn{
+		ID:            f.ID,
+		Type:          f.Type,
+		LocalPort:     f.LocalPort,
+		RemotePort:    f.RemotePort,
+		BytesSent:     atomic.LoadUint64(&f.BytesSent),
+		BytesReceived: atomic.LoadUint64(&f.BytesReceived),
+	}
 }
 
 // Close closes the server and remote connection.
 func (f *ForwardConn) Close() (err error) {
 	f.closeOnce.Do(func() {
-		err = f.srv.Close()
-		err = f.remoteConn.Close()
+		var errs []error
+		if f.srv != nil {
+			errs = append(errs, f.srv.Close())
+		}
+		if f.listener != nil {
+			errs = append(errs, f.listener.Close())
+		}
+		if f.packetConn != nil {
+			errs = append(errs, f.packetConn.Close())
+		}
+		// the session closes the remote conn it runs over
+		if f.session != nil {
+			errs = append(errs, f.session.Close())
+		} else {
+			errs = append(errs, f.remoteConn.Close())
+		}
+		err = errors.Join(errs...)
 		RemoveForwardConn(f.ID)
 	})
 	return err
@@ -131,7 +231,7 @@ func isClosed(c chan struct{}) bool {
 	}
 }
 
-func handleFunc(remoteConn net.Conn, log *logging.Logger, closeChan chan struct{}, once sync.Once, lock *sync.Mutex) func(w http.ResponseWriter, req *http.Request) {
+func handleFunc(remoteConn net.Conn, log *logging.Logger, closeChan chan struct{}, once *sync.Once, lock *sync.Mutex) func(w http.ResponseWriter, req *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		lock.Lock()
 		defer lock.Unlock()
@@ -0,0 +1,324 @@
+// Package appnet pkg/app/appnet/forwarding_raw.go
+package appnet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/yamux"
+
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+)
+
+const (
+	// maxDatagramSize is the biggest UDP payload that can be framed over a stream.
+	maxDatagramSize = 1<<16 - 1
+	// udpSessionTimeout is the time after which an idle UDP session is closed.
+	udpSessionTimeout = 2 * time.Minute
+)
+
+// ServeForward serves the remote side of a raw TCP or UDP forward conn. Every stream
+// opened by the requesting visor over remoteConn is connected to lHost.
+func ServeForward(log *logging.Logger, remoteConn net.Conn, fwdType ForwardType, lHost string) error {
+	session, err := yamux.Server(remoteConn, forwardSessionConfig())
+	if err != nil {
+		return fmt.Errorf("yamux server failure: %w", err)
+	}
+	defer closeLogged(log, session)
+
+	for {
+		stream, err := session.Accept()
+		if err != nil {
+			if session.IsClosed() {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+
+		switch fwdType {
+		case ForwardTCP:
+			go serveTCPStream(log, stream, lHost)
+		case ForwardUDP:
+			go serveUDPStream(log, stream, lHost)
+		default:
+			closeLogged(log, stream)
+			return fmt.Errorf("%w: %s", ErrUnknownForwardType, fwdType)
+		}
+	}
+}
+
+func serveTCPStream(log *logging.Logger, stream net.Conn, lHost string) {
+	conn, err := net.Dial("tcp", lHost)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to dial %s", lHost)
+		closeLogged(log, stream)
+		return
+	}
+	splice(log, conn, stream)
+}
+
+func serveUDPStream(log *logging.Logger, stream net.Conn, lHost string) {
+	conn, err := net.Dial("udp", lHost)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to dial %s", lHost)
+		closeLogged(log, stream)
+		return
+	}
+	defer closeLogged(log, conn)
+	defer closeLogged(log, stream)
+
+	go func() {
+		defer closeLogged(log, stream)
+		buf := make([]byte, maxDatagramSize)
+		for {
+			if err := conn.SetReadDeadline(time.Now().Add(udpSessionTimeout)); err != nil {
+				return
+			}
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			if err := writeDatagram(stream, buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, err := readDatagram(stream, buf)
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(buf[:n]); err != nil {
+			log.WithError(err).Debugf("Failed to write datagram to %s", lHost)
+			return
+		}
+	}
+}
+
+func (f *ForwardConn) serveTCP() error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%v", f.LocalPort))
+	if err != nil {
+		return err
+	}
+	f.listener = l
+
+	session, err := yamux.Client(f.remoteConn, forwardSessionConfig())
+	if err != nil {
+		return fmt.Errorf("yamux client failure: %w", err)
+	}
+	f.session = session
+	go f.closeOnSessionEnd()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					f.log.WithError(err).Error("Error accepting forwarded TCP conn.")
+				}
+				return
+			}
+			stream, err := session.Open()
+			if err != nil {
+				f.log.WithError(err).Error("Error opening forwarding stream.")
+				closeLogged(f.log, conn)
+				return
+			}
+			go splice(f.log, conn, stream)
+		}
+	}()
+	return nil
+}
+
+// udpSession is a stream carrying the datagrams of a single local UDP peer.
+type udpSession struct {
+	stream     net.Conn
+	lastActive int64
+}
+
+func (f *ForwardConn) serveUDP() error {
+	pc, err := net.ListenPacket("udp", fmt.Sprintf(":%v", f.LocalPort))
+	if err != nil {
+		return err
+	}
+	f.packetConn = pc
+
+	session, err := yamux.Client(f.remoteConn, forwardSessionConfig())
+	if err != nil {
+		return fmt.Errorf("yamux client failure: %w", err)
+	}
+	f.session = session
+	go f.closeOnSessionEnd()
+
+	var mu sync.Mutex
+	peers := make(map[string]*udpSession)
+
+	go func() {
+		ticker := time.NewTicker(udpSessionTimeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-session.CloseChan():
+				return
+			case <-ticker.C:
+				deadline := time.Now().Add(-udpSessionTimeout).UnixNano()
+				mu.Lock()
+				for addr, s := range peers {
+					if atomic.LoadInt64(&s.lastActive) < deadline {
+						closeLogged(f.log, s.stream)
+						delete(peers, addr)
+					}
+				}
+				mu.Unlock()
+			}
+		}
+	}()
+
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					f.log.WithError(err).Error("Error reading forwarded UDP datagram.")
+				}
+				return
+			}
+
+			mu.Lock()
+			s, ok := peers[addr.String()]
+			if !ok {
+				stream, err := session.Open()
+				if err != nil {
+					mu.Unlock()
+					f.log.WithError(err).Error("Error opening forwarding stream.")
+					return
+				}
+				s = &udpSession{stream: stream}
+				peers[addr.String()] = s
+				go f.readDatagrams(pc, addr, s, func() {
+					mu.Lock()
+					if peers[addr.String()] == s {
+						delete(peers, addr.String())
+					}
+					mu.Unlock()
+				})
+			}
+			mu.Unlock()
+
+			atomic.StoreInt64(&s.lastActive, time.Now().UnixNano())
+			if err := writeDatagram(s.stream, buf[:n]); err != nil {
+				f.log.WithError(err).Debugf("Failed to forward datagram from %s", addr)
+			}
+		}
+	}()
+	return nil
+}
+
+// readDatagrams writes the datagrams received over the session's stream back to the local peer.
+func (f *ForwardConn) readDatagrams(pc net.PacketConn, addr net.Addr, s *udpSession, done func()) {
+	defer done()
+	defer closeLogged(f.log, s.stream)
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, err := readDatagram(s.stream, buf)
+		if err != nil {
+			return
+		}
+		atomic.StoreInt64(&s.lastActive, time.Now().UnixNano())
+		if _, err := pc.WriteTo(buf[:n], addr); err != nil {
+			f.log.WithError(err).Debugf("Failed to write datagram to %s", addr)
+			return
+		}
+	}
+}
+
+func (f *ForwardConn) closeOnSessionEnd() {
+	<-f.session.CloseChan()
+	if err := f.Close(); err != nil {
+		f.log.WithError(err).Debug("Forward conn closed with error.")
+	}
+}
+
+func forwardSessionConfig() *yamux.Config {
+	sessionCfg := yamux.DefaultConfig()
+	sessionCfg.EnableKeepAlive = false
+	sessionCfg.LogOutput = io.Discard
+	return sessionCfg
+}
+
+// writeDatagram writes p to w prefixed by its length.
+func writeDatagram(w io.Writer, p []byte) error {
+	if len(p) > maxDatagramSize {
+		return fmt.Errorf("datagram of %d bytes is too big", len(p))
+	}
+	frame := make([]byte, 2+len(p))
+	binary.BigEndian.PutUint16(frame, uint16(len(p)))
+	copy(frame[2:], p)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readDatagram reads a length prefixed datagram from r into buf.
+func readDatagram(r io.Reader, buf []byte) (int, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[:]))
+	if n > len(buf) {
+		return 0, fmt.Errorf("datagram of %d bytes does not fit buffer", n)
+	}
+	return io.ReadFull(r, buf[:n])
+}
+
+// splice copies data between a and b until either of them is closed.
+func splice(log *logging.Logger, a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	cp := func(dst, src net.Conn) {
+		defer wg.Done()
+		if _, err := io.Copy(dst, src); err != nil && !errors.Is(err, net.ErrClosed) {
+			log.WithError(err).Debug("Forwarding copy stopped.")
+		}
+		closeLogged(log, dst)
+		closeLogged(log, src)
+	}
+	go cp(a, b)
+	go cp(b, a)
+	wg.Wait()
+}
+
+func closeLogged(log *logging.Logger, c io.Closer) {
+	if err := c.Close(); err != nil && !errors.Is(err, net.ErrClosed) && !errors.Is(err, yamux.ErrSessionShutdown) {
+		log.WithError(err).Debug("Failed to close forwarding conn.")
+	}
+}
+
+// countingConn counts the bytes written to and read from the underlying conn.
+type countingConn struct {
+	net.Conn
+	sent     *uint64
+	received *uint64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddUint64(c.received, uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddUint64(c.sent, uint64(n))
+	return n, err
+}
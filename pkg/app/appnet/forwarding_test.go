@@ -0,0 +1,163 @@
+// Package appnet pkg/app/appnet/forwarding_test.go
+package appnet
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+)
+
+func TestParseForwardType(t *testing.T) {
+	fwdType, err := ParseForwardType("")
+	require.NoError(t, err)
+	require.Equal(t, ForwardHTTP, fwdType)
+
+	fwdType, err = ParseForwardType("udp")
+	require.NoError(t, err)
+	require.Equal(t, ForwardUDP, fwdType)
+
+	_, err = ParseForwardType("sctp")
+	require.ErrorIs(t, err, ErrUnknownForwardType)
+}
+
+func TestDatagramFraming(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, writeDatagram(&b, []byte("foo")))
+	require.NoError(t, writeDatagram(&b, []byte{}))
+	require.NoError(t, writeDatagram(&b, []byte("barbaz")))
+
+	buf := make([]byte, maxDatagramSize)
+	for _, want := range []string{"foo", "", "barbaz"} {
+		n, err := readDatagram(&b, buf)
+		require.NoError(t, err)
+		require.Equal(t, want, string(buf[:n]))
+	}
+	_, err := readDatagram(&b, buf)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestForwardConnTCP(t *testing.T) {
+	log := logging.MustGetLogger("fwd_test")
+
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close() // nolint:errcheck
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(conn, conn) // nolint:errcheck
+		}
+	}()
+
+	clientConn, serverConn := net.Pipe()
+	go ServeForward(log, serverConn, ForwardTCP, echo.Addr().String()) // nolint:errcheck
+
+	localPort := freePort(t, "tcp")
+	fwd := NewForwardConn(log, clientConn, 0, localPort, ForwardTCP)
+	require.NoError(t, fwd.Serve())
+	defer fwd.Close() // nolint:errcheck
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", localPort))
+		require.NoError(t, err)
+
+		msg := []byte(fmt.Sprintf("hello %d", i))
+		_, err = conn.Write(msg)
+		require.NoError(t, err)
+
+		buf := make([]byte, len(msg))
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		require.Equal(t, msg, buf)
+		require.NoError(t, conn.Close())
+	}
+
+	snapshot := fwd.Snapshot()
+	require.Equal(t, ForwardTCP, snapshot.Type)
+	require.NotZero(t, snapshot.BytesSent)
+	require.NotZero(t, snapshot.BytesReceived)
+}
+
+func TestForwardConnUDP(t *testing.T) {
+	log := logging.MustGetLogger("fwd_test")
+
+	echo, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer echo.Close() // nolint:errcheck
+	go func() {
+		buf := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := echo.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteTo(buf[:n], addr) // nolint:errcheck
+		}
+	}()
+
+	clientConn, serverConn := net.Pipe()
+	go ServeForward(log, serverConn, ForwardUDP, echo.LocalAddr().String()) // nolint:errcheck
+
+	localPort := freePort(t, "udp")
+	remote := &closeCounter{Conn: clientConn}
+	fwd := NewForwardConn(log, remote, 0, localPort, ForwardUDP)
+	require.NoError(t, fwd.Serve())
+	defer fwd.Close() // nolint:errcheck
+
+	conn, err := net.Dial("udp", fmt.Sprintf("127.0.0.1:%d", localPort))
+	require.NoError(t, err)
+	defer conn.Close() // nolint:errcheck
+
+	buf := make([]byte, maxDatagramSize)
+	for _, msg := range []string{"ping", "pong"} {
+		_, err = conn.Write([]byte(msg))
+		require.NoError(t, err)
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, msg, string(buf[:n]))
+	}
+
+	// the remote conn is closed once, by the session running over it
+	require.NoError(t, fwd.Close())
+	require.Equal(t, int32(1), remote.closes.Load())
+}
+
+// closeCounter counts the calls to Close.
+type closeCounter struct {
+	net.Conn
+	closes atomic.Int32
+}
+
+func (c *closeCounter) Close() error {
+	c.closes.Add(1)
+	return c.Conn.Close()
+}
+
+func freePort(t *testing.T, network string) int {
+	switch network {
+	case "udp":
+		pc, err := net.ListenPacket(network, "127.0.0.1:0")
+		require.NoError(t, err)
+		defer pc.Close() // nolint:errcheck
+		return pc.LocalAddr().(*net.UDPAddr).Port
+	default:
+		l, err := net.Listen(network, "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close() // nolint:errcheck
+		return l.Addr().(*net.TCPAddr).Port
+	}
+}
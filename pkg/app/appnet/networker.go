@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/routing"
 )
 
 //go:generate mockery --name Networker --case underscore --inpackage
@@ -65,6 +66,11 @@ type Networker interface {
 	ListenContext(ctx context.Context, addr Addr) (net.Listener, error)
 }
 
+// PolicyDialer is implemented by the networkers whose dials may be constrained by a route policy.
+type PolicyDialer interface {
+	DialPolicy(ctx context.Context, addr Addr, policy *routing.RoutePolicy) (net.Conn, error)
+}
+
 // Dial dials the remote `addr`.
 func Dial(addr Addr) (net.Conn, error) {
 	return DialContext(context.Background(), addr)
@@ -89,6 +95,20 @@ func DialContext(ctx context.Context, addr Addr) (net.Conn, error) {
 	return n.DialContext(ctx, addr)
 }
 
+// DialPolicy dials the remote `addr` over the routes allowed by `policy`.
+// The policy is ignored by the networkers not implementing PolicyDialer.
+func DialPolicy(ctx context.Context, addr Addr, policy *routing.RoutePolicy) (net.Conn, error) {
+	n, err := ResolveNetworker(addr.Net)
+	if err != nil {
+		return nil, err
+	}
+
+	if pd, ok := n.(PolicyDialer); ok && policy != nil {
+		return pd.DialPolicy(ctx, addr, policy)
+	}
+	return n.DialContext(ctx, addr)
+}
+
 // Listen starts listening on the local `addr`.
 func Listen(addr Addr) (net.Listener, error) {
 	return ListenContext(context.Background(), addr)
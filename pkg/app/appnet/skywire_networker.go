@@ -49,6 +49,11 @@ func (r *SkywireNetworker) Dial(addr Addr) (net.Conn, error) {
 
 // DialContext dials remote `addr` via `skynet` with context.
 func (r *SkywireNetworker) DialContext(ctx context.Context, addr Addr) (conn net.Conn, err error) {
+	return r.DialPolicy(ctx, addr, nil)
+}
+
+// DialPolicy dials remote `addr` via `skynet` over the routes allowed by `policy`.
+func (r *SkywireNetworker) DialPolicy(ctx context.Context, addr Addr, policy *routing.RoutePolicy) (conn net.Conn, err error) {
 	localPort, freePort, err := r.porter.ReserveEphemeral(ctx, nil)
 	if err != nil {
 		return nil, err
@@ -61,7 +66,10 @@ func (r *SkywireNetworker) DialContext(ctx context.Context, addr Addr) (conn net
 		}
 	}()
 
-	conn, err = r.r.DialRoutes(ctx, addr.PubKey, routing.Port(localPort), addr.Port, router.DefaultDialOptions())
+	opts := router.DefaultDialOptions()
+	opts.Policy = policy
+
+	conn, err = r.r.DialRoutes(ctx, addr.PubKey, routing.Port(localPort), addr.Port, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,12 @@
 // Package appserver pkg/app/appserver/app_state.go
 package appserver
 
-import "github.com/skycoin/skywire/pkg/routing"
+import (
+	"fmt"
+	"time"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
 
 // AppStatus defines running status of an App.
 type AppStatus int
@@ -20,6 +25,23 @@ const (
 	AppStatusStarting
 )
 
+// RestartPolicy defines when an app is restarted after its process exits.
+type RestartPolicy string
+
+const (
+	// RestartNever never restarts the app.
+	RestartNever RestartPolicy = "never"
+
+	// RestartOnFailure restarts the app when its process exits with an error.
+	RestartOnFailure RestartPolicy = "on-failure"
+
+	// RestartAlways restarts the app whenever its process exits without being stopped.
+	RestartAlways RestartPolicy = "always"
+)
+
+// DefaultRestartWindow is the period over which the restarts of an app are counted by default.
+const DefaultRestartWindow = 10 * time.Minute
+
 // AppConfig defines app startup parameters.
 type AppConfig struct {
 	Name      string       `json:"name"`
@@ -27,6 +49,35 @@ type AppConfig struct {
 	Args      []string     `json:"args,omitempty"`
 	AutoStart bool         `json:"auto_start"`
 	Port      routing.Port `json:"port"`
+	// RestartPolicy defines when the app is restarted after its process exits, never by default.
+	RestartPolicy RestartPolicy `json:"restart_policy,omitempty"`
+	// MaxRestarts is the largest number of restarts within RestartWindow, 0 means no limit.
+	MaxRestarts int `json:"max_restarts,omitempty"`
+	// RestartWindow is the period over which restarts are counted, examples: 10m, 1h etc.
+	RestartWindow string `json:"restart_window,omitempty"`
+	// RoutePolicy constrains the intermediate visors of the routes dialed by the app.
+	RoutePolicy *routing.RoutePolicy `json:"route_policy,omitempty"`
+}
+
+// Restarts returns the restart policy of the app and the period over which its restarts are counted.
+func (ac AppConfig) Restarts() (RestartPolicy, time.Duration, error) {
+	policy := ac.RestartPolicy
+	switch policy {
+	case "":
+		policy = RestartNever
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return RestartNever, 0, fmt.Errorf("invalid restart policy %q", policy)
+	}
+
+	if ac.RestartWindow == "" {
+		return policy, DefaultRestartWindow, nil
+	}
+	window, err := time.ParseDuration(ac.RestartWindow)
+	if err != nil || window <= 0 {
+		return RestartNever, 0, fmt.Errorf("invalid restart window %q", ac.RestartWindow)
+	}
+	return policy, window, nil
 }
 
 // AppState defines state parameters for a registered App.
@@ -34,6 +85,10 @@ type AppState struct {
 	AppConfig
 	Status         AppStatus `json:"status"`
 	DetailedStatus string    `json:"detailed_status"`
+	// Crashes is the number of times the process of the app exited with an error.
+	Crashes int `json:"crashes"`
+	// ExitCode is the exit code of the last process of the app, if it exited.
+	ExitCode *int `json:"exit_code,omitempty"`
 }
 
 // AppDetailedStatus is a app's detailed status.
@@ -142,6 +142,20 @@ func (_m *MockRPCIngressClient) Read(connID uint16, b []byte) (int, error) {
 	return r0, r1
 }
 
+// SetClientsUsage provides a mock function with given fields: usage
+func (_m *MockRPCIngressClient) SetClientsUsage(usage []ClientUsage) error {
+	ret := _m.Called(usage)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]ClientUsage) error); ok {
+		r0 = rf(usage)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetConnectionDuration provides a mock function with given fields: dur
 func (_m *MockRPCIngressClient) SetConnectionDuration(dur int64) error {
 	ret := _m.Called(dur)
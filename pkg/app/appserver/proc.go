@@ -19,6 +19,7 @@ import (
 	"github.com/orandin/lumberjackrus"
 	"github.com/sirupsen/logrus"
 
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
 	"github.com/skycoin/skywire-utilities/pkg/logging"
 	"github.com/skycoin/skywire/pkg/app/appcommon"
 	"github.com/skycoin/skywire/pkg/app/appdisc"
@@ -46,8 +47,11 @@ type Proc struct {
 
 	cmd       *exec.Cmd
 	isRunning int32
+	stopping  int32 // set once the proc is requested to stop
 	waitMx    sync.Mutex
 	waitErr   error
+	// onExit is called once the proc exits, if not nil.
+	onExit func(stopped bool, err error)
 
 	rpcGWMu  sync.Mutex
 	rpcGW    *RPCIngressGateway // gateway shared over 'conn' - introduced AFTER proc is started
@@ -66,6 +70,9 @@ type Proc struct {
 	// connection duration (i.e. when vpn client is connected, the app will set the connection duration)
 	connDuration   int64
 	connDurationMu sync.RWMutex
+	// data usage of the clients keyed by their public keys (i.e. set by vpn-server)
+	clientsUsage   map[cipher.PubKey]ClientUsage
+	clientsUsageMu sync.RWMutex
 
 	errMx sync.RWMutex
 	err   string
@@ -215,11 +222,18 @@ func (p *Proc) Start() error {
 		}()
 
 		defer func() {
+			stopped := atomic.LoadInt32(&p.stopping) == 1
+
 			// here will definitely be an error notifying that the process
 			// is already stopped. We do this to remove proc from the manager,
 			// therefore giving the correct app status to hypervisor.
 			_ = p.m.SetError(p.appName, p.err) //nolint:errcheck
 			_ = p.m.Stop(p.appName)            //nolint:errcheck
+
+			// the proc is removed from the manager first, so that the app may be started again.
+			if p.onExit != nil {
+				p.onExit(stopped, p.waitErr)
+			}
 		}()
 
 		select {
@@ -294,6 +308,7 @@ func (p *Proc) Stop() error {
 	if atomic.LoadInt32(&p.isRunning) == 0 {
 		return errProcNotStarted
 	}
+	atomic.StoreInt32(&p.stopping, 1)
 
 	if p.cmd.Process != nil {
 		if runtime.GOOS != "windows" {
@@ -377,6 +392,26 @@ func (p *Proc) ConnectionDuration() int64 {
 	return p.connDuration
 }
 
+// SetClientsUsage sets the data usage of the proc's clients.
+func (p *Proc) SetClientsUsage(usage []ClientUsage) {
+	clientsUsage := make(map[cipher.PubKey]ClientUsage, len(usage))
+	for _, u := range usage {
+		clientsUsage[u.PK] = u
+	}
+
+	p.clientsUsageMu.Lock()
+	defer p.clientsUsageMu.Unlock()
+	p.clientsUsage = clientsUsage
+}
+
+// ClientUsage gets the data usage of the proc's client with `pk`.
+func (p *Proc) ClientUsage(pk cipher.PubKey) (ClientUsage, bool) {
+	p.clientsUsageMu.RLock()
+	defer p.clientsUsageMu.RUnlock()
+	u, ok := p.clientsUsage[pk]
+	return u, ok
+}
+
 // DetailedStatus gets proc's detailed status.
 func (p *Proc) DetailedStatus() string {
 	p.statusMx.RLock()
@@ -426,6 +461,21 @@ type ConnectionSummary struct {
 	BandwidthReceived  uint64        `json:"bandwidth_received"`
 	Error              string        `json:"error"`
 	ConnectionDuration int64         `json:"connection_duration,omitempty"`
+	RemotePK           cipher.PubKey `json:"remote_pk"`
+	Usage              *ClientUsage  `json:"usage,omitempty"`
+}
+
+// ClientUsage is the data usage of an app client within the current quota period.
+type ClientUsage struct {
+	PK     cipher.PubKey `json:"pk"`
+	Period string        `json:"period"`
+	// Sent and Received are counted from the app's point of view.
+	Sent     uint64 `json:"sent"`
+	Received uint64 `json:"received"`
+	// MonthlyCap is the transfer cap of the period in bytes, 0 means unlimited.
+	MonthlyCap uint64 `json:"monthly_cap"`
+	// RateLimit is the rate limit in bytes per second, 0 means unlimited.
+	RateLimit uint64 `json:"rate_limit"`
 }
 
 // ConnectionsSummary returns all of the proc's connections stats.
@@ -459,6 +509,12 @@ func (p *Proc) ConnectionsSummary() []ConnectionSummary {
 			})
 			return true
 		}
+		var usage *ClientUsage
+		remotePK := wrappedConn.RemoteAddr().(appnet.Addr).PubKey
+		if u, ok := p.ClientUsage(remotePK); ok {
+			usage = &u
+		}
+
 		summaries = append(summaries, ConnectionSummary{
 			IsAlive: skywireConn.IsAlive(),
 			// Latency in summary is expected to be in ms and not ns so we change the base to ms
@@ -468,6 +524,8 @@ func (p *Proc) ConnectionsSummary() []ConnectionSummary {
 			BandwidthSent:      skywireConn.BandwidthSent(),
 			BandwidthReceived:  skywireConn.BandwidthReceived(),
 			ConnectionDuration: p.ConnectionDuration(),
+			RemotePK:           remotePK,
+			Usage:              usage,
 		})
 
 		return true
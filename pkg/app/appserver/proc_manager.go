@@ -54,6 +54,14 @@ type ProcManager interface {
 	Addr() net.Addr
 }
 
+// ProcCallbacks contains callbacks which a ProcManager uses to notify about the procs of the apps.
+type ProcCallbacks struct {
+	OnProcStart func(appName string)
+	// OnProcExit is called once the proc of an app exits. `stopped` is true if the proc was
+	// requested to stop, `err` is the error the proc exited with if any.
+	OnProcExit func(appName string, stopped bool, err error)
+}
+
 // procManager manages skywire applications. It implements `ProcManager`.
 type procManager struct {
 	mLog *logging.MasterLogger
@@ -69,7 +77,8 @@ type procManager struct {
 
 	errors map[string]string
 	// event broadcaster: broadcasts events to apps
-	eb *appevent.Broadcaster
+	eb        *appevent.Broadcaster
+	callbacks *ProcCallbacks
 
 	mx   sync.RWMutex
 	done chan struct{}
@@ -78,7 +87,8 @@ type procManager struct {
 }
 
 // NewProcManager constructs `ProcManager`.
-func NewProcManager(mLog *logging.MasterLogger, discF *appdisc.Factory, eb *appevent.Broadcaster, addr, logStorePath string) (ProcManager, error) {
+func NewProcManager(mLog *logging.MasterLogger, discF *appdisc.Factory, eb *appevent.Broadcaster, callbacks *ProcCallbacks,
+	addr, logStorePath string) (ProcManager, error) {
 	if mLog == nil {
 		mLog = logging.NewMasterLogger()
 	}
@@ -104,6 +114,7 @@ func NewProcManager(mLog *logging.MasterLogger, discF *appdisc.Factory, eb *appe
 		procsByKey:   make(map[appcommon.ProcKey]*Proc),
 		errors:       make(map[string]string),
 		eb:           eb,
+		callbacks:    callbacks,
 		done:         make(chan struct{}),
 		logStorePath: logStorePath,
 	}
@@ -211,6 +222,10 @@ func (m *procManager) Start(conf appcommon.ProcConfig) (appcommon.ProcID, error)
 	}
 
 	proc := NewProc(nil, conf, disc, m, conf.AppName, m.logStorePath)
+	if m.callbacks != nil && m.callbacks.OnProcExit != nil {
+		appName, onExit := conf.AppName, m.callbacks.OnProcExit
+		proc.onExit = func(stopped bool, err error) { onExit(appName, stopped, err) }
+	}
 	m.procs[conf.AppName] = proc
 	m.procsByKey[conf.ProcKey] = proc
 
@@ -221,6 +236,9 @@ func (m *procManager) Start(conf appcommon.ProcConfig) (appcommon.ProcID, error)
 		return 0, err
 	}
 	delete(m.errors, conf.AppName)
+	if m.callbacks != nil && m.callbacks.OnProcStart != nil {
+		m.callbacks.OnProcStart(conf.AppName)
+	}
 	return appcommon.ProcID(proc.cmd.Process.Pid), nil
 }
 
@@ -17,6 +17,7 @@ import (
 type RPCIngressClient interface {
 	SetDetailedStatus(status string) error
 	SetConnectionDuration(dur int64) error
+	SetClientsUsage(usage []ClientUsage) error
 	SetError(appErr string) error
 	SetAppPort(appPort routing.Port) error
 	Dial(remote appnet.Addr) (connID uint16, localPort routing.Port, err error)
@@ -55,6 +56,11 @@ func (c *rpcIngressClient) SetConnectionDuration(dur int64) error {
 	return c.rpc.Call(c.formatMethod("SetConnectionDuration"), dur, nil)
 }
 
+// SetClientsUsage sets the data usage of the app's clients.
+func (c *rpcIngressClient) SetClientsUsage(usage []ClientUsage) error {
+	return c.rpc.Call(c.formatMethod("SetClientsUsage"), &usage, nil)
+}
+
 // SetError sets error of an app.
 func (c *rpcIngressClient) SetError(appErr string) error {
 	return c.rpc.Call(c.formatMethod("SetError"), &appErr, nil)
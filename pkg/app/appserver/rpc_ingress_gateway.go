@@ -2,6 +2,7 @@
 package appserver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -92,6 +93,13 @@ func (r *RPCIngressGateway) SetConnectionDuration(dur int64, _ *struct{}) (err e
 	return nil
 }
 
+// SetClientsUsage sets the data usage of the app's clients (vpn-server in this instance)
+func (r *RPCIngressGateway) SetClientsUsage(usage *[]ClientUsage, _ *struct{}) (err error) {
+	defer rpcutil.LogCall(r.log, "SetClientsUsage", usage)(nil, &err)
+	r.proc.SetClientsUsage(*usage)
+	return nil
+}
+
 // SetError sets error of an app.
 func (r *RPCIngressGateway) SetError(appErr *string, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetError", appErr)(nil, &err)
@@ -106,6 +114,14 @@ func (r *RPCIngressGateway) SetAppPort(port routing.Port, _ *struct{}) (err erro
 	return nil
 }
 
+// routePolicy returns the route policy of the app, nil if there's none.
+func (r *RPCIngressGateway) routePolicy() *routing.RoutePolicy {
+	if r.proc == nil {
+		return nil
+	}
+	return r.proc.conf.RoutePolicy
+}
+
 // DialResp contains response parameters for `Dial`.
 type DialResp struct {
 	ConnID    uint16
@@ -121,7 +137,7 @@ func (r *RPCIngressGateway) Dial(remote *appnet.Addr, resp *DialResp) (err error
 		return err
 	}
 
-	conn, err := appnet.Dial(*remote)
+	conn, err := appnet.DialPolicy(context.Background(), *remote, r.routePolicy())
 	if err != nil {
 		free()
 		return err
@@ -74,6 +74,11 @@ func (c *Client) SetConnectionDuration(dur int64) error {
 	return c.rpcC.SetConnectionDuration(dur)
 }
 
+// SetClientsUsage sets the data usage of the app's clients within the visor.
+func (c *Client) SetClientsUsage(usage []appserver.ClientUsage) error {
+	return c.rpcC.SetClientsUsage(usage)
+}
+
 // SetError sets app error within the visor.
 func (c *Client) SetError(appErr string) error {
 	return c.rpcC.SetError(appErr)
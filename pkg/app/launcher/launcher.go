@@ -41,7 +41,11 @@ type AppLauncher struct {
 	r     router.Router
 	procM appserver.ProcManager
 	apps  map[string]appserver.AppConfig
-	mx    sync.Mutex
+	// supervisions are the crash supervision states of the apps.
+	supervisions map[string]*supervision
+	// closed is set once the launcher is closed, the apps are no longer restarted then.
+	closed bool
+	mx     sync.Mutex
 }
 
 // AppLauncherConfig configures the launcher.
@@ -76,11 +80,12 @@ func (l *AppLauncher) ResetConfig(conf AppLauncherConfig) {
 // NewLauncher creates a new launcher.
 func NewLauncher(log logrus.FieldLogger, conf AppLauncherConfig, dmsgC *dmsg.Client, r router.Router, procM appserver.ProcManager) (*AppLauncher, error) {
 	launcher := &AppLauncher{
-		conf:  conf,
-		log:   log,
-		r:     r,
-		procM: procM,
-		mx:    sync.Mutex{},
+		conf:         conf,
+		log:          log,
+		r:            r,
+		procM:        procM,
+		supervisions: make(map[string]*supervision),
+		mx:           sync.Mutex{},
 	}
 
 	// Ensure the existence of directories.
@@ -167,6 +172,10 @@ func (l *AppLauncher) AppState(name string) (*appserver.AppState, bool) {
 		return nil, false
 	}
 	state := &appserver.AppState{AppConfig: ac, Status: appserver.AppStatusStopped}
+	if s, ok := l.supervisions[name]; ok {
+		state.Crashes = s.crashes
+		state.ExitCode = s.exitCode
+	}
 	if err, ok := l.procM.ErrorByName(ac.Name); ok { //nolint:errcheck
 		if err != "" {
 			state.DetailedStatus = err
@@ -236,6 +245,11 @@ func (l *AppLauncher) startApp(cmd string, args, envs []string) error {
 		return ErrAppNotFound
 	}
 
+	// Keep what the app is started with for its restarts.
+	s := l.supervision(cmd)
+	s.cancelRestart()
+	s.args, s.envs, s.stopRequested = args, envs, false
+
 	if args != nil {
 		ac.Args = args
 	}
@@ -261,6 +275,8 @@ func (l *AppLauncher) startApp(cmd string, args, envs []string) error {
 func (l *AppLauncher) StopApp(name string) (*appserver.Proc, error) {
 	log := l.log.WithField("func", "StopApp").WithField("app_name", name)
 
+	l.requestStop(name)
+
 	proc, ok := l.procM.ProcByName(name)
 	if !ok {
 		return nil, ErrAppNotRunning
@@ -278,6 +294,8 @@ func (l *AppLauncher) StopApp(name string) (*appserver.Proc, error) {
 func (l *AppLauncher) KillApp(name string) error {
 	log := l.log.WithField("func", "KillApp").WithField("app_name", name)
 
+	l.requestStop(name)
+
 	if err := l.killApp(name); err != nil {
 		log.WithError(err).Warn("Failed to kill app.")
 		return err
@@ -286,6 +304,19 @@ func (l *AppLauncher) KillApp(name string) error {
 	return nil
 }
 
+// requestStop prevents the app from being restarted, until it is started again.
+func (l *AppLauncher) requestStop(name string) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if _, ok := l.apps[name]; !ok {
+		return
+	}
+	s := l.supervision(name)
+	s.cancelRestart()
+	s.stopRequested = true
+}
+
 // RestartApp restarts a running app.
 func (l *AppLauncher) RestartApp(name, binary string) error {
 	l.log.WithField("func", "RestartApp").WithField("app_name", name).
@@ -317,6 +348,7 @@ func makeProcConfig(lc AppLauncherConfig, ac appserver.AppConfig, envs []string)
 		RoutingPort: ac.Port,
 		BinaryLoc:   filepath.Join(lc.BinPath, ac.Binary),
 		LogDBLoc:    filepath.Join(lc.LocalPath, ac.Name+"_log.db"),
+		RoutePolicy: ac.RoutePolicy,
 	}
 	err := ensureDir(&procConf.ProcWorkDir)
 	return procConf, err
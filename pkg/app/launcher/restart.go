@@ -0,0 +1,177 @@
+// Package launcher restart.go
+package launcher
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+
+	"github.com/skycoin/skywire/pkg/app/appserver"
+)
+
+const (
+	// restartBackoffMin is the delay before the first restart of an app within its restart window.
+	restartBackoffMin = time.Second
+	// restartBackoffMax is the longest delay before restarting an app.
+	restartBackoffMax = 5 * time.Minute
+)
+
+// supervision is the crash supervision state of an app.
+type supervision struct {
+	// args and envs are the ones the app was last started with.
+	args []string
+	envs []string
+
+	crashes  int
+	exitCode *int
+	// restarts are the times of the restarts within the restart window.
+	restarts []time.Time
+	// stopRequested is set once the app is stopped from the outer code, until it is started again.
+	stopRequested bool
+	timer         *time.Timer
+}
+
+// restartDelay returns the delay before restarting an app which exited at `now`, and false if
+// the app should not be restarted. The delay doubles with every restart within the window.
+func (s *supervision) restartDelay(policy appserver.RestartPolicy, window time.Duration, maxRestarts int, crashed bool, now time.Time) (time.Duration, bool) {
+	switch policy {
+	case appserver.RestartAlways:
+	case appserver.RestartOnFailure:
+		if !crashed {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	restarts := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < window {
+			restarts = append(restarts, t)
+		}
+	}
+	s.restarts = restarts
+	if maxRestarts > 0 && len(s.restarts) >= maxRestarts {
+		return 0, false
+	}
+
+	delay := restartBackoffMin
+	for i := 0; i < len(s.restarts) && delay < restartBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > restartBackoffMax {
+		delay = restartBackoffMax
+	}
+	s.restarts = append(s.restarts, now)
+	return delay, true
+}
+
+// cancelRestart cancels the pending restart of the app, if any.
+func (s *supervision) cancelRestart() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// supervision returns the supervision state of the app of `name`.
+func (l *AppLauncher) supervision(name string) *supervision {
+	s, ok := l.supervisions[name]
+	if !ok {
+		s = &supervision{}
+		l.supervisions[name] = s
+	}
+	return s
+}
+
+// ProcExited records the exit of the process of an app and restarts the app according to
+// its restart policy. `stopped` is true if the process was requested to stop.
+func (l *AppLauncher) ProcExited(name string, stopped bool, err error) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.procExited(name, stopped, err)
+}
+
+func (l *AppLauncher) procExited(name string, stopped bool, err error) {
+	log := l.log.WithField("func", "ProcExited").WithField("app_name", name)
+
+	ac, ok := l.apps[name]
+	if !ok {
+		return
+	}
+
+	s := l.supervision(name)
+	if code, ok := exitCode(err); ok {
+		s.exitCode = &code
+	}
+	if stopped || s.stopRequested || l.closed {
+		return
+	}
+	crashed := err != nil
+	if crashed {
+		s.crashes++
+	}
+
+	policy, window, pErr := ac.Restarts()
+	if pErr != nil {
+		log.WithError(pErr).Warn("Invalid restart settings, the app is not restarted.")
+		return
+	}
+	delay, ok := s.restartDelay(policy, window, ac.MaxRestarts, crashed, time.Now())
+	if !ok {
+		if policy != appserver.RestartNever && (crashed || policy == appserver.RestartAlways) {
+			log.WithField("max_restarts", ac.MaxRestarts).
+				WithField("restart_window", window).
+				Warn("Restart limit reached, the app is not restarted.")
+		}
+		return
+	}
+
+	log.WithError(err).WithField("delay", delay).Info("App exited, restarting...")
+	s.cancelRestart()
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		l.mx.Lock()
+		defer l.mx.Unlock()
+
+		// the restart was cancelled
+		if s.timer != timer || l.closed {
+			return
+		}
+		s.timer = nil
+
+		err := l.startApp(name, s.args, s.envs)
+		if err == nil || errors.Is(err, appserver.ErrAppAlreadyStarted) {
+			return
+		}
+		log.WithError(err).Warn("Failed to restart app.")
+		// a failed start is supervised like a crash, so that it is retried
+		l.procExited(name, false, err)
+	})
+	s.timer = timer
+}
+
+// Close cancels the pending restarts of the apps, which are no longer restarted.
+func (l *AppLauncher) Close() error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.closed = true
+	for _, s := range l.supervisions {
+		s.cancelRestart()
+	}
+	return nil
+}
+
+// exitCode returns the exit code of a process which exited with `err`, and false if it is unknown.
+func exitCode(err error) (int, bool) {
+	if err == nil {
+		return 0, true
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
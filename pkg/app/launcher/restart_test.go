@@ -0,0 +1,161 @@
+// Package launcher restart_test.go
+package launcher
+
+import (
+	"errors"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/app/appcommon"
+	"github.com/skycoin/skywire/pkg/app/appserver"
+)
+
+func TestSupervision_restartDelay(t *testing.T) {
+	now := time.Now()
+
+	t.Run("policies", func(t *testing.T) {
+		s := &supervision{}
+		_, ok := s.restartDelay(appserver.RestartNever, time.Minute, 0, true, now)
+		require.False(t, ok)
+		_, ok = s.restartDelay(appserver.RestartOnFailure, time.Minute, 0, false, now)
+		require.False(t, ok)
+		_, ok = s.restartDelay(appserver.RestartOnFailure, time.Minute, 0, true, now)
+		require.True(t, ok)
+		_, ok = s.restartDelay(appserver.RestartAlways, time.Minute, 0, false, now)
+		require.True(t, ok)
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		s := &supervision{}
+		for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second} {
+			delay, ok := s.restartDelay(appserver.RestartAlways, time.Hour, 0, true, now)
+			require.True(t, ok)
+			require.Equal(t, want, delay)
+		}
+		for i := 0; i < 20; i++ {
+			delay, ok := s.restartDelay(appserver.RestartAlways, time.Hour, 0, true, now)
+			require.True(t, ok)
+			require.LessOrEqual(t, delay, restartBackoffMax)
+		}
+
+		// restarts out of the window are forgotten
+		delay, ok := s.restartDelay(appserver.RestartAlways, time.Hour, 0, true, now.Add(2*time.Hour))
+		require.True(t, ok)
+		require.Equal(t, time.Second, delay)
+	})
+
+	t.Run("max restarts", func(t *testing.T) {
+		s := &supervision{}
+		for i := 0; i < 3; i++ {
+			_, ok := s.restartDelay(appserver.RestartOnFailure, time.Minute, 3, true, now)
+			require.True(t, ok)
+		}
+		_, ok := s.restartDelay(appserver.RestartOnFailure, time.Minute, 3, true, now.Add(30*time.Second))
+		require.False(t, ok)
+		_, ok = s.restartDelay(appserver.RestartOnFailure, time.Minute, 3, true, now.Add(time.Minute))
+		require.True(t, ok)
+	})
+}
+
+// startCounter is a proc manager counting the started procs.
+type startCounter struct {
+	appserver.ProcManager
+	mx      sync.Mutex
+	started int
+}
+
+func (c *startCounter) Start(appcommon.ProcConfig) (appcommon.ProcID, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.started++
+	return appcommon.ProcID(c.started), nil
+}
+
+func (c *startCounter) count() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.started
+}
+
+func newTestLauncher(t *testing.T, procM appserver.ProcManager, ac appserver.AppConfig) *AppLauncher {
+	return &AppLauncher{
+		conf:         AppLauncherConfig{BinPath: t.TempDir(), LocalPath: t.TempDir()},
+		log:          logging.MustGetLogger("launcher"),
+		procM:        procM,
+		apps:         map[string]appserver.AppConfig{ac.Name: ac},
+		supervisions: make(map[string]*supervision),
+	}
+}
+
+func TestAppLauncher_ProcExited(t *testing.T) {
+	const name = "app"
+	crash := errors.New("crashed")
+
+	t.Run("restart after a crash", func(t *testing.T) {
+		procM := &startCounter{}
+		l := newTestLauncher(t, procM, appserver.AppConfig{Name: name, RestartPolicy: appserver.RestartOnFailure})
+
+		l.ProcExited(name, false, nil)
+		l.mx.Lock()
+		require.Nil(t, l.supervisions[name].timer)
+		l.mx.Unlock()
+
+		l.ProcExited(name, false, crash)
+		require.Eventually(t, func() bool {
+			l.mx.Lock()
+			defer l.mx.Unlock()
+			return procM.count() == 1 && l.supervisions[name].timer == nil
+		}, 3*restartBackoffMin, 10*time.Millisecond)
+		require.Equal(t, 1, l.supervisions[name].crashes)
+	})
+
+	t.Run("no restart once stopped", func(t *testing.T) {
+		procM := &startCounter{}
+		l := newTestLauncher(t, procM, appserver.AppConfig{Name: name, RestartPolicy: appserver.RestartAlways})
+
+		l.ProcExited(name, false, crash)
+		l.requestStop(name)
+		l.ProcExited(name, true, crash)
+		time.Sleep(2 * restartBackoffMin)
+		require.Equal(t, 0, procM.count())
+	})
+
+	t.Run("close cancels the restarts", func(t *testing.T) {
+		procM := &startCounter{}
+		l := newTestLauncher(t, procM, appserver.AppConfig{Name: name, RestartPolicy: appserver.RestartAlways})
+
+		l.ProcExited(name, false, crash)
+		l.mx.Lock()
+		require.NotNil(t, l.supervisions[name].timer)
+		l.mx.Unlock()
+
+		require.NoError(t, l.Close())
+		require.Nil(t, l.supervisions[name].timer)
+
+		// exits of the procs stopped on shutdown are not rescheduled
+		l.ProcExited(name, false, crash)
+		require.Nil(t, l.supervisions[name].timer)
+
+		time.Sleep(2 * restartBackoffMin)
+		require.Equal(t, 0, procM.count())
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	code, ok := exitCode(nil)
+	require.True(t, ok)
+	require.Equal(t, 0, code)
+
+	_, ok = exitCode(errors.New("failed"))
+	require.False(t, ok)
+
+	err := exec.Command("sh", "-c", "exit 3").Run()
+	code, ok = exitCode(err)
+	require.True(t, ok)
+	require.Equal(t, 3, code)
+}
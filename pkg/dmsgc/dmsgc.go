@@ -21,8 +21,10 @@ type DmsgConfig struct {
 	ConnectedServersType string        `json:"servers_type"`
 }
 
-// New makes new dmsg client from configuration
-func New(pk cipher.PubKey, sk cipher.SecKey, eb *appevent.Broadcaster, conf *DmsgConfig, httpC *http.Client, masterLogger *logging.MasterLogger) *dmsg.Client {
+// New makes new dmsg client from configuration.
+// The session events are broadcast to the apps, and passed to `callbacks` if not nil.
+func New(pk cipher.PubKey, sk cipher.SecKey, eb *appevent.Broadcaster, conf *DmsgConfig, httpC *http.Client, masterLogger *logging.MasterLogger,
+	callbacks *dmsg.ClientCallbacks) *dmsg.Client {
 	dmsgConf := &dmsg.Config{
 		MinSessions: conf.SessionsCount,
 		Callbacks: &dmsg.ClientCallbacks{
@@ -30,13 +32,19 @@ func New(pk cipher.PubKey, sk cipher.SecKey, eb *appevent.Broadcaster, conf *Dms
 				data := appevent.TCPDialData{RemoteNet: network, RemoteAddr: addr}
 				event := appevent.NewEvent(appevent.TCPDial, data)
 				_ = eb.Broadcast(context.Background(), event) //nolint:errcheck
+				if callbacks != nil && callbacks.OnSessionDial != nil {
+					_ = callbacks.OnSessionDial(network, addr) //nolint:errcheck
+				}
 				// @evanlinjin: An error is not returned here as this will cancel the session dial.
 				return nil
 			},
-			OnSessionDisconnect: func(network, addr string, _ error) {
+			OnSessionDisconnect: func(network, addr string, err error) {
 				data := appevent.TCPCloseData{RemoteNet: network, RemoteAddr: addr}
 				event := appevent.NewEvent(appevent.TCPClose, data)
 				_ = eb.Broadcast(context.Background(), event) //nolint:errcheck
+				if callbacks != nil && callbacks.OnSessionDisconnect != nil {
+					callbacks.OnSessionDisconnect(network, addr, err)
+				}
 			},
 		},
 		ConnectedServersType: conf.ConnectedServersType,
@@ -26,6 +26,9 @@ var ErrTransportNotFound = errors.New("transport not found")
 type RouteOptions struct {
 	MinHops uint16
 	MaxHops uint16
+	// Policy constrains the routes found. The router applies it to the routes returned as well,
+	// so that it is honoured by route finders not supporting it.
+	Policy *routing.RoutePolicy `json:",omitempty"`
 }
 
 // FindRoutesRequest parses json body for /routes endpoint request
@@ -0,0 +1,290 @@
+// Package rfclient pkg/routefinder/rfclient/graph.go
+package rfclient
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
+)
+
+// yenCandidateFactor limits the paths generated by the k shortest paths search to this many times the
+// routes wanted, so that a graph with few routes of the minimum hops is not searched indefinitely.
+const yenCandidateFactor = 4
+
+// Graph caches the transports between the visors, the local route finder searches the routes in it.
+type Graph struct {
+	mu    sync.RWMutex
+	nodes map[cipher.PubKey]*graphNode
+}
+
+type graphNode struct {
+	tps     map[uuid.UUID]cipher.PubKey // transport ID -> remote visor
+	fetched time.Time                   // zero if the transports of the visor were never fetched
+}
+
+// NewGraph creates an empty Graph.
+func NewGraph() *Graph {
+	return &Graph{nodes: make(map[cipher.PubKey]*graphNode)}
+}
+
+// node returns the node of `pk`, creating it if needed.
+// NOTE: not thread-safe.
+func (g *Graph) node(pk cipher.PubKey) *graphNode {
+	n, ok := g.nodes[pk]
+	if !ok {
+		n = &graphNode{tps: make(map[uuid.UUID]cipher.PubKey)}
+		g.nodes[pk] = n
+	}
+	return n
+}
+
+// AddTransports adds the transports of `entries` to the graph.
+func (g *Graph) AddTransports(entries ...transport.Entry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, entry := range entries {
+		g.node(entry.Edges[0]).tps[entry.ID] = entry.Edges[1]
+		g.node(entry.Edges[1]).tps[entry.ID] = entry.Edges[0]
+	}
+}
+
+// SetTransports replaces the transports of the visor of `pk` with the ones of `entries` it is an edge of.
+func (g *Graph) SetTransports(pk cipher.PubKey, entries []transport.Entry) {
+	g.setTransports(pk, entries, time.Now())
+}
+
+func (g *Graph) setTransports(pk cipher.PubKey, entries []transport.Entry, fetched time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := g.node(pk)
+	for id, remote := range n.tps {
+		if rn, ok := g.nodes[remote]; ok {
+			delete(rn.tps, id)
+		}
+	}
+	n.tps = make(map[uuid.UUID]cipher.PubKey, len(entries))
+	for _, entry := range entries {
+		remote := entry.Edges[0]
+		if remote == pk {
+			remote = entry.Edges[1]
+		} else if entry.Edges[1] != pk {
+			continue
+		}
+		n.tps[entry.ID] = remote
+		g.node(remote).tps[entry.ID] = pk
+	}
+	n.fetched = fetched
+}
+
+// markFetched sets the time the transports of `pk` were fetched at, keeping them.
+func (g *Graph) markFetched(pk cipher.PubKey, fetched time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.node(pk).fetched = fetched
+}
+
+// fetched returns the time the transports of `pk` were fetched at, zero if never.
+func (g *Graph) fetched(pk cipher.PubKey) time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if n, ok := g.nodes[pk]; ok {
+		return n.fetched
+	}
+	return time.Time{}
+}
+
+// neighbours returns the visors sharing a transport with `pk`.
+func (g *Graph) neighbours(pk cipher.PubKey) []cipher.PubKey {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	n, ok := g.nodes[pk]
+	if !ok {
+		return nil
+	}
+	pks := make([]cipher.PubKey, 0, len(n.tps))
+	for _, remote := range n.tps {
+		pks = append(pks, remote)
+	}
+	return pks
+}
+
+// hops returns the hops over the transports of `pk`, in a stable order.
+// NOTE: not thread-safe.
+func (g *Graph) hops(pk cipher.PubKey) []routing.Hop {
+	n, ok := g.nodes[pk]
+	if !ok {
+		return nil
+	}
+	hops := make([]routing.Hop, 0, len(n.tps))
+	for id, remote := range n.tps {
+		hops = append(hops, routing.Hop{TpID: id, From: pk, To: remote})
+	}
+	sort.Slice(hops, func(i, j int) bool {
+		if c := bytes.Compare(hops[i].To[:], hops[j].To[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(hops[i].TpID[:], hops[j].TpID[:]) < 0
+	})
+	return hops
+}
+
+// shortestPath returns a path of the fewest hops, at most `maxHops`, from `src` to `dst`
+// which avoids the `blockedPKs` visors and the `blockedTps` transports.
+// NOTE: not thread-safe.
+func (g *Graph) shortestPath(src, dst cipher.PubKey, maxHops int, blockedPKs map[cipher.PubKey]bool,
+	blockedTps map[uuid.UUID]bool) ([]routing.Hop, bool) {
+	if src == dst {
+		return []routing.Hop{}, true
+	}
+
+	prev := map[cipher.PubKey]routing.Hop{}
+	visited := map[cipher.PubKey]bool{src: true}
+	frontier := []cipher.PubKey{src}
+	for depth := 0; depth < maxHops && len(frontier) > 0; depth++ {
+		var next []cipher.PubKey
+		for _, pk := range frontier {
+			for _, hop := range g.hops(pk) {
+				if visited[hop.To] || blockedTps[hop.TpID] || (blockedPKs[hop.To] && hop.To != dst) {
+					continue
+				}
+				visited[hop.To] = true
+				prev[hop.To] = hop
+				if hop.To == dst {
+					path := make([]routing.Hop, depth+1)
+					for i, at := depth, dst; i >= 0; i-- {
+						path[i] = prev[at]
+						at = path[i].From
+					}
+					return path, true
+				}
+				next = append(next, hop.To)
+			}
+		}
+		frontier = next
+	}
+	return nil, false
+}
+
+// kShortestPaths returns up to `k` loopless paths of `minHops` to `maxHops` hops from `src` to `dst`,
+// avoiding the `excluded` visors, from the shortest one. It implements the Yen's algorithm.
+func (g *Graph) kShortestPaths(src, dst cipher.PubKey, minHops, maxHops, k int, excluded map[cipher.PubKey]bool) [][]routing.Hop {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if maxHops < 1 || k < 1 {
+		return nil
+	}
+	if src == dst {
+		return filterPaths(g.loops(src, maxHops, excluded), minHops, 0)
+	}
+
+	first, ok := g.shortestPath(src, dst, maxHops, excluded, nil)
+	if !ok {
+		return nil
+	}
+	found := [][]routing.Hop{first}
+	var candidates [][]routing.Hop
+
+	for len(filterPaths(found, minHops, k)) < k && len(found) < k*yenCandidateFactor {
+		last := found[len(found)-1]
+		for i := range last {
+			root := last[:i]
+
+			blockedTps := make(map[uuid.UUID]bool)
+			for _, path := range found {
+				if len(path) > i && samePath(path[:i], root) {
+					blockedTps[path[i].TpID] = true
+				}
+			}
+			blockedPKs := make(map[cipher.PubKey]bool, len(excluded)+len(root))
+			for pk := range excluded {
+				blockedPKs[pk] = true
+			}
+			for _, hop := range root {
+				blockedPKs[hop.From] = true
+			}
+
+			spur, ok := g.shortestPath(last[i].From, dst, maxHops-i, blockedPKs, blockedTps)
+			if !ok {
+				continue
+			}
+			path := append(append(make([]routing.Hop, 0, len(root)+len(spur)), root...), spur...)
+			if !containsPath(found, path) && !containsPath(candidates, path) {
+				candidates = append(candidates, path)
+			}
+		}
+		if len(candidates) == 0 {
+			break
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool { return len(candidates[i]) < len(candidates[j]) })
+		found = append(found, candidates[0])
+		candidates = candidates[1:]
+	}
+
+	return filterPaths(found, minHops, k)
+}
+
+// loops returns the routes from `pk` back to itself, going out over each of its transports and back
+// over a shortest path. All of them are returned, so that routes through a given visor may be picked.
+// NOTE: not thread-safe.
+func (g *Graph) loops(pk cipher.PubKey, maxHops int, excluded map[cipher.PubKey]bool) [][]routing.Hop {
+	var paths [][]routing.Hop
+	for _, out := range g.hops(pk) {
+		if excluded[out.To] {
+			continue
+		}
+		back, ok := g.shortestPath(out.To, pk, maxHops-1, excluded, nil)
+		if !ok {
+			continue
+		}
+		paths = append(paths, append([]routing.Hop{out}, back...))
+	}
+	sort.SliceStable(paths, func(i, j int) bool { return len(paths[i]) < len(paths[j]) })
+	return paths
+}
+
+// filterPaths returns the `paths` of at least `minHops` hops, up to `max` of them if not 0.
+func filterPaths(paths [][]routing.Hop, minHops, max int) [][]routing.Hop {
+	var out [][]routing.Hop
+	for _, path := range paths {
+		if max > 0 && len(out) == max {
+			break
+		}
+		if len(path) >= minHops {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+func samePath(a, b []routing.Hop) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].TpID != b[i].TpID {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPath(paths [][]routing.Hop, path []routing.Hop) bool {
+	for _, p := range paths {
+		if samePath(p, path) {
+			return true
+		}
+	}
+	return false
+}
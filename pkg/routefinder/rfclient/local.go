@@ -0,0 +1,213 @@
+// Package rfclient pkg/routefinder/rfclient/local.go
+package rfclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
+)
+
+const (
+	defaultLocalTTL     = 5 * time.Minute
+	defaultLocalRoutes  = 5
+	defaultLocalMaxHops = 4
+	localFetchTimeout   = 10 * time.Second
+	localFetchWorkers   = 8
+)
+
+// TransportLister lists the transports of a visor. It is implemented by transport.DiscoveryClient.
+type TransportLister interface {
+	GetTransportsByEdge(ctx context.Context, pk cipher.PubKey) ([]*transport.Entry, error)
+}
+
+// LocalConfig configures the local route finder.
+type LocalConfig struct {
+	// PK is the public key of the local visor.
+	PK cipher.PubKey
+	// Discovery fetches the transports of the visors. If nil, the routes are only searched in the graph as it is.
+	Discovery TransportLister
+	// Transports returns the transports of the local visor, used instead of the fetched ones if set.
+	Transports func() []transport.Entry
+	// TTL is the time the fetched transports of a visor are used for before being fetched again.
+	TTL time.Duration
+	// Routes is the maximum number of routes found between two visors.
+	Routes int
+	// MaxHops caps the hops of the routes found, the graph is fetched up to that depth.
+	MaxHops uint16
+}
+
+type localClient struct {
+	conf  LocalConfig
+	graph *Graph
+	log   *logging.Logger
+}
+
+// NewLocal constructs a Client which finds the routes in `graph` itself, with the k shortest paths search.
+// The graph is kept up to date from the transport discovery and the transports of the local visor,
+// so that routes may be found while the route finder service is unreachable.
+func NewLocal(conf LocalConfig, graph *Graph, mlogger *logging.MasterLogger) Client {
+	if conf.TTL == 0 {
+		conf.TTL = defaultLocalTTL
+	}
+	if conf.Routes == 0 {
+		conf.Routes = defaultLocalRoutes
+	}
+	if conf.MaxHops == 0 {
+		conf.MaxHops = defaultLocalMaxHops
+	}
+	if graph == nil {
+		graph = NewGraph()
+	}
+	log := logging.MustGetLogger("routefinder_local")
+	if mlogger != nil {
+		log = mlogger.PackageLogger("routefinder_local")
+	}
+	return &localClient{
+		conf:  conf,
+		graph: graph,
+		log:   log,
+	}
+}
+
+// FindRoutes returns the shortest routes between the visors of each of `rts`, within the hops of `opts`.
+// The visors excluded by the route policy of `opts` are avoided, the rest of it is left to the router.
+// ErrTransportNotFound is returned if no route is found between the visors of any of `rts`.
+func (c *localClient) FindRoutes(ctx context.Context, rts []routing.PathEdges, opts *RouteOptions) (map[routing.PathEdges][][]routing.Hop, error) {
+	minHops, maxHops := uint16(0), c.conf.MaxHops
+	var policy *routing.RoutePolicy
+	if opts != nil {
+		minHops = opts.MinHops
+		if opts.MaxHops != 0 && opts.MaxHops < maxHops {
+			maxHops = opts.MaxHops
+		}
+		policy = opts.Policy
+	}
+
+	if c.conf.Transports != nil {
+		c.graph.SetTransports(c.conf.PK, c.conf.Transports())
+	}
+
+	paths := make(map[routing.PathEdges][][]routing.Hop, len(rts))
+	for _, edges := range rts {
+		// a route of n hops is found once the visors up to half of it away from either edge are fetched
+		c.crawl(ctx, edges[0], int(maxHops+1)/2)
+		c.crawl(ctx, edges[1], int(maxHops)/2)
+
+		excluded := make(map[cipher.PubKey]bool)
+		if policy != nil {
+			for _, pk := range policy.ExcludePKs {
+				if pk != edges[0] && pk != edges[1] {
+					excluded[pk] = true
+				}
+			}
+		}
+
+		found := c.graph.kShortestPaths(edges[0], edges[1], int(minHops), int(maxHops), c.conf.Routes, excluded)
+		if len(found) == 0 {
+			return nil, ErrTransportNotFound
+		}
+		paths[edges] = found
+	}
+	return paths, nil
+}
+
+// crawl fetches the transports of the visors up to `depth` - 1 transports away from `pk`.
+func (c *localClient) crawl(ctx context.Context, pk cipher.PubKey, depth int) {
+	if c.conf.Discovery == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, localFetchTimeout)
+	defer cancel()
+
+	visited := map[cipher.PubKey]bool{pk: true}
+	frontier := []cipher.PubKey{pk}
+	for level := 0; level < depth && len(frontier) > 0 && ctx.Err() == nil; level++ {
+		c.fetch(ctx, frontier)
+
+		var next []cipher.PubKey
+		for _, from := range frontier {
+			for _, to := range c.graph.neighbours(from) {
+				if !visited[to] {
+					visited[to] = true
+					next = append(next, to)
+				}
+			}
+		}
+		frontier = next
+	}
+}
+
+// fetch fetches the transports of the visors of `pks` which were not fetched within the TTL.
+// The cached transports of a visor are kept if fetching them fails, they are fetched again after the TTL.
+func (c *localClient) fetch(ctx context.Context, pks []cipher.PubKey) {
+	sem := make(chan struct{}, localFetchWorkers)
+	var wg sync.WaitGroup
+	for _, pk := range pks {
+		if time.Since(c.graph.fetched(pk)) < c.conf.TTL {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(pk cipher.PubKey) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			entries, err := c.conf.Discovery.GetTransportsByEdge(ctx, pk)
+			if err != nil {
+				c.log.WithError(err).WithField("pk", pk).Debug("Failed to fetch transports, using the cached ones.")
+				c.graph.markFetched(pk, time.Now())
+				return
+			}
+			tps := make([]transport.Entry, 0, len(entries))
+			for _, entry := range entries {
+				if entry != nil {
+					tps = append(tps, *entry)
+				}
+			}
+			c.graph.setTransports(pk, tps, time.Now())
+		}(pk)
+	}
+	wg.Wait()
+}
+
+type fallbackClient struct {
+	primary  Client
+	fallback Client
+	log      *logging.Logger
+}
+
+// NewFallback constructs a Client which finds the routes with `fallback` when `primary` fails.
+// The error of `primary` is returned if `fallback` finds no route either.
+func NewFallback(primary, fallback Client, mlogger *logging.MasterLogger) Client {
+	log := logging.MustGetLogger("routefinder")
+	if mlogger != nil {
+		log = mlogger.PackageLogger("routefinder")
+	}
+	return &fallbackClient{
+		primary:  primary,
+		fallback: fallback,
+		log:      log,
+	}
+}
+
+// FindRoutes implements Client.
+func (c *fallbackClient) FindRoutes(ctx context.Context, rts []routing.PathEdges, opts *RouteOptions) (map[routing.PathEdges][][]routing.Hop, error) {
+	paths, err := c.primary.FindRoutes(ctx, rts, opts)
+	if err == nil || ctx.Err() != nil {
+		return paths, err
+	}
+	c.log.WithError(err).Debug("Failed to find routes, trying the fallback route finder.")
+	paths, fErr := c.fallback.FindRoutes(ctx, rts, opts)
+	if errors.Is(fErr, ErrTransportNotFound) {
+		return nil, err
+	}
+	return paths, fErr
+}
@@ -0,0 +1,164 @@
+// Package rfclient pkg/routefinder/rfclient/local_test.go
+package rfclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
+	"github.com/skycoin/skywire/pkg/transport/network"
+)
+
+// testGraph is src - a - dst, src - b - dst, src - dst and a - b.
+type testGraph struct {
+	src, dst, a, b                     cipher.PubKey
+	direct, srcA, aDst, srcB, bDst, aB transport.Entry
+}
+
+func newTestGraph() testGraph {
+	var g testGraph
+	g.src, _ = cipher.GenerateKeyPair()
+	g.dst, _ = cipher.GenerateKeyPair()
+	g.a, _ = cipher.GenerateKeyPair()
+	g.b, _ = cipher.GenerateKeyPair()
+	g.direct = transport.MakeEntry(g.src, g.dst, network.DMSG, transport.LabelUser)
+	g.srcA = transport.MakeEntry(g.src, g.a, network.DMSG, transport.LabelUser)
+	g.aDst = transport.MakeEntry(g.a, g.dst, network.DMSG, transport.LabelUser)
+	g.srcB = transport.MakeEntry(g.src, g.b, network.DMSG, transport.LabelUser)
+	g.bDst = transport.MakeEntry(g.b, g.dst, network.DMSG, transport.LabelUser)
+	g.aB = transport.MakeEntry(g.a, g.b, network.DMSG, transport.LabelUser)
+	return g
+}
+
+func (g testGraph) entries() []transport.Entry {
+	return []transport.Entry{g.direct, g.srcA, g.aDst, g.srcB, g.bDst, g.aB}
+}
+
+func TestLocalClient_FindRoutes(t *testing.T) {
+	g := newTestGraph()
+	graph := NewGraph()
+	graph.AddTransports(g.entries()...)
+	c := NewLocal(LocalConfig{PK: g.src, Routes: 10}, graph, nil)
+
+	fwd := routing.PathEdges{g.src, g.dst}
+	rev := routing.PathEdges{g.dst, g.src}
+	paths, err := c.FindRoutes(context.Background(), []routing.PathEdges{fwd, rev}, &RouteOptions{MaxHops: 3})
+	require.NoError(t, err)
+
+	// the direct route, both 2 hops routes and both 3 hops routes through a - b
+	require.Len(t, paths[fwd], 5)
+	require.Len(t, paths[rev], 5)
+	require.Equal(t, []routing.Hop{{TpID: g.direct.ID, From: g.src, To: g.dst}}, paths[fwd][0])
+	for _, path := range paths[fwd] {
+		require.Equal(t, g.src, path[0].From)
+		require.Equal(t, g.dst, path[len(path)-1].To)
+		for i := 1; i < len(path); i++ {
+			require.Equal(t, path[i-1].To, path[i].From)
+		}
+	}
+	for i := 1; i < len(paths[fwd]); i++ {
+		require.LessOrEqual(t, len(paths[fwd][i-1]), len(paths[fwd][i]))
+	}
+
+	paths, err = c.FindRoutes(context.Background(), []routing.PathEdges{fwd}, &RouteOptions{MinHops: 2, MaxHops: 2})
+	require.NoError(t, err)
+	require.Len(t, paths[fwd], 2)
+
+	// the excluded visors are avoided
+	paths, err = c.FindRoutes(context.Background(), []routing.PathEdges{fwd},
+		&RouteOptions{MinHops: 2, MaxHops: 3, Policy: &routing.RoutePolicy{ExcludePKs: []cipher.PubKey{g.a}}})
+	require.NoError(t, err)
+	require.Equal(t, [][]routing.Hop{{
+		{TpID: g.srcB.ID, From: g.src, To: g.b},
+		{TpID: g.bDst.ID, From: g.b, To: g.dst},
+	}}, paths[fwd])
+
+	unknown, _ := cipher.GenerateKeyPair()
+	_, err = c.FindRoutes(context.Background(), []routing.PathEdges{{g.src, unknown}}, nil)
+	require.Equal(t, ErrTransportNotFound, err)
+}
+
+func TestLocalClient_FindRoutes_loops(t *testing.T) {
+	g := newTestGraph()
+	graph := NewGraph()
+	graph.AddTransports(g.entries()...)
+	c := NewLocal(LocalConfig{PK: g.src, Routes: 1}, graph, nil)
+
+	// all the loops are returned, for the visors to be picked from
+	loop := routing.PathEdges{g.src, g.src}
+	paths, err := c.FindRoutes(context.Background(), []routing.PathEdges{loop}, &RouteOptions{MaxHops: 2})
+	require.NoError(t, err)
+	require.Len(t, paths[loop], 3)
+	for _, path := range paths[loop] {
+		require.Len(t, path, 2)
+		require.Equal(t, g.src, path[0].From)
+		require.Equal(t, path[0].To, path[1].From)
+		require.Equal(t, g.src, path[1].To)
+	}
+}
+
+type testLister map[cipher.PubKey][]*transport.Entry
+
+func (l testLister) GetTransportsByEdge(_ context.Context, pk cipher.PubKey) ([]*transport.Entry, error) {
+	entries, ok := l[pk]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return entries, nil
+}
+
+func TestLocalClient_crawl(t *testing.T) {
+	g := newTestGraph()
+	lister := testLister{}
+	for _, entry := range []transport.Entry{g.srcA, g.aDst} {
+		entry := entry
+		lister[entry.Edges[0]] = append(lister[entry.Edges[0]], &entry)
+		lister[entry.Edges[1]] = append(lister[entry.Edges[1]], &entry)
+	}
+
+	graph := NewGraph()
+	c := NewLocal(LocalConfig{
+		PK:         g.src,
+		Discovery:  lister,
+		Transports: func() []transport.Entry { return []transport.Entry{g.srcA, g.srcB} },
+	}, graph, nil)
+
+	fwd := routing.PathEdges{g.src, g.dst}
+	paths, err := c.FindRoutes(context.Background(), []routing.PathEdges{fwd}, &RouteOptions{MaxHops: 2})
+	require.NoError(t, err)
+	require.Equal(t, [][]routing.Hop{{
+		{TpID: g.srcA.ID, From: g.src, To: g.a},
+		{TpID: g.aDst.ID, From: g.a, To: g.dst},
+	}}, paths[fwd])
+
+	// the transports of the local visor are not fetched
+	require.False(t, graph.fetched(g.src).IsZero())
+	require.Contains(t, graph.neighbours(g.src), g.b)
+}
+
+func TestFallbackClient_FindRoutes(t *testing.T) {
+	g := newTestGraph()
+	graph := NewGraph()
+	graph.AddTransports(g.direct)
+
+	errUnreachable := errors.New("unreachable")
+	primary := &MockClient{}
+	primary.On("FindRoutes", mock.Anything, mock.Anything, mock.Anything).Return(nil, errUnreachable)
+	c := NewFallback(primary, NewLocal(LocalConfig{PK: g.src}, graph, nil), nil)
+
+	fwd := routing.PathEdges{g.src, g.dst}
+	paths, err := c.FindRoutes(context.Background(), []routing.PathEdges{fwd}, nil)
+	require.NoError(t, err)
+	require.Equal(t, [][]routing.Hop{{{TpID: g.direct.ID, From: g.src, To: g.dst}}}, paths[fwd])
+
+	// the error of the primary route finder is returned if the fallback finds no route either
+	c = NewFallback(primary, NewLocal(LocalConfig{PK: g.src}, NewGraph(), nil), nil)
+	_, err = c.FindRoutes(context.Background(), []routing.PathEdges{fwd}, nil)
+	require.Equal(t, errUnreachable, err)
+}
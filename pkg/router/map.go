@@ -47,6 +47,10 @@ func MakeMap(ctx context.Context, dialer network.Dialer, pks []cipher.PubKey) (M
 		rcM[res.client.rPK] = res.client
 	}
 
+	if err == nil && len(rcM) < len(pks) {
+		// the context expired before all of the routers were dialed
+		err = ctx.Err()
+	}
 	if err != nil {
 		rcM.CloseAll() // TODO: log this
 	}
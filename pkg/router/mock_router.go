@@ -142,6 +142,22 @@ func (_m *MockRouter) ReserveKeys(n int) ([]routing.RouteID, error) {
 	return r0, r1
 }
 
+// RouteGroupsStats provides a mock function with given fields:
+func (_m *MockRouter) RouteGroupsStats() []RouteGroupStats {
+	ret := _m.Called()
+
+	var r0 []RouteGroupStats
+	if rf, ok := ret.Get(0).(func() []RouteGroupStats); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]RouteGroupStats)
+		}
+	}
+
+	return r0
+}
+
 // RoutesCount provides a mock function with given fields:
 func (_m *MockRouter) RoutesCount() int {
 	ret := _m.Called()
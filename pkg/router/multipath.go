@@ -0,0 +1,475 @@
+// Package router pkg/router/multipath.go
+package router
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+const (
+	// pathFailPings is the number of ping intervals without a pong after which a path is considered failed.
+	pathFailPings = 3
+	// multipathSeqSize is the size of the sequence number prefixing data packets of multipath route groups.
+	multipathSeqSize = 8
+	// maxReorderPackets is the number of out-of-order packets buffered while waiting for a missing one.
+	maxReorderPackets = 256
+	// reorderTimeout is the time a missing packet is waited for, it's considered lost on a failed path then.
+	reorderTimeout = time.Second
+	// addPathTimeout limits setting up an additional path of a route group.
+	addPathTimeout = 30 * time.Second
+)
+
+// MultipathPolicy defines how a route group uses its paths.
+type MultipathPolicy byte
+
+const (
+	// MultipathNone keeps a single path, it's used with visors not supporting multipath route groups.
+	MultipathNone MultipathPolicy = iota
+	// MultipathFailover sends packets over a single path, switching to another one once it fails.
+	MultipathFailover
+	// MultipathStripe spreads packets over all the healthy paths.
+	MultipathStripe
+)
+
+func (p MultipathPolicy) String() string {
+	switch p {
+	case MultipathNone:
+		return "none"
+	case MultipathFailover:
+		return "failover"
+	case MultipathStripe:
+		return "stripe"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(p))
+	}
+}
+
+// pathState tracks whether a path of a route group delivers pings.
+type pathState struct {
+	added    time.Time
+	lastPong time.Time
+	failed   bool
+	// hops of the forward and reverse routes, only known to the initiator
+	hops        []routing.Hop
+	reverseHops []routing.Hop
+}
+
+// multipathPolicy returns the negotiated multipath policy.
+func (rg *RouteGroup) multipathPolicy() MultipathPolicy {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return rg.multipath
+}
+
+// negotiateMultipath updates the multipath policy from the handshake `payload` of the remote.
+// The responder adopts the requested policy, the initiator drops its request unless the remote confirms it.
+// NOTE: not thread-safe.
+func (rg *RouteGroup) negotiateMultipath(payload []byte) {
+	remote := MultipathNone
+	if len(payload) > 1 {
+		remote = MultipathPolicy(payload[1])
+	}
+	if remote != MultipathFailover && remote != MultipathStripe {
+		remote = MultipathNone
+	}
+
+	if rg.multipath == MultipathNone || remote != rg.multipath {
+		rg.multipath = remote
+	}
+	if rg.multipath != MultipathNone {
+		rg.logger.Debugf("Using %s multipath policy", rg.multipath)
+	}
+}
+
+// pathHealthy checks whether the path `i` answered pings recently.
+// NOTE: not thread-safe.
+func (rg *RouteGroup) pathHealthy(i int) bool {
+	state := rg.paths[i]
+	if state.failed || rg.tps[i] == nil || rg.tps[i].IsClosed() {
+		return false
+	}
+
+	last := state.lastPong
+	if last.Before(state.added) {
+		last = state.added
+	}
+
+	return time.Since(last) < pathFailPings*rg.cfg.PingInterval
+}
+
+// nextPath picks the path of the next packet according to the multipath policy, skipping
+// the paths which already failed the current write.
+// NOTE: not thread-safe.
+func (rg *RouteGroup) nextPath(skip map[int]bool) (int, error) {
+	n := len(rg.fwd)
+	if n == 0 || len(rg.tps) != n {
+		return 0, ErrNoRules
+	}
+
+	start := rg.active
+	if rg.multipath == MultipathStripe {
+		start = rg.next % n
+		rg.next = (start + 1) % n
+	}
+
+	for k := 0; k < n; k++ {
+		i := (start + k) % n
+		if skip[i] || !rg.pathHealthy(i) {
+			continue
+		}
+		if rg.multipath == MultipathFailover && i != rg.active {
+			rg.logger.Infof("Switching from path %d to path %d", rg.active, i)
+			rg.active = i
+		}
+		return i, nil
+	}
+
+	// no healthy path left, keep trying the ones which didn't fail this write
+	for k := 0; k < n; k++ {
+		if i := (start + k) % n; !skip[i] && rg.tps[i] != nil {
+			return i, nil
+		}
+	}
+
+	return 0, ErrNoSuitableTransport
+}
+
+// setPathFailed marks the path `i` failed until it answers a ping again.
+// NOTE: not thread-safe.
+func (rg *RouteGroup) setPathFailed(i int, err error) {
+	if i >= len(rg.paths) || rg.paths[i].failed {
+		return
+	}
+
+	rg.logger.WithError(err).Warnf("Path %d of %d failed", i, len(rg.paths))
+	rg.paths[i].failed = true
+	rg.scores.observeFailure(rg.paths[i].hops)
+}
+
+func (rg *RouteGroup) markPathFailed(i int, err error) {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	rg.setPathFailed(i, err)
+}
+
+// writeMultipath writes the payload prefixed with its sequence number over the path picked
+// by the multipath policy, retrying over the other paths if it fails.
+func (rg *RouteGroup) writeMultipath(p []byte) (int, error) {
+	data := make([]byte, multipathSeqSize+len(p))
+	binary.BigEndian.PutUint64(data, atomic.AddUint64(&rg.seq, 1))
+	copy(data[multipathSeqSize:], p)
+
+	skip := make(map[int]bool)
+	for {
+		rg.mu.Lock()
+		i, err := rg.nextPath(skip)
+		if err != nil {
+			rg.mu.Unlock()
+			return 0, err
+		}
+		tp, rule := rg.tps[i], rg.fwd[i]
+		rg.mu.Unlock()
+
+		_, err = rg.write(data, tp, rule)
+		if err == nil {
+			return len(p), nil
+		}
+		if _, ok := err.(timeoutError); ok {
+			return 0, err
+		}
+
+		rg.markPathFailed(i, err)
+		skip[i] = true
+	}
+}
+
+// pathOfReverseRule returns the index of the path the packets of route `id` arrive on, -1 if not found.
+// NOTE: not thread-safe.
+func (rg *RouteGroup) pathOfReverseRule(id routing.RouteID) int {
+	for i, rule := range rg.rvs {
+		if rule != nil && rule.KeyRouteID() == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// removePath removes the path the reverse rule `id` belongs to, along with its forward rule.
+// Returns false if the rule doesn't belong to the route group or it's the last path, which
+// is removed by closing the route group.
+func (rg *RouteGroup) removePath(id routing.RouteID) bool {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	i := rg.pathOfReverseRule(id)
+	if i < 0 || len(rg.fwd) < 2 {
+		return false
+	}
+
+	rg.rt.DelRules([]routing.RouteID{rg.fwd[i].KeyRouteID()})
+
+	rg.fwd = append(rg.fwd[:i], rg.fwd[i+1:]...)
+	rg.rvs = append(rg.rvs[:i], rg.rvs[i+1:]...)
+	rg.tps = append(rg.tps[:i], rg.tps[i+1:]...)
+	rg.paths = append(rg.paths[:i], rg.paths[i+1:]...)
+	if rg.active > i || rg.active == len(rg.fwd) {
+		rg.active--
+	}
+	rg.next = 0
+
+	rg.logger.Infof("Removed path %d, %d left", i, len(rg.fwd))
+
+	return true
+}
+
+// missingPaths returns the number of paths to set up to get back to the number of paths dialed.
+func (rg *RouteGroup) missingPaths() int {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	return rg.wantPaths - len(rg.fwd)
+}
+
+// forwardHops returns the forward routes of the paths.
+func (rg *RouteGroup) forwardHops() [][]routing.Hop {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	hops := make([][]routing.Hop, 0, len(rg.paths))
+	for _, state := range rg.paths {
+		if state.hops != nil {
+			hops = append(hops, state.hops)
+		}
+	}
+
+	return hops
+}
+
+// reverseHops returns the reverse routes of the paths.
+func (rg *RouteGroup) reverseHops() [][]routing.Hop {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	hops := make([][]routing.Hop, 0, len(rg.paths))
+	for _, state := range rg.paths {
+		if state.reverseHops != nil {
+			hops = append(hops, state.reverseHops)
+		}
+	}
+
+	return hops
+}
+
+// reorderBuffer restores the order of data packets of a multipath route group.
+type reorderBuffer struct {
+	mu       sync.Mutex
+	next     uint64
+	pending  map[uint64][]byte
+	gapSince time.Time
+}
+
+func newReorderBuffer() *reorderBuffer {
+	return &reorderBuffer{
+		next:    1,
+		pending: make(map[uint64][]byte),
+	}
+}
+
+// push adds the payload of packet `seq` and delivers the payloads which are ready in order.
+func (b *reorderBuffer) push(seq uint64, payload []byte, deliver func([]byte) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if seq < b.next {
+		// duplicate, or the packet was given up on already
+		return nil
+	}
+	b.pending[seq] = payload
+
+	return b.drain(deliver)
+}
+
+// flush gives up on the packets missing for longer than reorderTimeout.
+func (b *reorderBuffer) flush(deliver func([]byte) error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.drain(deliver)
+}
+
+// NOTE: not thread-safe.
+func (b *reorderBuffer) drain(deliver func([]byte) error) error {
+	for {
+		progressed := false
+		for payload, ok := b.pending[b.next]; ok; payload, ok = b.pending[b.next] {
+			delete(b.pending, b.next)
+			b.next++
+			progressed = true
+			if err := deliver(payload); err != nil {
+				return err
+			}
+		}
+		if len(b.pending) == 0 {
+			b.gapSince = time.Time{}
+			return nil
+		}
+		if progressed || b.gapSince.IsZero() {
+			b.gapSince = time.Now()
+		}
+		if len(b.pending) < maxReorderPackets && time.Since(b.gapSince) < reorderTimeout {
+			return nil
+		}
+
+		// the missing packets were lost on a failed path, skip to the oldest one received
+		first := uint64(0)
+		for seq := range b.pending {
+			if first == 0 || seq < first {
+				first = seq
+			}
+		}
+		b.next = first
+		b.gapSince = time.Time{}
+	}
+}
+
+// disjointPaths picks up to `n` of `candidates` sharing neither transports nor intermediate visors
+// with each other and with the `taken` paths.
+func disjointPaths(candidates [][]routing.Hop, n int, taken [][]routing.Hop) [][]routing.Hop {
+	usedTps := make(map[[16]byte]struct{})
+	usedVisors := make(map[[33]byte]struct{})
+	use := func(path []routing.Hop) {
+		for i, hop := range path {
+			usedTps[hop.TpID] = struct{}{}
+			if i < len(path)-1 {
+				usedVisors[hop.To] = struct{}{}
+			}
+		}
+	}
+	for _, path := range taken {
+		use(path)
+	}
+
+	picked := make([][]routing.Hop, 0, n)
+	for _, path := range candidates {
+		if len(picked) == n {
+			break
+		}
+		if len(path) == 0 {
+			continue
+		}
+
+		disjoint := true
+		for i, hop := range path {
+			if _, ok := usedTps[hop.TpID]; ok {
+				disjoint = false
+				break
+			}
+			if _, ok := usedVisors[hop.To]; ok && i < len(path)-1 {
+				disjoint = false
+				break
+			}
+		}
+		if disjoint {
+			picked = append(picked, path)
+			use(path)
+		}
+	}
+
+	return picked
+}
+
+// multipathRouteGroup returns the live multipath route group of `desc`, which further paths are added to.
+func (r *router) multipathRouteGroup(desc routing.RouteDescriptor) (*RouteGroup, bool) {
+	nrg, ok := r.noiseRouteGroup(desc)
+	if !ok || nrg == nil || !nrg.IsAlive() || nrg.rg.multipathPolicy() == MultipathNone {
+		return nil, false
+	}
+
+	return nrg.rg, true
+}
+
+// addRouteGroupPath adds the path of edge `rules` to the route group `rg`, along with the hops of its routes if known.
+func (r *router) addRouteGroupPath(rg *RouteGroup, rules routing.EdgeRules, fwdHops, rvsHops []routing.Hop) error {
+	tp := r.tm.Transport(rules.Forward.NextTransportID())
+	if tp == nil {
+		return ErrBadTransport
+	}
+	if err := r.SaveRoutingRules(rules.Forward, rules.Reverse); err != nil {
+		return fmt.Errorf("SaveRoutingRules: %w", err)
+	}
+
+	rg.appendPath(rules.Forward, rules.Reverse, tp, fwdHops, rvsHops)
+	r.logger.Debugf("Added path over transport %s to route group %s", tp.Entry.ID, &rules.Desc)
+
+	return nil
+}
+
+// addPaths sets up the additional `forward`/`reverse` routes as paths of the route group `rg`.
+func (r *router) addPaths(rg *RouteGroup, forward, reverse [][]routing.Hop) {
+	for i := 0; i < len(forward) && i < len(reverse); i++ {
+		if !rg.IsAlive() {
+			return
+		}
+
+		req := routing.BidirectionalRoute{
+			Desc:      rg.desc,
+			KeepAlive: DefaultRouteKeepAlive,
+			Forward:   forward[i],
+			Reverse:   reverse[i],
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), addPathTimeout)
+		rules, err := r.dialRoute(ctx, req)
+		cancel()
+		if err != nil {
+			r.logger.WithError(err).Warnf("Failed to set up additional path of route group %s", &rg.desc)
+			continue
+		}
+
+		if err := r.addRouteGroupPath(rg, rules, forward[i], reverse[i]); err != nil {
+			r.logger.WithError(err).Warnf("Failed to add path to route group %s", &rg.desc)
+		}
+	}
+}
+
+// replenishPaths replaces the failed paths of the route group `rg` dialed by the local visor.
+func (r *router) replenishPaths(rg *RouteGroup) {
+	missing := rg.missingPaths()
+	if missing <= 0 || !rg.IsAlive() {
+		return
+	}
+
+	rg.mu.Lock()
+	policy := rg.policy
+	rg.mu.Unlock()
+
+	candFwd, candRvs, err := r.fetchRoutes(rg.desc.SrcPK(), rg.desc.DstPK(), policy)
+	if err == nil {
+		candFwd, err = r.allowedRoutes(candFwd, policy)
+	}
+	if err == nil {
+		candRvs, err = r.allowedRoutes(candRvs, policy)
+	}
+	if err != nil {
+		r.logger.WithError(err).Warnf("Failed to find replacement paths for route group %s", &rg.desc)
+		return
+	}
+
+	forward := disjointPaths(r.scores.rank(candFwd), missing, rg.forwardHops())
+	reverse := disjointPaths(r.scores.rank(candRvs), len(forward), rg.reverseHops())
+	if len(forward) == 0 {
+		r.logger.Debugf("No disjoint replacement path for route group %s", &rg.desc)
+		return
+	}
+
+	r.addPaths(rg, forward, reverse)
+}
@@ -0,0 +1,293 @@
+// Package router pkg/router/peer_setup.go
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/router/setupmetrics"
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport"
+	"github.com/skycoin/skywire/pkg/transport/network"
+)
+
+const (
+	// peerSetupMaxHops is the most hops of the routes the visor sets up itself, without the setup nodes.
+	peerSetupMaxHops = 2
+	// maxPeerRouteIDs limits the route IDs reserved at once by a visor setting up a route itself.
+	maxPeerRouteIDs = 8
+	// maxPeerReservedIDs limits the route IDs reserved by a visor on all of its route setup conns.
+	maxPeerReservedIDs = 4 * maxPeerRouteIDs
+	// peerUnusedIDsExpiry is how long the route IDs left unused on a closed route setup conn still count
+	// against the limit of the visor, so that it can't keep reserving route IDs by reconnecting.
+	peerUnusedIDsExpiry = time.Minute
+)
+
+// dialRoute sets up the route of `req`. The direct and two hops routes are set up by the visor itself
+// over its transports to the destination or to a shared neighbour, so that they do not depend on the
+// setup nodes. The setup nodes are used for the longer routes, or if that fails.
+func (r *router) dialRoute(ctx context.Context, req routing.BidirectionalRoute) (routing.EdgeRules, error) {
+	if r.dmsgC != nil && peerSetupable(req) {
+		rules, err := CreateRouteGroup(ctx, &peerSetupDialer{r: r, Dialer: WrapDmsgClient(r.dmsgC)}, req, setupmetrics.NewEmpty())
+		if err == nil {
+			return rules, nil
+		}
+		if ctx.Err() != nil {
+			return routing.EdgeRules{}, err
+		}
+		r.logger.WithError(err).Debugf("Failed to set up route to %s without the setup nodes, using them", req.Desc.DstPK())
+	}
+
+	return r.conf.RouteGroupDialer.Dial(ctx, r.logger, r.dmsgC, r.conf.SetupNodes, req)
+}
+
+// peerSetupable returns true if the visor may set up the route of `req` itself.
+func peerSetupable(req routing.BidirectionalRoute) bool {
+	return len(req.Forward) > 0 && len(req.Forward) <= peerSetupMaxHops &&
+		len(req.Reverse) > 0 && len(req.Reverse) <= peerSetupMaxHops
+}
+
+// peerSetupDialer dials the routers of a route set up by the visor itself. The local router is served in memory.
+type peerSetupDialer struct {
+	network.Dialer
+	r *router
+}
+
+// Dial implements network.Dialer.
+func (d *peerSetupDialer) Dial(ctx context.Context, remote cipher.PubKey, port uint16) (net.Conn, error) {
+	if remote != d.r.conf.PubKey {
+		return d.Dialer.Dial(ctx, remote, port)
+	}
+	conn, srvConn := net.Pipe()
+	go d.r.rpcSrv.ServeConn(srvConn)
+	return conn, nil
+}
+
+// servePeerSetup serves the route setup requests of the visor of `pk` setting up a route itself.
+func (r *router) servePeerSetup(conn net.Conn, pk cipher.PubKey) {
+	peer := &peerSession{
+		pk:           pk,
+		localPK:      r.conf.PubKey,
+		reservations: r.peerIDs,
+		transport:    r.transportRemote,
+		neighbour:    func() bool { return r.hasTransportTo(pk) },
+		ids:          make(map[routing.RouteID]struct{}),
+	}
+	defer peer.close()
+
+	rpcS := rpc.NewServer()
+	if err := rpcS.RegisterName(RPCName, newPeerRPCGateway(r, peer, r.mLogger)); err != nil {
+		r.logger.WithError(err).Error("Failed to register peer RPC gateway")
+		if err := conn.Close(); err != nil {
+			r.logger.WithError(err).Debug("Failed to close peer setup conn")
+		}
+		return
+	}
+	rpcS.ServeConn(conn)
+}
+
+// hasTransportTo returns true if the visor has a transport to the visor of `pk`.
+func (r *router) hasTransportTo(pk cipher.PubKey) bool {
+	if r.tm == nil {
+		return false
+	}
+	var found bool
+	r.tm.WalkTransports(func(tp *transport.ManagedTransport) bool {
+		found = tp.Remote() == pk && !tp.IsClosed()
+		return !found
+	})
+	return found
+}
+
+// transportRemote returns the remote visor of the transport of `id`, false if there is no such transport.
+func (r *router) transportRemote(id uuid.UUID) (cipher.PubKey, bool) {
+	if r.tm == nil {
+		return cipher.PubKey{}, false
+	}
+	tp := r.tm.Transport(id)
+	if tp == nil || tp.IsClosed() {
+		return cipher.PubKey{}, false
+	}
+	return tp.Remote(), true
+}
+
+// peerReservations counts the route IDs reserved by the visors setting up routes themselves, on their
+// open route setup conns and the unused ones of their closed conns until they expire.
+type peerReservations struct {
+	mx  sync.Mutex
+	ids map[cipher.PubKey]int
+}
+
+func newPeerReservations() *peerReservations {
+	return &peerReservations{ids: make(map[cipher.PubKey]int)}
+}
+
+// acquire counts `n` route IDs reserved by the visor of `pk`, false if it would exceed maxPeerReservedIDs.
+func (p *peerReservations) acquire(pk cipher.PubKey, n int) bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.ids[pk]+n > maxPeerReservedIDs {
+		return false
+	}
+	p.ids[pk] += n
+	return true
+}
+
+// release forgets `n` route IDs reserved by the visor of `pk`.
+func (p *peerReservations) release(pk cipher.PubKey, n int) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if p.ids[pk] -= n; p.ids[pk] <= 0 {
+		delete(p.ids, pk)
+	}
+}
+
+// full returns true if the visor of `pk` may not reserve route IDs anymore.
+func (p *peerReservations) full(pk cipher.PubKey) bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	return p.ids[pk] >= maxPeerReservedIDs
+}
+
+// peerSession is the route setup conn of a visor setting up a route itself. The rules it adds must be
+// keyed by the route IDs reserved on the conn, each of them is used once. The edge rules must be the ones
+// of a route group between the visor and the router, the intermediary rules must forward to the visor,
+// or from it if the router has a transport to it.
+type peerSession struct {
+	pk           cipher.PubKey
+	localPK      cipher.PubKey
+	reservations *peerReservations
+	// transport returns the remote visor of the transport of `id`, false if there is no such transport.
+	transport func(id uuid.UUID) (cipher.PubKey, bool)
+	// neighbour returns true if the router has a transport to the visor.
+	neighbour func() bool
+
+	mx       sync.Mutex
+	acquired int
+	ids      map[routing.RouteID]struct{} // reserved and not used yet
+}
+
+// acquire checks the limits of the route IDs before reserving `n` of them.
+func (s *peerSession) acquire(n int) error {
+	if n > maxPeerRouteIDs {
+		return fmt.Errorf("%d route IDs requested, at most %d are allowed", n, maxPeerRouteIDs)
+	}
+	if !s.reservations.acquire(s.pk, n) {
+		return fmt.Errorf("more than %d route IDs reserved", maxPeerReservedIDs)
+	}
+
+	s.mx.Lock()
+	s.acquired += n
+	s.mx.Unlock()
+	return nil
+}
+
+// reserved records the route IDs reserved on the conn.
+func (s *peerSession) reserved(ids []routing.RouteID) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, id := range ids {
+		s.ids[id] = struct{}{}
+	}
+}
+
+// use consumes the route IDs keying `rules`, which must all be reserved on the conn.
+func (s *peerSession) use(rules ...routing.Rule) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	keys := make(map[routing.RouteID]struct{}, len(rules))
+	for _, rule := range rules {
+		key := rule.KeyRouteID()
+		if _, ok := s.ids[key]; !ok {
+			return fmt.Errorf("route ID %d is not reserved", key)
+		}
+		if _, ok := keys[key]; ok {
+			return fmt.Errorf("route ID %d is used twice", key)
+		}
+		keys[key] = struct{}{}
+	}
+	for key := range keys {
+		delete(s.ids, key)
+	}
+	return nil
+}
+
+// checkEdgeRules checks the edge rules of a route group the visor is an edge of.
+func (s *peerSession) checkEdgeRules(rules routing.EdgeRules) error {
+	src, dst := rules.Desc.SrcPK(), rules.Desc.DstPK()
+	if (src != s.pk || dst != s.localPK) && (src != s.localPK || dst != s.pk) {
+		return fmt.Errorf("route group %s is not one between %s and %s", &rules.Desc, s.pk, s.localPK)
+	}
+	if !validRule(rules.Forward, routing.RuleForward) || !validRule(rules.Reverse, routing.RuleReverse) {
+		return errors.New("invalid edge rules")
+	}
+	return s.use(rules.Forward, rules.Reverse)
+}
+
+// checkIntermediaryRules checks intermediary rules forwarding to or from the visor.
+func (s *peerSession) checkIntermediaryRules(rules []routing.Rule) error {
+	for _, rule := range rules {
+		if !validRule(rule, routing.RuleIntermediary) {
+			return errors.New("invalid intermediary rule")
+		}
+		remote, ok := s.transport(rule.NextTransportID())
+		if !ok {
+			return fmt.Errorf("no transport %s", rule.NextTransportID())
+		}
+		if remote != s.pk && !s.neighbour() {
+			return fmt.Errorf("rule %d neither forwards to nor from %s", rule.KeyRouteID(), s.pk)
+		}
+	}
+	return s.use(rules...)
+}
+
+// close releases the route IDs used on the conn from the limit of the visor, the unused ones are
+// released once they expire.
+func (s *peerSession) close() {
+	s.mx.Lock()
+	acquired, unused := s.acquired, min(len(s.ids), s.acquired)
+	s.acquired = 0
+	s.ids = make(map[routing.RouteID]struct{})
+	s.mx.Unlock()
+
+	s.reservations.release(s.pk, acquired-unused)
+	if unused > 0 {
+		time.AfterFunc(peerUnusedIDsExpiry, func() { s.reservations.release(s.pk, unused) })
+	}
+}
+
+// validRule returns true if `rule` is a complete rule of type `typ`.
+func validRule(rule routing.Rule, typ routing.RuleType) bool {
+	const (
+		pkSize   = len(cipher.PubKey{})
+		uuidSize = len(uuid.UUID{})
+		descSize = 2*pkSize + 2*2
+	)
+
+	if len(rule) < routing.RuleHeaderSize || rule.Type() != typ {
+		return false
+	}
+	switch typ {
+	case routing.RuleReverse:
+		return len(rule) >= routing.RuleHeaderSize+descSize
+	case routing.RuleForward:
+		return len(rule) >= routing.RuleHeaderSize+4+descSize+uuidSize
+	case routing.RuleIntermediary:
+		return len(rule) >= routing.RuleHeaderSize+4+uuidSize
+	default:
+		return false
+	}
+}
@@ -0,0 +1,118 @@
+// Package router pkg/router/peer_setup_test.go
+package router
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/skycoin/dmsg/pkg/dmsg"
+	"github.com/skycoin/dmsg/pkg/dmsgtest"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+// setupNodesDialer fails to set up the routes with the setup nodes.
+type setupNodesDialer struct {
+	err   error
+	calls atomic.Int32
+}
+
+func (d *setupNodesDialer) Dial(context.Context, *logging.Logger, *dmsg.Client, []cipher.PubKey, routing.BidirectionalRoute) (routing.EdgeRules, error) {
+	d.calls.Add(1)
+	return routing.EdgeRules{}, d.err
+}
+
+func TestRouter_dialRoute_peerSetup(t *testing.T) {
+	env := dmsgtest.NewEnv(t, 10*time.Second)
+	require.NoError(t, env.Startup(0, 1, 3, &dmsg.Config{MinSessions: 1}))
+	t.Cleanup(env.Shutdown)
+	// the streams of the clients are forwarded once the server has all of their sessions
+	srv := env.AllServers()[0]
+	require.Eventually(t, func() bool { return len(srv.GetSessions()) == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	errSetupNodes := errors.New("no setup node")
+	newRouter := func(dmsgC *dmsg.Client) (*router, *setupNodesDialer) {
+		rgDialer := &setupNodesDialer{err: errSetupNodes}
+
+		r, err := New(dmsgC, &Config{
+			MasterLogger:     mlog,
+			PubKey:           dmsgC.LocalPK(),
+			RouteGroupDialer: rgDialer,
+		}, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, r.Close()) })
+		go r.(*router).serveSetup()
+		return r.(*router), rgDialer
+	}
+
+	clients := env.AllClients()
+	src, srcDialer := newRouter(clients[0])
+	dst, _ := newRouter(clients[1])
+	mid, _ := newRouter(clients[2])
+	srcPK, dstPK, midPK := src.conf.PubKey, dst.conf.PubKey, mid.conf.PubKey
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("direct route", func(t *testing.T) {
+		tpID := uuid.New()
+		req := routing.BidirectionalRoute{
+			Desc:      routing.NewRouteDescriptor(srcPK, dstPK, 1, 2),
+			KeepAlive: DefaultRouteKeepAlive,
+			Forward:   []routing.Hop{{TpID: tpID, From: srcPK, To: dstPK}},
+			Reverse:   []routing.Hop{{TpID: tpID, From: dstPK, To: srcPK}},
+		}
+		require.True(t, peerSetupable(req))
+
+		rules, err := src.dialRoute(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, tpID, rules.Forward.NextTransportID())
+		require.Zero(t, srcDialer.calls.Load())
+
+		var dstRules routing.EdgeRules
+		select {
+		case dstRules = <-dst.accept:
+		case <-ctx.Done():
+			t.Fatal("the destination did not receive its rules")
+		}
+		require.Equal(t, req.Desc, dstRules.Desc)
+		require.Equal(t, tpID, dstRules.Forward.NextTransportID())
+		require.Equal(t, rules.Forward.NextRouteID(), dstRules.Reverse.KeyRouteID())
+		require.Equal(t, dstRules.Forward.NextRouteID(), rules.Reverse.KeyRouteID())
+
+		// the reservations are released once the route is set up
+		require.Eventually(t, func() bool {
+			dst.peerIDs.mx.Lock()
+			defer dst.peerIDs.mx.Unlock()
+			return len(dst.peerIDs.ids) == 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("intermediary without transports", func(t *testing.T) {
+		req := routing.BidirectionalRoute{
+			Desc:      routing.NewRouteDescriptor(srcPK, dstPK, 3, 4),
+			KeepAlive: DefaultRouteKeepAlive,
+			Forward:   []routing.Hop{{TpID: uuid.New(), From: srcPK, To: midPK}, {TpID: uuid.New(), From: midPK, To: dstPK}},
+			Reverse:   []routing.Hop{{TpID: uuid.New(), From: dstPK, To: midPK}, {TpID: uuid.New(), From: midPK, To: srcPK}},
+		}
+		require.True(t, peerSetupable(req))
+
+		// the intermediary refuses the rules, the setup nodes are used
+		_, err := src.dialRoute(ctx, req)
+		require.ErrorIs(t, err, errSetupNodes)
+		require.Equal(t, int32(1), srcDialer.calls.Load())
+		require.Empty(t, mid.rt.AllRules())
+		select {
+		case <-dst.accept:
+			t.Fatal("the destination received rules of a route which was not set up")
+		default:
+		}
+	})
+}
@@ -71,6 +71,8 @@ func DefaultRouteGroupConfig() *RouteGroupConfig {
 type RouteGroup struct {
 	// atomic requires 64-bit alignment for struct field access
 	lastSent int64
+	// sequence number of the last data packet sent over a multipath route group
+	seq uint64
 
 	mu sync.Mutex
 
@@ -95,6 +97,22 @@ type RouteGroup struct {
 	fwd []routing.Rule // forward rules (for writing)
 	rvs []routing.Rule // reverse rules (for reading)
 
+	// The following fields are used by multipath route groups:
+	// - paths has the same number of elements as 'fwd', tracking the health of each path.
+	// - active is the path used by the failover policy, next is the path of the next stripe.
+	// - wantPaths is the number of paths dialed, failed paths are replaced up to it.
+	multipath MultipathPolicy
+	paths     []pathState
+	active    int
+	next      int
+	wantPaths int
+	reorder   *reorderBuffer
+
+	// scores records the measurements of the paths with known hops, may be nil.
+	scores *routeScores
+	// policy constrains the replacement paths of a multipath route group, may be nil.
+	policy *routing.RoutePolicy
+
 	// 'readCh' reads in incoming packets of this route group.
 	// - Router should serve call '(*transport.Manager).ReadPacket' in a loop,
 	//      and push to the appropriate '(RouteGroup).readCh'.
@@ -113,7 +131,9 @@ type RouteGroup struct {
 	closed           chan struct{}
 	// used to wait for all the `Close` packets to run through the loop and come back
 	closeDone sync.WaitGroup
-	once      sync.Once
+	// number of `Close` packets still awaited, guards `closeDone` against extra responses
+	closePending int32
+	once         sync.Once
 
 	errorMu    sync.RWMutex
 	closeError error
@@ -145,6 +165,7 @@ func NewRouteGroup(cfg *RouteGroupConfig, rt routing.Table, desc routing.RouteDe
 		writeDeadline:      deadline.MakePipeDeadline(),
 		handshakeProcessed: make(chan struct{}),
 		networkStats:       newNetworkStats(),
+		reorder:            newReorderBuffer(),
 	}
 
 	return rg
@@ -170,7 +191,7 @@ func (rg *RouteGroup) Read(p []byte) (n int, err error) {
 }
 
 // Write writes payload to a RouteGroup
-// For the first version, only the first ForwardRule (fwd[0]) is used for writing.
+// Unless multipath is negotiated, only the first ForwardRule (fwd[0]) is used for writing.
 func (rg *RouteGroup) Write(p []byte) (n int, err error) {
 	if rg.isClosed() {
 		return 0, io.ErrClosedPipe
@@ -185,6 +206,11 @@ func (rg *RouteGroup) Write(p []byte) (n int, err error) {
 	}
 
 	rg.mu.Lock()
+	if rg.multipath != MultipathNone {
+		rg.mu.Unlock()
+		return rg.writeMultipath(p)
+	}
+
 	tp, err := rg.tp()
 	if err != nil {
 		rg.mu.Unlock()
@@ -439,24 +465,39 @@ func (rg *RouteGroup) sendPing() error {
 		return nil
 	}
 
-	tp := rg.tps[0]
-	rule := rg.fwd[0]
-	rg.mu.Unlock()
-
-	if tp == nil {
-		return nil
+	// multipath route groups probe every path to detect the failed ones
+	n := 1
+	if rg.multipath != MultipathNone {
+		n = len(rg.tps)
 	}
+	tps := append([]*transport.ManagedTransport(nil), rg.tps[:n]...)
+	rules := append([]routing.Rule(nil), rg.fwd[:n]...)
+	rg.mu.Unlock()
 
 	throughput := rg.networkStats.RemoteThroughput()
 	timestamp := time.Now().UTC().UnixNano() / int64(time.Millisecond)
 	rg.networkStats.SetDownloadSpeed(uint32(throughput))
 
-	packet := routing.MakePingPacket(rule.NextRouteID(), timestamp, throughput)
+	var err error
+	for i, tp := range tps {
+		if tp == nil {
+			continue
+		}
 
-	return rg.writePacket(context.Background(), tp, packet, rule.KeyRouteID())
+		packet := routing.MakePingPacket(rules[i].NextRouteID(), timestamp, throughput)
+		if wErr := rg.writePacket(context.Background(), tp, packet, rules[i].KeyRouteID()); wErr != nil {
+			if n > 1 {
+				rg.markPathFailed(i, wErr)
+			}
+			err = wErr
+		}
+	}
+
+	return err
 }
 
-func (rg *RouteGroup) sendPong(timestamp int64) error {
+// sendPong answers the ping over the path `i` it came from.
+func (rg *RouteGroup) sendPong(i int, timestamp int64) error {
 	rg.mu.Lock()
 
 	if len(rg.tps) == 0 || len(rg.fwd) == 0 {
@@ -465,8 +506,11 @@ func (rg *RouteGroup) sendPong(timestamp int64) error {
 		return nil
 	}
 
-	tp := rg.tps[0]
-	rule := rg.fwd[0]
+	if i < 0 || i >= len(rg.tps) || i >= len(rg.fwd) {
+		i = 0
+	}
+	tp := rg.tps[i]
+	rule := rg.fwd[i]
 	rg.mu.Unlock()
 
 	if tp == nil {
@@ -482,6 +526,12 @@ func (rg *RouteGroup) pingServiceFn(_ time.Duration) {
 	if err := rg.sendPing(); err != nil {
 		rg.logger.Warnf("Failed to send network probe: %v", err)
 	}
+
+	if rg.multipathPolicy() != MultipathNone {
+		if err := rg.reorder.flush(rg.deliver); err != nil {
+			rg.logger.Warnf("Failed to deliver reordered packets: %v", err)
+		}
+	}
 }
 
 func (rg *RouteGroup) servicePacketLoop(name string, interval time.Duration, f sendServicePacketFn) {
@@ -531,7 +581,10 @@ func (rg *RouteGroup) sendKeepAlive() error {
 		packet := routing.MakeKeepAlivePacket(rule.NextRouteID())
 
 		if err := rg.writePacket(context.Background(), tp, packet, rule.KeyRouteID()); err != nil {
-			return err
+			if rg.multipath == MultipathNone {
+				return err
+			}
+			rg.setPathFailed(i, err)
 		}
 	}
 
@@ -555,7 +608,7 @@ func (rg *RouteGroup) sendHandshake(encrypt bool) error {
 		}
 
 		rule := rg.fwd[i]
-		packet := routing.MakeHandshakePacket(rule.NextRouteID(), encrypt)
+		packet := routing.MakeMultipathHandshakePacket(rule.NextRouteID(), encrypt, byte(rg.multipath))
 
 		err := rg.writePacket(context.Background(), tp, packet, rule.KeyRouteID())
 		if err == nil {
@@ -604,8 +657,14 @@ func (rg *RouteGroup) close(code routing.CloseCode) error {
 	closeInitiator := rg.isCloseInitiator()
 
 	if closeInitiator {
-		// will wait for close response from all the transports
-		rg.closeDone.Add(len(rg.tps))
+		// will wait for close response from all the transports. remote closes
+		// a multipath route group on the first packet, so only one comes back
+		pending := len(rg.tps)
+		if rg.multipath != MultipathNone {
+			pending = 1
+		}
+		atomic.StoreInt32(&rg.closePending, int32(pending))
+		rg.closeDone.Add(pending)
 	}
 
 	rg.broadcastClosePackets(code)
@@ -645,6 +704,9 @@ func (rg *RouteGroup) handlePacket(packet routing.Packet) error {
 		rg.handshakeProcessedOnce.Do(func() {
 			// first packet is data packet, so we're communicating with the old visor
 			rg.encrypt = false
+			rg.mu.Lock()
+			rg.multipath = MultipathNone
+			rg.mu.Unlock()
 			close(rg.handshakeProcessed)
 		})
 		return rg.handleDataPacket(packet)
@@ -656,6 +718,10 @@ func (rg *RouteGroup) handlePacket(packet routing.Packet) error {
 				rg.encrypt = false
 			}
 
+			rg.mu.Lock()
+			rg.negotiateMultipath(packet.Payload())
+			rg.mu.Unlock()
+
 			close(rg.handshakeProcessed)
 		})
 	case routing.PingPacket:
@@ -679,10 +745,25 @@ func (rg *RouteGroup) handleDataPacket(packet routing.Packet) error {
 	}
 	rg.networkStats.AddBandwidthReceived(uint64(packet.Size()))
 
+	if rg.multipathPolicy() != MultipathNone {
+		payload := packet.Payload()
+		if len(payload) < multipathSeqSize {
+			return fmt.Errorf("multipath data packet too short: %d bytes", len(payload))
+		}
+		seq := binary.BigEndian.Uint64(payload)
+
+		return rg.reorder.push(seq, payload[multipathSeqSize:], rg.deliver)
+	}
+
+	return rg.deliver(packet.Payload())
+}
+
+// deliver pushes the payload to the reader.
+func (rg *RouteGroup) deliver(payload []byte) error {
 	select {
 	case <-rg.closed:
 		return io.ErrClosedPipe
-	case rg.readCh <- packet.Payload():
+	case rg.readCh <- payload:
 	}
 
 	return nil
@@ -708,7 +789,9 @@ func (rg *RouteGroup) handleClosePacket(code routing.CloseCode) error {
 		// this route group initiated close loop and got response
 		rg.logger.Debugf("Handling response close packet with code %d", code)
 
-		rg.closeDone.Done()
+		if atomic.AddInt32(&rg.closePending, -1) >= 0 {
+			rg.closeDone.Done()
+		}
 		return nil
 	}
 
@@ -725,7 +808,17 @@ func (rg *RouteGroup) handlePingPacket(packet routing.Packet) error {
 
 	rg.networkStats.SetUploadSpeed(uint32(throughput))
 
-	return rg.sendPong(int64(timestamp))
+	rg.mu.Lock()
+	i := rg.pathOfReverseRule(packet.RouteID())
+	var hops []routing.Hop
+	if i >= 0 && i < len(rg.paths) {
+		hops = rg.paths[i].hops
+	}
+	rg.mu.Unlock()
+
+	rg.scores.observeThroughput(hops, float64(throughput))
+
+	return rg.sendPong(i, int64(timestamp))
 }
 
 func (rg *RouteGroup) handlePongPacket(packet routing.Packet) error {
@@ -740,7 +833,25 @@ func (rg *RouteGroup) handlePongPacket(packet routing.Packet) error {
 
 	rg.logger.WithField("func", "RouteGroup.handlePongPacket").Tracef("Latency is around %d ms", latency)
 
-	rg.networkStats.SetLatency(uint32(latency))
+	rg.mu.Lock()
+	i := rg.pathOfReverseRule(packet.RouteID())
+	var hops []routing.Hop
+	if i >= 0 && i < len(rg.paths) {
+		if rg.paths[i].failed {
+			rg.logger.Infof("Path %d of %d recovered", i, len(rg.paths))
+		}
+		rg.paths[i].lastPong = time.Now()
+		rg.paths[i].failed = false
+		hops = rg.paths[i].hops
+	}
+	// the latency of the path currently used by the failover policy is reported
+	report := rg.multipath != MultipathFailover || i == rg.active
+	rg.mu.Unlock()
+
+	if report {
+		rg.networkStats.SetLatency(uint32(latency))
+	}
+	rg.scores.observeLatency(hops, time.Duration(latency)*time.Millisecond)
 
 	return nil
 }
@@ -801,6 +912,11 @@ func (rg *RouteGroup) isClosed() bool {
 }
 
 func (rg *RouteGroup) appendRules(forward, reverse routing.Rule, tp *transport.ManagedTransport) {
+	rg.appendPath(forward, reverse, tp, nil, nil)
+}
+
+// appendPath adds a path along with the hops of its forward and reverse routes, if known.
+func (rg *RouteGroup) appendPath(forward, reverse routing.Rule, tp *transport.ManagedTransport, fwdHops, rvsHops []routing.Hop) {
 	rg.mu.Lock()
 	defer rg.mu.Unlock()
 
@@ -808,6 +924,7 @@ func (rg *RouteGroup) appendRules(forward, reverse routing.Rule, tp *transport.M
 	rg.rvs = append(rg.rvs, reverse)
 
 	rg.tps = append(rg.tps, tp)
+	rg.paths = append(rg.paths, pathState{added: time.Now(), hops: fwdHops, reverseHops: rvsHops})
 }
 
 func chanClosed(ch chan struct{}) bool {
@@ -3,7 +3,9 @@ package router
 
 import (
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
@@ -44,3 +46,67 @@ func createRouteGroup(cfg *RouteGroupConfig) *RouteGroup {
 	rg := NewRouteGroup(cfg, rt, desc, l)
 	return rg
 }
+
+func TestReorderBuffer(t *testing.T) {
+	b := newReorderBuffer()
+
+	var got []string
+	deliver := func(payload []byte) error {
+		got = append(got, string(payload))
+		return nil
+	}
+
+	require.NoError(t, b.push(2, []byte("b"), deliver))
+	require.Empty(t, got)
+	require.NoError(t, b.push(1, []byte("a"), deliver))
+	require.Equal(t, []string{"a", "b"}, got)
+
+	// duplicates are dropped
+	require.NoError(t, b.push(1, []byte("a"), deliver))
+	require.Equal(t, []string{"a", "b"}, got)
+
+	// packet 3 is lost, 4 is delivered once the reorder timeout passes
+	require.NoError(t, b.push(4, []byte("d"), deliver))
+	require.Equal(t, []string{"a", "b"}, got)
+	b.gapSince = time.Now().Add(-reorderTimeout)
+	require.NoError(t, b.flush(deliver))
+	require.Equal(t, []string{"a", "b", "d"}, got)
+
+	// late packet 3 is dropped
+	require.NoError(t, b.push(3, []byte("c"), deliver))
+	require.Equal(t, []string{"a", "b", "d"}, got)
+}
+
+func TestDisjointPaths(t *testing.T) {
+	src, _ := cipher.GenerateKeyPair()
+	dst, _ := cipher.GenerateKeyPair()
+	mid1, _ := cipher.GenerateKeyPair()
+	mid2, _ := cipher.GenerateKeyPair()
+
+	direct := []routing.Hop{{TpID: uuid.New(), From: src, To: dst}}
+	viaMid1 := []routing.Hop{{TpID: uuid.New(), From: src, To: mid1}, {TpID: uuid.New(), From: mid1, To: dst}}
+	viaMid1Again := []routing.Hop{{TpID: uuid.New(), From: src, To: mid1}, {TpID: uuid.New(), From: mid1, To: dst}}
+	sharedTp := []routing.Hop{{TpID: direct[0].TpID, From: src, To: mid2}, {TpID: uuid.New(), From: mid2, To: dst}}
+	viaMid2 := []routing.Hop{{TpID: uuid.New(), From: src, To: mid2}, {TpID: uuid.New(), From: mid2, To: dst}}
+
+	candidates := [][]routing.Hop{direct, viaMid1, viaMid1Again, sharedTp, viaMid2}
+
+	require.Equal(t, [][]routing.Hop{direct}, disjointPaths(candidates, 1, nil))
+	require.Equal(t, [][]routing.Hop{direct, viaMid1, viaMid2}, disjointPaths(candidates, 5, nil))
+	require.Equal(t, [][]routing.Hop{viaMid2}, disjointPaths(candidates, 2, [][]routing.Hop{direct, viaMid1}))
+}
+
+func TestRouteGroup_pathHops(t *testing.T) {
+	rg := createRouteGroup(DefaultRouteGroupConfig())
+	src, _ := cipher.GenerateKeyPair()
+	dst, _ := cipher.GenerateKeyPair()
+
+	fwd := []routing.Hop{{TpID: uuid.New(), From: src, To: dst}}
+	rvs := []routing.Hop{{TpID: uuid.New(), From: dst, To: src}}
+	rg.appendRules(routing.Rule{}, routing.Rule{}, nil)
+	rg.appendPath(routing.Rule{}, routing.Rule{}, nil, fwd, rvs)
+
+	// the replacement paths share nothing with the known routes of both directions
+	require.Equal(t, [][]routing.Hop{fwd}, rg.forwardHops())
+	require.Equal(t, [][]routing.Hop{rvs}, rg.reverseHops())
+}
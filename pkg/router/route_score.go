@@ -0,0 +1,288 @@
+// Package router pkg/router/route_score.go
+package router
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+const (
+	// unmeasuredHopLatency is the latency assumed for a hop over a transport not measured yet.
+	unmeasuredHopLatency = 50 * time.Millisecond
+	// routeFailurePenalty is the cost added to a hop for every recent route failure over its transport.
+	routeFailurePenalty = 500 * time.Millisecond
+	// routeFailureWindow is the period route failures are remembered for.
+	routeFailureWindow = 10 * time.Minute
+	// lowThroughputPenalty is the cost added to a hop over a transport with no measured throughput,
+	// it decreases linearly down to 0 for transports reaching goodThroughput.
+	lowThroughputPenalty = 100 * time.Millisecond
+	// goodThroughput is the throughput (bytes/s) from which hops are not penalized.
+	goodThroughput = 1 << 20
+	// scoreSmoothing is the weight of a new measurement in the moving averages of the transports.
+	scoreSmoothing = 0.3
+	// maxScoredTransports limits the number of transports scores are kept for.
+	maxScoredTransports = 1024
+	// scoreTTL is the time the score of a transport is kept for after its last update.
+	scoreTTL = time.Hour
+)
+
+var (
+	// ErrNotEnoughRoutes is returned when the route finder returns fewer routes than required by the DialOptions.
+	ErrNotEnoughRoutes = errors.New("not enough routes found")
+	// ErrRoutePolicy is returned when none of the routes found is allowed by the route policy.
+	ErrRoutePolicy = errors.New("no route allowed by the route policy")
+	// ErrCountriesUnknown is returned when the route policy excludes countries which can't be checked.
+	ErrCountriesUnknown = errors.New("the countries excluded by the route policy can't be checked")
+)
+
+// tpScore is what is measured of the routes over a transport.
+type tpScore struct {
+	latency    time.Duration // moving average of the latency of a hop over the transport, 0 if unmeasured
+	throughput float64       // moving average of the throughput (bytes/s) of the routes over the transport
+	failures   []time.Time   // route failures within routeFailureWindow
+	updated    time.Time
+}
+
+// routeScores scores the candidate routes from the measurements of the route groups.
+// A nil *routeScores scores all the routes by their number of hops.
+type routeScores struct {
+	mu  sync.Mutex
+	tps map[uuid.UUID]*tpScore
+}
+
+func newRouteScores() *routeScores {
+	return &routeScores{tps: make(map[uuid.UUID]*tpScore)}
+}
+
+// tp returns the score of the transport of `id`, creating it if needed.
+// NOTE: not thread-safe.
+func (s *routeScores) tp(id uuid.UUID, now time.Time) *tpScore {
+	score, ok := s.tps[id]
+	if !ok {
+		if len(s.tps) >= maxScoredTransports {
+			for tpID, old := range s.tps {
+				if now.Sub(old.updated) > scoreTTL {
+					delete(s.tps, tpID)
+				}
+			}
+		}
+		score = &tpScore{}
+		s.tps[id] = score
+	}
+	score.updated = now
+	return score
+}
+
+// observeLatency records the round trip `latency` of the route of `hops`, spread over its hops.
+func (s *routeScores) observeLatency(hops []routing.Hop, latency time.Duration) {
+	if s == nil || len(hops) == 0 || latency <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	perHop := latency / time.Duration(len(hops))
+	for _, hop := range hops {
+		score := s.tp(hop.TpID, now)
+		if score.latency == 0 {
+			score.latency = perHop
+			continue
+		}
+		score.latency += time.Duration(scoreSmoothing * float64(perHop-score.latency))
+	}
+}
+
+// observeThroughput records the `throughput` (bytes/s) of the route of `hops`.
+func (s *routeScores) observeThroughput(hops []routing.Hop, throughput float64) {
+	if s == nil || throughput <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, hop := range hops {
+		score := s.tp(hop.TpID, now)
+		if score.throughput == 0 {
+			score.throughput = throughput
+			continue
+		}
+		score.throughput += scoreSmoothing * (throughput - score.throughput)
+	}
+}
+
+// observeFailure records a failure of the route of `hops`.
+func (s *routeScores) observeFailure(hops []routing.Hop) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, hop := range hops {
+		score := s.tp(hop.TpID, now)
+		score.failures = append(score.failures, now)
+	}
+}
+
+// latency returns the round trip latency of the route of `hops`, false if a hop is not measured.
+func (s *routeScores) latency(hops []routing.Hop) (time.Duration, bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latency time.Duration
+	for _, hop := range hops {
+		score, ok := s.tps[hop.TpID]
+		if !ok || score.latency == 0 {
+			return 0, false
+		}
+		latency += score.latency
+	}
+	return latency, true
+}
+
+// cost returns the cost of the route of `hops`, lower is better.
+func (s *routeScores) cost(hops []routing.Hop, now time.Time) time.Duration {
+	if s == nil {
+		return time.Duration(len(hops)) * (unmeasuredHopLatency + lowThroughputPenalty)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cost time.Duration
+	for _, hop := range hops {
+		score, ok := s.tps[hop.TpID]
+		if !ok {
+			cost += unmeasuredHopLatency + lowThroughputPenalty
+			continue
+		}
+
+		failures := score.failures[:0]
+		for _, t := range score.failures {
+			if now.Sub(t) < routeFailureWindow {
+				failures = append(failures, t)
+			}
+		}
+		score.failures = failures
+
+		latency := score.latency
+		if latency == 0 {
+			latency = unmeasuredHopLatency
+		}
+		throughput := score.throughput
+		if throughput > goodThroughput {
+			throughput = goodThroughput
+		}
+		cost += latency +
+			time.Duration(float64(lowThroughputPenalty)*(1-throughput/goodThroughput)) +
+			time.Duration(len(failures))*routeFailurePenalty
+	}
+	return cost
+}
+
+// rank returns the non-empty `paths` sorted from the lowest to the highest cost.
+// Paths of equal cost keep the order of the route finder.
+func (s *routeScores) rank(paths [][]routing.Hop) [][]routing.Hop {
+	now := time.Now()
+	ranked := make([][]routing.Hop, 0, len(paths))
+	costs := make([]time.Duration, 0, len(paths))
+	for _, path := range paths {
+		if len(path) == 0 {
+			continue
+		}
+		ranked = append(ranked, path)
+		costs = append(costs, s.cost(path, now))
+	}
+
+	sort.Stable(byCost{paths: ranked, costs: costs})
+	return ranked
+}
+
+type byCost struct {
+	paths [][]routing.Hop
+	costs []time.Duration
+}
+
+func (b byCost) Len() int           { return len(b.paths) }
+func (b byCost) Less(i, j int) bool { return b.costs[i] < b.costs[j] }
+func (b byCost) Swap(i, j int) {
+	b.paths[i], b.paths[j] = b.paths[j], b.paths[i]
+	b.costs[i], b.costs[j] = b.costs[j], b.costs[i]
+}
+
+// bestRoutes ranks the candidate `paths` and keeps up to `maxRts` of them.
+// ErrNotEnoughRoutes is returned if fewer than `minRts` are found.
+func (s *routeScores) bestRoutes(paths [][]routing.Hop, minRts, maxRts int) ([][]routing.Hop, error) {
+	if minRts < 1 {
+		minRts = 1
+	}
+	if maxRts < minRts {
+		maxRts = minRts
+	}
+
+	ranked := s.rank(paths)
+	if len(ranked) < minRts {
+		return nil, fmt.Errorf("%w: %d of %d", ErrNotEnoughRoutes, len(ranked), minRts)
+	}
+	if len(ranked) > maxRts {
+		ranked = ranked[:maxRts]
+	}
+	return ranked, nil
+}
+
+// allowedRoutes returns the `paths` allowed by the route `policy`, ErrRoutePolicy if there are none.
+// It fails with ErrCountriesUnknown if the policy excludes countries which can't be resolved.
+func (r *router) allowedRoutes(paths [][]routing.Hop, policy *routing.RoutePolicy) ([][]routing.Hop, error) {
+	if policy == nil || len(paths) == 0 {
+		return paths, nil
+	}
+
+	var country func(pk cipher.PubKey) string
+	var countryErr error
+	if len(policy.ExcludeCountries) != 0 {
+		if r.conf.Countries == nil {
+			return nil, ErrCountriesUnknown
+		}
+		country = func(pk cipher.PubKey) string {
+			c, err := r.conf.Countries.Country(pk)
+			if err != nil && countryErr == nil {
+				countryErr = err
+			}
+			return c
+		}
+	}
+
+	allowed := make([][]routing.Hop, 0, len(paths))
+	for _, path := range paths {
+		ok := policy.Allows(path, country)
+		if countryErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCountriesUnknown, countryErr)
+		}
+		if !ok {
+			continue
+		}
+		if policy.MaxLatency > 0 {
+			if latency, ok := r.scores.latency(path); ok && latency > policy.MaxLatency {
+				continue
+			}
+		}
+		allowed = append(allowed, path)
+	}
+	if len(allowed) == 0 {
+		return nil, ErrRoutePolicy
+	}
+	return allowed, nil
+}
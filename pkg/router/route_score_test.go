@@ -0,0 +1,165 @@
+// Package router pkg/router/route_score_test.go
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+func TestRouteScores_rank(t *testing.T) {
+	src, _ := cipher.GenerateKeyPair()
+	dst, _ := cipher.GenerateKeyPair()
+	mid, _ := cipher.GenerateKeyPair()
+
+	direct := []routing.Hop{{TpID: uuid.New(), From: src, To: dst}}
+	viaMid := []routing.Hop{{TpID: uuid.New(), From: src, To: mid}, {TpID: uuid.New(), From: mid, To: dst}}
+	candidates := [][]routing.Hop{viaMid, direct, nil}
+
+	// unmeasured routes are ranked by their number of hops
+	var nilScores *routeScores
+	require.Equal(t, [][]routing.Hop{direct, viaMid}, nilScores.rank(candidates))
+	s := newRouteScores()
+	require.Equal(t, [][]routing.Hop{direct, viaMid}, s.rank(candidates))
+
+	// a slow direct transport loses against fast ones
+	s.observeLatency(direct, 2*time.Second)
+	s.observeLatency(viaMid, 20*time.Millisecond)
+	s.observeThroughput(viaMid, goodThroughput)
+	require.Equal(t, [][]routing.Hop{viaMid, direct}, s.rank(candidates))
+
+	// failures are penalized
+	s = newRouteScores()
+	s.observeThroughput(direct, goodThroughput)
+	s.observeThroughput(viaMid, goodThroughput)
+	require.Equal(t, [][]routing.Hop{direct, viaMid}, s.rank(candidates))
+	s.observeFailure(direct)
+	require.Equal(t, [][]routing.Hop{viaMid, direct}, s.rank(candidates))
+
+	// old failures are forgotten
+	s.tps[direct[0].TpID].failures[0] = time.Now().Add(-routeFailureWindow)
+	require.Equal(t, [][]routing.Hop{direct, viaMid}, s.rank(candidates))
+}
+
+func TestRouteScores_bestRoutes(t *testing.T) {
+	src, _ := cipher.GenerateKeyPair()
+	dst, _ := cipher.GenerateKeyPair()
+
+	paths := make([][]routing.Hop, 3)
+	for i := range paths {
+		paths[i] = []routing.Hop{{TpID: uuid.New(), From: src, To: dst}}
+	}
+
+	s := newRouteScores()
+	best, err := s.bestRoutes(paths, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, paths[:1], best)
+
+	best, err = s.bestRoutes(paths, 2, 5)
+	require.NoError(t, err)
+	require.Equal(t, paths, best)
+
+	_, err = s.bestRoutes(paths, 4, 4)
+	require.True(t, errors.Is(err, ErrNotEnoughRoutes))
+}
+
+func TestRouter_dialBestRoute(t *testing.T) {
+	src, _ := cipher.GenerateKeyPair()
+	dst, _ := cipher.GenerateKeyPair()
+
+	fwd := [][]routing.Hop{
+		{{TpID: uuid.New(), From: src, To: dst}},
+		{{TpID: uuid.New(), From: src, To: dst}},
+	}
+	rev := [][]routing.Hop{
+		{{TpID: uuid.New(), From: dst, To: src}},
+	}
+	desc := routing.NewRouteDescriptor(src, dst, 1, 2)
+	wantRules := routing.EdgeRules{Desc: desc}
+
+	dialer := &MockRouteGroupDialer{}
+	dialer.On("Dial", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req routing.BidirectionalRoute) bool { return req.Forward[0] == fwd[0][0] })).
+		Return(routing.EdgeRules{}, errors.New("setup failed"))
+	dialer.On("Dial", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.MatchedBy(func(req routing.BidirectionalRoute) bool { return req.Forward[0] == fwd[1][0] })).
+		Return(wantRules, nil)
+
+	r := &router{
+		conf:   &Config{RouteGroupDialer: dialer},
+		logger: logging.MustGetLogger("router"),
+		scores: newRouteScores(),
+	}
+
+	rules, fwdPath, revPath, err := r.dialBestRoute(context.Background(), desc, fwd, rev)
+	require.NoError(t, err)
+	require.Equal(t, wantRules, rules)
+	require.Equal(t, fwd[1], fwdPath)
+	require.Equal(t, rev[0], revPath)
+
+	// the failed route is ranked last from now on
+	require.Equal(t, [][]routing.Hop{fwd[1], fwd[0]}, r.scores.rank(fwd))
+}
+
+type countriesMap map[cipher.PubKey]string
+
+func (c countriesMap) Country(pk cipher.PubKey) (string, error) {
+	if c == nil {
+		return "", errors.New("countries not fetched")
+	}
+	return c[pk], nil
+}
+
+func TestRouter_allowedRoutes(t *testing.T) {
+	src, _ := cipher.GenerateKeyPair()
+	dst, _ := cipher.GenerateKeyPair()
+	mid1, _ := cipher.GenerateKeyPair()
+	mid2, _ := cipher.GenerateKeyPair()
+
+	viaMid1 := []routing.Hop{{TpID: uuid.New(), From: src, To: mid1}, {TpID: uuid.New(), From: mid1, To: dst}}
+	viaMid2 := []routing.Hop{{TpID: uuid.New(), From: src, To: mid2}, {TpID: uuid.New(), From: mid2, To: dst}}
+	paths := [][]routing.Hop{viaMid1, viaMid2}
+
+	r := &router{
+		conf:   &Config{Countries: countriesMap{mid1: "DE", mid2: "US"}},
+		scores: newRouteScores(),
+	}
+
+	allowed, err := r.allowedRoutes(paths, nil)
+	require.NoError(t, err)
+	require.Equal(t, paths, allowed)
+
+	allowed, err = r.allowedRoutes(paths, &routing.RoutePolicy{ExcludeCountries: []string{"DE"}})
+	require.NoError(t, err)
+	require.Equal(t, [][]routing.Hop{viaMid2}, allowed)
+
+	// unmeasured routes are allowed by the max latency
+	policy := &routing.RoutePolicy{MaxLatency: 100 * time.Millisecond}
+	r.scores.observeLatency(viaMid1, time.Second)
+	allowed, err = r.allowedRoutes(paths, policy)
+	require.NoError(t, err)
+	require.Equal(t, [][]routing.Hop{viaMid2}, allowed)
+
+	_, err = r.allowedRoutes(paths, &routing.RoutePolicy{RequirePKs: []cipher.PubKey{dst}})
+	require.True(t, errors.Is(err, ErrRoutePolicy))
+
+	// excluded countries which can't be checked fail the dial
+	r.conf.Countries = countriesMap(nil)
+	_, err = r.allowedRoutes(paths, &routing.RoutePolicy{ExcludeCountries: []string{"DE"}})
+	require.True(t, errors.Is(err, ErrCountriesUnknown))
+	r.conf.Countries = nil
+	_, err = r.allowedRoutes(paths, &routing.RoutePolicy{ExcludeCountries: []string{"DE"}})
+	require.True(t, errors.Is(err, ErrCountriesUnknown))
+	allowed, err = r.allowedRoutes(paths, &routing.RoutePolicy{ExcludePKs: []cipher.PubKey{mid1}})
+	require.NoError(t, err)
+	require.Equal(t, [][]routing.Hop{viaMid2}, allowed)
+}
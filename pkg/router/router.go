@@ -31,7 +31,10 @@ const (
 	DefaultRouteKeepAlive = 30 * time.Second
 	// DefaultRulesGCInterval is the default duration for garbage collection of routing rules.
 	DefaultRulesGCInterval = 5 * time.Second
-	acceptSize             = 1024
+	// restoredRulesCheckDelay is the time given to transports to come back before the rules
+	// restored from a persistent routing table are checked against them.
+	restoredRulesCheckDelay = 30 * time.Second
+	acceptSize              = 1024
 
 	handshakeAwaitTimeout = 2 * time.Second
 
@@ -71,6 +74,25 @@ type Config struct {
 	RulesGCInterval  time.Duration
 	MinHops          uint16
 	MaxHops          uint16
+	// RoutingTable is the table rules are kept in, an in-memory table is used if nil.
+	RoutingTable routing.Table
+	// Callbacks are used to notify about the route groups, if not nil.
+	Callbacks *Callbacks
+	// Countries resolves the countries of the visors for the route policies, if not nil.
+	Countries CountryResolver
+}
+
+// CountryResolver resolves the countries of visors.
+type CountryResolver interface {
+	// Country returns the ISO 3166-1 alpha-2 code of the country of the visor of `pk`, empty if unknown.
+	// It returns an error if the countries can't be resolved.
+	Country(pk cipher.PubKey) (string, error)
+}
+
+// Callbacks contains callbacks which a Router uses to notify about its route groups.
+type Callbacks struct {
+	OnRouteGroupCreated func(desc routing.RouteDescriptor)
+	OnRouteGroupClosed  func(desc routing.RouteDescriptor)
 }
 
 // SetDefaults sets default values for certain empty values.
@@ -90,14 +112,31 @@ func (c *Config) SetDefaults() {
 	if c.MaxHops == 0 {
 		c.MaxHops = maxHops
 	}
+
+	if c.RoutingTable == nil {
+		c.RoutingTable = routing.NewTable(c.Logger)
+	}
 }
 
 // DialOptions describes dial options.
 type DialOptions struct {
+	// MinForwardRts/MaxForwardRts bound the number of candidate forward routes, which are ranked by
+	// the latency, throughput and failures measured locally. The best one is set up, the others are
+	// tried if it fails. Dialing fails if fewer than MinForwardRts are found. The additional paths
+	// of a multipath route group are picked from all of the routes found.
 	MinForwardRts int
 	MaxForwardRts int
+	// MinConsumeRts/MaxConsumeRts bound the number of candidate reverse routes in the same way.
 	MinConsumeRts int
 	MaxConsumeRts int
+
+	// Paths is the number of disjoint paths of the route group, 1 disables multipath.
+	// Additional paths are only set up if the remote visor supports multipath route groups.
+	Paths int
+	// Multipath is the policy the paths are used with, defaults to failover if Paths is more than 1.
+	Multipath MultipathPolicy
+	// Policy constrains the intermediate visors of the routes, if not nil.
+	Policy *routing.RoutePolicy
 }
 
 // DefaultDialOptions returns default dial options.
@@ -108,9 +147,22 @@ func DefaultDialOptions() *DialOptions {
 		MaxForwardRts: 1,
 		MinConsumeRts: 1,
 		MaxConsumeRts: 1,
+		Paths:         1,
 	}
 }
 
+// multipath returns the multipath policy requested by the options.
+func (o *DialOptions) multipath() MultipathPolicy {
+	if o == nil || o.Paths < 2 {
+		return MultipathNone
+	}
+	if o.Multipath == MultipathNone {
+		return MultipathFailover
+	}
+
+	return o.Multipath
+}
+
 // Router is responsible for creating and keeping track of routes.
 // Internally, it uses the routing table, route finder client and setup client.
 type Router interface {
@@ -138,6 +190,7 @@ type Router interface {
 	IntroduceRules(rules routing.EdgeRules) error
 	Serve(context.Context) error
 	SetupIsTrusted(cipher.PubKey) bool
+	RouteGroupsStats() []RouteGroupStats
 
 	// Routing table related methods
 	RoutesCount() int
@@ -147,6 +200,16 @@ type Router interface {
 	DelRules([]routing.RouteID)
 }
 
+// RouteGroupStats contains the network statistics of a route group.
+type RouteGroupStats struct {
+	Desc              routing.RouteDescriptor
+	Latency           time.Duration
+	UploadSpeed       uint32
+	DownloadSpeed     uint32
+	BandwidthSent     uint64
+	BandwidthReceived uint64
+}
+
 // Router implements visor.PacketRouter. It manages routing table by
 // communicating with setup nodes, forward packets according to local
 // rules and manages route groups for apps.
@@ -167,7 +230,9 @@ type router struct {
 	done             chan struct{}
 	once             sync.Once
 	routeSetupHookMu sync.Mutex
-	routeSetupHooks  []RouteSetupHook // see RouteSetupHook description
+	routeSetupHooks  []RouteSetupHook  // see RouteSetupHook description
+	scores           *routeScores      // local scores of the candidate routes
+	peerIDs          *peerReservations // route IDs reserved by the visors setting up routes themselves
 }
 
 // New constructs a new Router.
@@ -193,7 +258,7 @@ func New(dmsgC *dmsg.Client, config *Config, routeSetupHooks []RouteSetupHook) (
 		logger:          config.Logger,
 		mLogger:         config.MasterLogger,
 		tm:              config.TransportManager,
-		rt:              routing.NewTable(config.Logger),
+		rt:              config.RoutingTable,
 		sl:              sl,
 		dmsgC:           dmsgC,
 		rgsNs:           make(map[routing.RouteDescriptor]*NoiseRouteGroup),
@@ -203,8 +268,12 @@ func New(dmsgC *dmsg.Client, config *Config, routeSetupHooks []RouteSetupHook) (
 		done:            make(chan struct{}),
 		trustedVisors:   trustedVisors,
 		routeSetupHooks: routeSetupHooks,
+		scores:          newRouteScores(),
+		peerIDs:         newPeerReservations(),
 	}
 
+	r.dropRestoredEdgeRules()
+
 	go r.rulesGCLoop()
 
 	if err := r.rpcSrv.Register(NewRPCGateway(r, config.MasterLogger)); err != nil {
@@ -262,19 +331,17 @@ func (r *router) DialRoutes(
 		return nil, ErrNoTransportFound
 	}
 
-	forwardPath, reversePath, err := r.fetchBestRoutes(lPK, rPK, opts)
+	if opts == nil {
+		opts = DefaultDialOptions()
+	}
+	forwardPaths, reversePaths, err := r.fetchBestRoutes(lPK, rPK, opts)
 	if err != nil {
 		return nil, fmt.Errorf("route finder: %w", err)
 	}
 
-	req := routing.BidirectionalRoute{
-		Desc:      forwardDesc,
-		KeepAlive: DefaultRouteKeepAlive,
-		Forward:   forwardPath,
-		Reverse:   reversePath,
-	}
-
-	rules, err := r.conf.RouteGroupDialer.Dial(ctx, r.logger, r.dmsgC, r.conf.SetupNodes, req)
+	// the route set up is one of the best candidates, the additional paths are picked from all of them
+	rules, forwardPath, reversePath, err := r.dialBestRoute(ctx, forwardDesc,
+		firstRoutes(forwardPaths, opts.MaxForwardRts), firstRoutes(reversePaths, opts.MaxConsumeRts))
 	if err != nil {
 		r.logger.WithError(err).Error("Error dialing route group")
 		return nil, err
@@ -292,13 +359,38 @@ func (r *router) DialRoutes(
 		Initiator: true,
 	}
 
-	nrg, err := r.saveRouteGroupRules(rules, nsConf)
+	// the additional paths of a multipath route group share nothing with the one set up
+	var extraForward, extraReverse [][]routing.Hop
+	if multipath := opts.multipath(); multipath != MultipathNone {
+		extraForward = disjointPaths(forwardPaths, opts.Paths-1, [][]routing.Hop{forwardPath})
+		extraReverse = disjointPaths(reversePaths, len(extraForward), [][]routing.Hop{reversePath})
+		extraForward = extraForward[:len(extraReverse)]
+		if len(extraForward) < opts.Paths-1 {
+			r.logger.Debugf("Found %d of %d disjoint paths from %s to %s", len(extraForward)+1, opts.Paths, lPK, rPK)
+		}
+	}
+
+	multipath := MultipathNone
+	if len(extraForward) > 0 {
+		multipath = opts.multipath()
+	}
+
+	nrg, err := r.saveRouteGroupRules(rules, nsConf, multipath, forwardPath, reversePath)
 	if err != nil {
 		return nil, fmt.Errorf("saveRouteGroupRules: %w", err)
 	}
 
 	nrg.rg.startOffServiceLoops()
 
+	if multipath != MultipathNone && nrg.rg.multipathPolicy() != MultipathNone {
+		nrg.rg.mu.Lock()
+		nrg.rg.wantPaths = 1 + len(extraForward)
+		nrg.rg.policy = opts.Policy
+		nrg.rg.mu.Unlock()
+
+		go r.addPaths(nrg.rg, extraForward, extraReverse)
+	}
+
 	r.logger.Debugf("Created new routes to %s on port %d", rPK, lPort)
 
 	return nrg, nil
@@ -345,7 +437,7 @@ func (r *router) PingRoute(
 		Reverse:   reversePath,
 	}
 
-	rules, err := r.conf.RouteGroupDialer.Dial(ctx, r.logger, r.dmsgC, r.conf.SetupNodes, req)
+	rules, err := r.dialRoute(ctx, req)
 	if err != nil {
 		r.logger.WithError(err).Error("Error dialing route group")
 		return nil, err
@@ -363,7 +455,7 @@ func (r *router) PingRoute(
 		Initiator: true,
 	}
 
-	nrg, err := r.saveRouteGroupRules(rules, nsConf)
+	nrg, err := r.saveRouteGroupRules(rules, nsConf, MultipathNone, nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("saveRouteGroupRules: %w", err)
 	}
@@ -414,7 +506,7 @@ func (r *router) AcceptRoutes(ctx context.Context) (net.Conn, error) {
 		Initiator: false,
 	}
 
-	nrg, err := r.saveRouteGroupRules(rules, nsConf)
+	nrg, err := r.saveRouteGroupRules(rules, nsConf, MultipathNone, nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("saveRouteGroupRules: %w", err)
 	}
@@ -432,9 +524,54 @@ func (r *router) Serve(ctx context.Context) error {
 
 	go r.serveSetup()
 
+	go r.checkRestoredRules(ctx)
+
 	return nil
 }
 
+// dropRestoredEdgeRules removes the edge rules restored from a persistent routing table,
+// as the route groups they belonged to did not survive the restart.
+func (r *router) dropRestoredEdgeRules() {
+	var ids []routing.RouteID
+	for _, rule := range r.rt.AllRules() {
+		if rule.Type() != routing.RuleIntermediary {
+			ids = append(ids, rule.KeyRouteID())
+		}
+	}
+	if len(ids) > 0 {
+		r.logger.WithField("rules_count", len(ids)).Debug("Removing restored edge rules.")
+		r.rt.DelRules(ids)
+	}
+}
+
+// checkRestoredRules removes the restored intermediary rules whose next transport
+// was not re-established after the restart.
+func (r *router) checkRestoredRules(ctx context.Context) {
+	restored := r.rt.AllRules()
+	if len(restored) == 0 {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-r.done:
+		return
+	case <-time.After(restoredRulesCheckDelay):
+	}
+
+	var ids []routing.RouteID
+	for _, rule := range restored {
+		if rule.Type() == routing.RuleIntermediary && r.tm.Transport(rule.NextTransportID()) == nil {
+			ids = append(ids, rule.KeyRouteID())
+		}
+	}
+	if len(ids) > 0 {
+		r.logger.WithField("rules_count", len(ids)).Info("Removing restored rules of unavailable transports.")
+		r.rt.DelRules(ids)
+	}
+}
+
 func (r *router) serveTransportManager(ctx context.Context) {
 	for {
 		packet, err := r.tm.ReadPacket()
@@ -474,7 +611,13 @@ func (r *router) serveSetup() {
 
 		remotePK := conn.RawRemoteAddr().PK
 		if !r.SetupIsTrusted(remotePK) {
-			r.logger.Warnf("closing conn from untrusted setup node: %v", conn.Close())
+			if r.peerIDs.full(remotePK) {
+				r.logger.Warnf("closing conn from untrusted setup node: %v", conn.Close())
+				continue
+			}
+			// a visor setting up a direct or two hops route itself
+			r.logger.Debugf("handling peer setup request: visorPK(%s)", remotePK)
+			go r.servePeerSetup(conn, remotePK)
 			continue
 		}
 
@@ -484,7 +627,10 @@ func (r *router) serveSetup() {
 	}
 }
 
-func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Config) (*NoiseRouteGroup, error) {
+// saveRouteGroupRules creates the route group of `rules` and performs the handshake.
+// The initiator requests the `multipath` policy, the remote may refuse it.
+func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Config, multipath MultipathPolicy,
+	fwdHops, rvsHops []routing.Hop) (*NoiseRouteGroup, error) {
 	r.logger.Debugf("Saving route group rules with desc: %s", &rules.Desc)
 
 	// When route group is wrapped with noise, it's put into `nrgs`. but before that,
@@ -505,7 +651,9 @@ func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Confi
 	nrg, ok := r.rgsNs[rules.Desc]
 
 	rg := NewRouteGroup(DefaultRouteGroupConfig(), r.rt, rules.Desc, r.mLogger)
-	rg.appendRules(rules.Forward, rules.Reverse, r.tm.Transport(rules.Forward.NextTransportID()))
+	rg.multipath = multipath
+	rg.scores = r.scores
+	rg.appendPath(rules.Forward, rules.Reverse, r.tm.Transport(rules.Forward.NextTransportID()), fwdHops, rvsHops)
 	// we put raw rg so it can be accessible to the router when handshake packets come in
 	r.rgsRaw[rules.Desc] = rg
 	r.mx.Unlock()
@@ -533,6 +681,9 @@ func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Confi
 		// with the old visor
 		rg.handshakeProcessedOnce.Do(func() {
 			rg.encrypt = false
+			rg.mu.Lock()
+			rg.multipath = MultipathNone
+			rg.mu.Unlock()
 			close(rg.handshakeProcessed)
 		})
 	}
@@ -589,6 +740,18 @@ func (r *router) saveRouteGroupRules(rules routing.EdgeRules, nsConf noise.Confi
 	delete(r.rgsRaw, rules.Desc)
 	r.mx.Unlock()
 
+	if cb := r.conf.Callbacks; cb != nil {
+		if cb.OnRouteGroupCreated != nil {
+			cb.OnRouteGroupCreated(rules.Desc)
+		}
+		if cb.OnRouteGroupClosed != nil {
+			go func(desc routing.RouteDescriptor) {
+				<-rg.closed
+				cb.OnRouteGroupClosed(desc)
+			}(rules.Desc)
+		}
+	}
+
 	return nrg, nil
 }
 
@@ -1037,12 +1200,78 @@ func (r *router) RemoveRouteDescriptor(desc routing.RouteDescriptor) {
 	}
 }
 
-func (r *router) fetchBestRoutes(src, dst cipher.PubKey, opts *DialOptions) (fwd, rev []routing.Hop, err error) {
-	// TODO: use opts
+// fetchBestRoutes returns all of the candidate forward and reverse routes between `src` and `dst`,
+// best first. It fails if there are fewer than the minimums of `opts`.
+func (r *router) fetchBestRoutes(src, dst cipher.PubKey, opts *DialOptions) (fwd, rev [][]routing.Hop, err error) {
 	if opts == nil {
-		opts = DefaultDialOptions() // nolint
+		opts = DefaultDialOptions()
+	}
+
+	fwd, rev, err = r.fetchRoutes(src, dst, opts.Policy)
+	if err != nil {
+		return nil, nil, err
 	}
+	if len(fwd) == 0 || len(rev) == 0 {
+		return nil, nil, rfclient.ErrTransportNotFound
+	}
+	if fwd, err = r.allowedRoutes(fwd, opts.Policy); err != nil {
+		return nil, nil, fmt.Errorf("forward routes: %w", err)
+	}
+	if rev, err = r.allowedRoutes(rev, opts.Policy); err != nil {
+		return nil, nil, fmt.Errorf("reverse routes: %w", err)
+	}
+
+	if fwd, err = r.scores.bestRoutes(fwd, opts.MinForwardRts, len(fwd)); err != nil {
+		return nil, nil, fmt.Errorf("forward routes: %w", err)
+	}
+	if rev, err = r.scores.bestRoutes(rev, opts.MinConsumeRts, len(rev)); err != nil {
+		return nil, nil, fmt.Errorf("reverse routes: %w", err)
+	}
+
+	return fwd, rev, nil
+}
+
+// firstRoutes returns the first `n` of the ranked `paths`, all of them if `n` is not positive.
+func firstRoutes(paths [][]routing.Hop, n int) [][]routing.Hop {
+	if n > 0 && len(paths) > n {
+		return paths[:n]
+	}
+	return paths
+}
+
+// dialBestRoute sets up the first of the candidate routes which can be set up, pairing the forward
+// and reverse ones by rank. The failures are recorded to the route scores.
+func (r *router) dialBestRoute(ctx context.Context, desc routing.RouteDescriptor, fwd, rev [][]routing.Hop) (
+	rules routing.EdgeRules, fwdPath, revPath []routing.Hop, err error) {
+	for i := 0; i < len(fwd); i++ {
+		fwdPath, revPath = fwd[i], rev[min(i, len(rev)-1)]
+		req := routing.BidirectionalRoute{
+			Desc:      desc,
+			KeepAlive: DefaultRouteKeepAlive,
+			Forward:   fwdPath,
+			Reverse:   revPath,
+		}
+
+		rules, err = r.dialRoute(ctx, req)
+		if err == nil {
+			return rules, fwdPath, revPath, nil
+		}
+
+		r.scores.observeFailure(fwdPath)
+		r.scores.observeFailure(revPath)
+		if ctx.Err() != nil {
+			break
+		}
+		if i < len(fwd)-1 {
+			r.logger.WithError(err).Warnf("Failed to set up route %d of %d to %s, trying the next one", i+1, len(fwd), desc.DstPK())
+		}
+	}
+
+	return routing.EdgeRules{}, nil, nil, err
+}
 
+// fetchRoutes requests the candidate routes between `src` and `dst` allowed by `policy` from the route finder.
+func (r *router) fetchRoutes(src, dst cipher.PubKey, policy *routing.RoutePolicy) (fwd, rev [][]routing.Hop, err error) {
 	r.logger.Debugf("Requesting new routes from %s to %s", src, dst)
 
 	timer := time.NewTimer(retryDuration)
@@ -1055,7 +1284,7 @@ fetchRoutesAgain:
 	ctx := context.Background()
 
 	paths, err := r.conf.RouteFinder.FindRoutes(ctx, []routing.PathEdges{forward, backward},
-		&rfclient.RouteOptions{MinHops: r.conf.MinHops, MaxHops: r.conf.MaxHops})
+		&rfclient.RouteOptions{MinHops: r.conf.MinHops, MaxHops: r.conf.MaxHops, Policy: policy})
 
 	if err == rfclient.ErrTransportNotFound {
 		return nil, nil, err
@@ -1073,13 +1302,13 @@ fetchRoutesAgain:
 
 	r.logger.Debugf("Found routes Forward: %s. Reverse %s", paths[forward], paths[backward])
 
-	return paths[forward][0], paths[backward][0], nil
+	return paths[forward], paths[backward], nil
 }
 
+// fetchPingRoute returns the best of the routes from `src` to itself through the visor of `pingKey`.
 func (r *router) fetchPingRoute(src, pingKey cipher.PubKey, opts *DialOptions) (fwd, rev []routing.Hop, err error) {
-	// TODO: use opts
 	if opts == nil {
-		opts = DefaultDialOptions() // nolint
+		opts = DefaultDialOptions()
 	}
 
 	r.logger.Debugf("Requesting new routes from %s to %s", src, src)
@@ -1094,7 +1323,7 @@ fetchRoutesAgain:
 	ctx := context.Background()
 
 	paths, err := r.conf.RouteFinder.FindRoutes(ctx, []routing.PathEdges{forward, backward},
-		&rfclient.RouteOptions{MinHops: 0, MaxHops: 2})
+		&rfclient.RouteOptions{MinHops: 0, MaxHops: 2, Policy: opts.Policy})
 	if err == rfclient.ErrTransportNotFound {
 		return nil, nil, err
 	}
@@ -1109,7 +1338,6 @@ fetchRoutesAgain:
 		}
 	}
 
-	var hopTo, hopFrom bool
 	// check if the remote pk is present in both the hops
 	// [
 	// 	{
@@ -1123,21 +1351,45 @@ fetchRoutesAgain:
 	// 		"To":"<local-pk>"
 	// 	}
 	// ]
-	for _, hop := range paths[forward][0] {
-		if hop.To == pingKey {
-			hopTo = true
+	candidates := make([][]routing.Hop, 0, len(paths[forward]))
+	for _, path := range paths[forward] {
+		var hopTo, hopFrom bool
+		for _, hop := range path {
+			if hop.To == pingKey {
+				hopTo = true
+			}
+			if hop.From == pingKey {
+				hopFrom = true
+			}
 		}
-		if hop.From == pingKey {
-			hopFrom = true
+		if !hopTo && hopFrom {
+			continue
 		}
+		candidates = append(candidates, path)
 	}
-	if !hopTo && hopFrom {
+	if len(candidates) == 0 && len(paths[forward]) > 0 {
 		return nil, nil, fmt.Errorf("Unable to fetch route with a hop from %v", pingKey)
 	}
+	if candidates, err = r.allowedRoutes(candidates, opts.Policy); err != nil {
+		return nil, nil, fmt.Errorf("forward routes: %w", err)
+	}
+	backwardPaths, err := r.allowedRoutes(paths[backward], opts.Policy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reverse routes: %w", err)
+	}
 
-	r.logger.Debugf("Found routes Forward: %s. Reverse %s", paths[forward], paths[backward])
+	fwdPaths, err := r.scores.bestRoutes(candidates, opts.MinForwardRts, opts.MaxForwardRts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("forward routes: %w", err)
+	}
+	revPaths, err := r.scores.bestRoutes(backwardPaths, opts.MinConsumeRts, opts.MaxConsumeRts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reverse routes: %w", err)
+	}
+
+	r.logger.Debugf("Found routes Forward: %s. Reverse %s", fwdPaths[0], revPaths[0])
 
-	return paths[forward][0], paths[backward][0], nil
+	return fwdPaths[0], revPaths[0], nil
 }
 
 // SetupIsTrusted checks if setup node is trusted.
@@ -1209,6 +1461,11 @@ func (r *router) removeNoiseRouteGroup(desc routing.RouteDescriptor) {
 }
 
 func (r *router) IntroduceRules(rules routing.EdgeRules) error {
+	// an additional path of a multipath route group already set up
+	if rg, ok := r.multipathRouteGroup(rules.Desc); ok {
+		return r.addRouteGroupPath(rg, rules, nil, nil)
+	}
+
 	select {
 	case <-r.done:
 		return io.ErrClosedPipe
@@ -1225,6 +1482,25 @@ func (r *router) IntroduceRules(rules routing.EdgeRules) error {
 	}
 }
 
+// RouteGroupsStats returns the network statistics of the established route groups.
+func (r *router) RouteGroupsStats() []RouteGroupStats {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	stats := make([]RouteGroupStats, 0, len(r.rgsNs))
+	for desc, nrg := range r.rgsNs {
+		stats = append(stats, RouteGroupStats{
+			Desc:              desc,
+			Latency:           nrg.rg.Latency(),
+			UploadSpeed:       nrg.rg.UploadSpeed(),
+			DownloadSpeed:     nrg.rg.DownloadSpeed(),
+			BandwidthSent:     nrg.rg.BandwidthSent(),
+			BandwidthReceived: nrg.rg.BandwidthReceived(),
+		})
+	}
+	return stats
+}
+
 // RoutesCount returns count of the routes stored within the routing table.
 func (r *router) RoutesCount() int {
 	return r.rt.Count()
@@ -1308,6 +1584,14 @@ func (r *router) removeRouteGroupOfRule(rule routing.Rule) {
 	}
 
 	rDesc := rule.RouteDescriptor()
+
+	// a multipath route group only loses the path of the rule, as long as other paths remain
+	if nrg, ok := r.noiseRouteGroup(rDesc); ok && nrg != nil && nrg.rg.removePath(rule.KeyRouteID()) {
+		log.WithField("rt_desc", rDesc.String()).Debug("Removed path of multipath route group.")
+		go r.replenishPaths(nrg.rg)
+		return
+	}
+
 	log.WithField("rt_desc", rDesc.String()).
 		Debug("Closing noise route group associated with rule...")
 
@@ -1320,6 +1604,10 @@ func (r *router) removeRouteGroupOfRule(rule routing.Rule) {
 		log.Debug("Noise route group already closed. Nothing to be done.")
 		return
 	}
+	// the rules of a live route group expire once its routes stop delivering the keep-alives
+	for _, hops := range nrg.rg.forwardHops() {
+		r.scores.observeFailure(hops)
+	}
 	if err := nrg.Close(); err != nil {
 		log.WithError(err).Error("Failed to close noise route group.")
 		return
@@ -10,6 +10,9 @@ import (
 type RPCGateway struct {
 	logger *logging.Logger
 	router Router
+
+	// peer is the route setup session of the visor setting up a route itself, nil for the trusted setup nodes.
+	peer *peerSession
 }
 
 // NewRPCGateway creates a new RPCGateway.
@@ -20,8 +23,23 @@ func NewRPCGateway(router Router, mLog *logging.MasterLogger) *RPCGateway {
 	}
 }
 
+// newPeerRPCGateway creates a new RPCGateway serving the visor of `peer` setting up a route itself, without
+// the setup nodes. The rules it adds are checked by `peer`.
+func newPeerRPCGateway(router Router, peer *peerSession, mLog *logging.MasterLogger) *RPCGateway {
+	gw := NewRPCGateway(router, mLog)
+	gw.peer = peer
+	return gw
+}
+
 // AddEdgeRules adds edge rules.
 func (r *RPCGateway) AddEdgeRules(rules routing.EdgeRules, ok *bool) error {
+	if r.peer != nil {
+		if err := r.peer.checkEdgeRules(rules); err != nil {
+			*ok = false
+			return r.notAllowed(err)
+		}
+	}
+
 	if err := r.router.IntroduceRules(rules); err != nil {
 		*ok = false
 
@@ -37,6 +55,13 @@ func (r *RPCGateway) AddEdgeRules(rules routing.EdgeRules, ok *bool) error {
 
 // AddIntermediaryRules adds intermediary rules.
 func (r *RPCGateway) AddIntermediaryRules(rules []routing.Rule, ok *bool) error {
+	if r.peer != nil {
+		if err := r.peer.checkIntermediaryRules(rules); err != nil {
+			*ok = false
+			return r.notAllowed(err)
+		}
+	}
+
 	if err := r.router.SaveRoutingRules(rules...); err != nil {
 		*ok = false
 
@@ -52,13 +77,27 @@ func (r *RPCGateway) AddIntermediaryRules(rules []routing.Rule, ok *bool) error
 
 // ReserveIDs reserves route IDs.
 func (r *RPCGateway) ReserveIDs(n uint8, routeIDs *[]routing.RouteID) error {
+	if r.peer != nil {
+		if err := r.peer.acquire(int(n)); err != nil {
+			return r.notAllowed(err)
+		}
+	}
+
 	ids, err := r.router.ReserveKeys(int(n))
 	if err != nil {
 		r.logger.WithError(err).Warnf("Request completed with error.")
 		return routing.Failure{Code: routing.FailureReserveRtIDs, Msg: err.Error()}
 	}
 
+	if r.peer != nil {
+		r.peer.reserved(ids)
+	}
 	*routeIDs = ids
 
 	return nil
 }
+
+func (r *RPCGateway) notAllowed(err error) error {
+	r.logger.WithError(err).Warnf("Refused route setup request of %s.", r.peer.pk)
+	return routing.Failure{Code: routing.FailureNotAllowed, Msg: err.Error()}
+}
@@ -4,6 +4,8 @@ package router
 import (
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
@@ -146,3 +148,145 @@ func TestRPCGateway_ReserveIDs(t *testing.T) {
 		require.Nil(t, gotIds)
 	})
 }
+
+func TestRPCGateway_peer(t *testing.T) {
+	srcPK, _ := cipher.GenerateKeyPair()
+	dstPK, _ := cipher.GenerateKeyPair()
+	otherPK, _ := cipher.GenerateKeyPair()
+
+	toSrc, toDst, toOther := uuid.New(), uuid.New(), uuid.New()
+	transports := map[uuid.UUID]cipher.PubKey{toSrc: srcPK, toDst: dstPK, toOther: otherPK}
+
+	// the router is the destination of the route group
+	newPeer := func(pk cipher.PubKey, neighbour bool) *peerSession {
+		return &peerSession{
+			pk:           pk,
+			localPK:      dstPK,
+			reservations: newPeerReservations(),
+			transport: func(id uuid.UUID) (cipher.PubKey, bool) {
+				remote, ok := transports[id]
+				return remote, ok
+			},
+			neighbour: func() bool { return neighbour },
+			ids:       make(map[routing.RouteID]struct{}),
+		}
+	}
+	reserve := func(t *testing.T, r *MockRouter, gateway *RPCGateway, ids ...routing.RouteID) {
+		r.On("ReserveKeys", len(ids)).Return(ids, testhelpers.NoErr).Once()
+		var got []routing.RouteID
+		require.NoError(t, gateway.ReserveIDs(uint8(len(ids)), &got))
+		require.Equal(t, ids, got)
+	}
+	requireNotAllowed := func(t *testing.T, err error) {
+		require.Error(t, err)
+		require.Equal(t, routing.FailureNotAllowed, err.(routing.Failure).Code)
+	}
+
+	desc := routing.NewRouteDescriptor(srcPK, dstPK, 100, 110)
+	edgeRules := func(fwdID, revID routing.RouteID) routing.EdgeRules {
+		return routing.EdgeRules{
+			Desc:    desc,
+			Forward: routing.ForwardRule(DefaultRouteKeepAlive, fwdID, 1, toDst, dstPK, srcPK, 110, 100),
+			Reverse: routing.ConsumeRule(DefaultRouteKeepAlive, revID, srcPK, dstPK, 100, 110),
+		}
+	}
+
+	t.Run("edge rules keyed by reserved IDs", func(t *testing.T) {
+		r := &MockRouter{}
+		gateway := newPeerRPCGateway(r, newPeer(srcPK, false), mlog)
+		reserve(t, r, gateway, 1, 2)
+
+		var ok bool
+		requireNotAllowed(t, gateway.AddEdgeRules(edgeRules(1, 3), &ok))
+		require.False(t, ok)
+		requireNotAllowed(t, gateway.AddEdgeRules(edgeRules(1, 1), &ok))
+		requireNotAllowed(t, gateway.AddEdgeRules(routing.EdgeRules{Desc: desc, Forward: routing.Rule{0, 0, 0}, Reverse: routing.Rule{1, 1, 1}}, &ok))
+
+		rules := edgeRules(1, 2)
+		r.On("IntroduceRules", rules).Return(testhelpers.NoErr).Once()
+		require.NoError(t, gateway.AddEdgeRules(rules, &ok))
+		require.True(t, ok)
+
+		// the route IDs are used once
+		requireNotAllowed(t, gateway.AddEdgeRules(rules, &ok))
+		r.AssertNumberOfCalls(t, "IntroduceRules", 1)
+	})
+
+	t.Run("edge rules of another route group", func(t *testing.T) {
+		r := &MockRouter{}
+		gateway := newPeerRPCGateway(r, newPeer(otherPK, true), mlog)
+		reserve(t, r, gateway, 1, 2)
+
+		var ok bool
+		requireNotAllowed(t, gateway.AddEdgeRules(edgeRules(1, 2), &ok))
+
+		// the route group must be one of the router too
+		peer := newPeer(srcPK, true)
+		peer.localPK = otherPK
+		gateway = newPeerRPCGateway(r, peer, mlog)
+		reserve(t, r, gateway, 1, 2)
+		requireNotAllowed(t, gateway.AddEdgeRules(edgeRules(1, 2), &ok))
+		r.AssertNotCalled(t, "IntroduceRules", mock.Anything)
+	})
+
+	t.Run("intermediary rules of a neighbour", func(t *testing.T) {
+		r := &MockRouter{}
+		gateway := newPeerRPCGateway(r, newPeer(srcPK, true), mlog)
+		reserve(t, r, gateway, 1, 2)
+
+		fwd := routing.IntermediaryForwardRule(DefaultRouteKeepAlive, 1, 5, toDst)
+		rev := routing.IntermediaryForwardRule(DefaultRouteKeepAlive, 2, 6, toSrc)
+		var ok bool
+		requireNotAllowed(t, gateway.AddIntermediaryRules([]routing.Rule{routing.IntermediaryForwardRule(DefaultRouteKeepAlive, 3, 5, toDst)}, &ok))
+		requireNotAllowed(t, gateway.AddIntermediaryRules([]routing.Rule{routing.IntermediaryForwardRule(DefaultRouteKeepAlive, 1, 5, uuid.New())}, &ok))
+
+		r.On("SaveRoutingRules", fwd, rev).Return(testhelpers.NoErr).Once()
+		require.NoError(t, gateway.AddIntermediaryRules([]routing.Rule{fwd, rev}, &ok))
+		require.True(t, ok)
+	})
+
+	t.Run("intermediary rules of a remote visor", func(t *testing.T) {
+		r := &MockRouter{}
+		gateway := newPeerRPCGateway(r, newPeer(srcPK, false), mlog)
+		reserve(t, r, gateway, 1, 2)
+
+		// only forwarding to the visor is allowed without a transport from it
+		var ok bool
+		requireNotAllowed(t, gateway.AddIntermediaryRules([]routing.Rule{routing.IntermediaryForwardRule(DefaultRouteKeepAlive, 1, 5, toOther)}, &ok))
+		r.AssertNotCalled(t, "SaveRoutingRules", mock.Anything)
+
+		rule := routing.IntermediaryForwardRule(DefaultRouteKeepAlive, 1, 5, toSrc)
+		r.On("SaveRoutingRules", rule).Return(testhelpers.NoErr).Once()
+		require.NoError(t, gateway.AddIntermediaryRules([]routing.Rule{rule}, &ok))
+		require.True(t, ok)
+	})
+
+	t.Run("reservation limits", func(t *testing.T) {
+		r := &MockRouter{}
+		peer := newPeer(srcPK, true)
+		gateway := newPeerRPCGateway(r, peer, mlog)
+
+		var ids []routing.RouteID
+		requireNotAllowed(t, gateway.ReserveIDs(maxPeerRouteIDs+1, &ids))
+
+		reserved := make([]routing.RouteID, maxPeerRouteIDs)
+		for i := range reserved {
+			reserved[i] = routing.RouteID(i + 1)
+		}
+		r.On("ReserveKeys", maxPeerRouteIDs).Return(reserved, testhelpers.NoErr)
+		for i := 0; i < maxPeerReservedIDs/maxPeerRouteIDs; i++ {
+			require.NoError(t, gateway.ReserveIDs(maxPeerRouteIDs, &ids))
+		}
+		require.True(t, peer.reservations.full(srcPK))
+
+		// the limit applies to all of the conns of the visor
+		other := newPeerRPCGateway(r, &peerSession{pk: srcPK, reservations: peer.reservations}, mlog)
+		requireNotAllowed(t, other.ReserveIDs(1, &ids))
+
+		// the route IDs left unused still count until they expire
+		peer.close()
+		require.False(t, peer.reservations.full(srcPK))
+		require.Equal(t, maxPeerRouteIDs, peer.reservations.ids[srcPK])
+	})
+
+}
@@ -25,6 +25,7 @@ const (
 	FailureCreateRoutes
 	FailureRoutesCreated
 	FailureReserveRtIDs
+	FailureNotAllowed
 )
 
 func (fc FailureCode) String() string {
@@ -39,6 +40,8 @@ func (fc FailureCode) String() string {
 		return "FailureRoutesCreated"
 	case FailureReserveRtIDs:
 		return "FailureReserveRtIDs"
+	case FailureNotAllowed:
+		return "FailureNotAllowed"
 	default:
 		return fmt.Sprintf("unknown(%d)", fc)
 	}
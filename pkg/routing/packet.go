@@ -176,6 +176,17 @@ func MakeHandshakePacket(id RouteID, supportEncryption bool) Packet {
 	return packet
 }
 
+// MakeMultipathHandshakePacket constructs a new HandshakePacket which also carries the multipath policy
+// requested or accepted for the route group. Visors without multipath support only read the first byte of the payload.
+func MakeMultipathHandshakePacket(id RouteID, supportEncryption bool, multipath byte) Packet {
+	packet := make([]byte, PacketHeaderSize+2)
+	copy(packet, MakeHandshakePacket(id, supportEncryption))
+	binary.BigEndian.PutUint16(packet[PacketPayloadSizeOffset:], uint16(2))
+	packet[PacketPayloadOffset+1] = multipath
+
+	return packet
+}
+
 // MakeErrorPacket constructs a new ErrorPacket.
 // If payload size is more than uint16, MakeErrorPacket returns an error.
 func MakeErrorPacket(id RouteID, errPayload []byte) (Packet, error) {
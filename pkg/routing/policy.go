@@ -0,0 +1,154 @@
+// Package routing pkg/routing/policy.go
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+// RoutePolicy constrains the intermediate visors of the routes, the source and destination
+// visors of a route are not subject to it.
+type RoutePolicy struct {
+	// ExcludePKs are the visors the routes must not pass through.
+	ExcludePKs []cipher.PubKey
+	// RequirePKs are the visors the routes must all pass through.
+	RequirePKs []cipher.PubKey
+	// ExcludeCountries are the ISO 3166-1 alpha-2 codes of the countries the routes must not pass through.
+	// Visors of unknown country are rejected unless AllowUnknownCountries is set, dialing fails if the
+	// countries can't be resolved.
+	ExcludeCountries []string
+	// AllowUnknownCountries allows the visors of unknown country when countries are excluded.
+	AllowUnknownCountries bool
+	// MaxLatency is the highest round trip latency of the routes, 0 for no limit.
+	// Routes of unknown latency are allowed.
+	MaxLatency time.Duration
+}
+
+// routePolicyJSON is the JSON representation of RoutePolicy.
+type routePolicyJSON struct {
+	ExcludePKs            []cipher.PubKey `json:"exclude_pks,omitempty"`
+	RequirePKs            []cipher.PubKey `json:"require_pks,omitempty"`
+	ExcludeCountries      []string        `json:"exclude_countries,omitempty"`
+	AllowUnknownCountries bool            `json:"allow_unknown_countries,omitempty"`
+	MaxLatency            string          `json:"max_latency,omitempty"` // examples: 150ms, 1s etc.
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p RoutePolicy) MarshalJSON() ([]byte, error) {
+	out := routePolicyJSON{
+		ExcludePKs:            p.ExcludePKs,
+		RequirePKs:            p.RequirePKs,
+		ExcludeCountries:      p.ExcludeCountries,
+		AllowUnknownCountries: p.AllowUnknownCountries,
+	}
+	if p.MaxLatency > 0 {
+		out.MaxLatency = p.MaxLatency.String()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *RoutePolicy) UnmarshalJSON(b []byte) error {
+	var in routePolicyJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*p = RoutePolicy{
+		ExcludePKs:            in.ExcludePKs,
+		RequirePKs:            in.RequirePKs,
+		ExcludeCountries:      in.ExcludeCountries,
+		AllowUnknownCountries: in.AllowUnknownCountries,
+	}
+	if in.MaxLatency != "" {
+		latency, err := time.ParseDuration(in.MaxLatency)
+		if err != nil {
+			return fmt.Errorf("invalid max latency %q: %w", in.MaxLatency, err)
+		}
+		p.MaxLatency = latency
+	}
+	return p.Validate()
+}
+
+// Validate checks the policy.
+func (p *RoutePolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, pk := range p.ExcludePKs {
+		if pk.Null() {
+			return fmt.Errorf("route policy contains a null public key")
+		}
+	}
+	for _, pk := range p.RequirePKs {
+		if pk.Null() {
+			return fmt.Errorf("route policy contains a null public key")
+		}
+		for _, excluded := range p.ExcludePKs {
+			if pk == excluded {
+				return fmt.Errorf("route policy both requires and excludes %s", pk)
+			}
+		}
+	}
+	for _, country := range p.ExcludeCountries {
+		if len(country) != 2 {
+			return fmt.Errorf("invalid country code %q in route policy", country)
+		}
+	}
+	if p.MaxLatency < 0 {
+		return fmt.Errorf("negative max latency in route policy")
+	}
+	return nil
+}
+
+// Allows checks the intermediate visors of the route of `hops` against the policy.
+// `country` returns the country code of a visor, empty if unknown, all of the countries are unknown if it's nil.
+// The latency of the route is checked by the router, which measures it.
+func (p *RoutePolicy) Allows(hops []Hop, country func(pk cipher.PubKey) string) bool {
+	if p == nil {
+		return true
+	}
+
+	required := make(map[cipher.PubKey]bool, len(p.RequirePKs))
+	for _, pk := range p.RequirePKs {
+		required[pk] = false
+	}
+
+	for i := 0; i < len(hops)-1; i++ {
+		pk := hops[i].To
+		for _, excluded := range p.ExcludePKs {
+			if pk == excluded {
+				return false
+			}
+		}
+		if _, ok := required[pk]; ok {
+			required[pk] = true
+		}
+		if len(p.ExcludeCountries) == 0 {
+			continue
+		}
+		var c string
+		if country != nil {
+			c = country(pk)
+		}
+		if c == "" && !p.AllowUnknownCountries {
+			return false
+		}
+		for _, excluded := range p.ExcludeCountries {
+			if strings.EqualFold(c, excluded) {
+				return false
+			}
+		}
+	}
+
+	for _, found := range required {
+		if !found {
+			return false
+		}
+	}
+	return true
+}
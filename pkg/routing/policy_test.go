@@ -0,0 +1,113 @@
+// Package routing pkg/routing/policy_test.go
+package routing
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+func TestRoutePolicy_Allows(t *testing.T) {
+	src, _ := cipher.GenerateKeyPair()
+	dst, _ := cipher.GenerateKeyPair()
+	mid1, _ := cipher.GenerateKeyPair()
+	mid2, _ := cipher.GenerateKeyPair()
+
+	route := func(pks ...cipher.PubKey) []Hop {
+		hops := make([]Hop, 0, len(pks)-1)
+		for i := 0; i < len(pks)-1; i++ {
+			hops = append(hops, Hop{TpID: uuid.New(), From: pks[i], To: pks[i+1]})
+		}
+		return hops
+	}
+	direct := route(src, dst)
+	viaMid1 := route(src, mid1, dst)
+	viaMid2 := route(src, mid2, dst)
+
+	countries := map[cipher.PubKey]string{mid1: "DE", mid2: "US", src: "CN", dst: "CN"}
+	country := func(pk cipher.PubKey) string { return countries[pk] }
+
+	var nilPolicy *RoutePolicy
+	assert.True(t, nilPolicy.Allows(viaMid1, country))
+
+	excludePK := &RoutePolicy{ExcludePKs: []cipher.PubKey{mid1}}
+	assert.False(t, excludePK.Allows(viaMid1, country))
+	assert.True(t, excludePK.Allows(viaMid2, country))
+
+	// the source and destination are not constrained
+	excludeEdges := &RoutePolicy{ExcludePKs: []cipher.PubKey{src, dst}, ExcludeCountries: []string{"cn"}}
+	assert.True(t, excludeEdges.Allows(direct, country))
+
+	requirePK := &RoutePolicy{RequirePKs: []cipher.PubKey{mid2}}
+	assert.False(t, requirePK.Allows(direct, country))
+	assert.False(t, requirePK.Allows(viaMid1, country))
+	assert.True(t, requirePK.Allows(viaMid2, country))
+
+	excludeCountry := &RoutePolicy{ExcludeCountries: []string{"us"}}
+	assert.True(t, excludeCountry.Allows(viaMid1, country))
+	assert.False(t, excludeCountry.Allows(viaMid2, country))
+	// visors of unknown country are rejected unless allowed
+	unknown := func(cipher.PubKey) string { return "" }
+	assert.False(t, excludeCountry.Allows(viaMid2, nil))
+	assert.False(t, excludeCountry.Allows(viaMid2, unknown))
+	assert.True(t, excludeCountry.Allows(direct, unknown))
+	excludeCountry.AllowUnknownCountries = true
+	assert.True(t, excludeCountry.Allows(viaMid2, nil))
+	assert.True(t, excludeCountry.Allows(viaMid2, unknown))
+}
+
+func TestRoutePolicy_Validate(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+
+	tests := []struct {
+		name   string
+		policy *RoutePolicy
+		valid  bool
+	}{
+		{"nil", nil, true},
+		{"empty", &RoutePolicy{}, true},
+		{"valid", &RoutePolicy{ExcludePKs: []cipher.PubKey{pk}, ExcludeCountries: []string{"DE"}, MaxLatency: time.Second}, true},
+		{"null pk", &RoutePolicy{RequirePKs: []cipher.PubKey{{}}}, false},
+		{"required and excluded", &RoutePolicy{ExcludePKs: []cipher.PubKey{pk}, RequirePKs: []cipher.PubKey{pk}}, false},
+		{"invalid country", &RoutePolicy{ExcludeCountries: []string{"Germany"}}, false},
+		{"negative latency", &RoutePolicy{MaxLatency: -time.Second}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Validate()
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestRoutePolicy_JSON(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	policy := RoutePolicy{
+		ExcludePKs:            []cipher.PubKey{pk},
+		ExcludeCountries:      []string{"DE"},
+		AllowUnknownCountries: true,
+		MaxLatency:            150 * time.Millisecond,
+	}
+
+	b, err := json.Marshal(policy)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"exclude_pks":["`+pk.Hex()+`"],"exclude_countries":["DE"],"allow_unknown_countries":true,"max_latency":"150ms"}`, string(b))
+
+	var got RoutePolicy
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Equal(t, policy, got)
+
+	require.Error(t, json.Unmarshal([]byte(`{"max_latency":"fast"}`), &got))
+	require.Error(t, json.Unmarshal([]byte(`{"exclude_countries":["Germany"]}`), &got))
+}
@@ -0,0 +1,217 @@
+// Package routing pkg/routing/table_persistent.go
+package routing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+)
+
+// tableSnapshot is the on-disk representation of a routing table.
+type tableSnapshot struct {
+	TakenAt time.Time           `json:"taken_at"`
+	NextID  RouteID             `json:"next_id"`
+	Rules   []tableSnapshotRule `json:"rules"`
+}
+
+type tableSnapshotRule struct {
+	Rule     Rule      `json:"rule"`
+	Activity time.Time `json:"activity"`
+}
+
+const (
+	// snapshotDelay batches the rule changes made in a row into a single write.
+	snapshotDelay = time.Second
+	// activitySnapshotInterval is the most often a snapshot is written for the activity alone,
+	// it is below the default keep-alive of routes.
+	activitySnapshotInterval = 15 * time.Second
+)
+
+// persistentTable is a memTable which snapshots its rules to a file, so they survive restarts.
+// Rule changes are written after snapshotDelay or along with the garbage collection, whichever comes first.
+// Activity alone is written along with the garbage collection, at most once per activitySnapshotInterval.
+type persistentTable struct {
+	*memTable
+	path       string
+	snapshotMx sync.Mutex
+
+	dirtyMx      sync.Mutex
+	dirty        bool        // the rules changed since the last snapshot
+	timer        *time.Timer // the pending write of the changed rules
+	lastSnapshot time.Time
+	active       atomic.Bool // the activity changed since the last snapshot
+}
+
+// NewPersistentTable returns a routing table snapshotted to the file at `path`.
+// The rules of an existing snapshot that were not timed out when it was taken are restored,
+// the time the visor was down is not counted towards their keep-alive.
+func NewPersistentTable(log *logging.Logger, path string) (Table, error) {
+	pt := &persistentTable{
+		memTable: NewTable(log).(*memTable),
+		path:     path,
+	}
+
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pt, nil
+		}
+		return nil, fmt.Errorf("failed to read routing table %s: %w", path, err)
+	}
+
+	var snapshot tableSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode routing table %s: %w", path, err)
+	}
+
+	downtime := time.Since(snapshot.TakenAt)
+	if downtime < 0 {
+		downtime = 0
+	}
+	pt.nextID = snapshot.NextID
+	for _, sr := range snapshot.Rules {
+		if len(sr.Rule) < RuleHeaderSize {
+			continue
+		}
+		if snapshot.TakenAt.Sub(sr.Activity) > sr.Rule.KeepAlive() {
+			continue
+		}
+		key := sr.Rule.KeyRouteID()
+		pt.rules[key] = sr.Rule
+		pt.activity[key] = sr.Activity.Add(downtime)
+	}
+	log.Infof("Restored %d routing rules from %s", len(pt.rules), path)
+
+	return pt, nil
+}
+
+func (pt *persistentTable) ReserveKeys(n int) ([]RouteID, error) {
+	ids, err := pt.memTable.ReserveKeys(n)
+	if err != nil {
+		return nil, err
+	}
+	pt.markDirty()
+
+	return ids, nil
+}
+
+func (pt *persistentTable) SaveRule(rule Rule) error {
+	if err := pt.memTable.SaveRule(rule); err != nil {
+		return err
+	}
+	pt.markDirty()
+
+	return nil
+}
+
+func (pt *persistentTable) Rule(key RouteID) (Rule, error) {
+	rule, err := pt.memTable.Rule(key)
+	if err == nil && rule.Type() == RuleReverse {
+		pt.active.Store(true)
+	}
+
+	return rule, err
+}
+
+func (pt *persistentTable) UpdateActivity(key RouteID) error {
+	if err := pt.memTable.UpdateActivity(key); err != nil {
+		return err
+	}
+	pt.active.Store(true)
+
+	return nil
+}
+
+func (pt *persistentTable) DelRules(keys []RouteID) {
+	pt.memTable.DelRules(keys)
+	pt.markDirty()
+}
+
+func (pt *persistentTable) CollectGarbage() []Rule {
+	rules := pt.memTable.CollectGarbage()
+
+	pt.dirtyMx.Lock()
+	write := pt.dirty || len(rules) > 0 ||
+		(pt.active.Load() && time.Since(pt.lastSnapshot) >= activitySnapshotInterval)
+	pt.dirtyMx.Unlock()
+	if write {
+		pt.saveSnapshot()
+	}
+
+	return rules
+}
+
+// markDirty schedules a snapshot of the changed rules.
+func (pt *persistentTable) markDirty() {
+	pt.dirtyMx.Lock()
+	defer pt.dirtyMx.Unlock()
+
+	pt.dirty = true
+	if pt.timer == nil {
+		pt.timer = time.AfterFunc(snapshotDelay, pt.saveSnapshot)
+	}
+}
+
+// saveSnapshot writes the snapshot, the rules stay dirty if it fails so the next garbage collection retries.
+func (pt *persistentTable) saveSnapshot() {
+	pt.dirtyMx.Lock()
+	if pt.timer != nil {
+		pt.timer.Stop()
+		pt.timer = nil
+	}
+	pt.dirty = false
+	pt.dirtyMx.Unlock()
+	pt.active.Store(false)
+
+	if err := pt.snapshot(); err != nil {
+		pt.log.WithError(err).Warn("Failed to save routing table snapshot.")
+
+		pt.dirtyMx.Lock()
+		pt.dirty = true
+		pt.dirtyMx.Unlock()
+		return
+	}
+
+	pt.dirtyMx.Lock()
+	pt.lastSnapshot = time.Now()
+	pt.dirtyMx.Unlock()
+}
+
+func (pt *persistentTable) snapshot() error {
+	pt.snapshotMx.Lock()
+	defer pt.snapshotMx.Unlock()
+
+	pt.RLock()
+	snapshot := tableSnapshot{
+		TakenAt: time.Now(),
+		NextID:  pt.nextID,
+		Rules:   make([]tableSnapshotRule, 0, len(pt.rules)),
+	}
+	for key, rule := range pt.rules {
+		snapshot.Rules = append(snapshot.Rules, tableSnapshotRule{
+			Rule:     rule,
+			Activity: pt.activity[key],
+		})
+	}
+	pt.RUnlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(pt.path), 0700); err != nil {
+		return err
+	}
+	tmp := pt.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, pt.path)
+}
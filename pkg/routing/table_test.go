@@ -4,6 +4,7 @@ package routing
 import (
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -74,3 +75,65 @@ func RoutingTableSuite(t *testing.T, tbl Table) {
 func TestRoutingTable(t *testing.T) {
 	RoutingTableSuite(t, NewTable(logging.MustGetLogger("tt")))
 }
+
+func TestPersistentRoutingTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routing_table.json")
+	log := logging.MustGetLogger("tt")
+
+	tbl, err := NewPersistentTable(log, path)
+	require.NoError(t, err)
+	RoutingTableSuite(t, tbl)
+
+	ids, err := tbl.ReserveKeys(2)
+	require.NoError(t, err)
+	rule := IntermediaryForwardRule(15*time.Minute, ids[0], 2, uuid.New())
+	require.NoError(t, tbl.SaveRule(rule))
+	require.NoError(t, tbl.SaveRule(IntermediaryForwardRule(time.Nanosecond, ids[1], 3, uuid.New())))
+	time.Sleep(time.Millisecond)
+	tbl.CollectGarbage()
+
+	restored, err := NewPersistentTable(log, path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, restored.Count())
+	r, err := restored.Rule(ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, rule, r)
+
+	next, err := restored.ReserveKeys(1)
+	require.NoError(t, err)
+	assert.Greater(t, next[0], ids[1])
+}
+
+func TestPersistentRoutingTable_writes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routing_table.json")
+
+	tbl, err := NewPersistentTable(logging.MustGetLogger("tt"), path)
+	require.NoError(t, err)
+
+	// nothing changed, nothing is written
+	tbl.CollectGarbage()
+	require.NoFileExists(t, path)
+
+	// the changes are written along with the garbage collection
+	ids, err := tbl.ReserveKeys(2)
+	require.NoError(t, err)
+	require.NoError(t, tbl.SaveRule(IntermediaryForwardRule(15*time.Minute, ids[0], 2, uuid.New())))
+	require.NoFileExists(t, path)
+	tbl.CollectGarbage()
+	require.FileExists(t, path)
+
+	require.NoError(t, os.Remove(path))
+	tbl.CollectGarbage()
+	require.NoFileExists(t, path)
+
+	// or after the snapshot delay
+	require.NoError(t, tbl.SaveRule(IntermediaryForwardRule(15*time.Minute, ids[1], 3, uuid.New())))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, 5*snapshotDelay, snapshotDelay/10)
+
+	restored, err := NewPersistentTable(logging.MustGetLogger("tt"), path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, restored.Count())
+}
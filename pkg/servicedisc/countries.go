@@ -0,0 +1,109 @@
+// Package servicedisc pkg/servicedisc/countries.go
+package servicedisc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+const (
+	// countriesFetchTimeout limits fetching the visors from service discovery.
+	countriesFetchTimeout = 20 * time.Second
+	// countriesRetryInterval is the delay before fetching the visors again after a failure.
+	countriesRetryInterval = 30 * time.Second
+)
+
+// ErrCountriesUnavailable is returned when the visors could not be fetched from service discovery yet.
+var ErrCountriesUnavailable = errors.New("the countries of the visors are not available yet")
+
+// Countries resolves the countries of the visors registered in service discovery from their geo data.
+// The visors are fetched in the background, on Prefetch or on the first lookup, then refreshed
+// once per refresh interval. Lookups never wait for a fetch.
+type Countries struct {
+	log     logrus.FieldLogger
+	client  *HTTPClient
+	refresh time.Duration
+
+	mu        sync.Mutex
+	countries map[cipher.PubKey]string // nil until the visors are fetched
+	updated   time.Time                // of the last fetch, successful or not
+	updating  bool
+}
+
+// NewCountries creates Countries fetching the visors with `client`, which should be of ServiceTypeVisor.
+func NewCountries(log logrus.FieldLogger, client *HTTPClient, refresh time.Duration) *Countries {
+	return &Countries{
+		log:     log,
+		client:  client,
+		refresh: refresh,
+	}
+}
+
+// Prefetch starts fetching the visors in the background, so that they are known by the first lookup.
+func (c *Countries) Prefetch() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.startUpdate()
+}
+
+// Country returns the country code of the visor of `pk`, empty if unknown. It returns
+// ErrCountriesUnavailable if the visors have not been fetched yet.
+func (c *Countries) Country(pk cipher.PubKey) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	interval := c.refresh
+	if c.countries == nil {
+		interval = countriesRetryInterval
+	}
+	if c.updated.IsZero() || time.Since(c.updated) > interval {
+		c.startUpdate()
+	}
+
+	if c.countries == nil {
+		return "", ErrCountriesUnavailable
+	}
+	return c.countries[pk], nil
+}
+
+// startUpdate fetches the visors in the background, unless they are being fetched already.
+// It is called with mu held.
+func (c *Countries) startUpdate() {
+	if c.updating {
+		return
+	}
+	c.updating = true
+	go c.update()
+}
+
+func (c *Countries) update() {
+	ctx, cancel := context.WithTimeout(context.Background(), countriesFetchTimeout)
+	services, err := c.client.Services(ctx, 0, "", "")
+	cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.updating = false
+	c.updated = time.Now()
+	if err != nil {
+		// the visors fetched before, if any, are kept
+		c.log.WithError(err).Warn("Failed to fetch the countries of the visors.")
+		return
+	}
+
+	countries := make(map[cipher.PubKey]string, len(services))
+	for _, service := range services {
+		if service.Geo != nil && service.Geo.Country != "" {
+			countries[service.Addr.PubKey()] = service.Geo.Country
+		}
+	}
+	c.countries = countries
+}
@@ -0,0 +1,50 @@
+// Package servicedisc pkg/servicedisc/countries_test.go
+package servicedisc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/geo"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+)
+
+func TestCountries(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	unknown, _ := cipher.GenerateKeyPair()
+
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		services := []Service{{Addr: NewSWAddr(pk, 0), Type: ServiceTypeVisor, Geo: &geo.LocationData{Country: "DE"}}}
+		require.NoError(t, json.NewEncoder(w).Encode(services))
+	}))
+	defer srv.Close()
+
+	log := logging.MustGetLogger("countries")
+	client := NewClient(log, nil, Config{Type: ServiceTypeVisor, DiscAddr: srv.URL}, srv.Client(), "")
+	c := NewCountries(log, client, time.Hour)
+	c.Prefetch()
+
+	// lookups don't wait for the fetch
+	start := time.Now()
+	_, err := c.Country(pk)
+	require.ErrorIs(t, err, ErrCountriesUnavailable)
+	require.Less(t, time.Since(start), time.Second)
+
+	close(release)
+	require.Eventually(t, func() bool {
+		country, err := c.Country(pk)
+		return err == nil && country == "DE"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	country, err := c.Country(unknown)
+	require.NoError(t, err)
+	require.Empty(t, country)
+}
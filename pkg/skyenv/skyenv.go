@@ -76,6 +76,10 @@ const (
 	TransportRPCTimeout = 1 * time.Minute  // TransportRPCTimeout ...
 	UpdateRPCTimeout    = 6 * time.Hour    // UpdateRPCTimeout update requires huge timeout
 
+	// Metrics constants
+
+	MetricsAddr = "localhost:9110" // MetricsAddr ...
+
 	// Default skywire app server and discovery constants
 
 	AppSrvAddr                = "localhost:5505" // AppSrvAddr ...
@@ -85,8 +89,9 @@ const (
 
 	// Routing constants
 
-	TpLogStore = "transport_logs" // TpLogStore ...
-	Custom     = "custom"         // Custom ...
+	TpLogStore   = "transport_logs"     // TpLogStore ...
+	Custom       = "custom"             // Custom ...
+	RoutingTable = "routing_table.json" // RoutingTable ...
 
 	// LocalPath constants
 	LocalPath = "./local"
@@ -111,6 +116,9 @@ const (
 
 	// NodeInfo is the name of the survey file
 	NodeInfo string = "node-info.json"
+
+	// AuditLog is the name of the audit log of the mutating actions performed on the visor
+	AuditLog string = "audit.log"
 )
 
 // SkywireConfig returns the full path to the package config
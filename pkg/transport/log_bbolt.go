@@ -0,0 +1,372 @@
+// Package transport pkg/transport/log_bbolt.go
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/transport/network"
+)
+
+const (
+	// BBoltLogStoreFile is the name of the bbolt transport log store within the log store location.
+	BBoltLogStoreFile = "transport_logs.db"
+	// logSeriesInterval is the resolution of the time series of the bbolt transport log store.
+	logSeriesInterval = time.Hour
+)
+
+var (
+	logTransportsBucket = []byte("transports")
+	logSeriesBucket     = []byte("series")
+)
+
+// ErrLogStoreNotQueryable is returned on querying a log store not keeping time series.
+var ErrLogStoreNotQueryable = errors.New("transport log store does not support queries")
+
+// LogGroupBy is a dimension the transport logs are aggregated by.
+type LogGroupBy string
+
+// LogGroupBy values.
+const (
+	LogGroupByPeer      LogGroupBy = "peer"
+	LogGroupByType      LogGroupBy = "type"
+	LogGroupByTransport LogGroupBy = "transport"
+)
+
+// LogQuery selects and aggregates the traffic recorded by a QueryableLogStore.
+type LogQuery struct {
+	// From and To limit the time range, To defaults to now.
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// RemotePK selects the transports of a single peer, null selects all of them.
+	RemotePK cipher.PubKey `json:"remote_pk"`
+	// Type selects the transports of a single type, empty selects all of them.
+	Type network.Type `json:"type,omitempty"`
+	// GroupBy lists the dimensions to aggregate by, the traffic is summed up into a single row if empty.
+	GroupBy []LogGroupBy `json:"group_by,omitempty"`
+}
+
+// LogStats is the traffic aggregated for a LogQuery.
+// The fields not grouped by are left zero.
+type LogStats struct {
+	TpID      uuid.UUID     `json:"tp_id"`
+	RemotePK  cipher.PubKey `json:"remote_pk"`
+	Type      network.Type  `json:"type"`
+	RecvBytes uint64        `json:"recv"`
+	SentBytes uint64        `json:"sent"`
+}
+
+// QueryableLogStore is a LogStore keeping time series of the traffic per transport and peer.
+type QueryableLogStore interface {
+	LogStore
+	// RecordTransport records `entry` along with the remote edge and type of the transport.
+	RecordTransport(id uuid.UUID, remote cipher.PubKey, tpType network.Type, entry *LogEntry) error
+	// Stats aggregates the traffic selected by `q`.
+	Stats(q LogQuery) ([]LogStats, error)
+}
+
+// bboltLogRecord holds the latest totals of a transport, the series keep the differences between them.
+type bboltLogRecord struct {
+	RemotePK  cipher.PubKey `json:"remote_pk"`
+	Type      network.Type  `json:"type"`
+	RecvBytes uint64        `json:"recv"`
+	SentBytes uint64        `json:"sent"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// bboltLogPoint is the traffic of a transport within a single series interval.
+type bboltLogPoint struct {
+	RemotePK  cipher.PubKey `json:"remote_pk"`
+	Type      network.Type  `json:"type"`
+	RecvBytes uint64        `json:"recv"`
+	SentBytes uint64        `json:"sent"`
+}
+
+type bboltTransportLogStore struct {
+	db  *bbolt.DB
+	log *logging.Logger
+}
+
+// BBoltTransportLogStore implements QueryableLogStore persisted in a bbolt database within `dir`.
+// Series older than the rotation interval are removed.
+func BBoltTransportLogStore(ctx context.Context, dir string, rInterval time.Duration, log *logging.Logger) (QueryableLogStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil { //nolint
+		return nil, err
+	}
+
+	db, err := bbolt.Open(filepath.Join(dir, BBoltLogStoreFile), 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transport log store: %w", err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{logTransportsBucket, logSeriesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bLogStore := &bboltTransportLogStore{
+		db:  db,
+		log: log,
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Hour * 5)
+		defer ticker.Stop()
+		bLogStore.cleanLogs(rInterval)
+		for {
+			select {
+			case <-ctx.Done():
+				if err := db.Close(); err != nil {
+					log.WithError(err).Errorln("Failed to close transport log store")
+				}
+				return
+			case <-ticker.C:
+				bLogStore.cleanLogs(rInterval)
+			}
+		}
+	}()
+
+	return bLogStore, nil
+}
+
+func (tls *bboltTransportLogStore) Entry(tpID uuid.UUID) (*LogEntry, error) {
+	var entry *LogEntry
+	err := tls.db.View(func(tx *bbolt.Tx) error {
+		raw := tx.Bucket(logTransportsBucket).Get(tpID[:])
+		if raw == nil {
+			return nil
+		}
+		var rec bboltLogRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return err
+		}
+		entry = NewLogEntry()
+		entry.AddRecv(rec.RecvBytes)
+		entry.AddSent(rec.SentBytes)
+		return nil
+	})
+
+	return entry, err
+}
+
+func (tls *bboltTransportLogStore) Record(tpID uuid.UUID, lEntry *LogEntry) error {
+	return tls.RecordTransport(tpID, cipher.PubKey{}, "", lEntry)
+}
+
+func (tls *bboltTransportLogStore) RecordTransport(tpID uuid.UUID, remote cipher.PubKey, tpType network.Type, lEntry *LogEntry) error {
+	recv, sent := loadLogEntry(lEntry)
+	now := time.Now().UTC()
+
+	return tls.db.Update(func(tx *bbolt.Tx) error {
+		tpsB := tx.Bucket(logTransportsBucket)
+
+		var rec bboltLogRecord
+		if raw := tpsB.Get(tpID[:]); raw != nil {
+			if err := json.Unmarshal(raw, &rec); err != nil {
+				return err
+			}
+		}
+		if !remote.Null() {
+			rec.RemotePK = remote
+		}
+		if tpType != "" {
+			rec.Type = tpType
+		}
+		// the entry restarts from zero if it was reset
+		dRecv, dSent := recv, sent
+		if recv >= rec.RecvBytes && sent >= rec.SentBytes {
+			dRecv, dSent = recv-rec.RecvBytes, sent-rec.SentBytes
+		}
+		rec.RecvBytes, rec.SentBytes, rec.UpdatedAt = recv, sent, now
+
+		raw, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if err := tpsB.Put(tpID[:], raw); err != nil {
+			return err
+		}
+		if dRecv == 0 && dSent == 0 {
+			return nil
+		}
+
+		seriesB := tx.Bucket(logSeriesBucket)
+		key := logSeriesKey(now, tpID)
+		var point bboltLogPoint
+		if raw := seriesB.Get(key); raw != nil {
+			if err := json.Unmarshal(raw, &point); err != nil {
+				return err
+			}
+		}
+		point.RemotePK, point.Type = rec.RemotePK, rec.Type
+		point.RecvBytes += dRecv
+		point.SentBytes += dSent
+
+		if raw, err = json.Marshal(point); err != nil {
+			return err
+		}
+		return seriesB.Put(key, raw)
+	})
+}
+
+func (tls *bboltTransportLogStore) Stats(q LogQuery) ([]LogStats, error) {
+	to := q.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	group := make(map[LogGroupBy]bool, len(q.GroupBy))
+	for _, g := range q.GroupBy {
+		switch g {
+		case LogGroupByPeer, LogGroupByType, LogGroupByTransport:
+			group[g] = true
+		default:
+			return nil, fmt.Errorf("invalid group by %q", g)
+		}
+	}
+
+	stats := make(map[LogStats]*LogStats)
+	err := tls.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(logSeriesBucket).Cursor()
+		end := logSeriesSlot(to)
+		for k, v := c.Seek(logSeriesSlot(q.From)); k != nil && string(k[:8]) <= string(end); k, v = c.Next() {
+			var point bboltLogPoint
+			if err := json.Unmarshal(v, &point); err != nil {
+				return err
+			}
+			if !q.RemotePK.Null() && point.RemotePK != q.RemotePK {
+				continue
+			}
+			if q.Type != "" && point.Type != q.Type {
+				continue
+			}
+
+			var key LogStats
+			if group[LogGroupByTransport] {
+				copy(key.TpID[:], k[8:])
+			}
+			if group[LogGroupByPeer] {
+				key.RemotePK = point.RemotePK
+			}
+			if group[LogGroupByType] {
+				key.Type = point.Type
+			}
+			s, ok := stats[key]
+			if !ok {
+				s = &LogStats{TpID: key.TpID, RemotePK: key.RemotePK, Type: key.Type}
+				stats[key] = s
+			}
+			s.RecvBytes += point.RecvBytes
+			s.SentBytes += point.SentBytes
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]LogStats, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if ti, tj := result[i].RecvBytes+result[i].SentBytes, result[j].RecvBytes+result[j].SentBytes; ti != tj {
+			return ti > tj
+		}
+		if result[i].RemotePK != result[j].RemotePK {
+			return result[i].RemotePK.Hex() < result[j].RemotePK.Hex()
+		}
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].TpID.String() < result[j].TpID.String()
+	})
+
+	return result, nil
+}
+
+// cleanLogs removes the series and transports older than the given log rotation interval
+func (tls *bboltTransportLogStore) cleanLogs(rInterval time.Duration) {
+	before := time.Now().UTC().Add(-rInterval)
+	err := tls.db.Update(func(tx *bbolt.Tx) error {
+		// the keys are collected first, deleting while iterating makes the cursor skip the next key
+		var expired [][]byte
+		seriesB := tx.Bucket(logSeriesBucket)
+		c := seriesB.Cursor()
+		end := logSeriesSlot(before)
+		for k, _ := c.First(); k != nil && string(k[:8]) < string(end); k, _ = c.Next() {
+			expired = append(expired, k)
+		}
+		for _, k := range expired {
+			if err := seriesB.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		expired = expired[:0]
+		tpsB := tx.Bucket(logTransportsBucket)
+		err := tpsB.ForEach(func(k, v []byte) error {
+			var rec bboltLogRecord
+			if err := json.Unmarshal(v, &rec); err == nil && !rec.UpdatedAt.Before(before) {
+				return nil
+			}
+			expired = append(expired, k)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := tpsB.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		tls.log.WithError(err).Warn("Failed to clean transport logs")
+	}
+}
+
+// logSeriesSlot returns the key prefix of the series interval containing `t`.
+func logSeriesSlot(t time.Time) []byte {
+	if t.Before(time.Unix(0, 0)) {
+		t = time.Unix(0, 0)
+	}
+	return binary.BigEndian.AppendUint64(nil, uint64(t.Truncate(logSeriesInterval).Unix()))
+}
+
+func logSeriesKey(t time.Time, tpID uuid.UUID) []byte {
+	return append(logSeriesSlot(t), tpID[:]...)
+}
+
+func loadLogEntry(le *LogEntry) (recv, sent uint64) {
+	if le == nil {
+		return 0, 0
+	}
+	if le.RecvBytes != nil {
+		recv = atomic.LoadUint64(le.RecvBytes)
+	}
+	if le.SentBytes != nil {
+		sent = atomic.LoadUint64(le.SentBytes)
+	}
+	return recv, sent
+}
@@ -0,0 +1,53 @@
+// Package transport pkg/transport/log_bbolt_test.go
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/transport/network"
+)
+
+func TestBBoltTransportLogStore_cleanLogs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ls, err := BBoltTransportLogStore(ctx, t.TempDir(), time.Hour*24*7, logging.MustGetLogger("transport"))
+	require.NoError(t, err)
+	tls := ls.(*bboltTransportLogStore)
+
+	pk, _ := cipher.GenerateKeyPair()
+	ids := make([]uuid.UUID, 10)
+	for i := range ids {
+		ids[i] = uuid.New()
+		entry := NewLogEntry()
+		entry.AddSent(uint64(i + 1))
+		require.NoError(t, ls.RecordTransport(ids[i], pk, network.DMSG, entry))
+	}
+
+	// the recent transports are kept
+	tls.cleanLogs(time.Hour)
+	for _, id := range ids {
+		entry, err := ls.Entry(id)
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+	}
+
+	// every expired transport is removed, consecutive keys included
+	tls.cleanLogs(-time.Hour)
+	for _, id := range ids {
+		entry, err := ls.Entry(id)
+		require.NoError(t, err)
+		require.Nil(t, entry)
+	}
+
+	stats, err := ls.Stats(LogQuery{})
+	require.NoError(t, err)
+	require.Empty(t, stats)
+}
@@ -13,8 +13,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
 	"github.com/skycoin/skywire-utilities/pkg/logging"
 	"github.com/skycoin/skywire/pkg/transport"
+	"github.com/skycoin/skywire/pkg/transport/network"
 )
 
 func testTransportLogStore(t *testing.T, logStore transport.LogStore) {
@@ -57,6 +59,59 @@ func TestFileTransportLogStore(t *testing.T) {
 	testTransportLogStore(t, ls)
 }
 
+func TestBBoltTransportLogStore(t *testing.T) {
+	dir, err := os.MkdirTemp("", "log_store")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, os.RemoveAll(dir))
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logging.MustGetLogger("transport")
+	ls, err := transport.BBoltTransportLogStore(ctx, dir, time.Hour*24*7, log)
+	require.NoError(t, err)
+	testTransportLogStore(t, ls)
+
+	pk1, _ := cipher.GenerateKeyPair()
+	pk2, _ := cipher.GenerateKeyPair()
+	id1, id2, id3 := uuid.New(), uuid.New(), uuid.New()
+
+	entry1 := transport.NewLogEntry()
+	entry1.AddRecv(100)
+	require.NoError(t, ls.RecordTransport(id1, pk1, network.STCPR, entry1))
+	entry1.AddSent(50)
+	require.NoError(t, ls.RecordTransport(id1, pk1, network.STCPR, entry1))
+
+	entry2 := transport.NewLogEntry()
+	entry2.AddRecv(10)
+	entry2.AddSent(20)
+	require.NoError(t, ls.RecordTransport(id2, pk1, network.DMSG, entry2))
+
+	entry3 := transport.NewLogEntry()
+	entry3.AddSent(1)
+	require.NoError(t, ls.RecordTransport(id3, pk2, network.DMSG, entry3))
+
+	stats, err := ls.Stats(transport.LogQuery{RemotePK: pk1, GroupBy: []transport.LogGroupBy{transport.LogGroupByPeer}})
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, pk1, stats[0].RemotePK)
+	assert.Equal(t, uint64(110), stats[0].RecvBytes)
+	assert.Equal(t, uint64(70), stats[0].SentBytes)
+
+	stats, err = ls.Stats(transport.LogQuery{GroupBy: []transport.LogGroupBy{transport.LogGroupByType}})
+	require.NoError(t, err)
+	require.Len(t, stats, 3) // entries recorded without type are grouped together
+	assert.Equal(t, network.STCPR, stats[1].Type)
+	assert.Equal(t, network.DMSG, stats[2].Type)
+	assert.Equal(t, uint64(21), stats[2].SentBytes)
+
+	stats, err = ls.Stats(transport.LogQuery{To: time.Now().Add(-2 * time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+}
+
 func TestLogEntry_MarshalJSON(t *testing.T) {
 	entry := transport.NewLogEntry()
 	entry.AddSent(10)
@@ -461,7 +461,13 @@ func (mt *ManagedTransport) recordLog() {
 	mt.logMx.Lock()
 	defer mt.logMx.Unlock()
 
-	if err := mt.ls.Record(mt.Entry.ID, mt.LogEntry); err != nil {
+	var err error
+	if qls, ok := mt.ls.(QueryableLogStore); ok {
+		err = qls.RecordTransport(mt.Entry.ID, mt.rPK, mt.Type(), mt.LogEntry)
+	} else {
+		err = mt.ls.Record(mt.Entry.ID, mt.LogEntry)
+	}
+	if err != nil {
 		mt.log.WithError(err).Warn("Failed to record log entry.")
 	}
 }
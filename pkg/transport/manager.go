@@ -38,6 +38,13 @@ type ManagerConfig struct {
 	LogStore                  LogStore
 	PersistentTransportsCache []PersistentTransports
 	PTpsCacheMu               sync.RWMutex
+	Callbacks                 *ManagerCallbacks
+}
+
+// ManagerCallbacks contains callbacks which a Manager uses to notify about its transports.
+type ManagerCallbacks struct {
+	OnTransportUp   func(entry Entry)
+	OnTransportDown func(entry Entry)
 }
 
 // Manager manages Transports.
@@ -304,6 +311,7 @@ func (tm *Manager) acceptTransport(ctx context.Context, lis network.Listener) er
 
 		go func() {
 			mTp.Serve(tm.readCh)
+			tm.onTransportDown(mTp)
 
 			tm.mx.Lock()
 			delete(tm.tps, mTp.Entry.ID)
@@ -318,6 +326,9 @@ func (tm *Manager) acceptTransport(ctx context.Context, lis network.Listener) er
 	if err := mTp.Accept(ctx, transport); err != nil {
 		return err
 	}
+	if !ok {
+		tm.onTransportUp(mTp)
+	}
 
 	tm.Logger.Debugf("accepted tp: type(%s) remote(%s) tpID(%s) new(%v)", lis.Network(), transport.RemotePK(), tpID, !ok)
 	return nil
@@ -438,14 +449,30 @@ func (tm *Manager) saveTransport(ctx context.Context, remote cipher.PubKey, netT
 		}
 		return nil, err
 	}
-	go mTp.Serve(tm.readCh)
+	go func() {
+		mTp.Serve(tm.readCh)
+		tm.onTransportDown(mTp)
+	}()
 	tm.mx.Lock()
 	tm.tps[tpID] = mTp
 	tm.mx.Unlock()
+	tm.onTransportUp(mTp)
 	tm.Logger.Debugf("saved transport: remote(%s) type(%s) tpID(%s)", remote, netType, tpID)
 	return mTp, nil
 }
 
+func (tm *Manager) onTransportUp(mTp *ManagedTransport) {
+	if tm.Conf != nil && tm.Conf.Callbacks != nil && tm.Conf.Callbacks.OnTransportUp != nil {
+		tm.Conf.Callbacks.OnTransportUp(mTp.Entry)
+	}
+}
+
+func (tm *Manager) onTransportDown(mTp *ManagedTransport) {
+	if tm.Conf != nil && tm.Conf.Callbacks != nil && tm.Conf.Callbacks.OnTransportDown != nil {
+		tm.Conf.Callbacks.OnTransportDown(mTp.Entry)
+	}
+}
+
 // STCPRRemoteAddrs gets remote IPs for all known STCPR transports.
 func (tm *Manager) STCPRRemoteAddrs() []string {
 	var addrs []string
@@ -56,6 +56,7 @@ type Error struct {
 // APIClient implements address resolver API client.
 type APIClient interface {
 	BindSTCPR(ctx context.Context, port string) error
+	STCPRPort(ctx context.Context) (string, error)
 	BindSUDPH(filter *pfilter.PacketFilter, handshake Handshake) (<-chan RemoteVisor, error)
 	Resolve(ctx context.Context, netType string, pk cipher.PubKey) (VisorData, error)
 	Transports(ctx context.Context) (map[cipher.PubKey][]string, error)
@@ -84,6 +85,9 @@ type httpClient struct {
 	ready          chan struct{}
 	closed         chan struct{}
 	delBindSudphWg sync.WaitGroup
+	stcprPort      string        // set before stcprBound is closed
+	stcprBound     chan struct{} // closed once STCPR is bound
+	stcprBoundOnce sync.Once
 }
 
 // NewHTTP creates a new client setting a public key to the client to be used for auth.
@@ -114,6 +118,7 @@ func NewHTTP(remoteAddr string, pk cipher.PubKey, sk cipher.SecKey, httpC *http.
 		clientPublicIP: clientPublicIP,
 		ready:          make(chan struct{}),
 		closed:         make(chan struct{}),
+		stcprBound:     make(chan struct{}),
 	}
 
 	client.log.Debugf("Remote UDP server: %q", remoteUDP)
@@ -252,9 +257,27 @@ func (c *httpClient) BindSTCPR(ctx context.Context, port string) error {
 		return fmt.Errorf("status: %d, error: %w", resp.StatusCode, httpauth.ExtractError(resp.Body))
 	}
 
+	c.stcprBoundOnce.Do(func() {
+		c.stcprPort = port
+		close(c.stcprBound)
+	})
+
 	return nil
 }
 
+// STCPRPort returns the port bound by BindSTCPR, waiting until it is bound. Other network types listening
+// on the same port over UDP share the STCPR binding.
+func (c *httpClient) STCPRPort(ctx context.Context) (string, error) {
+	select {
+	case <-c.stcprBound:
+		return c.stcprPort, nil
+	case <-c.closed:
+		return "", ErrNotReady
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
 // delBindSTCPR uinbinds STCPR entry PK to IP:port on address resolver.
 func (c *httpClient) delBindSTCPR(ctx context.Context) error {
 	log := c.log.WithField("func", "httpClient.delBindSTCPR")
@@ -109,6 +109,50 @@ func (_m *MockAPIClient) Resolve(ctx context.Context, netType string, pk cipher.
 	return r0, r1
 }
 
-func (_m *MockAPIClient) Addresses(ctx context.Context) (string, error) {
-	return "", nil
+func (_m *MockAPIClient) Addresses(ctx context.Context) string {
+	return ""
+}
+
+// STCPRPort provides a mock function with given fields: ctx
+func (_m *MockAPIClient) STCPRPort(ctx context.Context) (string, error) {
+	ret := _m.Called(ctx)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context) string); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Transports provides a mock function with given fields: ctx
+func (_m *MockAPIClient) Transports(ctx context.Context) (map[cipher.PubKey][]string, error) {
+	ret := _m.Called(ctx)
+
+	var r0 map[cipher.PubKey][]string
+	if rf, ok := ret.Get(0).(func(context.Context) map[cipher.PubKey][]string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[cipher.PubKey][]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
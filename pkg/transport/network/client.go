@@ -55,10 +55,14 @@ type ClientFactory struct {
 	SK         cipher.SecKey
 	ListenAddr string
 	PKTable    stcp.PKTable
-	ARClient   addrresolver.APIClient
-	EB         *appevent.Broadcaster
-	DmsgC      *dmsg.Client
-	MLogger    *logging.MasterLogger
+	WSSConfig  *WSSConfig
+	// Obfuscation obfuscates the dialed transports if set,
+	// obfuscated transports are always accepted
+	Obfuscation *ObfuscationConfig
+	ARClient    addrresolver.APIClient
+	EB          *appevent.Broadcaster
+	DmsgC       *dmsg.Client
+	MLogger     *logging.MasterLogger
 }
 
 // MakeClient creates a new client of specified type
@@ -81,6 +85,7 @@ func (f *ClientFactory) MakeClient(netType Type, port int) (Client, error) {
 	generic.lPK = f.PK
 	generic.lSK = f.SK
 	generic.listenAddr = f.ListenAddr
+	generic.obfs = f.Obfuscation
 
 	resolved := &resolvedClient{genericClient: generic, ar: f.ARClient}
 
@@ -91,6 +96,10 @@ func (f *ClientFactory) MakeClient(netType Type, port int) (Client, error) {
 		return newStcpr(resolved, port), nil
 	case SUDPH:
 		return newSudph(resolved, port), nil
+	case QUIC:
+		return newQuic(resolved)
+	case WSS:
+		return newWss(generic, f.WSSConfig), nil
 	case DMSG:
 		return newDmsgClient(f.DmsgC), nil
 	}
@@ -109,6 +118,7 @@ type genericClient struct {
 	lSK        cipher.SecKey
 	listenAddr string
 	netType    Type
+	obfs       *ObfuscationConfig
 
 	log    *logging.Logger
 	mLog   *logging.MasterLogger
@@ -123,16 +133,48 @@ type genericClient struct {
 	closeOnce     sync.Once
 }
 
+// dialTransport dials a connection with `dial` and performs the handshakes of the transport over it.
+// Visors which don't support obfuscation are dialed again to perform a plain handshake, if the
+// obfuscation config allows it.
+func (c *genericClient) dialTransport(ctx context.Context, dial func(context.Context) (net.Conn, error), rPK cipher.PubKey, rPort uint16) (*transport, error) {
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tp, err := c.initTransport(ctx, conn, rPK, rPort, c.obfs)
+	if !errors.Is(err, ErrNotObfuscated) || !c.obfs.AllowPlain {
+		return tp, err
+	}
+
+	c.log.WithError(err).Warnf("Falling back to a plain handshake with %v", rPK)
+	if conn, err = dial(ctx); err != nil {
+		return nil, err
+	}
+	return c.initTransport(ctx, conn, rPK, rPort, nil)
+}
+
 // initTransport will initialize skywire transport over opened raw connection to
 // the remote client
-// The process will perform handshake over raw connection
-func (c *genericClient) initTransport(ctx context.Context, conn net.Conn, rPK cipher.PubKey, rPort uint16) (*transport, error) {
+// The process will perform handshake over raw connection, obfuscated if `obfs` is set
+func (c *genericClient) initTransport(ctx context.Context, conn net.Conn, rPK cipher.PubKey, rPort uint16, obfs *ObfuscationConfig) (*transport, error) {
 	lPort, freePort, err := c.porter.ReserveEphemeral(ctx)
 	if err != nil {
 		return nil, err
 	}
 	lAddr, rAddr := dmsg.Addr{PK: c.lPK, Port: lPort}, dmsg.Addr{PK: rPK, Port: rPort}
 	remoteAddr := conn.RemoteAddr()
+	if obfs != nil {
+		c.log.Debugf("Obfuscating connection to %v", remoteAddr)
+		obfsConn, err := obfuscate(conn, rPK, *obfs)
+		if err != nil {
+			freePort()
+			if err := conn.Close(); err != nil {
+				c.log.WithError(err).Warn("Failed to close connection")
+			}
+			return nil, err
+		}
+		conn = obfsConn
+	}
 	c.log.Debugf("Performing handshake with %v", remoteAddr)
 	hs := handshake.InitiatorHandshake(c.lSK, lAddr, rAddr)
 	return c.wrapTransport(conn, hs, true, freePort)
@@ -198,6 +240,17 @@ func (c *genericClient) acceptTransport() error {
 	remoteAddr := conn.RemoteAddr()
 	c.log.Debugf("Accepted connection from %v", remoteAddr)
 
+	rawConn := conn
+	if conn, err = acceptObfuscated(rawConn, c.lPK); err != nil {
+		if err := rawConn.Close(); err != nil {
+			c.log.WithError(err).Warn("Failed to close connection")
+		}
+		if errors.Is(err, io.EOF) {
+			return err
+		}
+		return handshake.Error(err.Error())
+	}
+
 	onClose := func() {}
 	hs := handshake.ResponderHandshake(handshake.MakeF2PortChecker(c.checkListener))
 	wrappedTransport, err := c.wrapTransport(conn, hs, false, onClose)
@@ -323,7 +376,12 @@ type dialFunc func(ctx context.Context, addr string) (net.Conn, error)
 // and dials that visor address(es)
 // dial process is specific to transport type and is provided by the client
 func (c *resolvedClient) dialVisor(ctx context.Context, rPK cipher.PubKey, dial dialFunc) (net.Conn, error) {
-	visorData, err := c.ar.Resolve(ctx, string(c.netType), rPK)
+	return c.dialVisorAs(ctx, c.netType, rPK, dial)
+}
+
+// dialVisorAs dials the visor of `rPK` at the address resolved for the `netType` binding.
+func (c *resolvedClient) dialVisorAs(ctx context.Context, netType Type, rPK cipher.PubKey, dial dialFunc) (net.Conn, error) {
+	visorData, err := c.ar.Resolve(ctx, string(netType), rPK)
 	if err != nil {
 		return nil, fmt.Errorf("resolve PK: %w", err)
 	}
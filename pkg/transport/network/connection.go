@@ -4,6 +4,7 @@ package network
 import (
 	"fmt"
 	"net"
+	"sync/atomic"
 	"time"
 
 	"github.com/skycoin/dmsg/pkg/dmsg"
@@ -16,6 +17,14 @@ import (
 
 const encryptHSTimout = 5 * time.Second
 
+// handshakeFailures is the number of failed transport handshakes.
+var handshakeFailures uint64
+
+// HandshakeFailures returns the number of failed transport handshakes since the process started.
+func HandshakeFailures() uint64 {
+	return atomic.LoadUint64(&handshakeFailures)
+}
+
 // Transport represents a network connection between two visors in skywire network
 // This transport wraps raw network connection and is ready to use for sending data.
 // It also provides skywire-specific methods on top of net.Conn
@@ -63,6 +72,7 @@ func DoHandshake(rawConn net.Conn, hs handshake.Handshake, netType Type, log *lo
 func doHandshake(rawConn net.Conn, hs handshake.Handshake, netType Type, log *logging.Logger) (*transport, error) {
 	lAddr, rAddr, err := hs(rawConn, time.Now().Add(handshake.Timeout))
 	if err != nil {
+		atomic.AddUint64(&handshakeFailures, 1)
 		if err := rawConn.Close(); err != nil {
 			log.WithError(err).Warnf("Failed to close connection")
 		}
@@ -0,0 +1,289 @@
+// Package lan discovers visors on the local network segment, so that STCP transports
+// can be established to them without filling in the STCP PK table by hand.
+package lan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/transport/network/stcp"
+)
+
+const (
+	// DefaultGroupAddr is the UDP multicast group visors announce themselves on.
+	DefaultGroupAddr = "239.255.76.67:7776"
+	// DefaultInterval is the interval between announcements.
+	DefaultInterval = 10 * time.Second
+	// expiryIntervals is the number of missed announcements after which a peer is forgotten.
+	expiryIntervals = 3
+	// maxAnnouncementSize is the largest announcement read from the group.
+	maxAnnouncementSize = 1024
+)
+
+var (
+	// ErrInvalidAnnouncement is returned when an announcement can't be decoded or its signature doesn't match.
+	ErrInvalidAnnouncement = errors.New("invalid LAN announcement")
+	// ErrStaleAnnouncement is returned when the timestamp of an announcement is out of the accepted window,
+	// or not newer than the one of the last announcement of the visor, as replayed announcements are.
+	ErrStaleAnnouncement = errors.New("stale LAN announcement")
+	// ErrAddrMismatch is returned when an announcement is received from another IP than the signed one.
+	ErrAddrMismatch = errors.New("LAN announcement received from another address")
+)
+
+// Config configures LAN discovery.
+type Config struct {
+	PK cipher.PubKey
+	SK cipher.SecKey
+	// Port is the STCP port announced to the other visors.
+	Port uint16
+	// GroupAddr is the multicast group to announce on and listen to, defaults to DefaultGroupAddr.
+	GroupAddr string
+	// Interval is the interval between announcements, defaults to DefaultInterval.
+	Interval time.Duration
+}
+
+// Peer is a visor discovered on the local network.
+type Peer struct {
+	PK       cipher.PubKey `json:"pk"`
+	Addr     string        `json:"addr"`
+	LastSeen time.Time     `json:"last_seen"`
+
+	timestamp int64 // of the last announcement
+}
+
+// announcement is the signed message visors multicast to the group. The IP the announcement is sent
+// from is signed, so that it can't be replayed from another host.
+type announcement struct {
+	PK        cipher.PubKey `json:"pk"`
+	IP        string        `json:"ip"`
+	Port      uint16        `json:"port"`
+	Timestamp int64         `json:"timestamp"`
+	Sig       cipher.Sig    `json:"sig"`
+}
+
+func (a announcement) payload() ([]byte, error) {
+	a.Sig = cipher.Sig{}
+	return json.Marshal(a)
+}
+
+func makeAnnouncement(pk cipher.PubKey, sk cipher.SecKey, ip net.IP, port uint16, now time.Time) ([]byte, error) {
+	a := announcement{PK: pk, IP: ip.String(), Port: port, Timestamp: now.UnixNano()}
+	payload, err := a.payload()
+	if err != nil {
+		return nil, err
+	}
+	if a.Sig, err = cipher.SignPayload(payload, sk); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(a)
+}
+
+func parseAnnouncement(data []byte) (announcement, error) {
+	var a announcement
+	if err := json.Unmarshal(data, &a); err != nil {
+		return a, ErrInvalidAnnouncement
+	}
+	payload, err := a.payload()
+	if err != nil {
+		return a, err
+	}
+	if err := cipher.VerifyPubKeySignedPayload(a.PK, a.Sig, payload); err != nil {
+		return a, ErrInvalidAnnouncement
+	}
+
+	return a, nil
+}
+
+// Discovery announces the visor on the local network and records the visors announcing themselves
+// in a STCP PK table.
+type Discovery struct {
+	conf   Config
+	table  stcp.DynamicPKTable
+	log    *logging.Logger
+	onPeer func(Peer)
+
+	mu    sync.Mutex
+	peers map[cipher.PubKey]*Peer
+}
+
+// New creates a Discovery filling `table`. `onPeer` is called, if not nil, whenever a peer
+// is discovered or its address changes.
+func New(conf Config, table stcp.DynamicPKTable, log *logging.Logger, onPeer func(Peer)) *Discovery {
+	if conf.GroupAddr == "" {
+		conf.GroupAddr = DefaultGroupAddr
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = DefaultInterval
+	}
+
+	return &Discovery{
+		conf:   conf,
+		table:  table,
+		log:    log,
+		onPeer: onPeer,
+		peers:  make(map[cipher.PubKey]*Peer),
+	}
+}
+
+// Serve announces the visor and listens to the announcements of the others until `ctx` is done.
+func (d *Discovery) Serve(ctx context.Context) error {
+	group, err := net.ResolveUDPAddr("udp4", d.conf.GroupAddr)
+	if err != nil {
+		return err
+	}
+	lis, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		if err := lis.Close(); err != nil {
+			d.log.WithError(err).Debug("Failed to close LAN discovery listener")
+		}
+	}()
+
+	go d.announce(ctx, group)
+
+	buf := make([]byte, maxAnnouncementSize)
+	for {
+		n, src, err := lis.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := d.handle(buf[:n], src.IP, time.Now()); err != nil {
+			d.log.WithError(err).Debugf("Dropping announcement from %v", src)
+		}
+	}
+}
+
+func (d *Discovery) announce(ctx context.Context, group *net.UDPAddr) {
+	conn, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		d.log.WithError(err).Warn("Failed to dial LAN discovery group")
+		return
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			d.log.WithError(err).Debug("Failed to close LAN discovery announcer")
+		}
+	}()
+
+	ip := conn.LocalAddr().(*net.UDPAddr).IP
+
+	ticker := time.NewTicker(d.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		msg, err := makeAnnouncement(d.conf.PK, d.conf.SK, ip, d.conf.Port, time.Now())
+		if err != nil {
+			d.log.WithError(err).Error("Failed to make LAN announcement")
+			return
+		}
+		if _, err := conn.Write(msg); err != nil {
+			d.log.WithError(err).Debug("Failed to send LAN announcement")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.expire(now)
+		}
+	}
+}
+
+// handle records the peer announcing itself in `data`, received from `ip`.
+func (d *Discovery) handle(data []byte, ip net.IP, now time.Time) error {
+	a, err := parseAnnouncement(data)
+	if err != nil {
+		return err
+	}
+	if a.PK == d.conf.PK {
+		return nil
+	}
+	if age := now.Sub(time.Unix(0, a.Timestamp)); age > d.ttl() || age < -d.ttl() {
+		return ErrStaleAnnouncement
+	}
+	if !ip.Equal(net.ParseIP(a.IP)) {
+		return ErrAddrMismatch
+	}
+
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(int(a.Port)))
+
+	d.mu.Lock()
+	peer, ok := d.peers[a.PK]
+	if ok && a.Timestamp <= peer.timestamp {
+		d.mu.Unlock()
+		return ErrStaleAnnouncement
+	}
+	changed := !ok || peer.Addr != addr
+	if !ok {
+		peer = &Peer{PK: a.PK}
+		d.peers[a.PK] = peer
+	}
+	peer.Addr = addr
+	peer.LastSeen = now
+	peer.timestamp = a.Timestamp
+	discovered := *peer
+	d.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+	if !d.table.Set(a.PK, addr) {
+		d.log.Debugf("Not using discovered address %s of %s: PK is in the STCP PK table", addr, a.PK)
+		return nil
+	}
+	d.log.Infof("Discovered visor %s at %s", a.PK, addr)
+	if d.onPeer != nil {
+		d.onPeer(discovered)
+	}
+
+	return nil
+}
+
+// expire forgets the peers which stopped announcing themselves.
+func (d *Discovery) expire(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for pk, peer := range d.peers {
+		if now.Sub(peer.LastSeen) > d.ttl() {
+			d.log.Debugf("Visor %s at %s is gone", pk, peer.Addr)
+			delete(d.peers, pk)
+			d.table.Remove(pk)
+		}
+	}
+}
+
+func (d *Discovery) ttl() time.Duration {
+	return expiryIntervals * d.conf.Interval
+}
+
+// Peers returns the discovered peers, sorted by PK.
+func (d *Discovery) Peers() []Peer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	peers := make([]Peer, 0, len(d.peers))
+	for _, peer := range d.peers {
+		peers = append(peers, *peer)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].PK.Hex() < peers[j].PK.Hex()
+	})
+
+	return peers
+}
@@ -0,0 +1,111 @@
+// Package lan pkg/transport/network/lan/lan_test.go
+package lan
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/transport/network/stcp"
+)
+
+func TestAnnouncement(t *testing.T) {
+	pk, sk := cipher.GenerateKeyPair()
+	now := time.Now()
+
+	ip := net.ParseIP("192.168.1.10")
+	msg, err := makeAnnouncement(pk, sk, ip, 7777, now)
+	require.NoError(t, err)
+
+	a, err := parseAnnouncement(msg)
+	require.NoError(t, err)
+	require.Equal(t, pk, a.PK)
+	require.Equal(t, "192.168.1.10", a.IP)
+	require.Equal(t, uint16(7777), a.Port)
+	require.Equal(t, now.UnixNano(), a.Timestamp)
+
+	// signed by another key
+	otherPK, _ := cipher.GenerateKeyPair()
+	forged, err := makeAnnouncement(otherPK, sk, ip, 7777, now)
+	require.NoError(t, err)
+	_, err = parseAnnouncement(forged)
+	require.ErrorIs(t, err, ErrInvalidAnnouncement)
+
+	_, err = parseAnnouncement([]byte("garbage"))
+	require.ErrorIs(t, err, ErrInvalidAnnouncement)
+}
+
+func TestDiscovery(t *testing.T) {
+	pk, sk := cipher.GenerateKeyPair()
+	peerPK, peerSK := cipher.GenerateKeyPair()
+	staticPK, staticSK := cipher.GenerateKeyPair()
+
+	table := stcp.NewDynamicTable(map[cipher.PubKey]string{staticPK: "10.0.0.1:7777"})
+	var discovered []Peer
+	d := New(Config{PK: pk, SK: sk, Port: 7777, Interval: time.Second}, table, logging.MustGetLogger("lan"),
+		func(p Peer) { discovered = append(discovered, p) })
+
+	now := time.Now()
+	ip := net.ParseIP("192.168.1.10")
+
+	// own announcements are ignored
+	msg, err := makeAnnouncement(pk, sk, ip, 7777, now)
+	require.NoError(t, err)
+	require.NoError(t, d.handle(msg, ip, now))
+	require.Empty(t, d.Peers())
+
+	msg, err = makeAnnouncement(peerPK, peerSK, ip, 7778, now)
+	require.NoError(t, err)
+
+	// announcements relayed from another address are rejected
+	require.ErrorIs(t, d.handle(msg, net.ParseIP("192.168.1.66"), now), ErrAddrMismatch)
+	require.Empty(t, discovered)
+
+	require.NoError(t, d.handle(msg, ip, now))
+	require.Len(t, discovered, 1)
+	require.Equal(t, "192.168.1.10:7778", discovered[0].Addr)
+	addr, ok := table.Addr(peerPK)
+	require.True(t, ok)
+	require.Equal(t, "192.168.1.10:7778", addr)
+	gotPK, ok := table.PubKey("192.168.1.10:7778")
+	require.True(t, ok)
+	require.Equal(t, peerPK, gotPK)
+
+	// repeated announcements only refresh the peer
+	later := now.Add(time.Second)
+	replayed := msg
+	msg, err = makeAnnouncement(peerPK, peerSK, ip, 7778, later)
+	require.NoError(t, err)
+	require.NoError(t, d.handle(msg, ip, later))
+	require.Len(t, discovered, 1)
+	require.Equal(t, later, d.Peers()[0].LastSeen)
+
+	// repeated and older announcements are rejected
+	require.ErrorIs(t, d.handle(msg, ip, later), ErrStaleAnnouncement)
+	require.ErrorIs(t, d.handle(replayed, ip, later), ErrStaleAnnouncement)
+
+	// static entries take precedence
+	staticIP := net.ParseIP("192.168.1.11")
+	msg, err = makeAnnouncement(staticPK, staticSK, staticIP, 7777, later)
+	require.NoError(t, err)
+	require.NoError(t, d.handle(msg, staticIP, later))
+	require.Len(t, discovered, 1)
+	addr, ok = table.Addr(staticPK)
+	require.True(t, ok)
+	require.Equal(t, "10.0.0.1:7777", addr)
+
+	// announcements out of the accepted window are rejected
+	require.ErrorIs(t, d.handle(msg, staticIP, later.Add(time.Minute)), ErrStaleAnnouncement)
+
+	d.expire(later.Add(2 * time.Second))
+	require.Len(t, d.Peers(), 2)
+	d.expire(later.Add(4 * time.Second))
+	require.Empty(t, d.Peers())
+	_, ok = table.Addr(peerPK)
+	require.False(t, ok)
+	require.Equal(t, 1, table.Count())
+}
@@ -23,6 +23,11 @@ const (
 	STCP Type = "stcp"
 	// DMSG is a type of a transport that works through an intermediary service
 	DMSG Type = "dmsg"
+	// QUIC is a type of a transport that works via QUIC and resolves addresses using address-resolver service.
+	QUIC Type = "quic"
+	// WSS is a type of a transport that works via WebSocket over TLS, passing through HTTP(S) proxies,
+	// and resolves addresses using WSS URL table.
+	WSS Type = "wss"
 )
 
 //go:generate mockery -name Dialer -case underscore -inpkg
@@ -0,0 +1,318 @@
+// Package network pkg/transport/network/obfuscation.go
+package network
+
+import (
+	"bytes"
+	"crypto/aes"
+	stdcipher "crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/transport/network/handshake"
+)
+
+const (
+	// obfsSeedSize is the size of the random seed opening obfuscated connections.
+	obfsSeedSize = 32
+	// obfsMagic identifies the obfuscation header once decrypted.
+	obfsMagic = 0x736b7977
+	// obfsHeaderSize is the size of the obfuscation header: magic, padding, jitter and preamble length.
+	obfsHeaderSize = 4 + 2 + 2 + 1
+	// obfsReplySize is the size of the reply to the obfuscation header: magic, padding and jitter.
+	obfsReplySize = 4 + 2 + 2
+	// obfsFrameHeaderSize is the size of the header of obfuscated frames: payload and padding lengths.
+	obfsFrameHeaderSize = 2 + 2
+	// obfsMaxPayload is the largest payload carried by a single obfuscated frame.
+	obfsMaxPayload = 1<<16 - 1
+
+	// MaxObfuscationPadding is the largest padding which may be negotiated for obfuscated transports.
+	MaxObfuscationPadding = 1024
+	// MaxObfuscationJitter is the largest write delay which may be negotiated for obfuscated transports.
+	MaxObfuscationJitter = time.Second
+)
+
+// ErrNotObfuscated is returned when the remote doesn't answer with a valid obfuscation header.
+var ErrNotObfuscated = errors.New("remote does not support obfuscation")
+
+// ObfuscationConfig defines the obfuscation of dialed transports. Obfuscated transports
+// hide the handshakes and the packets exchanged from deep packet inspection: all of the
+// bytes sent look random, packets are padded to random sizes and written with random delays.
+// The obfuscation is keyed by the public key of the responding visor and doesn't replace
+// the noise encryption of transports.
+type ObfuscationConfig struct {
+	// Padding is the largest number of random bytes added to every write.
+	Padding int
+	// Jitter is the longest random delay before every write.
+	Jitter time.Duration
+	// AllowPlain allows falling back to a plain handshake with the visors which don't support
+	// obfuscation, the dial fails otherwise.
+	AllowPlain bool
+}
+
+// normalize caps the config to the limits accepted by the remote visors.
+func (c ObfuscationConfig) normalize() ObfuscationConfig {
+	if c.Padding < 0 {
+		c.Padding = 0
+	}
+	if c.Padding > MaxObfuscationPadding {
+		c.Padding = MaxObfuscationPadding
+	}
+	if c.Jitter < 0 {
+		c.Jitter = 0
+	}
+	if c.Jitter > MaxObfuscationJitter {
+		c.Jitter = MaxObfuscationJitter
+	}
+	return c
+}
+
+// obfuscate opens an obfuscated connection over `conn` to the visor of `rPK`.
+// The initiator proposes the padding and jitter of `conf`, which are then used in both directions.
+func obfuscate(conn net.Conn, rPK cipher.PubKey, conf ObfuscationConfig) (net.Conn, error) {
+	conf = conf.normalize()
+
+	seed := make([]byte, obfsSeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	oc, err := newObfsConn(conn, seed, rPK, true)
+	if err != nil {
+		return nil, err
+	}
+
+	preamble := randInt(256)
+	header := make([]byte, obfsHeaderSize+preamble)
+	binary.BigEndian.PutUint32(header[0:], obfsMagic)
+	binary.BigEndian.PutUint16(header[4:], uint16(conf.Padding))
+	binary.BigEndian.PutUint16(header[6:], uint16(conf.Jitter/time.Millisecond))
+	header[8] = byte(preamble)
+	oc.enc.XORKeyStream(header, header)
+
+	if err := conn.SetDeadline(time.Now().Add(handshake.Timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(append(seed, header...)); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, obfsReplySize)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotObfuscated, err)
+	}
+	oc.dec.XORKeyStream(reply, reply)
+	if binary.BigEndian.Uint32(reply[0:]) != obfsMagic {
+		return nil, ErrNotObfuscated
+	}
+	oc.conf = ObfuscationConfig{
+		Padding: int(binary.BigEndian.Uint16(reply[4:])),
+		Jitter:  time.Duration(binary.BigEndian.Uint16(reply[6:])) * time.Millisecond,
+	}.normalize()
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return oc, nil
+}
+
+// acceptObfuscated detects whether the connection accepted by the visor of `lPK` is obfuscated
+// and returns the connection to perform the transport handshake over.
+func acceptObfuscated(conn net.Conn, lPK cipher.PubKey) (net.Conn, error) {
+	if err := conn.SetDeadline(time.Now().Add(handshake.Timeout)); err != nil {
+		return nil, err
+	}
+
+	// plain transports start with the first frame of the handshake
+	prefix := make([]byte, len(handshake.Message))
+	if _, err := io.ReadFull(conn, prefix); err != nil {
+		return nil, err
+	}
+	if string(prefix) == handshake.Message {
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			return nil, err
+		}
+		return &prefixedConn{Conn: conn, prefix: prefix}, nil
+	}
+
+	seed := make([]byte, obfsSeedSize)
+	copy(seed, prefix)
+	if _, err := io.ReadFull(conn, seed[len(prefix):]); err != nil {
+		return nil, err
+	}
+	oc, err := newObfsConn(conn, seed, lPK, false)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, obfsHeaderSize)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	oc.dec.XORKeyStream(header, header)
+	if binary.BigEndian.Uint32(header[0:]) != obfsMagic {
+		return nil, handshake.Error("bad obfuscation header")
+	}
+	preamble := make([]byte, header[8])
+	if _, err := io.ReadFull(conn, preamble); err != nil {
+		return nil, err
+	}
+	oc.dec.XORKeyStream(preamble, preamble)
+	oc.conf = ObfuscationConfig{
+		Padding: int(binary.BigEndian.Uint16(header[4:])),
+		Jitter:  time.Duration(binary.BigEndian.Uint16(header[6:])) * time.Millisecond,
+	}.normalize()
+
+	reply := make([]byte, obfsReplySize)
+	binary.BigEndian.PutUint32(reply[0:], obfsMagic)
+	binary.BigEndian.PutUint16(reply[4:], uint16(oc.conf.Padding))
+	binary.BigEndian.PutUint16(reply[6:], uint16(oc.conf.Jitter/time.Millisecond))
+	oc.enc.XORKeyStream(reply, reply)
+	if _, err := conn.Write(reply); err != nil {
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return oc, nil
+}
+
+// obfsConn encrypts the whole stream with keys derived from the seed and the responder PK,
+// and frames every write with random padding.
+type obfsConn struct {
+	net.Conn
+	conf ObfuscationConfig
+
+	wMu sync.Mutex
+	enc stdcipher.Stream
+
+	rMu  sync.Mutex
+	dec  stdcipher.Stream
+	rBuf bytes.Buffer
+}
+
+func newObfsConn(conn net.Conn, seed []byte, respPK cipher.PubKey, initiator bool) (*obfsConn, error) {
+	i2r, err := obfsStream(seed, respPK, "initiator")
+	if err != nil {
+		return nil, err
+	}
+	r2i, err := obfsStream(seed, respPK, "responder")
+	if err != nil {
+		return nil, err
+	}
+
+	oc := &obfsConn{Conn: conn, enc: i2r, dec: r2i}
+	if !initiator {
+		oc.enc, oc.dec = r2i, i2r
+	}
+	return oc, nil
+}
+
+func obfsStream(seed []byte, respPK cipher.PubKey, direction string) (stdcipher.Stream, error) {
+	h := sha256.New()
+	h.Write([]byte("skywire obfuscation " + direction)) //nolint:errcheck
+	h.Write(seed)                                       //nolint:errcheck
+	h.Write(respPK[:])                                  //nolint:errcheck
+
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	// every key is used for a single stream, so the IV may be constant
+	return stdcipher.NewCTR(block, make([]byte, aes.BlockSize)), nil
+}
+
+// Write implements net.Conn
+func (c *obfsConn) Write(p []byte) (int, error) {
+	// the delay doesn't hold the lock, so that concurrent writes are not delayed by each other
+	if c.conf.Jitter > 0 {
+		time.Sleep(time.Duration(randInt(int(c.conf.Jitter))))
+	}
+
+	c.wMu.Lock()
+	defer c.wMu.Unlock()
+
+	var n int
+	for len(p) > 0 {
+		payload := p
+		if len(payload) > obfsMaxPayload {
+			payload = payload[:obfsMaxPayload]
+		}
+		padding := randInt(c.conf.Padding + 1)
+
+		frame := make([]byte, obfsFrameHeaderSize+len(payload)+padding)
+		binary.BigEndian.PutUint16(frame[0:], uint16(len(payload)))
+		binary.BigEndian.PutUint16(frame[2:], uint16(padding))
+		copy(frame[obfsFrameHeaderSize:], payload)
+		c.enc.XORKeyStream(frame, frame)
+
+		if _, err := c.Conn.Write(frame); err != nil {
+			return n, err
+		}
+		n += len(payload)
+		p = p[len(payload):]
+	}
+
+	return n, nil
+}
+
+// Read implements net.Conn
+func (c *obfsConn) Read(p []byte) (int, error) {
+	c.rMu.Lock()
+	defer c.rMu.Unlock()
+
+	for c.rBuf.Len() == 0 {
+		header := make([]byte, obfsFrameHeaderSize)
+		if _, err := io.ReadFull(c.Conn, header); err != nil {
+			return 0, err
+		}
+		c.dec.XORKeyStream(header, header)
+
+		size := int(binary.BigEndian.Uint16(header[0:]))
+		padding := int(binary.BigEndian.Uint16(header[2:]))
+		body := make([]byte, size+padding)
+		if _, err := io.ReadFull(c.Conn, body); err != nil {
+			return 0, err
+		}
+		c.dec.XORKeyStream(body, body)
+		c.rBuf.Write(body[:size])
+	}
+
+	return c.rBuf.Read(p)
+}
+
+// prefixedConn replays the bytes read to detect obfuscation.
+type prefixedConn struct {
+	net.Conn
+	prefix []byte
+}
+
+// Read implements net.Conn
+func (c *prefixedConn) Read(p []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(p, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+	return c.Conn.Read(p)
+}
+
+// randInt returns a uniform random number in [0, n).
+func randInt(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0
+	}
+	return int(v.Int64())
+}
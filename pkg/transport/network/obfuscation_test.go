@@ -0,0 +1,229 @@
+// Package network pkg/transport/network/obfuscation_test.go
+package network
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/app/appevent"
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport/network/handshake"
+	"github.com/skycoin/skywire/pkg/transport/network/stcp"
+)
+
+// recordingConn records the bytes written to the connection.
+type recordingConn struct {
+	net.Conn
+	written bytes.Buffer
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	c.written.Write(p)
+	return c.Conn.Write(p)
+}
+
+func TestObfuscation(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+
+	t.Run("plain", func(t *testing.T) {
+		connA, connB := net.Pipe()
+		defer func() { require.NoError(t, connA.Close()) }()
+
+		go func() {
+			_, _ = connA.Write([]byte(handshake.Message + "rest")) //nolint:errcheck
+		}()
+
+		conn, err := acceptObfuscated(connB, pk)
+		require.NoError(t, err)
+		got := make([]byte, len(handshake.Message)+4)
+		_, err = io.ReadFull(conn, got)
+		require.NoError(t, err)
+		require.Equal(t, handshake.Message+"rest", string(got))
+		require.NoError(t, conn.Close())
+	})
+
+	t.Run("obfuscated", func(t *testing.T) {
+		connA, connB := net.Pipe()
+		recA := &recordingConn{Conn: connA}
+		conf := ObfuscationConfig{Padding: 64, Jitter: time.Millisecond}
+
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			conn, err := acceptObfuscated(connB, pk)
+			if err == nil {
+				accepted <- conn
+			}
+			close(accepted)
+		}()
+
+		oA, err := obfuscate(recA, pk, conf)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, oA.Close()) }()
+		oB := <-accepted
+		require.NotNil(t, oB)
+		require.Equal(t, conf, oB.(*obfsConn).conf)
+		require.Equal(t, conf, oA.(*obfsConn).conf)
+
+		msg := []byte(handshake.Message)
+		for i := 0; i < 10; i++ {
+			go func() {
+				_, _ = oA.Write(msg) //nolint:errcheck
+			}()
+			got := make([]byte, len(msg))
+			_, err = io.ReadFull(oB, got)
+			require.NoError(t, err)
+			require.Equal(t, msg, got)
+		}
+		require.NotContains(t, recA.written.String(), handshake.Message)
+
+		reply := bytes.Repeat([]byte("x"), obfsMaxPayload+10)
+		go func() {
+			_, _ = oB.Write(reply) //nolint:errcheck
+		}()
+		got := make([]byte, len(reply))
+		_, err = io.ReadFull(oA, got)
+		require.NoError(t, err)
+		require.Equal(t, reply, got)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		otherPK, _ := cipher.GenerateKeyPair()
+		connA, connB := net.Pipe()
+		defer func() { require.NoError(t, connA.Close()) }()
+
+		go func() {
+			_, _ = obfuscate(connA, otherPK, ObfuscationConfig{}) //nolint:errcheck
+		}()
+
+		_, err := acceptObfuscated(connB, pk)
+		require.Error(t, err)
+	})
+}
+
+func TestObfuscatedTransport(t *testing.T) {
+	const tpPort = 45
+
+	pkA, skA := cipher.GenerateKeyPair()
+	pkB, skB := cipher.GenerateKeyPair()
+
+	fB := &ClientFactory{PK: pkB, SK: skB, ListenAddr: "127.0.0.1:0", MLogger: logging.NewMasterLogger()}
+	cB, err := fB.MakeClient(STCP, 0)
+	require.NoError(t, err)
+	require.NoError(t, cB.Start())
+	defer func() { require.NoError(t, cB.Close()) }()
+
+	lis, err := cB.Listen(tpPort)
+	require.NoError(t, err)
+	addrB, err := cB.LocalAddr()
+	require.NoError(t, err)
+
+	fA := &ClientFactory{
+		PK:          pkA,
+		SK:          skA,
+		PKTable:     stcp.NewTable(map[cipher.PubKey]string{pkB: addrB.String()}),
+		Obfuscation: &ObfuscationConfig{Padding: 128},
+		EB:          appevent.NewBroadcaster(logging.MustGetLogger("eb"), time.Second),
+		MLogger:     logging.NewMasterLogger(),
+	}
+	cA, err := fA.MakeClient(STCP, 0)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, cA.Close()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	accepted := make(chan Transport, 1)
+	go func() {
+		tp, err := lis.AcceptTransport()
+		if err == nil {
+			accepted <- tp
+		}
+		close(accepted)
+	}()
+
+	tpA, err := cA.Dial(ctx, pkB, tpPort)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, tpA.Close()) }()
+
+	var tpB Transport
+	select {
+	case tpB = <-accepted:
+		require.NotNil(t, tpB)
+	case <-ctx.Done():
+		t.Fatal("transport not accepted")
+	}
+	defer func() { require.NoError(t, tpB.Close()) }()
+	require.Equal(t, pkA, tpB.RemotePK())
+
+	packet, err := routing.MakeDataPacket(routing.RouteID(1), []byte("foo"))
+	require.NoError(t, err)
+	_, err = tpA.Write(packet)
+	require.NoError(t, err)
+
+	got := make([]byte, len(packet))
+	_, err = io.ReadFull(tpB, got)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), routing.Packet(got).Payload())
+}
+
+func TestDialTransport_plainFallback(t *testing.T) {
+	pkB, _ := cipher.GenerateKeyPair()
+	pkA, skA := cipher.GenerateKeyPair()
+
+	f := &ClientFactory{
+		PK:          pkA,
+		SK:          skA,
+		PKTable:     stcp.NewTable(nil),
+		Obfuscation: &ObfuscationConfig{Padding: 128, AllowPlain: true},
+		MLogger:     logging.NewMasterLogger(),
+	}
+	c, err := f.MakeClient(STCP, 0)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, c.Close()) }()
+	generic := c.(*stcpClient).genericClient
+
+	// an older visor drops the obfuscation header it doesn't understand
+	errPlainDialed := errors.New("plain dialed")
+	var dials int
+	dial := func(context.Context) (net.Conn, error) {
+		dials++
+		if dials > 1 {
+			return nil, errPlainDialed
+		}
+		conn, remote := net.Pipe()
+		go func() {
+			_, _ = remote.Read(make([]byte, 1024)) //nolint:errcheck
+			_ = remote.Close()                     //nolint:errcheck
+		}()
+		return conn, nil
+	}
+
+	_, err = generic.dialTransport(context.Background(), dial, pkB, 1)
+	require.ErrorIs(t, err, errPlainDialed)
+	require.Equal(t, 2, dials)
+
+	// other failures are not retried
+	dials = 0
+	_, err = generic.dialTransport(context.Background(), func(context.Context) (net.Conn, error) {
+		dials++
+		return nil, io.ErrClosedPipe
+	}, pkB, 1)
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+	require.Equal(t, 1, dials)
+
+	// the obfuscation is required unless the fallback is allowed
+	generic.obfs.AllowPlain = false
+	dials = 0
+	_, err = generic.dialTransport(context.Background(), dial, pkB, 1)
+	require.ErrorIs(t, err, ErrNotObfuscated)
+	require.Equal(t, 1, dials)
+}
@@ -0,0 +1,274 @@
+// Package network pkg/transport/network/quic.go
+package network
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/transport/network/handshake"
+)
+
+const (
+	// quicALPN is the application protocol negotiated by QUIC transports.
+	quicALPN = "skywire"
+	// quicKeepAlivePeriod keeps idle QUIC connections and their NAT mappings open.
+	quicKeepAlivePeriod = 10 * time.Second
+)
+
+// quicClient dials and accepts transports over QUIC, every transport is a single stream of its own connection.
+// TLS is only required by QUIC and is not verified, the remote visor is authenticated by the noise handshake
+// performed over the stream like with the other network types.
+// QUIC shares the STCPR binding of the address resolver: it listens over UDP on the port bound by STCPR
+// and dials the addresses STCPR resolves to.
+type quicClient struct {
+	*resolvedClient
+	tlsConf *tls.Config
+	conf    *quic.Config
+}
+
+func newQuic(resolved *resolvedClient) (Client, error) {
+	cert, err := makeQuicCertificate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QUIC certificate: %w", err)
+	}
+
+	client := &quicClient{
+		resolvedClient: resolved,
+		tlsConf: &tls.Config{
+			Certificates:       []tls.Certificate{cert},
+			NextProtos:         []string{quicALPN},
+			InsecureSkipVerify: true, //nolint:gosec // remote is authenticated by the noise handshake
+			MinVersion:         tls.VersionTLS13,
+		},
+		conf: &quic.Config{
+			HandshakeIdleTimeout: handshake.Timeout,
+			KeepAlivePeriod:      quicKeepAlivePeriod,
+		},
+	}
+	client.netType = QUIC
+	return client, nil
+}
+
+// Dial implements interface
+func (c *quicClient) Dial(ctx context.Context, rPK cipher.PubKey, rPort uint16) (Transport, error) {
+	if c.isClosed() {
+		return nil, io.ErrClosedPipe
+	}
+	c.log.Debugf("Dialing PK %v", rPK)
+	return c.dialTransport(ctx, func(ctx context.Context) (net.Conn, error) {
+		return c.dialVisorAs(ctx, STCPR, rPK, c.dial)
+	}, rPK, rPort)
+}
+
+func (c *quicClient) dial(ctx context.Context, addr string) (net.Conn, error) {
+	if c.eb != nil {
+		c.eb.SendTCPDial(context.Background(), string(QUIC), addr)
+	}
+
+	conn, err := quic.DialAddr(ctx, addr, c.tlsConf, c.conf)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		_ = conn.CloseWithError(0, "") //nolint:errcheck
+		return nil, err
+	}
+
+	return &quicConn{Stream: stream, conn: conn}, nil
+}
+
+// Start implements Client interface
+func (c *quicClient) Start() error {
+	if c.connListener != nil {
+		return ErrAlreadyListening
+	}
+	go c.serve()
+	return nil
+}
+
+func (c *quicClient) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	port, err := c.ar.STCPRPort(ctx)
+	if err != nil {
+		c.log.WithError(err).Debug("Not listening for QUIC: STCPR is not bound")
+		return
+	}
+
+	pc, err := net.ListenPacket("udp", ":"+port)
+	if err != nil {
+		c.log.WithError(err).Errorf("Failed to listen on STCPR port %s", port)
+		return
+	}
+
+	lis, err := newQuicListener(pc, c.tlsConf, c.conf)
+	if err != nil {
+		c.log.WithError(err).Error("Failed to start QUIC listener")
+		if err := pc.Close(); err != nil {
+			c.log.WithError(err).Warn("Failed to close UDP listener")
+		}
+		return
+	}
+	c.log.Debugf("Listening for QUIC on STCPR port %s", port)
+
+	c.acceptTransports(lis)
+}
+
+// quicListener accepts the first stream of incoming QUIC connections as net.Conn.
+type quicListener struct {
+	pc    net.PacketConn
+	lis   *quic.Listener
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newQuicListener(pc net.PacketConn, tlsConf *tls.Config, conf *quic.Config) (*quicListener, error) {
+	lis, err := quic.Listen(pc, tlsConf, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &quicListener{
+		pc:    pc,
+		lis:   lis,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+	go l.serve()
+
+	return l, nil
+}
+
+func (l *quicListener) serve() {
+	defer l.close()
+	for {
+		conn, err := l.lis.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		go l.acceptStream(conn)
+	}
+}
+
+// acceptStream waits for the dialing visor to open the stream of the transport.
+func (l *quicListener) acceptStream(conn quic.Connection) {
+	ctx, cancel := context.WithTimeout(context.Background(), handshake.Timeout)
+	defer cancel()
+
+	stream, err := conn.AcceptStream(ctx)
+	if err != nil {
+		_ = conn.CloseWithError(0, "") //nolint:errcheck
+		return
+	}
+
+	qConn := &quicConn{Stream: stream, conn: conn}
+	select {
+	case l.conns <- qConn:
+	case <-l.done:
+		_ = qConn.Close() //nolint:errcheck
+	}
+}
+
+// Accept implements net.Listener
+func (l *quicListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+// Close implements net.Listener
+func (l *quicListener) Close() error {
+	err := l.lis.Close()
+	l.close()
+	if pcErr := l.pc.Close(); err == nil {
+		err = pcErr
+	}
+
+	return err
+}
+
+func (l *quicListener) close() {
+	l.once.Do(func() { close(l.done) })
+}
+
+// Addr implements net.Listener
+func (l *quicListener) Addr() net.Addr {
+	return l.lis.Addr()
+}
+
+// quicConn is a QUIC stream owning its connection.
+type quicConn struct {
+	quic.Stream
+	conn quic.Connection
+}
+
+// LocalAddr implements net.Conn
+func (c *quicConn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr implements net.Conn
+func (c *quicConn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Close implements net.Conn
+func (c *quicConn) Close() error {
+	err := c.Stream.Close()
+	if connErr := c.conn.CloseWithError(0, ""); err == nil {
+		err = connErr
+	}
+
+	return err
+}
+
+// makeQuicCertificate generates the self-signed certificate QUIC requires.
+func makeQuicCertificate() (tls.Certificate, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10 * 365 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, priv)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: priv}, nil
+}
@@ -0,0 +1,102 @@
+// Package network pkg/transport/network/quic_test.go
+package network
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport/network/addrresolver"
+)
+
+func TestQuicClient(t *testing.T) {
+	const tpPort = 45
+
+	pkA, skA := cipher.GenerateKeyPair()
+	pkB, skB := cipher.GenerateKeyPair()
+
+	// the STCPR port is shared with QUIC over UDP
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, portB, err := net.SplitHostPort(pc.LocalAddr().String())
+	require.NoError(t, err)
+	require.NoError(t, pc.Close())
+
+	arB := &addrresolver.MockAPIClient{}
+	arB.On("STCPRPort", mock.Anything).Return(portB, nil)
+	fB := &ClientFactory{PK: pkB, SK: skB, ARClient: arB, MLogger: logging.NewMasterLogger()}
+	cB, err := fB.MakeClient(QUIC, 0)
+	require.NoError(t, err)
+	require.NoError(t, cB.Start())
+	defer func() { require.NoError(t, cB.Close()) }()
+
+	lis, err := cB.Listen(tpPort)
+	require.NoError(t, err)
+
+	arA := &addrresolver.MockAPIClient{}
+	arA.On("Resolve", mock.Anything, string(STCPR), pkB).
+		Return(addrresolver.VisorData{RemoteAddr: net.JoinHostPort("127.0.0.1", portB)}, nil)
+	fA := &ClientFactory{PK: pkA, SK: skA, ARClient: arA, MLogger: logging.NewMasterLogger()}
+	cA, err := fA.MakeClient(QUIC, 0)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, cA.Close()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	accepted := make(chan Transport, 1)
+	go func() {
+		tp, err := lis.AcceptTransport()
+		if err == nil {
+			accepted <- tp
+		}
+		close(accepted)
+	}()
+
+	tpA, err := cA.Dial(ctx, pkB, tpPort)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, tpA.Close()) }()
+	require.Equal(t, QUIC, tpA.Network())
+	require.Equal(t, pkB, tpA.RemotePK())
+
+	// the remote accepts the transport once the handshakes are done
+	packet, err := routing.MakeDataPacket(routing.RouteID(1), []byte("foo"))
+	require.NoError(t, err)
+	_, err = tpA.Write(packet)
+	require.NoError(t, err)
+
+	var tpB Transport
+	select {
+	case tpB = <-accepted:
+		require.NotNil(t, tpB)
+	case <-ctx.Done():
+		t.Fatal("transport not accepted")
+	}
+	require.Equal(t, pkA, tpB.RemotePK())
+	require.Equal(t, uint16(tpPort), tpB.LocalPort())
+
+	got := make([]byte, len(packet))
+	_, err = io.ReadFull(tpB, got)
+	require.NoError(t, err)
+	require.Equal(t, routing.Packet(packet), routing.Packet(got))
+	require.Equal(t, []byte("foo"), routing.Packet(got).Payload())
+
+	// and the other way around
+	pong := routing.MakePongPacket(routing.RouteID(2), time.Now().UnixNano())
+	_, err = tpB.Write(pong)
+	require.NoError(t, err)
+	got = make([]byte, len(pong))
+	_, err = io.ReadFull(tpA, got)
+	require.NoError(t, err)
+	require.Equal(t, pong, routing.Packet(got))
+
+	require.NoError(t, tpB.Close())
+}
@@ -15,6 +15,11 @@ import (
 type STCPConfig struct {
 	PKTable          map[cipher.PubKey]string `json:"pk_table"`
 	ListeningAddress string                   `json:"listening_address"`
+	// LANDiscovery announces the visor on the local network and adds the visors announcing
+	// themselves to the PK table.
+	LANDiscovery bool `json:"lan_discovery,omitempty"`
+	// LANAutoconnect establishes transports to the visors found by LAN discovery.
+	LANAutoconnect bool `json:"lan_autoconnect,omitempty"`
 }
 
 type stcpClient struct {
@@ -40,20 +45,21 @@ func (c *stcpClient) Dial(ctx context.Context, rPK cipher.PubKey, rPort uint16)
 
 	c.log.Debugf("Dialing PK %v", rPK)
 
-	var conn net.Conn
 	addr, ok := c.table.Addr(rPK)
 	if !ok {
 		return nil, ErrStcpEntryNotFound
 	}
-	c.eb.SendTCPDial(context.Background(), string(STCP), addr)
-	dialer := net.Dialer{}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
-	if err != nil {
-		return nil, err
-	}
 
-	c.log.Debugf("Dialed %v:%v@%v", rPK, rPort, conn.RemoteAddr())
-	return c.initTransport(ctx, conn, rPK, rPort)
+	return c.dialTransport(ctx, func(ctx context.Context) (net.Conn, error) {
+		c.eb.SendTCPDial(context.Background(), string(STCP), addr)
+		dialer := net.Dialer{}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		c.log.Debugf("Dialed %v:%v@%v", rPK, rPort, conn.RemoteAddr())
+		return conn, nil
+	}, rPK, rPort)
 }
 
 // Start implements Client interface
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
 )
@@ -97,3 +98,92 @@ func (mt *memoryTable) PubKey(addr string) (cipher.PubKey, bool) {
 func (mt *memoryTable) Count() int {
 	return len(mt.entries)
 }
+
+// DynamicPKTable is a PKTable which entries may be added and removed at runtime,
+// e.g. by peer discovery. The entries it was created with can't be overridden.
+type DynamicPKTable interface {
+	PKTable
+	// Set associates `addr` with `pk`, it returns false if `pk` has a static entry.
+	Set(pk cipher.PubKey, addr string) bool
+	// Remove removes the dynamic entry of `pk`.
+	Remove(pk cipher.PubKey)
+}
+
+type dynamicTable struct {
+	static  PKTable
+	mu      sync.RWMutex
+	entries map[cipher.PubKey]string
+	reverse map[string]cipher.PubKey
+}
+
+// NewDynamicTable instantiates a memory implementation of DynamicPKTable with the static `entries`.
+func NewDynamicTable(entries map[cipher.PubKey]string) DynamicPKTable {
+	return &dynamicTable{
+		static:  NewTable(entries),
+		entries: make(map[cipher.PubKey]string),
+		reverse: make(map[string]cipher.PubKey),
+	}
+}
+
+// Addr obtains the address associated with the given public key.
+func (dt *dynamicTable) Addr(pk cipher.PubKey) (string, bool) {
+	if addr, ok := dt.static.Addr(pk); ok {
+		return addr, true
+	}
+
+	dt.mu.RLock()
+	defer dt.mu.RUnlock()
+
+	addr, ok := dt.entries[pk]
+	return addr, ok
+}
+
+// PubKey obtains the public key associated with the given address.
+func (dt *dynamicTable) PubKey(addr string) (cipher.PubKey, bool) {
+	if pk, ok := dt.static.PubKey(addr); ok {
+		return pk, true
+	}
+
+	dt.mu.RLock()
+	defer dt.mu.RUnlock()
+
+	pk, ok := dt.reverse[addr]
+	return pk, ok
+}
+
+// Count returns the number of entries within the PKTable implementation.
+func (dt *dynamicTable) Count() int {
+	dt.mu.RLock()
+	defer dt.mu.RUnlock()
+
+	return dt.static.Count() + len(dt.entries)
+}
+
+// Set associates `addr` with `pk`.
+func (dt *dynamicTable) Set(pk cipher.PubKey, addr string) bool {
+	if _, ok := dt.static.Addr(pk); ok {
+		return false
+	}
+
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	if old, ok := dt.entries[pk]; ok {
+		delete(dt.reverse, old)
+	}
+	dt.entries[pk] = addr
+	dt.reverse[addr] = pk
+
+	return true
+}
+
+// Remove removes the dynamic entry of `pk`.
+func (dt *dynamicTable) Remove(pk cipher.PubKey) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	if addr, ok := dt.entries[pk]; ok {
+		delete(dt.reverse, addr)
+		delete(dt.entries, pk)
+	}
+}
@@ -28,12 +28,9 @@ func (c *stcprClient) Dial(ctx context.Context, rPK cipher.PubKey, rPort uint16)
 		return nil, io.ErrClosedPipe
 	}
 	c.log.Debugf("Dialing PK %v", rPK)
-	conn, err := c.dialVisor(ctx, rPK, c.dial)
-	if err != nil {
-		return nil, err
-	}
-
-	return c.initTransport(ctx, conn, rPK, rPort)
+	return c.dialTransport(ctx, func(ctx context.Context) (net.Conn, error) {
+		return c.dialVisor(ctx, rPK, c.dial)
+	}, rPK, rPort)
 }
 
 func (c *stcprClient) dial(ctx context.Context, addr string) (net.Conn, error) {
@@ -133,12 +133,9 @@ func (c *sudphClient) Dial(ctx context.Context, rPK cipher.PubKey, rPort uint16)
 		return nil, io.ErrClosedPipe
 	}
 	// this will lookup visor address in address resolver and then dial that address
-	conn, err := c.dialVisor(ctx, rPK, c.dialWithTimeout)
-	if err != nil {
-		return nil, err
-	}
-
-	return c.initTransport(ctx, conn, rPK, rPort)
+	return c.dialTransport(ctx, func(ctx context.Context) (net.Conn, error) {
+		return c.dialVisor(ctx, rPK, c.dialWithTimeout)
+	}, rPK, rPort)
 }
 
 func (c *sudphClient) dialWithTimeout(ctx context.Context, addr string) (net.Conn, error) {
@@ -0,0 +1,248 @@
+// Package network pkg/transport/network/wss.go
+package network
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"nhooyr.io/websocket"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/routing"
+	"github.com/skycoin/skywire/pkg/transport/network/stcp"
+)
+
+const (
+	// DefaultWSSPath is the HTTP path WebSocket transports are accepted on by default.
+	DefaultWSSPath = "/skywire"
+	// wssReadLimit is the largest WebSocket message accepted. It fits the largest packet, or an obfuscated
+	// frame carrying it, so that the limit doesn't depend on the writes being split into noise frames.
+	wssReadLimit = max(routing.PacketHeaderSize+math.MaxUint16, obfsFrameHeaderSize+obfsMaxPayload+MaxObfuscationPadding)
+	// wssReadHeaderTimeout limits reading the headers of the WebSocket upgrade request.
+	wssReadHeaderTimeout = 10 * time.Second
+)
+
+// ErrWSSEntryNotFound is returned when requested PK is not found in the WebSocket URL table.
+var ErrWSSEntryNotFound = errors.New("entry not found in WSS URL table")
+
+// WSSConfig defines config for the WebSocket network, which tunnels transports through
+// HTTP(S) proxies. The dialing side honours the HTTPS_PROXY environment variable.
+type WSSConfig struct {
+	// PKTable maps the PKs of remote visors to the URLs they accept transports on, e.g. wss://example.com/skywire.
+	PKTable map[cipher.PubKey]string `json:"pk_table"`
+	// ListeningAddress is the address to accept transports on, transports are only dialed if empty.
+	ListeningAddress string `json:"listening_address,omitempty"`
+	// Path is the HTTP path to accept transports on, defaults to DefaultWSSPath.
+	Path string `json:"path,omitempty"`
+	// CertFile and KeyFile enable TLS on the listener. They may be left empty when TLS
+	// is terminated by a reverse proxy in front of the visor.
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+}
+
+type wssClient struct {
+	*genericClient
+	conf  WSSConfig
+	table stcp.PKTable
+	httpC *http.Client
+}
+
+func newWss(generic *genericClient, conf *WSSConfig) Client {
+	client := &wssClient{genericClient: generic}
+	if conf != nil {
+		client.conf = *conf
+	}
+	if client.conf.Path == "" {
+		client.conf.Path = DefaultWSSPath
+	}
+	client.table = stcp.NewTable(client.conf.PKTable)
+	client.httpC = &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			// the remote is authenticated by the noise handshake, which also makes the
+			// transports work through proxies intercepting TLS
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+		},
+	}
+	client.netType = WSS
+	return client
+}
+
+// Dial implements Client interface
+func (c *wssClient) Dial(ctx context.Context, rPK cipher.PubKey, rPort uint16) (Transport, error) {
+	if c.isClosed() {
+		return nil, io.ErrClosedPipe
+	}
+
+	c.log.Debugf("Dialing PK %v", rPK)
+
+	rawURL, ok := c.table.Addr(rPK)
+	if !ok {
+		return nil, ErrWSSEntryNotFound
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if c.eb != nil {
+		c.eb.SendTCPDial(context.Background(), string(WSS), u.Host)
+	}
+
+	return c.dialTransport(ctx, func(ctx context.Context) (net.Conn, error) {
+		wsConn, _, err := websocket.Dial(ctx, rawURL, &websocket.DialOptions{HTTPClient: c.httpC}) //nolint:bodyclose
+		if err != nil {
+			return nil, err
+		}
+		c.log.Debugf("Dialed %v:%v@%v", rPK, rPort, rawURL)
+		return newWssConn(wsConn, wssAddr(""), wssAddr(u.Host)), nil
+	}, rPK, rPort)
+}
+
+// Start implements Client interface
+func (c *wssClient) Start() error {
+	if c.connListener != nil {
+		return ErrAlreadyListening
+	}
+	if c.conf.ListeningAddress == "" {
+		c.log.Debug("Not accepting WSS transports: no listening address")
+		return nil
+	}
+	go c.serve()
+	return nil
+}
+
+func (c *wssClient) serve() {
+	lis, err := net.Listen("tcp", c.conf.ListeningAddress)
+	if err != nil {
+		c.log.Errorf("Failed to listen on %q: %v", c.conf.ListeningAddress, err)
+		return
+	}
+
+	wsLis := newWssListener(lis)
+	mux := http.NewServeMux()
+	mux.Handle(c.conf.Path, wsLis)
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: wssReadHeaderTimeout,
+	}
+	wsLis.srv = srv
+
+	go func() {
+		var err error
+		if c.conf.CertFile != "" || c.conf.KeyFile != "" {
+			err = srv.ServeTLS(lis, c.conf.CertFile, c.conf.KeyFile)
+		} else {
+			err = srv.Serve(lis)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			c.log.WithError(err).Error("WSS server stopped")
+		}
+		wsLis.close()
+	}()
+
+	c.acceptTransports(wsLis)
+}
+
+// wssListener upgrades the requests of the HTTP server to WebSocket connections.
+type wssListener struct {
+	lis   net.Listener
+	srv   *http.Server
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newWssListener(lis net.Listener) *wssListener {
+	return &wssListener{
+		lis:   lis,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (l *wssListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// transports are dialed by visors, not browsers, so there's no origin to verify
+	wsConn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+	if err != nil {
+		return
+	}
+
+	conn := newWssConn(wsConn, l.lis.Addr(), wssAddr(r.RemoteAddr))
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		_ = conn.Close() //nolint:errcheck
+	}
+}
+
+// Accept implements net.Listener
+func (l *wssListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+// Close implements net.Listener
+func (l *wssListener) Close() error {
+	l.close()
+	if l.srv != nil {
+		return l.srv.Close()
+	}
+
+	return l.lis.Close()
+}
+
+func (l *wssListener) close() {
+	l.once.Do(func() { close(l.done) })
+}
+
+// Addr implements net.Listener
+func (l *wssListener) Addr() net.Addr {
+	return l.lis.Addr()
+}
+
+// wssConn is a WebSocket connection carrying binary messages, with the addresses of the underlying connection.
+type wssConn struct {
+	net.Conn
+	lAddr, rAddr net.Addr
+}
+
+func newWssConn(wsConn *websocket.Conn, lAddr, rAddr net.Addr) *wssConn {
+	wsConn.SetReadLimit(wssReadLimit)
+	return &wssConn{
+		Conn:  websocket.NetConn(context.Background(), wsConn, websocket.MessageBinary),
+		lAddr: lAddr,
+		rAddr: rAddr,
+	}
+}
+
+// LocalAddr implements net.Conn
+func (c *wssConn) LocalAddr() net.Addr {
+	return c.lAddr
+}
+
+// RemoteAddr implements net.Conn
+func (c *wssConn) RemoteAddr() net.Addr {
+	return c.rAddr
+}
+
+// wssAddr is the host:port of a WebSocket connection.
+type wssAddr string
+
+// Network implements net.Addr
+func (wssAddr) Network() string { return "websocket" }
+
+// String implements net.Addr
+func (a wssAddr) String() string { return string(a) }
@@ -0,0 +1,121 @@
+// Package network pkg/transport/network/wss_test.go
+package network
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+func TestWssClient(t *testing.T) {
+	const tpPort = 45
+
+	pkA, skA := cipher.GenerateKeyPair()
+	pkB, skB := cipher.GenerateKeyPair()
+
+	// TLS listener with a self-signed certificate
+	cert, err := makeQuicCertificate()
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	fB := &ClientFactory{PK: pkB, SK: skB, MLogger: logging.NewMasterLogger(), WSSConfig: &WSSConfig{
+		ListeningAddress: "127.0.0.1:0",
+		Path:             "/tunnel",
+		CertFile:         certFile,
+		KeyFile:          keyFile,
+	}}
+	cB, err := fB.MakeClient(WSS, 0)
+	require.NoError(t, err)
+	require.NoError(t, cB.Start())
+	defer func() { require.NoError(t, cB.Close()) }()
+
+	lis, err := cB.Listen(tpPort)
+	require.NoError(t, err)
+
+	addrB, err := cB.LocalAddr()
+	require.NoError(t, err)
+
+	fA := &ClientFactory{PK: pkA, SK: skA, MLogger: logging.NewMasterLogger(), WSSConfig: &WSSConfig{
+		PKTable: map[cipher.PubKey]string{pkB: "wss://" + addrB.String() + "/tunnel"},
+	}}
+	cA, err := fA.MakeClient(WSS, 0)
+	require.NoError(t, err)
+	require.NoError(t, cA.Start())
+	defer func() { require.NoError(t, cA.Close()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = cA.Dial(ctx, pkA, tpPort)
+	require.ErrorIs(t, err, ErrWSSEntryNotFound)
+
+	accepted := make(chan Transport, 1)
+	go func() {
+		tp, err := lis.AcceptTransport()
+		if err == nil {
+			accepted <- tp
+		}
+		close(accepted)
+	}()
+
+	tpA, err := cA.Dial(ctx, pkB, tpPort)
+	require.NoError(t, err)
+	defer tpA.Close() //nolint:errcheck
+	require.Equal(t, WSS, tpA.Network())
+	require.Equal(t, pkB, tpA.RemotePK())
+
+	var tpB Transport
+	select {
+	case tpB = <-accepted:
+		require.NotNil(t, tpB)
+	case <-ctx.Done():
+		t.Fatal("transport not accepted")
+	}
+	require.Equal(t, pkA, tpB.RemotePK())
+
+	packet, err := routing.MakeDataPacket(routing.RouteID(1), []byte("foo"))
+	require.NoError(t, err)
+	_, err = tpA.Write(packet)
+	require.NoError(t, err)
+
+	got := make([]byte, len(packet))
+	_, err = io.ReadFull(tpB, got)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), routing.Packet(got).Payload())
+
+	// the largest packets fit a single message
+	packet, err = routing.MakeDataPacket(routing.RouteID(1), make([]byte, math.MaxUint16))
+	require.NoError(t, err)
+	_, err = tpA.Write(packet)
+	require.NoError(t, err)
+	got = make([]byte, len(packet))
+	_, err = io.ReadFull(tpB, got)
+	require.NoError(t, err)
+	require.Equal(t, routing.Packet(packet), routing.Packet(got))
+
+	// the close handshake completes as the remote reads
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, tpA) //nolint:errcheck
+		close(closed)
+	}()
+	require.NoError(t, tpB.Close())
+	<-closed
+}
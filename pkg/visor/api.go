@@ -25,6 +25,7 @@ import (
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
 	"github.com/skycoin/skywire-utilities/pkg/logging"
 	"github.com/skycoin/skywire-utilities/pkg/netutil"
+	"github.com/skycoin/skywire/pkg/app/appacl"
 	"github.com/skycoin/skywire/pkg/app/appcommon"
 	"github.com/skycoin/skywire/pkg/app/appnet"
 	"github.com/skycoin/skywire/pkg/app/appserver"
@@ -33,8 +34,10 @@ import (
 	"github.com/skycoin/skywire/pkg/skyenv"
 	"github.com/skycoin/skywire/pkg/transport"
 	"github.com/skycoin/skywire/pkg/transport/network"
+	"github.com/skycoin/skywire/pkg/transport/network/lan"
 	"github.com/skycoin/skywire/pkg/visor/dmsgtracker"
 	"github.com/skycoin/skywire/pkg/visor/visorconfig"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
 )
 
 // API represents visor API.
@@ -44,7 +47,7 @@ type API interface {
 	Summary() (*Summary, error)
 	Health() (*HealthInfo, error)
 	Uptime() (float64, error)
-	Reload() error
+	Reload() (ConfigReload, error)
 	Shutdown() error
 	RuntimeLogs() (string, error)
 	RemoteVisors() ([]string, error)
@@ -52,6 +55,7 @@ type API interface {
 	SetLogRotationInterval(visorconfig.Duration) error
 	IsDMSGClientReady() (bool, error)
 	Ports() (map[string]PortDetail, error)
+	Events(since uint64, wait time.Duration) ([]visorevent.Event, error)
 
 	//reward setting
 	SetRewardAddress(string) (string, error)
@@ -83,6 +87,9 @@ type API interface {
 	GetAppStats(appName string) (appserver.AppStats, error)
 	GetAppError(appName string) (string, error)
 	GetAppConnectionsSummary(appName string) ([]appserver.ConnectionSummary, error)
+	GetAppACL(appName string) ([]appacl.Entry, error)
+	SetAppACLEntry(appName string, entry appacl.Entry) error
+	RemoveAppACLEntry(appName string, pk cipher.PubKey) error
 
 	//vpn controls
 	StartVPNClient(pk cipher.PubKey) error
@@ -107,6 +114,9 @@ type API interface {
 	//transport discovery
 	DiscoverTransportsByPK(pk cipher.PubKey) ([]*transport.Entry, error)
 	DiscoverTransportByID(id uuid.UUID) (*transport.Entry, error)
+	//transport logs
+	TransportStats(q transport.LogQuery) ([]transport.LogStats, error)
+	LANPeers() ([]lan.Peer, error)
 
 	//routing
 	RoutingRules() ([]routing.Rule, error)
@@ -119,7 +129,7 @@ type API interface {
 	RegisterHTTPPort(localPort int) error
 	DeregisterHTTPPort(localPort int) error
 	ListHTTPPorts() ([]int, error)
-	Connect(remotePK cipher.PubKey, remotePort, localPort int) (uuid.UUID, error)
+	Connect(remotePK cipher.PubKey, remotePort, localPort int, fwdType appnet.ForwardType) (uuid.UUID, error)
 	Disconnect(id uuid.UUID) error
 	List() (map[uuid.UUID]*appnet.ForwardConn, error)
 	DialPing(config PingConfig) error
@@ -985,6 +995,73 @@ func (v *Visor) GetAppConnectionsSummary(appName string) ([]appserver.Connection
 	return nil, ErrProcNotAvailable
 }
 
+// GetAppACL implements API.
+func (v *Visor) GetAppACL(appName string) ([]appacl.Entry, error) {
+	path, err := v.appACLPath(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := appacl.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	l.Prune(time.Now())
+
+	return l.Entries, nil
+}
+
+// SetAppACLEntry implements API.
+func (v *Visor) SetAppACLEntry(appName string, entry appacl.Entry) error {
+	path, err := v.appACLPath(appName)
+	if err != nil {
+		return err
+	}
+
+	v.log.Infof("Setting %s ACL entry of %s to %s", appName, entry.PK, entry.Action)
+
+	return appacl.Update(path, func(l *appacl.List) error {
+		l.Prune(time.Now())
+		return l.Set(entry)
+	})
+}
+
+// RemoveAppACLEntry implements API.
+func (v *Visor) RemoveAppACLEntry(appName string, pk cipher.PubKey) error {
+	path, err := v.appACLPath(appName)
+	if err != nil {
+		return err
+	}
+
+	err = appacl.Update(path, func(l *appacl.List) error {
+		if !l.Remove(pk) {
+			return fmt.Errorf("no ACL entry of %s in app %s", pk, appName)
+		}
+		l.Prune(time.Now())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	v.log.Infof("Removed %s ACL entry of %s", appName, pk)
+
+	return nil
+}
+
+// appACLPath returns the path of the ACL file of an app supporting per-client access control.
+func (v *Visor) appACLPath(appName string) (string, error) {
+	allowedApps := map[string]struct{}{
+		visorconfig.SkysocksName:  {},
+		visorconfig.VPNServerName: {},
+	}
+	if _, ok := allowedApps[appName]; !ok {
+		return "", fmt.Errorf("app %s does not support access control lists", appName)
+	}
+
+	return filepath.Join(v.conf.LocalPath, appName, appacl.FileName), nil
+}
+
 // VPNServers gets available public VPN server from service discovery URL
 func (v *Visor) VPNServers(version, country string) ([]servicedisc.Service, error) {
 	log := logging.MustGetLogger("vpnservers")
@@ -1236,6 +1313,28 @@ func (v *Visor) DiscoverTransportByID(id uuid.UUID) (*transport.Entry, error) {
 	return entry, nil
 }
 
+// TransportStats implements API.
+func (v *Visor) TransportStats(q transport.LogQuery) ([]transport.LogStats, error) {
+	if v.tpM == nil {
+		return nil, ErrTrpMangerNotAvailable
+	}
+	ls, ok := v.tpM.Conf.LogStore.(transport.QueryableLogStore)
+	if !ok {
+		return nil, transport.ErrLogStoreNotQueryable
+	}
+
+	return ls.Stats(q)
+}
+
+// LANPeers implements API.
+func (v *Visor) LANPeers() ([]lan.Peer, error) {
+	if v.lanD == nil {
+		return nil, ErrLANDiscoveryNotAvailable
+	}
+
+	return v.lanD.Peers(), nil
+}
+
 // RoutingRules implements API.
 func (v *Visor) RoutingRules() ([]routing.Rule, error) {
 	return v.router.Rules(), nil
@@ -1453,7 +1552,7 @@ func (v *Visor) RouteGroups() ([]RouteGroupInfo, error) {
 }
 
 // Reload implements API.
-func (v *Visor) Reload() error {
+func (v *Visor) Reload() (ConfigReload, error) {
 	return reload(v)
 }
 
@@ -1463,6 +1562,24 @@ func (v *Visor) Shutdown() error {
 	return v.Close()
 }
 
+// MaxEventsWait is the longest time Events waits for new events, it is kept under the RPC timeout.
+const MaxEventsWait = 10 * time.Second
+
+// Events implements API.
+func (v *Visor) Events(since uint64, wait time.Duration) ([]visorevent.Event, error) {
+	if v == nil || v.events == nil {
+		return nil, ErrEventsNotAvailable
+	}
+	if wait > MaxEventsWait {
+		wait = MaxEventsWait
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wait)
+	defer cancel()
+
+	return v.events.Since(ctx, since), nil
+}
+
 // RuntimeLogs returns visor runtime logs
 func (v *Visor) RuntimeLogs() (string, error) {
 	var builder strings.Builder
@@ -1581,8 +1698,12 @@ func (v *Visor) ListHTTPPorts() ([]int, error) {
 }
 
 // Connect implements API.
-func (v *Visor) Connect(remotePK cipher.PubKey, remotePort, localPort int) (uuid.UUID, error) {
-	ok := isPortAvailable(v.log, localPort)
+func (v *Visor) Connect(remotePK cipher.PubKey, remotePort, localPort int, fwdType appnet.ForwardType) (uuid.UUID, error) {
+	fwdType, err := appnet.ParseForwardType(string(fwdType))
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	ok := fwdType == appnet.ForwardUDP || isPortAvailable(v.log, localPort)
 	if !ok {
 		return uuid.UUID{}, fmt.Errorf(":%v local port already in use", localPort)
 	}
@@ -1603,6 +1724,10 @@ func (v *Visor) Connect(remotePK cipher.PubKey, remotePort, localPort int) (uuid
 	cMsg := clientMsg{
 		Port: remotePort,
 	}
+	// the type is omitted for HTTP to stay compatible with visors that only forward HTTP
+	if fwdType != appnet.ForwardHTTP {
+		cMsg.Type = string(fwdType)
+	}
 
 	clientMsg, err := json.Marshal(cMsg)
 	if err != nil {
@@ -1631,20 +1756,30 @@ func (v *Visor) Connect(remotePK cipher.PubKey, remotePort, localPort int) (uuid
 		v.log.WithError(fmt.Errorf(*sErr)).Error("Server closed with error")
 		return uuid.UUID{}, fmt.Errorf(*sErr)
 	}
-	forwardConn := appnet.NewForwardConn(v.log, remoteConn, remotePort, localPort)
-	forwardConn.Serve()
+	forwardConn := appnet.NewForwardConn(v.log, remoteConn, remotePort, localPort, fwdType)
+	if err := forwardConn.Serve(); err != nil {
+		return uuid.UUID{}, err
+	}
 	return forwardConn.ID, nil
 }
 
 // Disconnect implements API.
 func (v *Visor) Disconnect(id uuid.UUID) error {
 	forwardConn := appnet.GetForwardConn(id)
+	if forwardConn == nil {
+		return fmt.Errorf("forward conn %s not found", id)
+	}
 	return forwardConn.Close()
 }
 
 // List implements API.
 func (v *Visor) List() (map[uuid.UUID]*appnet.ForwardConn, error) {
-	return appnet.GetAllForwardConns(), nil
+	forwardConns := appnet.GetAllForwardConns()
+	out := make(map[uuid.UUID]*appnet.ForwardConn, len(forwardConns))
+	for id, forwardConn := range forwardConns {
+		out[id] = forwardConn.Snapshot()
+	}
+	return out, nil
 }
 
 func isPortAvailable(log *logging.Logger, port int) bool {
@@ -0,0 +1,257 @@
+// Package auditlog pkg/visor/auditlog/auditlog.go
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+// Sources of the audited actions.
+const (
+	SourceHTTP = "http"
+	SourceRPC  = "rpc"
+)
+
+const (
+	// DefaultMaxFileSize is the default size in bytes the audit log is rotated at.
+	DefaultMaxFileSize = 8 << 20
+	// DefaultBackups is the default number of rotated audit logs kept.
+	DefaultBackups = 4
+)
+
+// redacted replaces the values of sensitive parameters.
+const redacted = "<redacted>"
+
+// sensitiveFields are the parts of the names of the parameters whose values are redacted.
+var sensitiveFields = []string{"password", "passcode", "secret", "token", "key"}
+
+// Entry is a single audited action.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	// User is the hypervisor user who performed the action, or the RPC caller.
+	User string `json:"user,omitempty"`
+	// Token is the ID of the API token the action was performed with, if any.
+	Token string `json:"token,omitempty"`
+	// Visor is the public key of the visor the action targets.
+	Visor  cipher.PubKey `json:"visor"`
+	Action string        `json:"action"`
+	Params interface{}   `json:"params,omitempty"`
+	// Status is the HTTP status of the response, only set for HTTP actions.
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Filter selects audit entries, its zero fields match any entry.
+type Filter struct {
+	Since time.Time
+	Until time.Time
+	User  string
+	// Limit is the maximum number of entries to return, the most recent ones are kept.
+	Limit int
+}
+
+// Match returns true if the entry is selected by the filter.
+func (f Filter) Match(e Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+	return f.User == "" || f.User == e.User
+}
+
+// Store is an append-only store of audit entries.
+type Store interface {
+	// Append records the entry.
+	Append(e Entry) error
+	// Entries returns the entries selected by the filter, from the oldest to the most recent.
+	Entries(f Filter) ([]Entry, error)
+}
+
+// NewFileStore returns a store appending the entries to the file at `path`, one JSON object per line.
+// The file and its directory are created on the first append. Once the file grows over `maxSize` bytes,
+// it is moved to `<path>.1` and the previous backups are shifted to `<path>.2` and so on: only `backups`
+// of them are kept, the entries of the older ones are lost. Non-positive values are replaced by
+// DefaultMaxFileSize and DefaultBackups.
+func NewFileStore(path string, maxSize int64, backups int) Store {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFileSize
+	}
+	if backups <= 0 {
+		backups = DefaultBackups
+	}
+	return &fileStore{path: path, maxSize: maxSize, backups: backups}
+}
+
+type fileStore struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+}
+
+// backupPath returns the path of the n-th most recent backup.
+func (s *fileStore) backupPath(n int) string {
+	return s.path + "." + strconv.Itoa(n)
+}
+
+// rotate moves the file to the most recent backup, shifting the previous ones and deleting the oldest.
+func (s *fileStore) rotate() error {
+	if err := os.Remove(s.backupPath(s.backups)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for n := s.backups - 1; n > 0; n-- {
+		if err := os.Rename(s.backupPath(n), s.backupPath(n+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(s.path, s.backupPath(1))
+}
+
+// Append implements Store
+func (s *fileStore) Append(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(s.path); err == nil && fi.Size()+int64(len(line)) >= s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close() //nolint:errcheck
+		return err
+	}
+	return f.Close()
+}
+
+// Entries implements Store
+func (s *fileStore) Entries(filter Filter) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0)
+	paths := make([]string, 0, s.backups+1)
+	for n := s.backups; n > 0; n-- {
+		paths = append(paths, s.backupPath(n))
+	}
+	for _, path := range append(paths, s.path) {
+		var err error
+		if entries, err = readEntries(path, filter, entries); err != nil {
+			return nil, err
+		}
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// readEntries appends the entries of the file at `path` selected by the filter to `entries`.
+func readEntries(path string, filter Filter, entries []Entry) ([]Entry, error) {
+	f, err := os.Open(path) //nolint:gosec
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			var e Entry
+			// a line may be torn by a crash during an append, it is skipped
+			if json.Unmarshal(line, &e) == nil && filter.Match(e) {
+				entries = append(entries, e)
+				// only the most recent entries are kept in memory
+				if filter.Limit > 0 && len(entries) >= 2*filter.Limit {
+					entries = append(entries[:0], entries[len(entries)-filter.Limit:]...)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Redact returns the decoded JSON value `v` with the values of the sensitive fields replaced:
+// passwords, passcodes, secrets, tokens and keys.
+func Redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if isSensitive(k) {
+				v[k] = redacted
+				continue
+			}
+			v[k] = Redact(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = Redact(val)
+		}
+	}
+	return v
+}
+
+// isSensitive returns true if the value of the field `name` is redacted.
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	if name == "sk" {
+		return true
+	}
+	for _, field := range sensitiveFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// Params returns `v` as audit entry parameters, with the values of the sensitive fields replaced.
+func Params(v interface{}) interface{} {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var params interface{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil
+	}
+	return Redact(params)
+}
@@ -0,0 +1,133 @@
+// Package auditlog pkg/visor/auditlog/auditlog_test.go
+package auditlog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	s := NewFileStore(path, 0, 0)
+	pk, _ := cipher.GenerateKeyPair()
+
+	entries, err := s.Entries(Filter{})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	start := time.Now()
+	for i, user := range []string{"admin", "operator", "admin", "admin"} {
+		require.NoError(t, s.Append(Entry{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Source: SourceHTTP,
+			User:   user,
+			Visor:  pk,
+			Action: "PUT /api/visors/{pk}/apps/{app}",
+			Params: Params(map[string]interface{}{"app": "vpn-client", "body": map[string]string{"passcode": "x", "password": "secret"}}),
+		}))
+	}
+
+	// a torn line is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err = s.Entries(Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	require.Equal(t, pk, entries[0].Visor)
+	require.Equal(t, map[string]interface{}{"app": "vpn-client", "body": map[string]interface{}{"passcode": redacted, "password": redacted}}, entries[0].Params)
+
+	entries, err = s.Entries(Filter{User: "admin"})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	entries, err = s.Entries(Filter{User: "admin", Since: start.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	entries, err = s.Entries(Filter{Until: start.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	entries, err = s.Entries(Filter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.True(t, entries[0].Time.Equal(start.Add(3*time.Minute)))
+}
+
+func TestFileStore_rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s := NewFileStore(path, 512, 2).(*fileStore)
+
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		require.NoError(t, s.Append(Entry{Time: start.Add(time.Duration(i) * time.Second), Source: SourceRPC, Action: "Reload"}))
+	}
+
+	// the files never grow over the maximum size
+	for _, p := range []string{path, s.backupPath(1), s.backupPath(2)} {
+		fi, err := os.Stat(p)
+		require.NoError(t, err)
+		require.Less(t, fi.Size(), s.maxSize)
+	}
+
+	// only the configured number of backups is kept
+	_, err := os.Stat(s.backupPath(3))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	var lines int
+	for _, p := range []string{path, s.backupPath(1), s.backupPath(2)} {
+		data, err := os.ReadFile(p) //nolint:gosec
+		require.NoError(t, err)
+		lines += bytes.Count(data, []byte("\n"))
+	}
+
+	// the entries of the oldest backup are returned first, the older ones are dropped
+	entries, err := s.Entries(Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, lines)
+	require.Less(t, len(entries), 30)
+	for i, e := range entries {
+		require.True(t, e.Time.Equal(start.Add(time.Duration(30-len(entries)+i)*time.Second)))
+	}
+
+	entries, err = s.Entries(Filter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.True(t, entries[1].Time.Equal(start.Add(29*time.Second)))
+}
+
+func TestRedact(t *testing.T) {
+	params := Params(map[string]interface{}{
+		"app":           "vpn-client",
+		"Password":      "p",
+		"passcode":      "c",
+		"client_secret": "s",
+		"token":         "t",
+		"sk":            "k",
+		"private_key":   "k",
+		"args":          []interface{}{map[string]interface{}{"api_token": "t", "name": "n"}},
+		"pk":            "031b80cd5773143a39d940dc0710b93dcccc262a85108018a7a95ab9af734f8055",
+	})
+
+	require.Equal(t, map[string]interface{}{
+		"app":           "vpn-client",
+		"Password":      redacted,
+		"passcode":      redacted,
+		"client_secret": redacted,
+		"token":         redacted,
+		"sk":            redacted,
+		"private_key":   redacted,
+		"args":          []interface{}{map[string]interface{}{"api_token": redacted, "name": "n"}},
+		"pk":            "031b80cd5773143a39d940dc0710b93dcccc262a85108018a7a95ab9af734f8055",
+	}, params)
+}
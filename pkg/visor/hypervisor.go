@@ -2,6 +2,7 @@
 package visor
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
@@ -10,6 +11,7 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,14 +33,22 @@ import (
 	"github.com/skycoin/skywire/pkg/app/appserver"
 	"github.com/skycoin/skywire/pkg/routing"
 	"github.com/skycoin/skywire/pkg/transport"
+	"github.com/skycoin/skywire/pkg/visor/auditlog"
 	"github.com/skycoin/skywire/pkg/visor/dmsgtracker"
 	"github.com/skycoin/skywire/pkg/visor/rewardconfig"
 	"github.com/skycoin/skywire/pkg/visor/usermanager"
 	"github.com/skycoin/skywire/pkg/visor/visorconfig"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
 )
 
 const (
 	httpTimeout = 30 * time.Second
+	// eventsKeepAlive is the interval of the comments keeping the event streams alive.
+	eventsKeepAlive = 15 * time.Second
+	// eventsRetryDelay is the delay before requesting the events of a visor again after a failure.
+	eventsRetryDelay = 5 * time.Second
+	// maxAuditBodySize is the size up to which the request and response bodies are read for the audit log.
+	maxAuditBodySize = 64 * 1024
 )
 
 const (
@@ -61,6 +71,7 @@ type Hypervisor struct {
 	remoteVisors map[cipher.PubKey]Conn // connected remote visors to hypervisor
 	dmsgC        *dmsg.Client
 	users        *usermanager.UserManager
+	audit        auditlog.Store
 	mu           *sync.RWMutex
 	selfConn     Conn
 	logger       *logging.Logger
@@ -75,17 +86,18 @@ func NewHypervisor(config visorconfig.HypervisorConfig, visor *Visor, dmsgC *dms
 		return nil, err
 	}
 
-	singleUserDB := usermanager.NewSingleUserStore("admin", boltUserDB)
-
 	selfConn := Conn{
 		Addr:  dmsg.Addr{PK: config.PK, Port: config.DmsgPort},
 		API:   visor,
 		PtyUI: nil,
 	}
 	mLogger := logging.NewMasterLogger()
+	// the audit log is shared with the visor, so that it holds both the RPC calls and the hypervisor requests
+	audit := auditlog.NewFileStore(filepath.Join(filepath.Dir(config.DBPath), visorconfig.AuditLog), 0, 0)
 	if visor != nil {
 		mLogger = visor.MasterLogger()
 		visor.remoteVisors = make(map[cipher.PubKey]Conn)
+		audit = visor.auditLog
 	}
 
 	hv := &Hypervisor{
@@ -93,7 +105,8 @@ func NewHypervisor(config visorconfig.HypervisorConfig, visor *Visor, dmsgC *dms
 		visor:        visor,
 		remoteVisors: make(map[cipher.PubKey]Conn),
 		dmsgC:        dmsgC,
-		users:        usermanager.NewUserManager(mLogger, singleUserDB, config.Cookies),
+		users:        usermanager.NewUserManager(mLogger, boltUserDB, config.Cookies),
+		audit:        audit,
 		mu:           new(sync.RWMutex),
 		selfConn:     selfConn,
 		logger:       mLogger.PackageLogger("hypervisor"),
@@ -210,6 +223,17 @@ func (hv *Hypervisor) makeMux() chi.Router {
 	r.Use(httputil.SetLoggerMiddleware(hv.logger))
 
 	r.Route("/", func(r chi.Router) {
+		// event streams are long-lived, so they are served without the API timeout
+		r.Group(func(r chi.Router) {
+			if hv.c.EnableAuth {
+				r.Use(hv.users.Authorize)
+			}
+
+			read := hv.permit(usermanager.RoleReadOnly)
+			r.With(read).Get("/api/events", hv.getEvents())
+			r.With(read).Get("/api/visors/{pk}/events", hv.getVisorEvents())
+		})
+
 		r.Route("/api", func(r chi.Router) {
 			r.Use(middleware.Timeout(httpTimeout))
 
@@ -219,7 +243,8 @@ func (hv *Hypervisor) makeMux() chi.Router {
 
 			if hv.c.EnableAuth {
 				r.Group(func(r chi.Router) {
-					r.Post("/create-account", hv.users.CreateAccount())
+					// the creation of the admin account is the first audited request
+					r.With(hv.auditRequests).Post("/create-account", hv.users.CreateAccount())
 					r.Post("/login", hv.users.Login())
 					r.Post("/logout", hv.users.Logout())
 				})
@@ -229,48 +254,65 @@ func (hv *Hypervisor) makeMux() chi.Router {
 				if hv.c.EnableAuth {
 					r.Use(hv.users.Authorize)
 				}
+				r.Use(hv.auditRequests)
+
+				read := hv.permit(usermanager.RoleReadOnly)
+				operate := hv.permit(usermanager.RoleOperator)
+				admin := hv.permit(usermanager.RoleAdmin)
 
 				r.Get("/user", hv.users.UserInfo())
 				r.Post("/change-password", hv.users.ChangePassword())
-				r.Get("/about", hv.getAbout())
-				r.Get("/dmsg", hv.getDmsg())
-
-				r.Get("/visors", hv.getVisors())
-				r.Get("/visors-summary", hv.getAllVisorsSummary())
-				r.Get("/visors/{pk}", hv.getVisor())
-				r.Get("/visors/{pk}/summary", hv.getVisorSummary())
-				r.Get("/visors/{pk}/health", hv.getHealth())
-				r.Get("/visors/{pk}/uptime", hv.getUptime())
-				r.Get("/visors/{pk}/apps", hv.getApps())
-				r.Get("/visors/{pk}/apps/{app}", hv.getApp())
-				r.Put("/visors/{pk}/apps/{app}", hv.putApp())
-				r.Get("/visors/{pk}/apps/{app}/logs", hv.appLogsSince())
-				r.Get("/visors/{pk}/apps/{app}/stats", hv.getAppStats())
-				r.Get("/visors/{pk}/apps/{app}/connections", hv.appConnections())
-				r.Get("/visors/{pk}/transport-types", hv.getTransportTypes())
-				r.Get("/visors/{pk}/transports", hv.getTransports())
-				r.Post("/visors/{pk}/transports", hv.postTransport())
-				r.Get("/visors/{pk}/transports/{tid}", hv.getTransport())
-				r.Delete("/visors/{pk}/transports/{tid}", hv.deleteTransport())
-				r.Delete("/visors/{pk}/transports/", hv.deleteTransports())
-				r.Put("/visors/{pk}/public-autoconnect", hv.putPublicAutoconnect())
-				r.Get("/visors/{pk}/routes", hv.getRoutes())
-				r.Post("/visors/{pk}/routes", hv.postRoute())
-				r.Get("/visors/{pk}/routes/{rid}", hv.getRoute())
-				r.Put("/visors/{pk}/routes/{rid}", hv.putRoute())
-				r.Delete("/visors/{pk}/routes/{rid}", hv.deleteRoute())
-				r.Delete("/visors/{pk}/routes/", hv.deleteRoutes())
-				r.Get("/visors/{pk}/routegroups", hv.getRouteGroups())
-				r.Post("/visors/{pk}/shutdown", hv.shutdown())
-				r.Get("/visors/{pk}/runtime-logs", hv.getRuntimeLogs())
-				r.Post("/visors/{pk}/min-hops", hv.postMinHops())
-				r.Get("/visors/{pk}/persistent-transports", hv.getPersistentTransports())
-				r.Put("/visors/{pk}/persistent-transports", hv.putPersistentTransports())
-				r.Get("/visors/{pk}/log/rotation", hv.getLogRotationInterval())
-				r.Put("/visors/{pk}/log/rotation", hv.putLogRotationInterval())
-				r.Get("/visors/{pk}/reward", hv.getRewardAddress())
-				r.Put("/visors/{pk}/reward", hv.putRewardAddress())
-				r.Delete("/visors/{pk}/reward", hv.deleteRewardAddress())
+				r.With(read).Get("/about", hv.getAbout())
+				r.With(read).Get("/dmsg", hv.getDmsg())
+				r.With(admin).Get("/audit", hv.getAudit())
+
+				if hv.c.EnableAuth {
+					r.With(read).Get("/tokens", hv.users.ListTokens())
+					r.With(read).Post("/tokens", hv.users.CreateToken())
+					r.With(read).Delete("/tokens/{id}", hv.users.RevokeToken())
+
+					r.With(admin).Get("/users", hv.users.ListUsers())
+					r.With(admin).Post("/users", hv.users.AddUser())
+					r.With(admin).Put("/users/{name}", hv.users.UpdateUser())
+					r.With(admin).Delete("/users/{name}", hv.users.RemoveUser())
+				}
+
+				r.With(read).Get("/visors", hv.getVisors())
+				r.With(read).Get("/visors-summary", hv.getAllVisorsSummary())
+				r.With(read).Get("/visors/{pk}", hv.getVisor())
+				r.With(read).Get("/visors/{pk}/summary", hv.getVisorSummary())
+				r.With(read).Get("/visors/{pk}/health", hv.getHealth())
+				r.With(read).Get("/visors/{pk}/uptime", hv.getUptime())
+				r.With(read).Get("/visors/{pk}/apps", hv.getApps())
+				r.With(read).Get("/visors/{pk}/apps/{app}", hv.getApp())
+				r.With(operate).Put("/visors/{pk}/apps/{app}", hv.putApp())
+				r.With(read).Get("/visors/{pk}/apps/{app}/logs", hv.appLogsSince())
+				r.With(read).Get("/visors/{pk}/apps/{app}/stats", hv.getAppStats())
+				r.With(read).Get("/visors/{pk}/apps/{app}/connections", hv.appConnections())
+				r.With(read).Get("/visors/{pk}/transport-types", hv.getTransportTypes())
+				r.With(read).Get("/visors/{pk}/transports", hv.getTransports())
+				r.With(operate).Post("/visors/{pk}/transports", hv.postTransport())
+				r.With(read).Get("/visors/{pk}/transports/{tid}", hv.getTransport())
+				r.With(operate).Delete("/visors/{pk}/transports/{tid}", hv.deleteTransport())
+				r.With(operate).Delete("/visors/{pk}/transports/", hv.deleteTransports())
+				r.With(operate).Put("/visors/{pk}/public-autoconnect", hv.putPublicAutoconnect())
+				r.With(read).Get("/visors/{pk}/routes", hv.getRoutes())
+				r.With(operate).Post("/visors/{pk}/routes", hv.postRoute())
+				r.With(read).Get("/visors/{pk}/routes/{rid}", hv.getRoute())
+				r.With(operate).Put("/visors/{pk}/routes/{rid}", hv.putRoute())
+				r.With(operate).Delete("/visors/{pk}/routes/{rid}", hv.deleteRoute())
+				r.With(operate).Delete("/visors/{pk}/routes/", hv.deleteRoutes())
+				r.With(read).Get("/visors/{pk}/routegroups", hv.getRouteGroups())
+				r.With(admin).Post("/visors/{pk}/shutdown", hv.shutdown())
+				r.With(read).Get("/visors/{pk}/runtime-logs", hv.getRuntimeLogs())
+				r.With(operate).Post("/visors/{pk}/min-hops", hv.postMinHops())
+				r.With(read).Get("/visors/{pk}/persistent-transports", hv.getPersistentTransports())
+				r.With(operate).Put("/visors/{pk}/persistent-transports", hv.putPersistentTransports())
+				r.With(read).Get("/visors/{pk}/log/rotation", hv.getLogRotationInterval())
+				r.With(operate).Put("/visors/{pk}/log/rotation", hv.putLogRotationInterval())
+				r.With(read).Get("/visors/{pk}/reward", hv.getRewardAddress())
+				r.With(admin).Put("/visors/{pk}/reward", hv.putRewardAddress())
+				r.With(admin).Delete("/visors/{pk}/reward", hv.deleteRewardAddress())
 			})
 		})
 
@@ -280,7 +322,7 @@ func (hv *Hypervisor) makeMux() chi.Router {
 				r.Use(hv.users.Authorize)
 			}
 
-			r.Get("/{pk}", hv.getPty())
+			r.With(hv.permit(usermanager.RoleAdmin)).Get("/{pk}", hv.getPty())
 		})
 
 		r.Handle("/*", http.FileServer(http.FS(hv.c.UIAssets)))
@@ -289,6 +331,155 @@ func (hv *Hypervisor) makeMux() chi.Router {
 	return r
 }
 
+// permit restricts an endpoint to the users of `role` if authentication is enabled.
+func (hv *Hypervisor) permit(role usermanager.Role) func(http.Handler) http.Handler {
+	if !hv.c.EnableAuth {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return hv.users.Permit(role)
+}
+
+// canAccessVisor returns false if the user of the request is restricted from the visor of `pk`.
+func (hv *Hypervisor) canAccessVisor(r *http.Request, pk cipher.PubKey) bool {
+	user, ok := usermanager.UserFromContext(r.Context())
+	return !ok || user.CanAccessVisor(pk)
+}
+
+// auditRequests is an http middleware recording the mutating requests in the audit log.
+func (hv *Hypervisor) auditRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the body is replayed to the handler, only its beginning is recorded
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBodySize))
+		if err != nil {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+
+		var resp bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&limitedWriter{w: &resp, n: maxAuditBodySize})
+		next.ServeHTTP(ww, r)
+
+		entry := auditlog.Entry{
+			Source: auditlog.SourceHTTP,
+			Visor:  hv.c.PK,
+			Action: r.Method + " " + r.URL.Path,
+			Status: ww.Status(),
+		}
+		if user, ok := usermanager.UserFromContext(r.Context()); ok {
+			entry.User = user.Name
+		}
+		if token, ok := usermanager.TokenFromContext(r.Context()); ok {
+			entry.Token = token.ID.String()
+		}
+
+		params := make(map[string]interface{})
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				entry.Action = r.Method + " " + pattern
+			}
+			for i, key := range rctx.URLParams.Keys {
+				if key == "pk" {
+					if err := entry.Visor.Set(rctx.URLParams.Values[i]); err != nil {
+						entry.Visor = hv.c.PK
+					}
+					continue
+				}
+				if key != "*" {
+					params[key] = rctx.URLParams.Values[i]
+				}
+			}
+		}
+		var decoded interface{}
+		if len(body) != 0 && json.Unmarshal(body, &decoded) == nil {
+			params["body"] = auditlog.Redact(decoded)
+		}
+		if len(params) != 0 {
+			entry.Params = params
+		}
+
+		if entry.Status >= http.StatusBadRequest {
+			var e struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(resp.Bytes(), &e) != nil || e.Error == "" {
+				e.Error = http.StatusText(entry.Status)
+			}
+			entry.Error = e.Error
+		}
+
+		if err := hv.audit.Append(entry); err != nil {
+			hv.log(r).WithError(err).Warn("Failed to record request in the audit log.")
+		}
+	})
+}
+
+// limitedWriter writes up to n bytes to w and discards the rest.
+type limitedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if lw.n > 0 {
+		b := p
+		if int64(len(b)) > lw.n {
+			b = b[:lw.n]
+		}
+		n, err := lw.w.Write(b)
+		lw.n -= int64(n)
+		if err != nil {
+			return n, err
+		}
+	}
+	return len(p), nil
+}
+
+func (hv *Hypervisor) getAudit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filter auditlog.Filter
+		var err error
+
+		q := r.URL.Query()
+		if since := q.Get("since"); since != "" {
+			if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+				httputil.WriteJSON(w, r, http.StatusBadRequest, fmt.Errorf("invalid since: %w", err))
+				return
+			}
+		}
+		if until := q.Get("until"); until != "" {
+			if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+				httputil.WriteJSON(w, r, http.StatusBadRequest, fmt.Errorf("invalid until: %w", err))
+				return
+			}
+		}
+		if limit := q.Get("limit"); limit != "" {
+			if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+				httputil.WriteJSON(w, r, http.StatusBadRequest, fmt.Errorf("invalid limit: %s", limit))
+				return
+			}
+		}
+		filter.User = q.Get("user")
+
+		entries, err := hv.audit.Entries(filter)
+		if err != nil {
+			httputil.WriteJSON(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		httputil.WriteJSON(w, r, http.StatusOK, entries)
+	}
+}
+
 func (hv *Hypervisor) log(r *http.Request) logrus.FieldLogger {
 	return httputil.GetLogger(r)
 }
@@ -341,7 +532,12 @@ func (hv *Hypervisor) getAbout() http.HandlerFunc {
 
 func (hv *Hypervisor) getDmsg() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		out := hv.getDmsgSummary()
+		out := make([]dmsgtracker.DmsgClientSummary, 0)
+		for _, summary := range hv.getDmsgSummary() {
+			if hv.canAccessVisor(r, summary.PK) {
+				out = append(out, summary)
+			}
+		}
 		httputil.WriteJSON(w, r, http.StatusOK, out)
 	}
 }
@@ -468,7 +664,136 @@ func (hv *Hypervisor) getVisors() http.HandlerFunc {
 		wg.Wait()
 		hv.mu.RUnlock()
 
-		httputil.WriteJSON(w, r, http.StatusOK, overviews)
+		visible := make([]Overview, 0, len(overviews))
+		for _, overview := range overviews {
+			if hv.canAccessVisor(r, overview.PubKey) {
+				visible = append(visible, overview)
+			}
+		}
+
+		httputil.WriteJSON(w, r, http.StatusOK, visible)
+	}
+}
+
+// streams the events of all of the visors the user can access.
+func (hv *Hypervisor) getEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		visors := make(map[cipher.PubKey]API)
+
+		hv.mu.RLock()
+		if hv.visor != nil {
+			visors[hv.c.PK] = hv.visor
+		}
+		for pk, c := range hv.remoteVisors {
+			visors[pk] = c.API
+		}
+		hv.mu.RUnlock()
+
+		for pk := range visors {
+			if !hv.canAccessVisor(r, pk) {
+				delete(visors, pk)
+			}
+		}
+
+		hv.streamEvents(w, r, visors, 0)
+	}
+}
+
+// streams the events of a single visor, from the event after the `since` query
+// parameter or the `Last-Event-ID` header if any.
+func (hv *Hypervisor) getVisorEvents() http.HandlerFunc {
+	return hv.withCtx(hv.visorCtx, func(w http.ResponseWriter, r *http.Request, ctx *httpCtx) {
+		var since uint64
+		lastID := r.URL.Query().Get("since")
+		if lastID == "" {
+			lastID = r.Header.Get("Last-Event-ID")
+		}
+		if lastID != "" {
+			var err error
+			if since, err = strconv.ParseUint(lastID, 10, 64); err != nil {
+				httputil.WriteJSON(w, r, http.StatusBadRequest, fmt.Errorf("invalid since: %s", lastID))
+				return
+			}
+		}
+
+		hv.streamEvents(w, r, map[cipher.PubKey]API{ctx.Addr.PK: ctx.API}, since)
+	})
+}
+
+// streamEvents streams the events of the visors as server-sent events until the request is done.
+func (hv *Hypervisor) streamEvents(w http.ResponseWriter, r *http.Request, visors map[cipher.PubKey]API, since uint64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httputil.WriteJSON(w, r, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	// the stream outlives the write timeout of the server
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		hv.log(r).WithError(err).Debug("Failed to clear the write deadline of the event stream.")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	events := make(chan visorevent.Event)
+	for pk, api := range visors {
+		go func(pk cipher.PubKey, api API) {
+			since := since
+			for ctx.Err() == nil {
+				batch, err := api.Events(since, MaxEventsWait)
+				if err != nil {
+					hv.log(r).WithError(err).WithField("visor", pk).Debug("Failed to obtain events.")
+					select {
+					case <-time.After(eventsRetryDelay):
+						continue
+					case <-ctx.Done():
+						return
+					}
+				}
+				for _, e := range batch {
+					since = e.Seq
+					select {
+					case events <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(pk, api)
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-events:
+			var raw []byte
+			if raw, err = json.Marshal(e); err != nil {
+				continue
+			}
+			// event IDs are only meaningful for the events of a single visor
+			if len(visors) == 1 {
+				_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Seq, e.Type, raw)
+			} else {
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, raw)
+			}
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err != nil {
+			hv.log(r).WithError(err).Debug("Failed to write event stream.")
+			return
+		}
+		flusher.Flush()
 	}
 }
 
@@ -579,7 +904,14 @@ func (hv *Hypervisor) getAllVisorsSummary() http.HandlerFunc {
 
 		hv.mu.RUnlock()
 
-		httputil.WriteJSON(w, r, http.StatusOK, summaries)
+		visible := make([]Summary, 0, len(summaries))
+		for _, summary := range summaries {
+			if hv.canAccessVisor(r, summary.Overview.PubKey) {
+				visible = append(visible, summary)
+			}
+		}
+
+		httputil.WriteJSON(w, r, http.StatusOK, visible)
 	}
 }
 
@@ -1377,7 +1709,7 @@ func (hv *Hypervisor) visorCtx(w http.ResponseWriter, r *http.Request) (*httpCtx
 		return nil, false
 	}
 
-	if useCsrf && (r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE") {
+	if useCsrf && !usermanager.IsTokenAuthorized(r.Context()) && (r.Method == "POST" || r.Method == "PUT" || r.Method == "DELETE") {
 		csrfToken := r.Header.Get(CSRFHeaderName)
 		if csrfToken == "" {
 			errMsg := fmt.Errorf("no csrf token for %s request", r.Method)
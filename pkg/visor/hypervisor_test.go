@@ -2,6 +2,7 @@
 package visor
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,12 +13,16 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/visor/auditlog"
 	"github.com/skycoin/skywire/pkg/visor/usermanager"
 	"github.com/skycoin/skywire/pkg/visor/visorconfig"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
 )
 
 // nolint: gosec
@@ -33,7 +38,7 @@ func TestNewNode(t *testing.T) {
 	config.EnableAuth = true
 	config.FillDefaults(false)
 
-	confDir := os.TempDir()
+	confDir := t.TempDir()
 
 	config.DBPath = filepath.Join(confDir, "users_test.db")
 
@@ -60,6 +65,18 @@ func TestNewNode(t *testing.T) {
 	t.Run("change_password", func(t *testing.T) {
 		testNodeChangePassword(t, config)
 	})
+
+	t.Run("roles", func(t *testing.T) {
+		testNodeRoles(t, config)
+	})
+
+	t.Run("tokens", func(t *testing.T) {
+		testNodeTokens(t, config)
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		testNodeAudit(t, config)
+	})
 }
 
 func makeStartNode(t *testing.T, config visorconfig.HypervisorConfig) (string, *http.Client, func()) {
@@ -88,6 +105,7 @@ func makeStartNode(t *testing.T, config visorconfig.HypervisorConfig) (string, *
 		srv.Close()
 		_ = visor.users.Close() // nolint:errcheck
 		require.NoError(t, os.Remove(config.DBPath))
+		_ = os.Remove(filepath.Join(filepath.Dir(config.DBPath), visorconfig.AuditLog)) //nolint:errcheck
 	}
 }
 
@@ -422,6 +440,397 @@ func testNodeChangePassword(t *testing.T, config visorconfig.HypervisorConfig) {
 	})
 }
 
+// - Create and login as admin.
+// - Add read-only and operator users.
+// - Read-only users may view but not change the visors or manage users.
+// - Operators restricted to a visor don't see the others.
+// - Removed users are logged out.
+// - The admin account can't be created again, removed or demoted.
+// nolint: funlen
+func testNodeRoles(t *testing.T, config visorconfig.HypervisorConfig) {
+	addr, adminC, stop := makeStartNode(t, config)
+	defer stop()
+
+	newClient := func() *http.Client {
+		jar, err := cookiejar.New(&cookiejar.Options{})
+		require.NoError(t, err)
+		return &http.Client{Transport: adminC.Transport, Jar: jar}
+	}
+	readerC, operatorC, admin2C := newClient(), newClient(), newClient()
+
+	visorPK, _ := cipher.GenerateKeyPair()
+	otherPK, _ := cipher.GenerateKeyPair()
+	decodeOK := func(t *testing.T, r *http.Response) {
+		var ok bool
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&ok))
+		assert.True(t, ok)
+	}
+	decodeErr := func(err error) func(t *testing.T, r *http.Response) {
+		return func(t *testing.T, r *http.Response) {
+			body, decErr := decodeErrorBody(r.Body)
+			assert.NoError(t, decErr)
+			assert.Equal(t, err.Error(), body.Error)
+		}
+	}
+
+	testCases(t, addr, adminC, []TestCase{
+		{ReqMethod: http.MethodPost, ReqURI: "/api/create-account", ReqBody: strings.NewReader(goodPayload), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{ReqMethod: http.MethodPost, ReqURI: "/api/login", ReqBody: strings.NewReader(goodPayload), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{
+			ReqMethod:  http.MethodPost,
+			ReqURI:     "/api/users",
+			ReqBody:    strings.NewReader(`{"username":"reader","password":"Secure1234!","role":"superuser"}`),
+			RespStatus: http.StatusBadRequest,
+			RespBody:   decodeErr(usermanager.ErrBadRole),
+		},
+		{
+			ReqMethod:  http.MethodPost,
+			ReqURI:     "/api/users",
+			ReqBody:    strings.NewReader(`{"username":"reader","password":"Secure1234!","role":"read-only"}`),
+			RespStatus: http.StatusOK,
+		},
+		{
+			ReqMethod:  http.MethodPost,
+			ReqURI:     "/api/users",
+			ReqBody:    strings.NewReader(fmt.Sprintf(`{"username":"operator","password":"Secure1234!","role":"operator","visors":["%s"]}`, visorPK)),
+			RespStatus: http.StatusOK,
+		},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/users",
+			RespStatus: http.StatusOK,
+			RespBody: func(t *testing.T, r *http.Response) {
+				var users []usermanager.UserSummary
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&users))
+				assert.Equal(t, []usermanager.UserSummary{
+					{Username: "admin", Role: usermanager.RoleAdmin},
+					{Username: "operator", Role: usermanager.RoleOperator, Visors: []cipher.PubKey{visorPK}},
+					{Username: "reader", Role: usermanager.RoleReadOnly},
+				}, users)
+			},
+		},
+		{
+			ReqMethod:  http.MethodDelete,
+			ReqURI:     "/api/users/admin",
+			RespStatus: http.StatusForbidden,
+			RespBody:   decodeErr(usermanager.ErrRemoveSelf),
+		},
+	})
+
+	testCases(t, addr, readerC, []TestCase{
+		{ReqMethod: http.MethodPost, ReqURI: "/api/login", ReqBody: strings.NewReader(`{"username":"reader","password":"Secure1234!"}`), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/visors",
+			RespStatus: http.StatusOK,
+			RespBody: func(t *testing.T, r *http.Response) {
+				var overviews []Overview
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&overviews))
+				assert.NotEmpty(t, overviews)
+			},
+		},
+		{ReqMethod: http.MethodPost, ReqURI: "/api/visors/" + visorPK.Hex() + "/shutdown", RespStatus: http.StatusForbidden, RespBody: decodeErr(usermanager.ErrForbidden)},
+		{ReqMethod: http.MethodDelete, ReqURI: "/api/visors/" + visorPK.Hex() + "/routes/1", RespStatus: http.StatusForbidden, RespBody: decodeErr(usermanager.ErrForbidden)},
+		{ReqMethod: http.MethodGet, ReqURI: "/api/users", RespStatus: http.StatusForbidden, RespBody: decodeErr(usermanager.ErrForbidden)},
+	})
+
+	testCases(t, addr, operatorC, []TestCase{
+		{ReqMethod: http.MethodPost, ReqURI: "/api/login", ReqBody: strings.NewReader(`{"username":"operator","password":"Secure1234!"}`), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/visors",
+			RespStatus: http.StatusOK,
+			RespBody: func(t *testing.T, r *http.Response) {
+				var overviews []Overview
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&overviews))
+				assert.Empty(t, overviews)
+			},
+		},
+		{ReqMethod: http.MethodGet, ReqURI: "/api/visors/" + otherPK.Hex() + "/transports", RespStatus: http.StatusForbidden, RespBody: decodeErr(usermanager.ErrForbidden)},
+	})
+
+	testCases(t, addr, adminC, []TestCase{
+		{ReqMethod: http.MethodDelete, ReqURI: "/api/users/reader", RespStatus: http.StatusOK, RespBody: decodeOK},
+	})
+
+	testCases(t, addr, readerC, []TestCase{
+		{ReqMethod: http.MethodGet, ReqURI: "/api/visors", RespStatus: http.StatusUnauthorized, RespBody: decodeErr(usermanager.ErrBadSession)},
+		{
+			ReqMethod:  http.MethodPost,
+			ReqURI:     "/api/create-account",
+			ReqBody:    strings.NewReader(`{"username":"admin","password":"Secure1234!"}`),
+			RespStatus: http.StatusForbidden,
+			RespBody:   decodeErr(usermanager.ErrAccountExists),
+		},
+	})
+
+	testCases(t, addr, adminC, []TestCase{
+		{
+			ReqMethod:  http.MethodPost,
+			ReqURI:     "/api/users",
+			ReqBody:    strings.NewReader(`{"username":"admin2","password":"Secure1234!","role":"admin"}`),
+			RespStatus: http.StatusOK,
+		},
+	})
+
+	testCases(t, addr, admin2C, []TestCase{
+		{ReqMethod: http.MethodPost, ReqURI: "/api/login", ReqBody: strings.NewReader(`{"username":"admin2","password":"Secure1234!"}`), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{ReqMethod: http.MethodDelete, ReqURI: "/api/users/admin", RespStatus: http.StatusForbidden, RespBody: decodeErr(usermanager.ErrRemoveAdmin)},
+		{
+			ReqMethod:  http.MethodPut,
+			ReqURI:     "/api/users/admin",
+			ReqBody:    strings.NewReader(`{"role":"operator"}`),
+			RespStatus: http.StatusForbidden,
+			RespBody:   decodeErr(usermanager.ErrRemoveAdmin),
+		},
+	})
+}
+
+// - Create and login as admin.
+// - Create a read-only token.
+// - The token gives read-only access without cookies, and can't manage tokens.
+// - Revoked tokens are rejected.
+// nolint: funlen
+func testNodeTokens(t *testing.T, config visorconfig.HypervisorConfig) {
+	addr, adminC, stop := makeStartNode(t, config)
+	defer stop()
+
+	// no cookie jar, requests are only authorized by the token
+	tokenC := &http.Client{Transport: adminC.Transport}
+
+	var token usermanager.TokenSummary
+	decodeOK := func(t *testing.T, r *http.Response) {
+		var ok bool
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&ok))
+		assert.True(t, ok)
+	}
+	decodeErr := func(err error) func(t *testing.T, r *http.Response) {
+		return func(t *testing.T, r *http.Response) {
+			body, decErr := decodeErrorBody(r.Body)
+			assert.NoError(t, decErr)
+			assert.Equal(t, err.Error(), body.Error)
+		}
+	}
+	withToken := func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+	}
+	visorPK, _ := cipher.GenerateKeyPair()
+
+	testCases(t, addr, adminC, []TestCase{
+		{ReqMethod: http.MethodPost, ReqURI: "/api/create-account", ReqBody: strings.NewReader(goodPayload), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{ReqMethod: http.MethodPost, ReqURI: "/api/login", ReqBody: strings.NewReader(goodPayload), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{
+			ReqMethod:  http.MethodPost,
+			ReqURI:     "/api/tokens",
+			ReqBody:    strings.NewReader(`{"name":"ci","scope":"read-only","expires_in":"1h"}`),
+			RespStatus: http.StatusOK,
+			RespBody: func(t *testing.T, r *http.Response) {
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&token))
+				assert.Equal(t, usermanager.RoleReadOnly, token.Scope)
+				assert.NotEmpty(t, token.Token)
+				assert.NotNil(t, token.Expiry)
+			},
+		},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/tokens",
+			RespStatus: http.StatusOK,
+			RespBody: func(t *testing.T, r *http.Response) {
+				var tokens []usermanager.TokenSummary
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&tokens))
+				require.Len(t, tokens, 1)
+				assert.Equal(t, token.ID, tokens[0].ID)
+				assert.Empty(t, tokens[0].Token)
+			},
+		},
+	})
+
+	testCases(t, addr, tokenC, []TestCase{
+		{ReqMethod: http.MethodGet, ReqURI: "/api/visors", ReqMod: withToken, RespStatus: http.StatusOK},
+		{ReqMethod: http.MethodPost, ReqURI: "/api/visors/" + visorPK.Hex() + "/shutdown", ReqMod: withToken, RespStatus: http.StatusForbidden, RespBody: decodeErr(usermanager.ErrForbidden)},
+		{ReqMethod: http.MethodGet, ReqURI: "/api/tokens", ReqMod: withToken, RespStatus: http.StatusForbidden, RespBody: decodeErr(usermanager.ErrTokenAuth)},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/visors",
+			ReqMod:     func(req *http.Request) { req.Header.Set("Authorization", "Bearer swt_invalid") },
+			RespStatus: http.StatusUnauthorized,
+			RespBody:   decodeErr(usermanager.ErrBadToken),
+		},
+	})
+
+	testCases(t, addr, adminC, []TestCase{
+		{ReqMethod: http.MethodDelete, ReqURI: "/api/tokens/" + token.ID.String(), RespStatus: http.StatusOK, RespBody: decodeOK},
+	})
+
+	testCases(t, addr, tokenC, []TestCase{
+		{ReqMethod: http.MethodGet, ReqURI: "/api/visors", ReqMod: withToken, RespStatus: http.StatusUnauthorized, RespBody: decodeErr(usermanager.ErrBadToken)},
+	})
+}
+
+func testNodeAudit(t *testing.T, config visorconfig.HypervisorConfig) {
+	addr, adminC, stop := makeStartNode(t, config)
+	defer stop()
+
+	visorPK, _ := cipher.GenerateKeyPair()
+	start := time.Now().Add(-time.Second)
+	decodeOK := func(t *testing.T, r *http.Response) {
+		var ok bool
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&ok))
+		assert.True(t, ok)
+	}
+	decodeEntries := func(check func(t *testing.T, entries []auditlog.Entry)) func(t *testing.T, r *http.Response) {
+		return func(t *testing.T, r *http.Response) {
+			var entries []auditlog.Entry
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&entries))
+			check(t, entries)
+		}
+	}
+
+	testCases(t, addr, adminC, []TestCase{
+		{ReqMethod: http.MethodPost, ReqURI: "/api/create-account", ReqBody: strings.NewReader(goodPayload), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{ReqMethod: http.MethodPost, ReqURI: "/api/login", ReqBody: strings.NewReader(goodPayload), RespStatus: http.StatusOK, RespBody: decodeOK},
+		{
+			ReqMethod:  http.MethodPost,
+			ReqURI:     "/api/users",
+			ReqBody:    strings.NewReader(`{"username":"reader","password":"Secure1234!","role":"read-only"}`),
+			RespStatus: http.StatusOK,
+		},
+		{ReqMethod: http.MethodPost, ReqURI: "/api/visors/" + visorPK.Hex() + "/shutdown", RespStatus: http.StatusForbidden},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/audit?user=admin&since=" + start.UTC().Format(time.RFC3339),
+			RespStatus: http.StatusOK,
+			RespBody: decodeEntries(func(t *testing.T, entries []auditlog.Entry) {
+				require.Len(t, entries, 2)
+
+				assert.Equal(t, auditlog.SourceHTTP, entries[0].Source)
+				assert.Equal(t, "admin", entries[0].User)
+				assert.Equal(t, config.PK, entries[0].Visor)
+				assert.Equal(t, "POST /api/users", entries[0].Action)
+				assert.Equal(t, http.StatusOK, entries[0].Status)
+				assert.Empty(t, entries[0].Error)
+				params, err := json.Marshal(entries[0].Params)
+				require.NoError(t, err)
+				assert.NotContains(t, string(params), "Secure1234!")
+				assert.Contains(t, string(params), "read-only")
+
+				assert.Equal(t, visorPK, entries[1].Visor)
+				assert.Equal(t, "POST /api/visors/{pk}/shutdown", entries[1].Action)
+				assert.Equal(t, http.StatusForbidden, entries[1].Status)
+				assert.NotEmpty(t, entries[1].Error)
+			}),
+		},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/audit?since=" + start.UTC().Format(time.RFC3339),
+			RespStatus: http.StatusOK,
+			RespBody: decodeEntries(func(t *testing.T, entries []auditlog.Entry) {
+				require.Len(t, entries, 3)
+
+				// the admin account is created before anyone is logged in
+				assert.Equal(t, "POST /api/create-account", entries[0].Action)
+				assert.Empty(t, entries[0].User)
+				assert.Equal(t, http.StatusOK, entries[0].Status)
+				params, err := json.Marshal(entries[0].Params)
+				require.NoError(t, err)
+				assert.NotContains(t, string(params), "Secure1234!")
+			}),
+		},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/audit?limit=1",
+			RespStatus: http.StatusOK,
+			RespBody: decodeEntries(func(t *testing.T, entries []auditlog.Entry) {
+				require.Len(t, entries, 1)
+				assert.Equal(t, "POST /api/visors/{pk}/shutdown", entries[0].Action)
+			}),
+		},
+		{
+			ReqMethod:  http.MethodGet,
+			ReqURI:     "/api/audit?user=reader",
+			RespStatus: http.StatusOK,
+			RespBody: decodeEntries(func(t *testing.T, entries []auditlog.Entry) {
+				assert.Empty(t, entries)
+			}),
+		},
+		{ReqMethod: http.MethodGet, ReqURI: "/api/audit?since=yesterday", RespStatus: http.StatusBadRequest},
+	})
+}
+
+func TestHypervisorEvents(t *testing.T) {
+	config := visorconfig.MakeConfig(false)
+	config.FillDefaults(false)
+	config.DBPath = filepath.Join(t.TempDir(), "users.db")
+
+	hv, err := NewHypervisor(config, nil, nil)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, hv.users.Close()) }()
+
+	pk, _ := cipher.GenerateKeyPair()
+	bus := visorevent.NewBus(pk, 0)
+	hv.remoteVisors[pk] = Conn{API: &Visor{events: bus}}
+	bus.Publish(visorevent.AppStarted, visorevent.AppData{Name: "skychat"})
+
+	// streams outlive the write timeout of the server
+	srv := httptest.NewUnstartedServer(hv.HTTPHandler())
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	// readEvent reads the ID and the event of the next server-sent event of the stream.
+	readEvent := func(t *testing.T, r *bufio.Reader) (string, visorevent.Event) {
+		var id string
+		var e visorevent.Event
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				return id, e
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+			}
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/visors/"+pk.Hex()+"/events", nil)
+	require.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+
+	id, e := readEvent(t, r)
+	require.Equal(t, "1", id)
+	require.Equal(t, visorevent.AppStarted, e.Type)
+	require.Equal(t, pk, e.Visor)
+
+	time.Sleep(2 * srv.Config.WriteTimeout)
+	bus.Publish(visorevent.TransportUp, visorevent.TransportData{Type: "stcpr"})
+	id, e = readEvent(t, r)
+	require.Equal(t, "2", id)
+	require.Equal(t, visorevent.TransportUp, e.Type)
+	require.NoError(t, resp.Body.Close())
+
+	// reconnecting streams resume after the last event received
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	id, e = readEvent(t, bufio.NewReader(resp.Body))
+	require.Equal(t, "2", id)
+	require.Equal(t, visorevent.TransportUp, e.Type)
+	require.NoError(t, resp.Body.Close())
+
+	resp, err = srv.Client().Get(srv.URL + "/api/visors/" + pk.Hex() + "/events?since=latest")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+}
+
 type ErrorBody struct {
 	Error string `json:"error"`
 }
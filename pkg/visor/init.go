@@ -16,7 +16,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ccding/go-stun/stun"
@@ -48,6 +50,7 @@ import (
 	"github.com/skycoin/skywire/pkg/transport"
 	"github.com/skycoin/skywire/pkg/transport/network"
 	"github.com/skycoin/skywire/pkg/transport/network/addrresolver"
+	"github.com/skycoin/skywire/pkg/transport/network/lan"
 	"github.com/skycoin/skywire/pkg/transport/network/stcp"
 	ts "github.com/skycoin/skywire/pkg/transport/setup"
 	"github.com/skycoin/skywire/pkg/transport/tpdclient"
@@ -56,7 +59,9 @@ import (
 	"github.com/skycoin/skywire/pkg/visor/dmsgtracker"
 	"github.com/skycoin/skywire/pkg/visor/logserver"
 	"github.com/skycoin/skywire/pkg/visor/visorconfig"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
 	vinit "github.com/skycoin/skywire/pkg/visor/visorinit"
+	"github.com/skycoin/skywire/pkg/visor/visormetrics"
 )
 
 type visorCtxKey int
@@ -69,6 +74,9 @@ const runtimeErrsKey runtimeErrsCtxKey = iota
 
 const ownerRWX = 0700
 
+// visorCountriesRefresh is how often the countries of the visors are fetched for the route policies.
+const visorCountriesRefresh = 30 * time.Minute
+
 // Visor initialization is split into modules, that can be initialized independently
 // Modules are declared here as package-level variables, but also need to be registered
 // in the modules system: they need init function and dependencies and their name to be set
@@ -87,8 +95,14 @@ var (
 	sudphC vinit.Module
 	// STCPR module
 	stcprC vinit.Module
+	// QUIC module
+	quicC vinit.Module
+	// WSS module
+	wssC vinit.Module
 	// STCP module
 	stcpC vinit.Module
+	// LAN discovery: find visors on the local network for STCP transports
+	lanC vinit.Module
 	// dmsg pty: a remote terminal to the visor working over dmsg protocol
 	pty vinit.Module
 	// Dmsg module
@@ -129,6 +143,8 @@ var (
 	skyFwd vinit.Module
 	// Ping module
 	pi vinit.Module
+	// Metrics endpoint module
+	met vinit.Module
 	// visor that groups all modules together
 	vis vinit.Module
 	// config initialization
@@ -153,7 +169,10 @@ func registerModules(logger *logging.MasterLogger) {
 	sc = maker("stun_client", initStunClient)
 	sudphC = maker("sudph", initSudphClient, &sc, &tr)
 	stcprC = maker("stcpr", initStcprClient, &tr)
+	quicC = maker("quic", initQuicClient, &tr)
+	wssC = maker("wss", initWssClient, &tr)
 	stcpC = maker("stcp", initStcpClient, &tr)
+	lanC = maker("lan_discovery", initLANDiscovery, &stcpC)
 	dmsgC = maker("dmsg", initDmsg, &ebc, &dmsgHTTP)
 	dmsgCtrl = maker("dmsg_ctrl", initDmsgCtrl, &dmsgC, &tr)
 	dmsgHTTPLogServer = maker("dmsghttp_logserver", initDmsgHTTPLogServer, &dmsgC, &tr)
@@ -172,8 +191,9 @@ func registerModules(logger *logging.MasterLogger) {
 	pvs = maker("public_visor", initPublicVisor, &tr, &ar, &disc, &stcprC)
 	skyFwd = maker("sky_forward_conn", initSkywireForwardConn, &dmsgC, &dmsgCtrl, &tr, &launch)
 	pi = maker("ping", initPing, &dmsgC, &tm)
+	met = maker("metrics", initMetrics, &dmsgC, &tr, &rt, &launch)
 	vis = vinit.MakeModule("visor", vinit.DoNothing, logger, &ebc, &ar, &disc, &pty,
-		&tr, &rt, &launch, &cli, &hvs, &ut, &pv, &pvs, &trs, &stcpC, &stcprC, &skyFwd, &pi, &systemSurvey)
+		&tr, &rt, &launch, &cli, &hvs, &ut, &pv, &pvs, &trs, &stcpC, &lanC, &stcprC, &quicC, &wssC, &skyFwd, &pi, &systemSurvey, &met)
 
 	hv = maker("hypervisor", initHypervisor, &vis)
 }
@@ -324,7 +344,20 @@ func initDmsg(ctx context.Context, v *Visor, log *logging.Logger) (err error) {
 	if err != nil {
 		return err
 	}
-	dmsgC := dmsgc.New(v.conf.PK, v.conf.SK, v.ebc, v.conf.Dmsg, httpC, v.MasterLogger())
+	var dmsgC *dmsg.Client
+	sessions := newDmsgSessionEvents(ctx, v.events, func() []string {
+		var addrs []string
+		for _, ses := range dmsgC.AllSessions() {
+			addrs = append(addrs, ses.RemoteTCPAddr().String())
+		}
+		return addrs
+	})
+	// OnSessionDial fires before the session is dialed, so the up event is
+	// only published once the session shows up as established.
+	dmsgC = dmsgc.New(v.conf.PK, v.conf.SK, v.ebc, v.conf.Dmsg, httpC, v.MasterLogger(), &dmsg.ClientCallbacks{
+		OnSessionDial:       sessions.dialed,
+		OnSessionDisconnect: sessions.disconnected,
+	})
 	wg := new(sync.WaitGroup)
 	wg.Add(1)
 	go func() {
@@ -346,6 +379,111 @@ func initDmsg(ctx context.Context, v *Visor, log *logging.Logger) (err error) {
 	return nil
 }
 
+const (
+	dmsgSessionPollInterval = 100 * time.Millisecond
+	dmsgSessionUpTimeout    = 30 * time.Second
+)
+
+// dmsgSessionEvents publishes dmsg session up/down events. A session is
+// reported up once it is established and down only if it was reported up.
+type dmsgSessionEvents struct {
+	ctx      context.Context
+	events   *visorevent.Bus
+	sessions func() []string // remote addresses of the established sessions
+
+	mx    sync.Mutex
+	dials map[string]chan struct{} // closed when the dial is over
+	up    map[string]bool
+}
+
+func newDmsgSessionEvents(ctx context.Context, events *visorevent.Bus, sessions func() []string) *dmsgSessionEvents {
+	return &dmsgSessionEvents{
+		ctx:      ctx,
+		events:   events,
+		sessions: sessions,
+		dials:    make(map[string]chan struct{}),
+		up:       make(map[string]bool),
+	}
+}
+
+func (e *dmsgSessionEvents) dialed(network, addr string) error {
+	done := make(chan struct{})
+	e.mx.Lock()
+	if prev, ok := e.dials[addr]; ok {
+		close(prev)
+	}
+	e.dials[addr] = done
+	e.mx.Unlock()
+
+	go e.waitUp(network, addr, done)
+	return nil
+}
+
+func (e *dmsgSessionEvents) waitUp(network, addr string, done chan struct{}) {
+	addrs := []string{addr}
+	if tcpAddr, err := net.ResolveTCPAddr(network, addr); err == nil && tcpAddr.String() != addr {
+		addrs = append(addrs, tcpAddr.String())
+	}
+
+	ticker := time.NewTicker(dmsgSessionPollInterval)
+	defer ticker.Stop()
+	timeout := time.NewTimer(dmsgSessionUpTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-done:
+			return
+		case <-timeout.C:
+			return
+		case <-ticker.C:
+		}
+		if !e.established(addrs) {
+			continue
+		}
+
+		e.mx.Lock()
+		if e.dials[addr] != done {
+			e.mx.Unlock()
+			return
+		}
+		delete(e.dials, addr)
+		e.up[addr] = true
+		e.mx.Unlock()
+
+		e.events.Publish(visorevent.DmsgSessionUp, visorevent.DmsgSessionData{Network: network, Addr: addr})
+		return
+	}
+}
+
+func (e *dmsgSessionEvents) established(addrs []string) bool {
+	for _, ses := range e.sessions() {
+		for _, addr := range addrs {
+			if ses == addr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *dmsgSessionEvents) disconnected(network, addr string, _ error) {
+	e.mx.Lock()
+	if done, ok := e.dials[addr]; ok {
+		close(done)
+		delete(e.dials, addr)
+	}
+	wasUp := e.up[addr]
+	delete(e.up, addr)
+	e.mx.Unlock()
+
+	if wasUp {
+		e.events.Publish(visorevent.DmsgSessionDown, visorevent.DmsgSessionData{Network: network, Addr: addr})
+	}
+}
+
 func initDmsgCtrl(ctx context.Context, v *Visor, _ *logging.Logger) error {
 	dmsgC := v.dmsgC
 	if dmsgC == nil {
@@ -493,6 +631,21 @@ func initStcprClient(ctx context.Context, v *Visor, log *logging.Logger) error {
 	return nil
 }
 
+func initQuicClient(ctx context.Context, v *Visor, log *logging.Logger) error { //nolint:all
+	if v.conf.Transport.EnableQuic {
+		// QUIC listens on the port bound by STCPR
+		v.tpM.InitClient(ctx, network.QUIC, 0)
+	}
+	return nil
+}
+
+func initWssClient(ctx context.Context, v *Visor, log *logging.Logger) error { //nolint:all
+	if v.conf.WSS != nil {
+		v.tpM.InitClient(ctx, network.WSS, 0)
+	}
+	return nil
+}
+
 func initStcpClient(ctx context.Context, v *Visor, log *logging.Logger) error { //nolint:all
 	if v.conf.STCP != nil {
 		v.tpM.InitClient(ctx, network.STCP, 0)
@@ -500,6 +653,65 @@ func initStcpClient(ctx context.Context, v *Visor, log *logging.Logger) error {
 	return nil
 }
 
+func initLANDiscovery(ctx context.Context, v *Visor, log *logging.Logger) error { //nolint:all
+	if v.conf.STCP == nil || !v.conf.STCP.LANDiscovery {
+		return nil
+	}
+
+	_, portStr, err := net.SplitHostPort(v.conf.STCP.ListeningAddress)
+	if err != nil {
+		return fmt.Errorf("invalid STCP listening address %q: %w", v.conf.STCP.ListeningAddress, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid STCP listening port %q: %w", portStr, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var onPeer func(lan.Peer)
+	if v.conf.STCP.LANAutoconnect {
+		onPeer = func(peer lan.Peer) {
+			go func() {
+				if _, err := v.tpM.SaveTransport(ctx, peer.PK, network.STCP, transport.LabelAutomatic); err != nil {
+					log.WithError(err).Warnf("Failed to establish LAN transport to %s", peer.PK)
+				}
+			}()
+		}
+	}
+
+	v.lanD = lan.New(lan.Config{
+		PK:   v.conf.PK,
+		SK:   v.conf.SK,
+		Port: uint16(port),
+	}, v.lanTable, log, onPeer)
+
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := v.lanD.Serve(ctx); err != nil {
+			log.WithError(err).Error("LAN discovery stopped")
+		}
+	}()
+
+	v.pushCloseStack("lan_discovery", func() error {
+		cancel()
+		wg.Wait()
+		return nil
+	})
+	return nil
+}
+
+// transportEventData returns the event data of the transport of `entry`.
+func transportEventData(local cipher.PubKey, entry transport.Entry) visorevent.TransportData {
+	return visorevent.TransportData{
+		ID:       entry.ID,
+		RemotePK: entry.RemoteEdge(local),
+		Type:     string(entry.Type),
+		Label:    string(entry.Label),
+	}
+}
+
 func initTransport(ctx context.Context, v *Visor, log *logging.Logger) error {
 
 	managerLogger := v.MasterLogger().PackageLogger("transport_manager")
@@ -518,6 +730,11 @@ func initTransport(ctx context.Context, v *Visor, log *logging.Logger) error {
 		if err != nil {
 			return err
 		}
+	} else if v.conf.Transport.LogStore.Type == visorconfig.BBoltLogStore {
+		logS, err = transport.BBoltTransportLogStore(ctx, v.conf.Transport.LogStore.Location, time.Duration(v.conf.Transport.LogStore.RotationInterval), log)
+		if err != nil {
+			return err
+		}
 	} else {
 		return fmt.Errorf("invalid store type: %v", v.conf.Transport.LogStore.Type)
 	}
@@ -534,6 +751,14 @@ func initTransport(ctx context.Context, v *Visor, log *logging.Logger) error {
 		DiscoveryClient:           tpdC,
 		LogStore:                  logS,
 		PersistentTransportsCache: pTps,
+		Callbacks: &transport.ManagerCallbacks{
+			OnTransportUp: func(entry transport.Entry) {
+				v.events.Publish(visorevent.TransportUp, transportEventData(v.conf.PK, entry))
+			},
+			OnTransportDown: func(entry transport.Entry) {
+				v.events.Publish(visorevent.TransportDown, transportEventData(v.conf.PK, entry))
+			},
+		},
 	}
 
 	// todo: pass down configuration?
@@ -541,16 +766,30 @@ func initTransport(ctx context.Context, v *Visor, log *logging.Logger) error {
 	var listenAddr string
 	if v.conf.STCP != nil {
 		table = stcp.NewTable(v.conf.STCP.PKTable)
+		if v.conf.STCP.LANDiscovery {
+			v.lanTable = stcp.NewDynamicTable(v.conf.STCP.PKTable)
+			table = v.lanTable
+		}
 		listenAddr = v.conf.STCP.ListeningAddress
 	}
+	var obfs *network.ObfuscationConfig
+	if o := v.conf.Transport.Obfuscation; o != nil && o.Enabled {
+		obfs = &network.ObfuscationConfig{
+			Padding:    o.Padding,
+			Jitter:     time.Duration(o.Jitter),
+			AllowPlain: o.Required != nil && !*o.Required,
+		}
+	}
 	factory := network.ClientFactory{
-		PK:         v.conf.PK,
-		SK:         v.conf.SK,
-		ListenAddr: listenAddr,
-		PKTable:    table,
-		ARClient:   v.arClient,
-		EB:         v.ebc,
-		MLogger:    v.MasterLogger(),
+		PK:          v.conf.PK,
+		SK:          v.conf.SK,
+		ListenAddr:  listenAddr,
+		PKTable:     table,
+		WSSConfig:   v.conf.WSS,
+		Obfuscation: obfs,
+		ARClient:    v.arClient,
+		EB:          v.ebc,
+		MLogger:     v.MasterLogger(),
 	}
 	tpM, err := transport.NewManager(managerLogger, v.arClient, v.ebc, &tpMConf, factory)
 	if err != nil {
@@ -681,6 +920,13 @@ func handleServerConn(log *logging.Logger, remoteConn net.Conn, v *Visor) {
 	}
 	log.Debugf("Received: %v", cMsg)
 
+	fwdType, err := appnet.ParseForwardType(cMsg.Type)
+	if err != nil {
+		log.WithError(err).Error("Invalid forward type")
+		sendError(log, remoteConn, err)
+		return
+	}
+
 	lHost := fmt.Sprintf("localhost:%v", cMsg.Port)
 	ok := isPortRegistered(cMsg.Port, v)
 	if !ok {
@@ -689,19 +935,29 @@ func handleServerConn(log *logging.Logger, remoteConn net.Conn, v *Visor) {
 		return
 	}
 
-	ok = isPortAvailable(log, cMsg.Port)
+	// UDP ports can't be probed by dialing, the datagrams are forwarded as long as the port is registered
+	ok = fwdType != appnet.ForwardUDP && isPortAvailable(log, cMsg.Port)
 	if ok {
 		log.Errorf("Failed to dial port %v", cMsg.Port)
 		sendError(log, remoteConn, fmt.Errorf("Failed to dial port %v", cMsg.Port))
 		return
 	}
 
-	log.Debugf("Forwarding %s", lHost)
+	log.Debugf("Forwarding %s %s", fwdType, lHost)
 
 	// send nil error to indicate to the remote connection that everything is ok
 	sendError(log, remoteConn, nil)
 
-	go forward(log, remoteConn, lHost)
+	if fwdType == appnet.ForwardHTTP {
+		go forward(log, remoteConn, lHost)
+		return
+	}
+
+	go func() {
+		if err := appnet.ServeForward(log, remoteConn, fwdType, lHost); err != nil {
+			log.WithError(err).Errorf("Failed to forward %s %s", fwdType, lHost)
+		}
+	}()
 }
 
 // forward reads a http.Request from the remote conn of the requesting visor forwards that request
@@ -774,7 +1030,8 @@ func closeConn(log *logging.Logger, conn net.Conn) {
 }
 
 type clientMsg struct {
-	Port int `json:"port"`
+	Port int    `json:"port"`
+	Type string `json:"type,omitempty"`
 }
 
 type serverReply struct {
@@ -969,6 +1226,38 @@ func getRouteSetupHooks(ctx context.Context, v *Visor, log *logging.Logger) []ro
 	}
 }
 
+// makeRouteFinder creates the route finder client of the configured mode.
+func makeRouteFinder(v *Visor, httpC *http.Client) (rfclient.Client, error) {
+	conf := v.conf.Routing
+	remote := rfclient.NewHTTP(conf.RouteFinder, time.Duration(conf.RouteFinderTimeout), httpC, v.MasterLogger())
+
+	local := rfclient.NewLocal(rfclient.LocalConfig{
+		PK:        v.conf.PK,
+		Discovery: v.tpM.Conf.DiscoveryClient,
+		Transports: func() []transport.Entry {
+			var entries []transport.Entry
+			v.tpM.WalkTransports(func(tp *transport.ManagedTransport) bool {
+				if !tp.IsClosed() {
+					entries = append(entries, tp.Entry)
+				}
+				return true
+			})
+			return entries
+		},
+	}, rfclient.NewGraph(), v.MasterLogger())
+
+	switch conf.RouteFinderMode {
+	case "", visorconfig.FallbackRouteFinder:
+		return rfclient.NewFallback(remote, local, v.MasterLogger()), nil
+	case visorconfig.RemoteRouteFinder:
+		return remote, nil
+	case visorconfig.LocalRouteFinder:
+		return rfclient.NewFallback(local, remote, v.MasterLogger()), nil
+	default:
+		return nil, fmt.Errorf("invalid route finder mode: %v", conf.RouteFinderMode)
+	}
+}
+
 func initRouter(ctx context.Context, v *Visor, log *logging.Logger) error {
 	conf := v.conf.Routing
 
@@ -977,8 +1266,25 @@ func initRouter(ctx context.Context, v *Visor, log *logging.Logger) error {
 		return err
 	}
 
-	rfClient := rfclient.NewHTTP(conf.RouteFinder, time.Duration(conf.RouteFinderTimeout), httpC, v.MasterLogger())
+	rfClient, err := makeRouteFinder(v, httpC)
+	if err != nil {
+		return err
+	}
 	logger := v.MasterLogger().PackageLogger("router")
+
+	var table routing.Table
+	switch conf.Table {
+	case "", visorconfig.MemoryRoutingTable:
+		table = routing.NewTable(logger)
+	case visorconfig.PersistentRoutingTable:
+		table, err = routing.NewPersistentTable(logger, filepath.Join(v.conf.LocalPath, visorconfig.RoutingTable))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid routing table type: %v", conf.Table)
+	}
+
 	rConf := router.Config{
 		Logger:           logger,
 		MasterLogger:     v.MasterLogger(),
@@ -990,6 +1296,32 @@ func initRouter(ctx context.Context, v *Visor, log *logging.Logger) error {
 		SetupNodes:       conf.RouteSetupNodes,
 		RulesGCInterval:  0, // TODO
 		MinHops:          v.conf.Routing.MinHops,
+		RoutingTable:     table,
+		Callbacks: &router.Callbacks{
+			OnRouteGroupCreated: func(desc routing.RouteDescriptor) {
+				v.events.Publish(visorevent.RouteGroupCreated, visorevent.RouteGroupData{Desc: desc.String()})
+			},
+			OnRouteGroupClosed: func(desc routing.RouteDescriptor) {
+				v.events.Publish(visorevent.RouteGroupClosed, visorevent.RouteGroupData{Desc: desc.String()})
+			},
+		},
+	}
+
+	// the countries of the visors are resolved for the route policies excluding countries
+	if v.conf.Launcher != nil && v.conf.Launcher.ServiceDisc != "" {
+		sdHTTPC, err := getHTTPClient(ctx, v, v.conf.Launcher.ServiceDisc)
+		if err != nil {
+			return err
+		}
+		sdClient := servicedisc.NewClient(log, v.MasterLogger(), servicedisc.Config{
+			Type:     servicedisc.ServiceTypeVisor,
+			PK:       v.conf.PK,
+			SK:       v.conf.SK,
+			DiscAddr: v.conf.Launcher.ServiceDisc,
+		}, sdHTTPC, "")
+		countries := servicedisc.NewCountries(log, sdClient, visorCountriesRefresh)
+		countries.Prefetch()
+		rConf.Countries = countries
 	}
 
 	routeSetupHooks := getRouteSetupHooks(ctx, v, log)
@@ -1022,8 +1354,26 @@ func initRouter(ctx context.Context, v *Visor, log *logging.Logger) error {
 func initLauncher(ctx context.Context, v *Visor, log *logging.Logger) error { //nolint:all
 	conf := v.conf.Launcher
 
+	// The launcher supervises the procs, it is set before any app starts.
+	var launch *launcher.AppLauncher
+
 	// Prepare proc manager.
-	procM, err := appserver.NewProcManager(v.MasterLogger(), &v.serviceDisc, v.ebc, conf.ServerAddr, v.conf.LocalPath)
+	procCallbacks := &appserver.ProcCallbacks{
+		OnProcStart: func(appName string) {
+			v.events.Publish(visorevent.AppStarted, visorevent.AppData{Name: appName})
+		},
+		OnProcExit: func(appName string, stopped bool, err error) {
+			if stopped || err == nil {
+				v.events.Publish(visorevent.AppStopped, visorevent.AppData{Name: appName})
+			} else {
+				v.events.Publish(visorevent.AppCrashed, visorevent.AppData{Name: appName, Error: err.Error()})
+			}
+			if launch != nil {
+				launch.ProcExited(appName, stopped, err)
+			}
+		},
+	}
+	procM, err := appserver.NewProcManager(v.MasterLogger(), &v.serviceDisc, v.ebc, procCallbacks, conf.ServerAddr, v.conf.LocalPath)
 	if err != nil {
 		err := fmt.Errorf("failed to start proc_manager: %w", err)
 		return err
@@ -1043,16 +1393,16 @@ func initLauncher(ctx context.Context, v *Visor, log *logging.Logger) error { //
 
 	launchLog := v.MasterLogger().PackageLogger("launcher")
 
-	launch, err := launcher.NewLauncher(launchLog, launchConf, v.dmsgC, v.router, procM)
+	launch, err = launcher.NewLauncher(launchLog, launchConf, v.dmsgC, v.router, procM)
 	if err != nil {
 		err := fmt.Errorf("failed to start launcher: %w", err)
 		return err
 	}
 
-	err = launch.AutoStart(launcher.EnvMap{
-		visorconfig.VPNClientName: vpnEnvMaker(v.conf, v.dmsgC, v.dmsgDC, v.tpM.STCPRRemoteAddrs()),
-		visorconfig.VPNServerName: vpnEnvMaker(v.conf, v.dmsgC, v.dmsgDC, nil),
-	})
+	// Closed before the proc manager, so that the apps it stops are not restarted.
+	v.pushCloseStack("launcher", launch.Close)
+
+	err = launch.AutoStart(v.appEnvMap())
 
 	if err != nil {
 		err := fmt.Errorf("failed to autostart apps: %w", err)
@@ -1067,6 +1417,14 @@ func initLauncher(ctx context.Context, v *Visor, log *logging.Logger) error { //
 	return nil
 }
 
+// appEnvMap returns the env makers of the apps which need extra envs to start.
+func (v *Visor) appEnvMap() launcher.EnvMap {
+	return launcher.EnvMap{
+		visorconfig.VPNClientName: vpnEnvMaker(v.conf, v.dmsgC, v.dmsgDC, v.tpM.STCPRRemoteAddrs()),
+		visorconfig.VPNServerName: vpnEnvMaker(v.conf, v.dmsgC, v.dmsgDC, nil),
+	}
+}
+
 // Make an env maker function for vpn application
 func vpnEnvMaker(conf *visorconfig.V1, dmsgC, dmsgDC *dmsg.Client, tpRemoteAddrs []string) launcher.EnvMaker {
 	return func() ([]string, error) {
@@ -1142,7 +1500,7 @@ func initCLI(ctx context.Context, v *Visor, log *logging.Logger) error { //nolin
 
 	v.pushCloseStack("cli.listener", cliL.Close)
 
-	rpcS, err := newRPCServer(v, "CLI")
+	rpcS, err := newRPCServer(v, "CLI", "cli")
 	if err != nil {
 		err := fmt.Errorf("failed to start rpc server for cli: %w", err)
 		return err
@@ -1153,51 +1511,86 @@ func initCLI(ctx context.Context, v *Visor, log *logging.Logger) error { //nolin
 }
 
 func initHypervisors(ctx context.Context, v *Visor, log *logging.Logger) error { //nolint:all
-
-	hvErrs := make(map[cipher.PubKey]chan error, len(v.conf.Hypervisors))
-	for _, hv := range v.conf.Hypervisors {
-		hvErrs[hv] = make(chan error, 1)
+	for _, hvPK := range v.conf.Hypervisors {
+		if err := v.serveHypervisor(hvPK); err != nil {
+			return err
+		}
 	}
 
-	for hvPK, hvErrs := range hvErrs {
-		log := v.MasterLogger().PackageLogger("hypervisor_client").WithField("hypervisor_pk", hvPK)
+	v.pushCloseStack("hypervisors", func() error {
+		v.hypervisorsMx.Lock()
+		hvPKs := make([]cipher.PubKey, 0, len(v.hypervisors))
+		for hvPK := range v.hypervisors {
+			hvPKs = append(hvPKs, hvPK)
+		}
+		v.hypervisorsMx.Unlock()
 
-		addr := dmsg.Addr{PK: hvPK, Port: visorconfig.DmsgHypervisorPort}
-		rpcS, err := newRPCServer(v, addr.PK.String()[:shortHashLen])
-		if err != nil {
-			err := fmt.Errorf("failed to start RPC server for hypervisor %s: %w", hvPK, err)
-			return err
+		for _, hvPK := range hvPKs {
+			v.closeHypervisor(hvPK)
 		}
+		return nil
+	})
 
-		ctx, cancel := context.WithCancel(context.Background())
-		wg := new(sync.WaitGroup)
-		wg.Add(1)
+	return nil
+}
 
-		go func(hvErrs chan error) {
-			defer wg.Done()
-			//			var autoPeerIP string
-			//			if v.autoPeer {
-			//				autoPeerIP = v.autoPeerIP
-			//			} else {
-			//				autoPeerIP = ""
-			//			}
-			defer delete(v.connectedHypervisors, hvPK)
-			v.connectedHypervisors[hvPK] = true
-			ServeRPCClient(ctx, log, v.dmsgC, rpcS, addr, hvErrs)
-			//			ServeRPCClient(ctx, log, autoPeerIP, v.dmsgC, rpcS, addr, hvErrs)
-
-		}(hvErrs)
-
-		v.pushCloseStack("hypervisor."+hvPK.String()[:shortHashLen], func() error {
-			cancel()
-			wg.Wait()
-			return nil
-		})
+// serveHypervisor serves the visor RPC to the remote hypervisor of `hvPK`, until closeHypervisor is called.
+// It does nothing if the hypervisor is already served.
+func (v *Visor) serveHypervisor(hvPK cipher.PubKey) error {
+	v.hypervisorsMx.Lock()
+	defer v.hypervisorsMx.Unlock()
+
+	if _, ok := v.hypervisors[hvPK]; ok {
+		return nil
+	}
+
+	log := v.MasterLogger().PackageLogger("hypervisor_client").WithField("hypervisor_pk", hvPK)
+
+	addr := dmsg.Addr{PK: hvPK, Port: visorconfig.DmsgHypervisorPort}
+	rpcS, err := newRPCServer(v, addr.PK.String()[:shortHashLen], "hypervisor:"+addr.PK.String())
+	if err != nil {
+		err := fmt.Errorf("failed to start RPC server for hypervisor %s: %w", hvPK, err)
+		return err
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+
+	go func(hvErrs chan error) {
+		defer wg.Done()
+		//			var autoPeerIP string
+		//			if v.autoPeer {
+		//				autoPeerIP = v.autoPeerIP
+		//			} else {
+		//				autoPeerIP = ""
+		//			}
+		defer delete(v.connectedHypervisors, hvPK)
+		v.connectedHypervisors[hvPK] = true
+		ServeRPCClient(ctx, log, v.dmsgC, rpcS, addr, hvErrs)
+		//			ServeRPCClient(ctx, log, autoPeerIP, v.dmsgC, rpcS, addr, hvErrs)
+
+	}(make(chan error, 1))
+
+	v.hypervisors[hvPK] = func() {
+		cancel()
+		wg.Wait()
+	}
 	return nil
 }
 
+// closeHypervisor stops serving the visor RPC to the remote hypervisor of `hvPK`.
+func (v *Visor) closeHypervisor(hvPK cipher.PubKey) {
+	v.hypervisorsMx.Lock()
+	closeFn, ok := v.hypervisors[hvPK]
+	delete(v.hypervisors, hvPK)
+	v.hypervisorsMx.Unlock()
+
+	if ok {
+		closeFn()
+	}
+}
+
 func initUptimeTracker(ctx context.Context, v *Visor, log *logging.Logger) error {
 	const tickDuration = 5 * time.Minute
 
@@ -1438,6 +1831,67 @@ func initPublicAutoconnect(ctx context.Context, v *Visor, log *logging.Logger) e
 	return nil
 }
 
+func initMetrics(_ context.Context, v *Visor, log *logging.Logger) error {
+	conf := v.conf.Metrics
+	if conf == nil || !conf.Enabled {
+		log.Debug("Metrics endpoint is disabled, skipping.")
+		return nil
+	}
+	addr := conf.Addr
+	if addr == "" {
+		addr = visorconfig.MetricsAddr
+	}
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", visormetrics.Handler(v.metricsSnapshot))
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Error("Metrics endpoint exited with error.")
+		}
+	}()
+	log.WithField("addr", lis.Addr()).Info("Serving metrics.")
+
+	v.pushCloseStack("metrics", srv.Close)
+	return nil
+}
+
+// metricsSnapshot returns the state of the visor exported as metrics.
+func (v *Visor) metricsSnapshot() visormetrics.Snapshot {
+	s := visormetrics.Snapshot{HandshakeFailures: network.HandshakeFailures()}
+	if v.tpM != nil {
+		v.tpM.WalkTransports(func(tp *transport.ManagedTransport) bool {
+			mt := visormetrics.Transport{ID: tp.Entry.ID, RemotePK: tp.Remote(), Type: string(tp.Type())}
+			if tp.LogEntry != nil {
+				mt.RecvBytes = atomic.LoadUint64(tp.LogEntry.RecvBytes)
+				mt.SentBytes = atomic.LoadUint64(tp.LogEntry.SentBytes)
+			}
+			s.Transports = append(s.Transports, mt)
+			return true
+		})
+	}
+	if v.router != nil {
+		s.Routes = v.router.RoutesCount()
+		s.RouteGroups = v.router.RouteGroupsStats()
+	}
+	if v.appL != nil {
+		s.Apps = v.appL.AppStates()
+	}
+	if v.dmsgC != nil {
+		s.DmsgSessions = v.dmsgC.SessionCount()
+	}
+	return s
+}
+
 func initHypervisor(_ context.Context, v *Visor, log *logging.Logger) error { //nolint:all
 	if v.conf.Hypervisor == nil {
 		v.log.Error("hypervisor config = nil")
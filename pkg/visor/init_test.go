@@ -0,0 +1,70 @@
+// Package visor pkg/visor/init_test.go
+package visor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
+)
+
+func TestDmsgSessionEvents(t *testing.T) {
+	const addr = "127.0.0.1:30080"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mx          sync.Mutex
+		established []string
+	)
+	setEstablished := func(addrs ...string) {
+		mx.Lock()
+		established = addrs
+		mx.Unlock()
+	}
+
+	bus := visorevent.NewBus(cipher.PubKey{}, 0)
+	e := newDmsgSessionEvents(ctx, bus, func() []string {
+		mx.Lock()
+		defer mx.Unlock()
+		return established
+	})
+
+	types := func() []string {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		var out []string
+		for _, ev := range bus.Since(ctx, 0) {
+			out = append(out, ev.Type)
+		}
+		return out
+	}
+
+	t.Run("failed dial", func(t *testing.T) {
+		require.NoError(t, e.dialed("tcp", addr))
+		e.disconnected("tcp", addr, nil)
+		time.Sleep(3 * dmsgSessionPollInterval)
+		assert.Empty(t, types())
+	})
+
+	t.Run("up once established", func(t *testing.T) {
+		require.NoError(t, e.dialed("tcp", addr))
+		time.Sleep(3 * dmsgSessionPollInterval)
+		assert.Empty(t, types())
+
+		setEstablished(addr)
+		require.Eventually(t, func() bool { return len(types()) == 1 }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, []string{visorevent.DmsgSessionUp}, types())
+
+		setEstablished()
+		e.disconnected("tcp", addr, nil)
+		assert.Equal(t, []string{visorevent.DmsgSessionUp, visorevent.DmsgSessionDown}, types())
+	})
+}
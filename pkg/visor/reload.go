@@ -0,0 +1,234 @@
+// Package visor pkg/visor/reload.go
+package visor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/app/appserver"
+	"github.com/skycoin/skywire/pkg/app/launcher"
+	"github.com/skycoin/skywire/pkg/visor/visorconfig"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
+)
+
+// ErrConfigNotReloadable is returned by Reload if the config was not read from a file.
+var ErrConfigNotReloadable = errors.New("config was piped via stdin, it cannot be reloaded")
+
+// ConfigReload reports the changed sections of the config found by Reload.
+type ConfigReload struct {
+	// Applied are the sections which were applied without restarting the visor.
+	Applied []string `json:"applied"`
+	// RestartRequired are the sections which only take effect once the visor is restarted.
+	RestartRequired []string `json:"restart_required"`
+}
+
+// reload reads the config file again and applies the changed sections which do not need a restart.
+func reload(v *Visor) (ConfigReload, error) {
+	report := ConfigReload{Applied: []string{}, RestartRequired: []string{}}
+	log := v.MasterLogger().PackageLogger("visor:reload")
+
+	if confPath == visorconfig.Stdin {
+		return report, ErrConfigNotReloadable
+	}
+	conf, err := readReloadConfig(v, log)
+	if err != nil {
+		return report, err
+	}
+
+	sections, err := v.conf.Diff(conf)
+	if err != nil {
+		return report, fmt.Errorf("failed to compare configs: %w", err)
+	}
+
+	var live []string
+	for _, section := range sections {
+		if visorconfig.IsLiveSection(section) {
+			live = append(live, section)
+		} else {
+			report.RestartRequired = append(report.RestartRequired, section)
+		}
+	}
+
+	oldConf := v.reloadState()
+	v.conf.ReloadSections(conf, live)
+
+	for _, section := range live {
+		if err := v.applySection(section, oldConf); err != nil {
+			log.WithError(err).WithField("section", section).Warn("Failed to apply config section, a restart is required.")
+			report.RestartRequired = append(report.RestartRequired, section)
+			continue
+		}
+		report.Applied = append(report.Applied, section)
+	}
+
+	log.WithField("applied", report.Applied).
+		WithField("restart_required", report.RestartRequired).
+		Info("Config reloaded.")
+	v.events.Publish(visorevent.ConfigReloaded, visorevent.ConfigReloadData{
+		Applied:         report.Applied,
+		RestartRequired: report.RestartRequired,
+	})
+	return report, nil
+}
+
+// readReloadConfig reads the config file of the visor with the command line overrides applied,
+// so that only the changes made to the file are reloaded.
+func readReloadConfig(v *Visor, log *logging.Logger) (*visorconfig.V1, error) {
+	f, err := os.ReadFile(confPath) //nolint
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	conf, compat, err := visorconfig.Parse(log, bytes.NewReader(f), confPath, visorBuildInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read in config: %w", err)
+	}
+	if !compat {
+		return nil, errors.New("config version is incompatible")
+	}
+
+	// the hypervisor flags override the section of the file
+	if hypervisorUI || noHypervisorUI {
+		conf.Hypervisor = v.conf.Hypervisor
+	}
+	applyConfigFlags(conf)
+	return conf, nil
+}
+
+// reloadState is the state of the live sections of the config before a reload.
+type reloadState struct {
+	apps        []appserver.AppConfig
+	hypervisors []cipher.PubKey
+}
+
+func (v *Visor) reloadState() reloadState {
+	var s reloadState
+	if v.conf.Launcher != nil {
+		s.apps = v.conf.Launcher.Apps
+	}
+	s.hypervisors = v.conf.Hypervisors
+	return s
+}
+
+// applySection applies the reloaded section of the config to the running visor.
+func (v *Visor) applySection(section string, old reloadState) error {
+	switch section {
+	case visorconfig.SectionLogLevel:
+		lvl, err := logging.LevelFromString(v.conf.LogLevel)
+		if err != nil {
+			return err
+		}
+		v.conf.MasterLogger().SetLevel(lvl)
+
+	case visorconfig.SectionApps:
+		return v.reloadApps(old.apps)
+
+	case visorconfig.SectionPersistentTransports:
+		if v.tpM == nil {
+			return ErrTrpMangerNotAvailable
+		}
+		v.tpM.SetPTpsCache(v.conf.PersistentTransports)
+
+	case visorconfig.SectionHypervisors:
+		hvPKs := make(map[cipher.PubKey]bool, len(v.conf.Hypervisors))
+		for _, hvPK := range v.conf.Hypervisors {
+			hvPKs[hvPK] = true
+		}
+		for _, hvPK := range old.hypervisors {
+			if !hvPKs[hvPK] {
+				v.closeHypervisor(hvPK)
+			}
+		}
+		for hvPK := range hvPKs {
+			if err := v.serveHypervisor(hvPK); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("section %q cannot be applied live", section)
+	}
+	return nil
+}
+
+// reloadApps resets the app configs of the launcher. The removed apps are stopped, the running apps
+// whose binary, port or args changed are restarted, and the new auto started apps are started.
+func (v *Visor) reloadApps(oldApps []appserver.AppConfig) error {
+	if v.appL == nil || v.procM == nil {
+		return ErrAppLauncherNotAvailable
+	}
+	log := v.MasterLogger().PackageLogger("visor:reload")
+
+	launchConf := v.conf.Launcher
+	v.appL.ResetConfig(launcher.AppLauncherConfig{
+		VisorPK:       v.conf.PK,
+		Apps:          launchConf.Apps,
+		ServerAddr:    launchConf.ServerAddr,
+		DisplayNodeIP: launchConf.DisplayNodeIP,
+	})
+
+	old := make(map[string]appserver.AppConfig, len(oldApps))
+	for _, ac := range oldApps {
+		old[ac.Name] = ac
+	}
+	apps := make(map[string]appserver.AppConfig, len(launchConf.Apps))
+	for _, ac := range launchConf.Apps {
+		apps[ac.Name] = ac
+	}
+
+	var errs []error
+	for name := range old {
+		if _, ok := apps[name]; ok {
+			continue
+		}
+		if _, running := v.procM.ProcByName(name); running {
+			log.WithField("app_name", name).Info("Stopping removed app.")
+			if _, err := v.appL.StopApp(name); err != nil {
+				errs = append(errs, fmt.Errorf("failed to stop %s: %w", name, err))
+			}
+		}
+	}
+
+	envMap := v.appEnvMap()
+	for name, ac := range apps {
+		prev, existed := old[name]
+		if _, running := v.procM.ProcByName(name); running {
+			if !existed || !appChanged(prev, ac) {
+				continue
+			}
+			log.WithField("app_name", name).Info("Restarting changed app.")
+			if err := v.appL.RestartApp(name, name); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if !ac.AutoStart || (existed && prev.AutoStart) {
+			continue
+		}
+
+		var envs []string
+		if makeEnvs, ok := envMap[name]; ok {
+			var err error
+			if envs, err = makeEnvs(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to make envs of %s: %w", name, err))
+				continue
+			}
+		}
+		log.WithField("app_name", name).Info("Starting new app.")
+		if err := v.appL.StartApp(name, nil, envs); err != nil {
+			errs = append(errs, fmt.Errorf("failed to start %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// appChanged returns true if a running app needs to be restarted to use its new config.
+func appChanged(old, ac appserver.AppConfig) bool {
+	return old.Binary != ac.Binary || old.Port != ac.Port || !reflect.DeepEqual(old.Args, ac.Args) ||
+		!reflect.DeepEqual(old.RoutePolicy, ac.RoutePolicy)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/app/appacl"
 	"github.com/skycoin/skywire/pkg/app/appcommon"
 	"github.com/skycoin/skywire/pkg/app/appnet"
 	"github.com/skycoin/skywire/pkg/app/appserver"
@@ -18,7 +19,10 @@ import (
 	"github.com/skycoin/skywire/pkg/servicedisc"
 	"github.com/skycoin/skywire/pkg/transport"
 	"github.com/skycoin/skywire/pkg/transport/network"
+	"github.com/skycoin/skywire/pkg/transport/network/lan"
 	"github.com/skycoin/skywire/pkg/util/rpcutil"
+	"github.com/skycoin/skywire/pkg/visor/auditlog"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
 )
 
 const (
@@ -44,13 +48,20 @@ var (
 type RPC struct {
 	visor API
 	log   logrus.FieldLogger
+
+	pk     cipher.PubKey
+	caller string         // caller recorded in the audit log
+	audit  auditlog.Store // audit log of the mutating calls, not audited if nil
 }
 
-func newRPCServer(v *Visor, remoteName string) (*rpc.Server, error) {
+func newRPCServer(v *Visor, remoteName, caller string) (*rpc.Server, error) {
 	rpcS := rpc.NewServer()
 	rpcG := &RPC{
-		visor: v,
-		log:   v.MasterLogger().PackageLogger("visor_rpc:" + remoteName),
+		visor:  v,
+		log:    v.MasterLogger().PackageLogger("visor_rpc:" + remoteName),
+		pk:     v.conf.PK,
+		caller: caller,
+		audit:  v.auditLog,
 	}
 
 	if err := rpcS.RegisterName(RPCPrefix, rpcG); err != nil {
@@ -60,6 +71,31 @@ func newRPCServer(v *Visor, remoteName string) (*rpc.Server, error) {
 	return rpcS, nil
 }
 
+// auditCall records a mutating call in the audit log once it returns.
+func (r *RPC) auditCall(method string, in interface{}) func(err *error) {
+	return func(err *error) {
+		if r.audit == nil {
+			return
+		}
+
+		entry := auditlog.Entry{
+			Source: auditlog.SourceRPC,
+			User:   r.caller,
+			Visor:  r.pk,
+			Action: method,
+		}
+		if in != nil {
+			entry.Params = auditlog.Params(in)
+		}
+		if *err != nil {
+			entry.Error = (*err).Error()
+		}
+		if aErr := r.audit.Append(entry); aErr != nil {
+			r.log.WithError(aErr).Warn("Failed to record call in the audit log.")
+		}
+	}
+}
+
 /*
 	<<< NODE HEALTH >>>
 */
@@ -90,6 +126,25 @@ func (r *RPC) Uptime(_ *struct{}, out *float64) (err error) {
 	return err
 }
 
+/*
+	<<< VISOR EVENTS >>>
+*/
+
+// EventsIn is input for Events.
+type EventsIn struct {
+	// Since is the sequence number of the last event received.
+	Since uint64
+	// Wait is how long to wait for an event if there is none yet.
+	Wait time.Duration
+}
+
+// Events returns the events of the visor published after the event `Since`.
+func (r *RPC) Events(in *EventsIn, out *[]visorevent.Event) (err error) {
+	// Not logged: the events are long-polled.
+	*out, err = r.visor.Events(in.Since, in.Wait)
+	return err
+}
+
 /*
 	<<< SKYCOIN REWARD ADDRESS SETTING >>>
 */
@@ -97,6 +152,7 @@ func (r *RPC) Uptime(_ *struct{}, out *float64) (err error) {
 // SetRewardAddress sets the reward address and privacy setting in reward.txt
 func (r *RPC) SetRewardAddress(p string, out *string) (err error) {
 	defer rpcutil.LogCall(r.log, "SetRewardAddress", p)(out, &err)
+	defer r.auditCall("SetRewardAddress", p)(&err)
 	p, err = r.visor.SetRewardAddress(p)
 	*out = p
 	return err
@@ -113,6 +169,7 @@ func (r *RPC) GetRewardAddress(_ *struct{}, out *string) (err error) {
 // DeleteRewardAddress deletes the reward.txt
 func (r *RPC) DeleteRewardAddress(_ *struct{}, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "DeleteRewardAddress", nil)(nil, &err)
+	defer r.auditCall("DeleteRewardAddress", nil)(&err)
 	return r.visor.DeleteRewardAddress()
 }
 
@@ -244,6 +301,7 @@ func (r *RPC) Apps(_ *struct{}, reply *[]*appserver.AppState) (err error) {
 // StartApp start App with provided name.
 func (r *RPC) StartApp(name *string, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "StartApp", name)(nil, &err)
+	defer r.auditCall("StartApp", name)(&err)
 
 	return r.visor.StartApp(*name)
 }
@@ -257,6 +315,7 @@ type SetAppAddIn struct {
 // AddApp add app to config
 func (r *RPC) AddApp(in *SetAppAddIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "AddApp", in)(nil, &err)
+	defer r.auditCall("AddApp", in)(&err)
 
 	return r.visor.AddApp(in.AppName, in.BinaryName)
 }
@@ -264,12 +323,14 @@ func (r *RPC) AddApp(in *SetAppAddIn, _ *struct{}) (err error) {
 // DoCustomSetting set custom setting to apps arguments
 func (r *RPC) DoCustomSetting(in *SetAppMapIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "DoCustomSetting", in)(nil, &err)
+	defer r.auditCall("DoCustomSetting", in)(&err)
 	return r.visor.DoCustomSetting(in.AppName, in.Val)
 }
 
 // RegisterApp registers a App with provided proc config.
 func (r *RPC) RegisterApp(procConf *appcommon.ProcConfig, reply *appcommon.ProcKey) (err error) {
 	defer rpcutil.LogCall(r.log, "RegisterApp", procConf)(reply, &err)
+	defer r.auditCall("RegisterApp", procConf)(&err)
 	procKey, err := r.visor.RegisterApp(*procConf)
 	*reply = procKey
 	return err
@@ -278,12 +339,14 @@ func (r *RPC) RegisterApp(procConf *appcommon.ProcConfig, reply *appcommon.ProcK
 // DeregisterApp de registers a App with provided proc key.
 func (r *RPC) DeregisterApp(procKey *appcommon.ProcKey, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "DeregisterApp", procKey)(nil, &err)
+	defer r.auditCall("DeregisterApp", procKey)(&err)
 	return r.visor.DeregisterApp(*procKey)
 }
 
 // StopApp stops App with provided name.
 func (r *RPC) StopApp(name *string, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "StopApp", name)(nil, &err)
+	defer r.auditCall("StopApp", name)(&err)
 
 	return r.visor.StopApp(*name)
 }
@@ -291,6 +354,7 @@ func (r *RPC) StopApp(name *string, _ *struct{}) (err error) {
 // KillApp kill App with provided name.
 func (r *RPC) KillApp(name *string, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "KillApp", name)(nil, &err)
+	defer r.auditCall("KillApp", name)(&err)
 
 	return r.visor.KillApp(*name)
 }
@@ -298,6 +362,7 @@ func (r *RPC) KillApp(name *string, _ *struct{}) (err error) {
 // StartVPNClient starts VPNClient App
 func (r *RPC) StartVPNClient(pk *cipher.PubKey, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "StartApp", pk)(nil, &err)
+	defer r.auditCall("StartVPNClient", pk)(&err)
 
 	return r.visor.StartVPNClient(*pk)
 }
@@ -305,6 +370,7 @@ func (r *RPC) StartVPNClient(pk *cipher.PubKey, _ *struct{}) (err error) {
 // StopVPNClient stops VPNClient App
 func (r *RPC) StopVPNClient(name *string, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "StopVPNClient", name)(nil, &err)
+	defer r.auditCall("StopVPNClient", name)(&err)
 
 	return r.visor.StopVPNClient(*name)
 }
@@ -320,6 +386,7 @@ func (r *RPC) FetchUptimeTrackerData(pk string, data *[]byte) (err error) {
 // StartSkysocksClient starts SkysocksClient App
 func (r *RPC) StartSkysocksClient(pk string, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "StartSkysocksClient", pk)(nil, &err)
+	defer r.auditCall("StartSkysocksClient", pk)(&err)
 
 	return r.visor.StartSkysocksClient(pk)
 }
@@ -327,6 +394,7 @@ func (r *RPC) StartSkysocksClient(pk string, _ *struct{}) (err error) {
 // StopSkysocksClients stops all SkysocksClient Apps
 func (r *RPC) StopSkysocksClients(_ *struct{}, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "StopSkysocksClients", nil)(nil, &err)
+	defer r.auditCall("StopSkysocksClients", nil)(&err)
 
 	return r.visor.StopSkysocksClients()
 }
@@ -334,6 +402,7 @@ func (r *RPC) StopSkysocksClients(_ *struct{}, _ *struct{}) (err error) {
 // RestartApp restarts App with provided name.
 func (r *RPC) RestartApp(name *string, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "RestartApp", name)(nil, &err)
+	defer r.auditCall("RestartApp", name)(&err)
 
 	return r.visor.RestartApp(*name)
 }
@@ -347,6 +416,7 @@ type SetAutoStartIn struct {
 // SetAutoStart sets auto-start settings for an app.
 func (r *RPC) SetAutoStart(in *SetAutoStartIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetAutoStart", in)(nil, &err)
+	defer r.auditCall("SetAutoStart", in)(&err)
 
 	return r.visor.SetAutoStart(in.AppName, in.AutoStart)
 }
@@ -360,6 +430,7 @@ type SetAppPasswordIn struct {
 // SetAppPassword sets password for the app.
 func (r *RPC) SetAppPassword(in *SetAppPasswordIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetAppPassword", in)(nil, &err)
+	defer r.auditCall("SetAppPassword", in)(&err)
 
 	return r.visor.SetAppPassword(in.AppName, in.Password)
 }
@@ -373,6 +444,7 @@ type SetAppNetworkInterfaceIn struct {
 // SetAppNetworkInterface sets network interface for the app.
 func (r *RPC) SetAppNetworkInterface(in *SetAppNetworkInterfaceIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetAppNetworkInterface", in)(nil, &err)
+	defer r.auditCall("SetAppNetworkInterface", in)(&err)
 
 	return r.visor.SetAppNetworkInterface(in.AppName, in.NetIfc)
 }
@@ -404,6 +476,7 @@ type SetAppMapIn struct {
 // SetAppPK sets PK for the app.
 func (r *RPC) SetAppPK(in *SetAppPKIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetAppPK", in)(nil, &err)
+	defer r.auditCall("SetAppPK", in)(&err)
 
 	return r.visor.SetAppPK(in.AppName, in.PK)
 }
@@ -411,6 +484,7 @@ func (r *RPC) SetAppPK(in *SetAppPKIn, _ *struct{}) (err error) {
 // SetAppKillswitch sets killswitch flag for the app
 func (r *RPC) SetAppKillswitch(in *SetAppBoolIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetAppKillswitch", in)(nil, &err)
+	defer r.auditCall("SetAppKillswitch", in)(&err)
 
 	return r.visor.SetAppKillswitch(in.AppName, in.Val)
 }
@@ -418,6 +492,7 @@ func (r *RPC) SetAppKillswitch(in *SetAppBoolIn, _ *struct{}) (err error) {
 // SetAppSecure sets secure flag for the app
 func (r *RPC) SetAppSecure(in *SetAppBoolIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetAppSecure", in)(nil, &err)
+	defer r.auditCall("SetAppSecure", in)(&err)
 
 	return r.visor.SetAppSecure(in.AppName, in.Val)
 }
@@ -425,6 +500,7 @@ func (r *RPC) SetAppSecure(in *SetAppBoolIn, _ *struct{}) (err error) {
 // SetAppAddress sets addr flag for the app
 func (r *RPC) SetAppAddress(in *SetAppStringIn, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetAppAddress", in)(nil, &err)
+	defer r.auditCall("SetAppAddress", in)(&err)
 
 	return r.visor.SetAppAddress(in.AppName, in.Val)
 }
@@ -465,6 +541,40 @@ func (r *RPC) GetAppConnectionsSummary(appName *string, out *[]appserver.Connect
 	return err
 }
 
+// GetAppACL returns the access control list entries of the app.
+func (r *RPC) GetAppACL(appName *string, out *[]appacl.Entry) (err error) {
+	defer rpcutil.LogCall(r.log, "GetAppACL", appName)(out, &err)
+
+	entries, err := r.visor.GetAppACL(*appName)
+	if entries != nil {
+		*out = entries
+	}
+
+	return err
+}
+
+// SetAppACLEntryIn is input for SetAppACLEntry.
+type SetAppACLEntryIn struct {
+	AppName string
+	Entry   appacl.Entry
+}
+
+// SetAppACLEntry adds or replaces an access control list entry of the app.
+func (r *RPC) SetAppACLEntry(in *SetAppACLEntryIn, _ *struct{}) (err error) {
+	defer rpcutil.LogCall(r.log, "SetAppACLEntry", in)(nil, &err)
+	defer r.auditCall("SetAppACLEntry", in)(&err)
+
+	return r.visor.SetAppACLEntry(in.AppName, in.Entry)
+}
+
+// RemoveAppACLEntry removes the access control list entry of a remote visor from the app.
+func (r *RPC) RemoveAppACLEntry(in *SetAppPKIn, _ *struct{}) (err error) {
+	defer rpcutil.LogCall(r.log, "RemoveAppACLEntry", in)(nil, &err)
+	defer r.auditCall("RemoveAppACLEntry", in)(&err)
+
+	return r.visor.RemoveAppACLEntry(in.AppName, in.PK)
+}
+
 /*
 	<<< TRANSPORT MANAGEMENT >>>
 */
@@ -518,6 +628,7 @@ type AddTransportIn struct {
 // AddTransport creates a transport for the visor.
 func (r *RPC) AddTransport(in *AddTransportIn, out *TransportSummary) (err error) {
 	defer rpcutil.LogCall(r.log, "AddTransport", in)(out, &err)
+	defer r.auditCall("AddTransport", in)(&err)
 
 	tp, err := r.visor.AddTransport(in.RemotePK, in.TpType, in.Timeout)
 	if tp != nil {
@@ -530,6 +641,7 @@ func (r *RPC) AddTransport(in *AddTransportIn, out *TransportSummary) (err error
 // RemoveTransport removes a Transport from the visor.
 func (r *RPC) RemoveTransport(tid *uuid.UUID, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "RemoveTransport", tid)(nil, &err)
+	defer r.auditCall("RemoveTransport", tid)(&err)
 
 	return r.visor.RemoveTransport(*tid)
 }
@@ -537,6 +649,7 @@ func (r *RPC) RemoveTransport(tid *uuid.UUID, _ *struct{}) (err error) {
 // RemoveAllTransports removes all Transports from the visor.
 func (r *RPC) RemoveAllTransports(_ *struct{}, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "RemoveAllTransports", nil)(nil, &err)
+	defer r.auditCall("RemoveAllTransports", nil)(&err)
 
 	return r.visor.RemoveAllTransports()
 }
@@ -567,6 +680,24 @@ func (r *RPC) DiscoverTransportByID(id *uuid.UUID, out *transport.Entry) (err er
 	return err
 }
 
+// TransportStats aggregates the traffic of the transports recorded in the transport log store.
+func (r *RPC) TransportStats(in *transport.LogQuery, out *[]transport.LogStats) (err error) {
+	defer rpcutil.LogCall(r.log, "TransportStats", in)(out, &err)
+
+	stats, err := r.visor.TransportStats(*in)
+	*out = stats
+
+	return err
+}
+
+// LANPeers obtains the visors found on the local network by LAN discovery.
+func (r *RPC) LANPeers(_ *struct{}, out *[]lan.Peer) (err error) {
+	defer rpcutil.LogCall(r.log, "LANPeers", nil)(out, &err)
+
+	*out, err = r.visor.LANPeers()
+	return err
+}
+
 /*
 	<<< ROUTES MANAGEMENT >>>
 */
@@ -590,6 +721,7 @@ func (r *RPC) RoutingRule(key *routing.RouteID, rule *routing.Rule) (err error)
 // SaveRoutingRule saves a routing rule.
 func (r *RPC) SaveRoutingRule(in *routing.Rule, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SaveRoutingRule", in)(nil, &err)
+	defer r.auditCall("SaveRoutingRule", in)(&err)
 
 	return r.visor.SaveRoutingRule(*in)
 }
@@ -597,6 +729,7 @@ func (r *RPC) SaveRoutingRule(in *routing.Rule, _ *struct{}) (err error) {
 // RemoveRoutingRule removes a RoutingRule based on given RouteID key.
 func (r *RPC) RemoveRoutingRule(key *routing.RouteID, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "RemoveRoutingRule", key)(nil, &err)
+	defer r.auditCall("RemoveRoutingRule", key)(&err)
 
 	return r.visor.RemoveRoutingRule(*key)
 }
@@ -627,17 +760,20 @@ func (r *RPC) RouteGroups(_ *struct{}, out *[]RouteGroupInfo) (err error) {
 */
 
 // Reload reloads the config - without restarting the visor
-func (r *RPC) Reload(_ *struct{}, _ *struct{}) (err error) {
-	// @evanlinjin: do not defer this log statement, as the underlying visor.Logger will get closed.
-	rpcutil.LogCall(r.log, "Reload", nil)(nil, nil)
+func (r *RPC) Reload(_ *struct{}, out *ConfigReload) (err error) {
+	defer rpcutil.LogCall(r.log, "Reload", nil)(out, &err)
+	defer r.auditCall("Reload", nil)(&err)
 
-	return r.visor.Reload()
+	report, err := r.visor.Reload()
+	*out = report
+	return err
 }
 
 // Shutdown shuts down visor.
 func (r *RPC) Shutdown(_ *struct{}, _ *struct{}) (err error) {
 	// @evanlinjin: do not defer this log statement, as the underlying visor.Logger will get closed.
 	rpcutil.LogCall(r.log, "Shutdown", nil)(nil, nil)
+	defer r.auditCall("Shutdown", nil)(&err)
 
 	return r.visor.Shutdown()
 }
@@ -645,6 +781,7 @@ func (r *RPC) Shutdown(_ *struct{}, _ *struct{}) (err error) {
 // SetMinHops sets min_hops in visor's routing config
 func (r *RPC) SetMinHops(n *uint16, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetMinHops", *n)
+	defer r.auditCall("SetMinHops", *n)(&err)
 	err = r.visor.SetMinHops(*n)
 	return
 }
@@ -661,6 +798,7 @@ func (r *RPC) GetPersistentTransports(_ *struct{}, out *[]transport.PersistentTr
 // SetPersistentTransports sets persistent_transports in visor's routing config
 func (r *RPC) SetPersistentTransports(pTs *[]transport.PersistentTransports, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetPersistentTransports", *pTs)(nil, &err)
+	defer r.auditCall("SetPersistentTransports", *pTs)(&err)
 	err = r.visor.SetPersistentTransports(*pTs)
 	return err
 }
@@ -668,6 +806,7 @@ func (r *RPC) SetPersistentTransports(pTs *[]transport.PersistentTransports, _ *
 // SetPublicAutoconnect sets public_autoconnect in visor's routing config
 func (r *RPC) SetPublicAutoconnect(pAc *bool, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "SetPublicAutoconnect", *pAc)(nil, &err)
+	defer r.auditCall("SetPublicAutoconnect", *pAc)(&err)
 	err = r.visor.SetPublicAutoconnect(*pAc)
 	return err
 }
@@ -730,12 +869,14 @@ func (r *RPC) IsDMSGClientReady(_ *struct{}, out *bool) (err error) {
 // RegisterHTTPPort registers the local port to be accessed by remote visors
 func (r *RPC) RegisterHTTPPort(port *int, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "RegisterHTTPPort", port)(nil, &err)
+	defer r.auditCall("RegisterHTTPPort", port)(&err)
 	return r.visor.RegisterHTTPPort(*port)
 }
 
 // DeregisterHTTPPort deregisters the local port that can be accessed by remote visors
 func (r *RPC) DeregisterHTTPPort(port *int, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "DeregisterHTTPPort", port)(nil, &err)
+	defer r.auditCall("DeregisterHTTPPort", port)(&err)
 	return r.visor.DeregisterHTTPPort(*port)
 }
 
@@ -752,13 +893,15 @@ type ConnectIn struct {
 	RemotePK   cipher.PubKey
 	RemotePort int
 	LocalPort  int
+	Type       appnet.ForwardType
 }
 
 // Connect creates a connection with the remote visor to listen on the remote port and serve that on the local port
 func (r *RPC) Connect(in *ConnectIn, out *uuid.UUID) (err error) {
 	defer rpcutil.LogCall(r.log, "Connect", in)(out, &err)
+	defer r.auditCall("Connect", in)(&err)
 
-	id, err := r.visor.Connect(in.RemotePK, in.RemotePort, in.LocalPort)
+	id, err := r.visor.Connect(in.RemotePK, in.RemotePort, in.LocalPort, in.Type)
 	*out = id
 	return err
 }
@@ -766,6 +909,7 @@ func (r *RPC) Connect(in *ConnectIn, out *uuid.UUID) (err error) {
 // Disconnect breaks the connection with the given id
 func (r *RPC) Disconnect(id *uuid.UUID, _ *struct{}) (err error) {
 	defer rpcutil.LogCall(r.log, "Disconnect", id)(nil, &err)
+	defer r.auditCall("Disconnect", id)(&err)
 	err = r.visor.Disconnect(*id)
 	return err
 }
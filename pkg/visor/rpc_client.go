@@ -19,6 +19,7 @@ import (
 	"github.com/skycoin/skywire-utilities/pkg/buildinfo"
 	"github.com/skycoin/skywire-utilities/pkg/cipher"
 	"github.com/skycoin/skywire-utilities/pkg/logging"
+	"github.com/skycoin/skywire/pkg/app/appacl"
 	"github.com/skycoin/skywire/pkg/app/appcommon"
 	"github.com/skycoin/skywire/pkg/app/appnet"
 	"github.com/skycoin/skywire/pkg/app/appserver"
@@ -27,8 +28,10 @@ import (
 	"github.com/skycoin/skywire/pkg/servicedisc"
 	"github.com/skycoin/skywire/pkg/transport"
 	"github.com/skycoin/skywire/pkg/transport/network"
+	"github.com/skycoin/skywire/pkg/transport/network/lan"
 	"github.com/skycoin/skywire/pkg/util/cipherutil"
 	"github.com/skycoin/skywire/pkg/visor/visorconfig"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
 )
 
 var (
@@ -357,6 +360,33 @@ func (rc *rpcClient) GetAppConnectionsSummary(appName string) ([]appserver.Conne
 	return summary, nil
 }
 
+// GetAppACL calls GetAppACL.
+func (rc *rpcClient) GetAppACL(appName string) ([]appacl.Entry, error) {
+	var entries []appacl.Entry
+
+	if err := rc.Call("GetAppACL", &appName, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// SetAppACLEntry calls SetAppACLEntry.
+func (rc *rpcClient) SetAppACLEntry(appName string, entry appacl.Entry) error {
+	return rc.Call("SetAppACLEntry", &SetAppACLEntryIn{
+		AppName: appName,
+		Entry:   entry,
+	}, &struct{}{})
+}
+
+// RemoveAppACLEntry calls RemoveAppACLEntry.
+func (rc *rpcClient) RemoveAppACLEntry(appName string, pk cipher.PubKey) error {
+	return rc.Call("RemoveAppACLEntry", &SetAppPKIn{
+		AppName: appName,
+		PK:      pk,
+	}, &struct{}{})
+}
+
 // TransportTypes calls TransportTypes.
 func (rc *rpcClient) TransportTypes() ([]string, error) {
 	var types []string
@@ -416,6 +446,27 @@ func (rc *rpcClient) DiscoverTransportByID(id uuid.UUID) (*transport.Entry, erro
 	return &entry, err
 }
 
+// TransportStats calls TransportStats.
+func (rc *rpcClient) TransportStats(q transport.LogQuery) ([]transport.LogStats, error) {
+	stats := make([]transport.LogStats, 0)
+	err := rc.Call("TransportStats", &q, &stats)
+	return stats, err
+}
+
+// Events calls Events.
+func (rc *rpcClient) Events(since uint64, wait time.Duration) ([]visorevent.Event, error) {
+	events := make([]visorevent.Event, 0)
+	err := rc.Call("Events", &EventsIn{Since: since, Wait: wait}, &events)
+	return events, err
+}
+
+// LANPeers calls LANPeers.
+func (rc *rpcClient) LANPeers() ([]lan.Peer, error) {
+	peers := make([]lan.Peer, 0)
+	err := rc.Call("LANPeers", &struct{}{}, &peers)
+	return peers, err
+}
+
 // SetPublicAutoconnect implements API.
 func (rc *rpcClient) SetPublicAutoconnect(pAc bool) error {
 	return rc.Call("SetPublicAutoconnect", &pAc, &struct{}{})
@@ -453,8 +504,10 @@ func (rc *rpcClient) RouteGroups() ([]RouteGroupInfo, error) {
 }
 
 // Reload calls Reload.
-func (rc *rpcClient) Reload() error {
-	return rc.Call("Reload", &struct{}{}, &struct{}{})
+func (rc *rpcClient) Reload() (ConfigReload, error) {
+	var report ConfigReload
+	err := rc.Call("Reload", &struct{}{}, &report)
+	return report, err
 }
 
 // Shutdown calls Shutdown.
@@ -556,12 +609,13 @@ func (rc *rpcClient) IsDMSGClientReady() (bool, error) {
 }
 
 // Connect calls Connect.
-func (rc *rpcClient) Connect(remotePK cipher.PubKey, remotePort, localPort int) (uuid.UUID, error) {
+func (rc *rpcClient) Connect(remotePK cipher.PubKey, remotePort, localPort int, fwdType appnet.ForwardType) (uuid.UUID, error) {
 	var out uuid.UUID
 	err := rc.Call("Connect", &ConnectIn{
 		RemotePK:   remotePK,
 		RemotePort: remotePort,
 		LocalPort:  localPort,
+		Type:       fwdType,
 	}, &out)
 	return out, err
 }
@@ -1084,6 +1138,21 @@ func (mc *mockRPCClient) GetAppConnectionsSummary(_ string) ([]appserver.Connect
 	return nil, nil
 }
 
+// GetAppACL implements API.
+func (mc *mockRPCClient) GetAppACL(_ string) ([]appacl.Entry, error) {
+	return nil, nil
+}
+
+// SetAppACLEntry implements API.
+func (mc *mockRPCClient) SetAppACLEntry(_ string, _ appacl.Entry) error {
+	return nil
+}
+
+// RemoveAppACLEntry implements API.
+func (mc *mockRPCClient) RemoveAppACLEntry(_ string, _ cipher.PubKey) error {
+	return nil
+}
+
 // TransportTypes implements API.
 func (mc *mockRPCClient) TransportTypes() ([]string, error) {
 	var res []string
@@ -1189,6 +1258,21 @@ func (mc *mockRPCClient) DiscoverTransportByID(uuid.UUID) (*transport.Entry, err
 	return nil, ErrNotImplemented
 }
 
+// TransportStats implements API.
+func (mc *mockRPCClient) TransportStats(transport.LogQuery) ([]transport.LogStats, error) {
+	return nil, ErrNotImplemented
+}
+
+// Events implements API.
+func (mc *mockRPCClient) Events(_ uint64, _ time.Duration) ([]visorevent.Event, error) {
+	return nil, ErrNotImplemented
+}
+
+// LANPeers implements API.
+func (mc *mockRPCClient) LANPeers() ([]lan.Peer, error) {
+	return nil, ErrNotImplemented
+}
+
 // SetPublicAutoconnect implements API.
 func (mc *mockRPCClient) SetPublicAutoconnect(_ bool) error {
 	return nil
@@ -1240,8 +1324,8 @@ func (mc *mockRPCClient) RouteGroups() ([]RouteGroupInfo, error) {
 }
 
 // Reload implements API.
-func (mc *mockRPCClient) Reload() error {
-	return nil
+func (mc *mockRPCClient) Reload() (ConfigReload, error) {
+	return ConfigReload{Applied: []string{}, RestartRequired: []string{}}, nil
 }
 
 // Shutdown implements API.
@@ -1311,7 +1395,7 @@ func (mc *mockRPCClient) IsDMSGClientReady() (bool, error) {
 }
 
 // Connect implements API.
-func (mc *mockRPCClient) Connect(remotePK cipher.PubKey, remotePort, localPort int) (uuid.UUID, error) { //nolint:all
+func (mc *mockRPCClient) Connect(remotePK cipher.PubKey, remotePort, localPort int, fwdType appnet.ForwardType) (uuid.UUID, error) { //nolint:all
 	return uuid.UUID{}, nil
 }
 
@@ -0,0 +1,206 @@
+// Package usermanager token.go
+package usermanager
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+const (
+	boltTokenBucketName = "tokens"
+	// tokenPrefix makes the tokens recognizable, e.g. by secret scanners.
+	tokenPrefix     = "swt_"
+	tokenSecretLen  = 32
+	maxTokenNameLen = 64
+)
+
+// Errors associated with API tokens.
+var (
+	ErrBadToken      = fmt.Errorf("API token is either invalid, revoked, or expired")
+	ErrTokenNotFound = fmt.Errorf("API token is either revoked or not found")
+	ErrBadTokenName  = fmt.Errorf("token name should be between 1 and %d chars", maxTokenNameLen)
+	ErrTokenScope    = fmt.Errorf("token scope can't exceed the role of its user")
+	ErrTokenAuth     = fmt.Errorf("API tokens can't be managed with API tokens")
+	ErrAdminToken    = fmt.Errorf("tokens restricted to visors can't be scoped %q", RoleAdmin)
+)
+
+// nolint: gochecknoinits
+func init() {
+	gob.Register(Token{})
+}
+
+// Token is a long-lived bearer token giving access to the hypervisor API on behalf of a user.
+// Only the hash of the token secret is stored.
+type Token struct {
+	ID   uuid.UUID
+	Name string
+	User string
+	// Scope is the most permissive role the token may act as, it is capped by the role of the user.
+	Scope Role
+	// Visors the token is restricted to, in addition to the restrictions of the user.
+	Visors  []cipher.PubKey
+	Hash    cipher.SHA256
+	Created time.Time
+	// Expiry is the time the token expires at, the token never expires if zero.
+	Expiry time.Time
+}
+
+// NewToken creates a token of `user` and returns it along with the bearer string
+// which is the only way to use it.
+func NewToken(user, name string, scope Role, visors []cipher.PubKey, expiry time.Time) (Token, string, error) {
+	if name == "" || len(name) > maxTokenNameLen {
+		return Token{}, "", ErrBadTokenName
+	}
+	if !scope.Valid() {
+		return Token{}, "", ErrBadRole
+	}
+	if scope == RoleAdmin && len(visors) != 0 {
+		return Token{}, "", ErrAdminToken
+	}
+
+	token := Token{
+		ID:      uuid.New(),
+		Name:    name,
+		User:    user,
+		Scope:   scope,
+		Visors:  visors,
+		Created: time.Now(),
+		Expiry:  expiry,
+	}
+	secret := cipher.RandByte(tokenSecretLen)
+	token.Hash = cipher.SumSHA256(secret)
+
+	raw := append(token.ID[:], secret...) //nolint:gocritic
+	return token, tokenPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// parseBearer splits a bearer string into the token ID and secret.
+func parseBearer(bearer string) (uuid.UUID, []byte, error) {
+	if !strings.HasPrefix(bearer, tokenPrefix) {
+		return uuid.UUID{}, nil, ErrBadToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(bearer, tokenPrefix))
+	if err != nil || len(raw) != len(uuid.UUID{})+tokenSecretLen {
+		return uuid.UUID{}, nil, ErrBadToken
+	}
+
+	var id uuid.UUID
+	copy(id[:], raw)
+	return id, raw[len(id):], nil
+}
+
+// Verify checks the secret of the token and its expiry.
+func (t *Token) Verify(secret []byte) bool {
+	if !t.Expiry.IsZero() && time.Now().After(t.Expiry) {
+		return false
+	}
+	return cipher.SumSHA256(secret) == t.Hash
+}
+
+// Restrict returns the user as permitted to act through the token,
+// false if the token isn't permitted to access any visor.
+func (t *Token) Restrict(user User) (User, bool) {
+	if !t.Scope.Includes(user.UserRole()) {
+		user.Role = t.Scope
+	}
+	if len(t.Visors) == 0 {
+		return user, true
+	}
+
+	visors := make([]cipher.PubKey, 0, len(t.Visors))
+	for _, pk := range t.Visors {
+		if user.CanAccessVisor(pk) {
+			visors = append(visors, pk)
+		}
+	}
+	user.Visors = visors
+
+	return user, len(visors) != 0
+}
+
+// Encode encodes the token to bytes.
+func (t *Token) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(t); err != nil {
+		return nil, fmt.Errorf("unexpected token encode error: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DecodeToken decodes the token from bytes.
+func DecodeToken(raw []byte) (*Token, error) {
+	var token Token
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("unexpected decode token error: %w", err)
+	}
+
+	return &token, nil
+}
+
+// TokenStore stores API tokens.
+type TokenStore interface {
+	Token(id uuid.UUID) (*Token, error)
+	Tokens() ([]Token, error)
+	AddToken(token Token) error
+	RemoveToken(id uuid.UUID) error
+}
+
+// Token obtains a single token. Returns nil if token does not exist.
+func (s *BoltUserStore) Token(id uuid.UUID) (token *Token, err error) {
+	err = s.View(func(tx *bbolt.Tx) error {
+		rawToken := tx.Bucket([]byte(boltTokenBucketName)).Get(id[:])
+		if rawToken == nil {
+			return nil
+		}
+
+		token, err = DecodeToken(rawToken)
+		return err
+	})
+
+	return token, err
+}
+
+// Tokens obtains all of the tokens.
+func (s *BoltUserStore) Tokens() (tokens []Token, err error) {
+	err = s.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(boltTokenBucketName)).ForEach(func(_, rawToken []byte) error {
+			token, err := DecodeToken(rawToken)
+			if err != nil {
+				return err
+			}
+			tokens = append(tokens, *token)
+			return nil
+		})
+	})
+
+	return tokens, err
+}
+
+// AddToken adds a new token.
+func (s *BoltUserStore) AddToken(token Token) error {
+	return s.Update(func(tx *bbolt.Tx) error {
+		encoded, err := token.Encode()
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket([]byte(boltTokenBucketName)).Put(token.ID[:], encoded)
+	})
+}
+
+// RemoveToken removes the token of given ID.
+func (s *BoltUserStore) RemoveToken(id uuid.UUID) error {
+	return s.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(boltTokenBucketName)).Delete(id[:])
+	})
+}
@@ -0,0 +1,51 @@
+// Package usermanager pkg/visor/usermanager/token_test.go
+package usermanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+func TestToken(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	otherPK, _ := cipher.GenerateKeyPair()
+
+	_, _, err := NewToken("admin", "", RoleAdmin, nil, time.Time{})
+	assert.ErrorIs(t, err, ErrBadTokenName)
+	_, _, err = NewToken("admin", "ci", RoleAdmin, []cipher.PubKey{pk}, time.Time{})
+	assert.ErrorIs(t, err, ErrAdminToken)
+
+	token, bearer, err := NewToken("admin", "ci", RoleOperator, []cipher.PubKey{pk}, time.Time{})
+	require.NoError(t, err)
+
+	id, secret, err := parseBearer(bearer)
+	require.NoError(t, err)
+	assert.Equal(t, token.ID, id)
+	assert.True(t, token.Verify(secret))
+	assert.False(t, token.Verify(cipher.RandByte(tokenSecretLen)))
+
+	_, _, err = parseBearer(bearer[len(tokenPrefix):])
+	assert.ErrorIs(t, err, ErrBadToken)
+
+	// the scope and visors of the token restrict the user
+	user, ok := token.Restrict(User{Name: "admin", Role: RoleAdmin})
+	require.True(t, ok)
+	assert.Equal(t, RoleOperator, user.UserRole())
+	assert.True(t, user.CanAccessVisor(pk))
+	assert.False(t, user.CanAccessVisor(otherPK))
+
+	// but never extend its role or visors
+	user, ok = token.Restrict(User{Name: "reader", Role: RoleReadOnly})
+	require.True(t, ok)
+	assert.Equal(t, RoleReadOnly, user.UserRole())
+	_, ok = token.Restrict(User{Name: "operator", Role: RoleOperator, Visors: []cipher.PubKey{otherPK}})
+	assert.False(t, ok)
+
+	token.Expiry = time.Now().Add(-time.Second)
+	assert.False(t, token.Verify(secret))
+}
@@ -31,8 +31,39 @@ var (
 	ErrUserExists     = fmt.Errorf("username already exists")
 	ErrNameNotAllowed = fmt.Errorf("name not allowed")
 	ErrNonASCII       = fmt.Errorf("non-ASCII character found")
+	ErrBadRole        = fmt.Errorf("role should be one of %q, %q or %q", RoleAdmin, RoleOperator, RoleReadOnly)
 )
 
+// Role defines what a user of the hypervisor is permitted to do.
+type Role string
+
+// Roles of the hypervisor users, from the most to the least permissive.
+const (
+	// RoleAdmin may do anything, including managing the other users.
+	RoleAdmin Role = "admin"
+	// RoleOperator may change the visors but not shut them down, open terminals or change reward addresses.
+	RoleOperator Role = "operator"
+	// RoleReadOnly may only view the visors.
+	RoleReadOnly Role = "read-only"
+)
+
+var roleRanks = map[Role]int{
+	RoleReadOnly: 1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Valid returns true if the role is known.
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+	return ok
+}
+
+// Includes returns true if the role is permitted to do everything `other` is.
+func (r Role) Includes(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
 // nolint: gochecknoinits
 func init() {
 	gob.Register(User{})
@@ -43,6 +74,31 @@ type User struct {
 	Name   string
 	PwSalt []byte
 	PwHash cipher.SHA256
+	Role   Role
+	// Visors the user is restricted to, the user may access any visor if empty.
+	// Admins may always access any visor.
+	Visors []cipher.PubKey
+}
+
+// UserRole returns the role of the user. Users stored before roles were introduced are admins.
+func (u *User) UserRole() Role {
+	if u.Role == "" {
+		return RoleAdmin
+	}
+	return u.Role
+}
+
+// CanAccessVisor returns true if the user isn't restricted from the visor of `pk`.
+func (u *User) CanAccessVisor(pk cipher.PubKey) bool {
+	if u.UserRole() == RoleAdmin || len(u.Visors) == 0 {
+		return true
+	}
+	for _, v := range u.Visors {
+		if v == pk {
+			return true
+		}
+	}
+	return false
 }
 
 // SetName checks the provided name, and sets the name if format is valid.
@@ -96,9 +152,11 @@ func DecodeUser(raw []byte) (*User, error) {
 // UserStore stores users.
 type UserStore interface {
 	User(name string) (*User, error)
+	Users() ([]User, error)
 	AddUser(user User) error
 	SetUser(user User) error
 	RemoveUser(name string) error
+	TokenStore
 	Close() error
 }
 
@@ -119,7 +177,10 @@ func NewBoltUserStore(path string) (*BoltUserStore, error) {
 	}
 
 	err = db.Update(func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(boltUserBucketName))
+		if _, err := tx.CreateBucketIfNotExists([]byte(boltUserBucketName)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists([]byte(boltTokenBucketName))
 		return err
 	})
 
@@ -142,6 +203,22 @@ func (s *BoltUserStore) User(name string) (user *User, err error) {
 	return user, err
 }
 
+// Users obtains all of the users, sorted by name.
+func (s *BoltUserStore) Users() (users []User, err error) {
+	err = s.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(boltUserBucketName)).ForEach(func(_, rawUser []byte) error {
+			user, err := DecodeUser(rawUser)
+			if err != nil {
+				return err
+			}
+			users = append(users, *user)
+			return nil
+		})
+	})
+
+	return users, err
+}
+
 // AddUser adds a new user.
 func (s *BoltUserStore) AddUser(user User) error {
 	return s.Update(func(tx *bbolt.Tx) error {
@@ -7,12 +7,16 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/gorilla/securecookie"
 
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
 	"github.com/skycoin/skywire-utilities/pkg/httputil"
 	"github.com/skycoin/skywire-utilities/pkg/logging"
 	"github.com/skycoin/skywire/pkg/visor/visorconfig"
@@ -20,6 +24,8 @@ import (
 
 const (
 	sessionCookieName = "swm-session"
+	// AdminName is the name of the account which may be created without logging in.
+	AdminName = "admin"
 )
 
 // Errors associated with user management.
@@ -31,6 +37,10 @@ var (
 	ErrMalformedRequest  = errors.New("request format is malformed")
 	ErrBadUsernameFormat = errors.New("format of 'username' is not accepted")
 	ErrUserNotFound      = errors.New("user is either deleted or not found")
+	ErrForbidden         = errors.New("user is not permitted to perform this action")
+	ErrRemoveSelf        = errors.New("users can't remove or demote themselves")
+	ErrRemoveAdmin       = fmt.Errorf("the %q user can't be removed or demoted", AdminName)
+	ErrAccountExists     = errors.New("the admin account is already created, users are added by admins")
 )
 
 // for use with context.Context
@@ -40,6 +50,7 @@ type ctxKey string
 const (
 	userKey    = ctxKey("user")
 	sessionKey = ctxKey("session")
+	tokenKey   = ctxKey("token")
 )
 
 // Session represents a user session.
@@ -57,6 +68,7 @@ type UserManager struct {
 	sessions map[uuid.UUID]Session
 	crypto   *securecookie.SecureCookie
 	mu       *sync.RWMutex
+	createMu sync.Mutex // held while creating the admin account
 }
 
 // NewUserManager creates a new UserManager.
@@ -141,9 +153,25 @@ func (s *UserManager) Logout() http.HandlerFunc {
 	}
 }
 
-// Authorize is an http middleware for authorizing requests.
+// Authorize is an http middleware for authorizing requests, either by a session cookie
+// or by an API token in the `Authorization: Bearer` header.
 func (s *UserManager) Authorize(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bearer, ok := bearerToken(r); ok {
+			user, token, err := s.tokenUser(bearer)
+			if err != nil {
+				httputil.WriteJSON(w, r, http.StatusUnauthorized, err)
+				return
+			}
+
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, userKey, user)
+			ctx = context.WithValue(ctx, tokenKey, token)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		user, session, ok := s.session(r)
 		if !ok {
 			httputil.WriteJSON(w, r, http.StatusUnauthorized, ErrBadSession)
@@ -158,6 +186,55 @@ func (s *UserManager) Authorize(next http.Handler) http.Handler {
 	})
 }
 
+// UserFromContext obtains the user of a request authorized by Authorize.
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(userKey).(User)
+	return user, ok
+}
+
+// TokenFromContext obtains the API token a request was authorized with by Authorize.
+func TokenFromContext(ctx context.Context) (Token, bool) {
+	token, ok := ctx.Value(tokenKey).(Token)
+	return token, ok
+}
+
+// IsTokenAuthorized returns true if the request was authorized by an API token.
+// Such requests don't carry cookies, so they need no CSRF protection.
+func IsTokenAuthorized(ctx context.Context) bool {
+	_, ok := TokenFromContext(ctx)
+	return ok
+}
+
+// Permit returns an http middleware only passing requests of users with the given role,
+// and which aren't restricted from the visor of the `pk` URL parameter if any. It requires
+// requests to be authorized by Authorize.
+func (s *UserManager) Permit(role Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				httputil.WriteJSON(w, r, http.StatusUnauthorized, ErrBadSession)
+				return
+			}
+
+			if !user.UserRole().Includes(role) {
+				httputil.WriteJSON(w, r, http.StatusForbidden, ErrForbidden)
+				return
+			}
+
+			if rawPK := chi.URLParam(r, "pk"); rawPK != "" {
+				var pk cipher.PubKey
+				if err := pk.Set(rawPK); err == nil && !user.CanAccessVisor(pk) {
+					httputil.WriteJSON(w, r, http.StatusForbidden, ErrForbidden)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // ChangePassword returns a HandlerFunc for changing the user's password.
 func (s *UserManager) ChangePassword() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -223,6 +300,27 @@ func (s *UserManager) CreateAccount() http.HandlerFunc {
 			return
 		}
 
+		// only the admin account may be created this way, the other users are added by admins
+		if user.Name != AdminName {
+			httputil.WriteJSON(w, r, http.StatusForbidden, ErrNameNotAllowed)
+			return
+		}
+		user.Role = RoleAdmin
+
+		// the account is only created while there is none, the endpoint is not authorized
+		s.createMu.Lock()
+		defer s.createMu.Unlock()
+		users, err := s.db.Users()
+		if err != nil {
+			s.log.WithError(err).Error("Failed to get users")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if len(users) != 0 {
+			httputil.WriteJSON(w, r, http.StatusForbidden, ErrAccountExists)
+			return
+		}
+
 		if err := user.SetPassword(rb.Password); err != nil {
 			httputil.WriteJSON(w, r, http.StatusBadRequest, err)
 			return
@@ -275,11 +373,15 @@ func (s *UserManager) UserInfo() http.HandlerFunc {
 		s.mu.RUnlock()
 
 		resp := struct {
-			Username string    `json:"username"`
-			Current  Session   `json:"current_session"`
-			Sessions []Session `json:"other_sessions"`
+			Username string          `json:"username"`
+			Role     Role            `json:"role"`
+			Visors   []cipher.PubKey `json:"visors,omitempty"`
+			Current  Session         `json:"current_session"`
+			Sessions []Session       `json:"other_sessions"`
 		}{
 			Username: user.Name,
+			Role:     user.UserRole(),
+			Visors:   user.Visors,
 			Current:  session,
 			Sessions: otherSessions,
 		}
@@ -288,6 +390,445 @@ func (s *UserManager) UserInfo() http.HandlerFunc {
 	}
 }
 
+// UserSummary is the view of a user returned by the user management endpoints.
+type UserSummary struct {
+	Username string          `json:"username"`
+	Role     Role            `json:"role"`
+	Visors   []cipher.PubKey `json:"visors,omitempty"`
+}
+
+func makeUserSummary(user User) UserSummary {
+	return UserSummary{
+		Username: user.Name,
+		Role:     user.UserRole(),
+		Visors:   user.Visors,
+	}
+}
+
+// ListUsers returns a HandlerFunc for listing the users.
+func (s *UserManager) ListUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		users, err := s.db.Users()
+		if err != nil {
+			s.log.WithError(err).Error("Failed to get users")
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		summaries := make([]UserSummary, 0, len(users))
+		for _, user := range users {
+			summaries = append(summaries, makeUserSummary(user))
+		}
+
+		httputil.WriteJSON(w, r, http.StatusOK, summaries)
+	}
+}
+
+// AddUser returns a HandlerFunc for adding a user.
+func (s *UserManager) AddUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rb struct {
+			Username string          `json:"username"`
+			Password string          `json:"password"`
+			Role     Role            `json:"role"`
+			Visors   []cipher.PubKey `json:"visors"`
+		}
+
+		if err := httputil.ReadJSON(r, &rb); err != nil {
+			if err != io.EOF {
+				s.log.Warnf("AddUser request: %v", err)
+			}
+
+			httputil.WriteJSON(w, r, http.StatusBadRequest, ErrMalformedRequest)
+
+			return
+		}
+
+		user := User{Role: rb.Role, Visors: rb.Visors}
+		if ok := user.SetName(rb.Username); !ok {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, ErrBadUsernameFormat)
+			return
+		}
+
+		if !user.Role.Valid() {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, ErrBadRole)
+			return
+		}
+
+		if err := user.SetPassword(rb.Password); err != nil {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.db.AddUser(user); err != nil {
+			if err == ErrUserExists {
+				httputil.WriteJSON(w, r, http.StatusConflict, ErrUserExists)
+				return
+			}
+
+			s.log.WithError(err).Errorf("Failed to add user %q", user.Name)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		httputil.WriteJSON(w, r, http.StatusOK, makeUserSummary(user))
+	}
+}
+
+// UpdateUser returns a HandlerFunc for changing the role, visors or password of the user
+// of the `name` URL parameter. The sessions of the user are ended.
+func (s *UserManager) UpdateUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rb struct {
+			Password string           `json:"password"`
+			Role     Role             `json:"role"`
+			Visors   *[]cipher.PubKey `json:"visors"`
+		}
+
+		if err := httputil.ReadJSON(r, &rb); err != nil {
+			if err != io.EOF {
+				s.log.Warnf("UpdateUser request: %v", err)
+			}
+
+			httputil.WriteJSON(w, r, http.StatusBadRequest, ErrMalformedRequest)
+
+			return
+		}
+
+		user, ok := s.userOfParam(w, r)
+		if !ok {
+			return
+		}
+
+		if rb.Role != "" {
+			if !rb.Role.Valid() {
+				httputil.WriteJSON(w, r, http.StatusBadRequest, ErrBadRole)
+				return
+			}
+
+			if self, _ := UserFromContext(r.Context()); self.Name == user.Name && rb.Role != RoleAdmin { //nolint:errcheck
+				httputil.WriteJSON(w, r, http.StatusForbidden, ErrRemoveSelf)
+				return
+			}
+			if user.Name == AdminName && rb.Role != RoleAdmin {
+				httputil.WriteJSON(w, r, http.StatusForbidden, ErrRemoveAdmin)
+				return
+			}
+
+			user.Role = rb.Role
+		}
+
+		if rb.Visors != nil {
+			user.Visors = *rb.Visors
+		}
+
+		if rb.Password != "" {
+			if err := user.SetPassword(rb.Password); err != nil {
+				httputil.WriteJSON(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		if err := s.db.SetUser(*user); err != nil {
+			s.log.WithError(err).Errorf("Failed to update user %q data", user.Name)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		s.delAllSessionsOfUser(user.Name)
+		httputil.WriteJSON(w, r, http.StatusOK, makeUserSummary(*user))
+	}
+}
+
+// RemoveUser returns a HandlerFunc for removing the user of the `name` URL parameter.
+func (s *UserManager) RemoveUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.userOfParam(w, r)
+		if !ok {
+			return
+		}
+
+		if self, _ := UserFromContext(r.Context()); self.Name == user.Name { //nolint:errcheck
+			httputil.WriteJSON(w, r, http.StatusForbidden, ErrRemoveSelf)
+			return
+		}
+		if user.Name == AdminName {
+			httputil.WriteJSON(w, r, http.StatusForbidden, ErrRemoveAdmin)
+			return
+		}
+
+		if err := s.db.RemoveUser(user.Name); err != nil {
+			s.log.WithError(err).Errorf("Failed to remove user %q", user.Name)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		// the tokens would be valid again for a new user of the same name
+		tokens, err := s.db.Tokens()
+		if err != nil {
+			s.log.WithError(err).Error("Failed to get tokens")
+		}
+		for _, token := range tokens {
+			if token.User != user.Name {
+				continue
+			}
+			if err := s.db.RemoveToken(token.ID); err != nil {
+				s.log.WithError(err).Errorf("Failed to remove token %v", token.ID)
+			}
+		}
+
+		s.delAllSessionsOfUser(user.Name)
+		httputil.WriteJSON(w, r, http.StatusOK, true)
+	}
+}
+
+func (s *UserManager) userOfParam(w http.ResponseWriter, r *http.Request) (*User, bool) {
+	name := chi.URLParam(r, "name")
+	if !checkUsernameFormat(name) {
+		httputil.WriteJSON(w, r, http.StatusBadRequest, ErrBadUsernameFormat)
+		return nil, false
+	}
+
+	user, err := s.db.User(name)
+	if err != nil {
+		s.log.WithError(err).Errorf("Failed to get user %q", name)
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return nil, false
+	}
+
+	if user == nil {
+		httputil.WriteJSON(w, r, http.StatusNotFound, ErrUserNotFound)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// TokenSummary is the view of an API token returned by the token endpoints.
+type TokenSummary struct {
+	ID      uuid.UUID       `json:"id"`
+	Name    string          `json:"name"`
+	User    string          `json:"username"`
+	Scope   Role            `json:"scope"`
+	Visors  []cipher.PubKey `json:"visors,omitempty"`
+	Created time.Time       `json:"created"`
+	Expiry  *time.Time      `json:"expiry,omitempty"`
+	// Token is the bearer string, only returned when the token is created.
+	Token string `json:"token,omitempty"`
+}
+
+func makeTokenSummary(token Token) TokenSummary {
+	summary := TokenSummary{
+		ID:      token.ID,
+		Name:    token.Name,
+		User:    token.User,
+		Scope:   token.Scope,
+		Visors:  token.Visors,
+		Created: token.Created,
+	}
+	if !token.Expiry.IsZero() {
+		summary.Expiry = &token.Expiry
+	}
+	return summary
+}
+
+// ListTokens returns a HandlerFunc for listing the API tokens of the user, or of all users for admins.
+func (s *UserManager) ListTokens() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.tokenManager(w, r)
+		if !ok {
+			return
+		}
+
+		tokens, err := s.db.Tokens()
+		if err != nil {
+			s.log.WithError(err).Error("Failed to get tokens")
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		summaries := make([]TokenSummary, 0, len(tokens))
+		for _, token := range tokens {
+			if token.User == user.Name || user.UserRole() == RoleAdmin {
+				summaries = append(summaries, makeTokenSummary(token))
+			}
+		}
+		sort.Slice(summaries, func(i, j int) bool {
+			return summaries[i].Created.Before(summaries[j].Created)
+		})
+
+		httputil.WriteJSON(w, r, http.StatusOK, summaries)
+	}
+}
+
+// CreateToken returns a HandlerFunc for creating an API token of the user.
+// The scope of the token defaults to the role of the user.
+func (s *UserManager) CreateToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rb struct {
+			Name      string          `json:"name"`
+			Scope     Role            `json:"scope"`
+			Visors    []cipher.PubKey `json:"visors"`
+			ExpiresIn string          `json:"expires_in"` // e.g. 720h, the token never expires if empty
+		}
+
+		if err := httputil.ReadJSON(r, &rb); err != nil {
+			if err != io.EOF {
+				s.log.Warnf("CreateToken request: %v", err)
+			}
+
+			httputil.WriteJSON(w, r, http.StatusBadRequest, ErrMalformedRequest)
+
+			return
+		}
+
+		user, ok := s.tokenManager(w, r)
+		if !ok {
+			return
+		}
+
+		if rb.Scope == "" {
+			rb.Scope = user.UserRole()
+		}
+		if rb.Scope.Valid() && !user.UserRole().Includes(rb.Scope) {
+			httputil.WriteJSON(w, r, http.StatusForbidden, ErrTokenScope)
+			return
+		}
+
+		var expiry time.Time
+		if rb.ExpiresIn != "" {
+			d, err := time.ParseDuration(rb.ExpiresIn)
+			if err != nil || d <= 0 {
+				httputil.WriteJSON(w, r, http.StatusBadRequest, ErrMalformedRequest)
+				return
+			}
+			expiry = time.Now().Add(d)
+		}
+
+		token, bearer, err := NewToken(user.Name, rb.Name, rb.Scope, rb.Visors, expiry)
+		if err != nil {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.db.AddToken(token); err != nil {
+			s.log.WithError(err).Errorf("Failed to add token of user %q", user.Name)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		summary := makeTokenSummary(token)
+		summary.Token = bearer
+		httputil.WriteJSON(w, r, http.StatusOK, summary)
+	}
+}
+
+// RevokeToken returns a HandlerFunc for revoking the API token of the `id` URL parameter.
+// Admins may revoke the tokens of any user.
+func (s *UserManager) RevokeToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.tokenManager(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			httputil.WriteJSON(w, r, http.StatusBadRequest, ErrMalformedRequest)
+			return
+		}
+
+		token, err := s.db.Token(id)
+		if err != nil {
+			s.log.WithError(err).Errorf("Failed to get token %v", id)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		if token == nil || (token.User != user.Name && user.UserRole() != RoleAdmin) {
+			httputil.WriteJSON(w, r, http.StatusNotFound, ErrTokenNotFound)
+			return
+		}
+
+		if err := s.db.RemoveToken(id); err != nil {
+			s.log.WithError(err).Errorf("Failed to remove token %v", id)
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		httputil.WriteJSON(w, r, http.StatusOK, true)
+	}
+}
+
+// tokenManager returns the user managing tokens, tokens may only be managed from sessions.
+func (s *UserManager) tokenManager(w http.ResponseWriter, r *http.Request) (User, bool) {
+	if IsTokenAuthorized(r.Context()) {
+		httputil.WriteJSON(w, r, http.StatusForbidden, ErrTokenAuth)
+		return User{}, false
+	}
+
+	user, ok := UserFromContext(r.Context())
+	if !ok {
+		httputil.WriteJSON(w, r, http.StatusUnauthorized, ErrBadSession)
+		return User{}, false
+	}
+
+	return user, true
+}
+
+// tokenUser returns the user acting through the API token of `bearer`.
+func (s *UserManager) tokenUser(bearer string) (User, Token, error) {
+	id, secret, err := parseBearer(bearer)
+	if err != nil {
+		return User{}, Token{}, err
+	}
+
+	token, err := s.db.Token(id)
+	if err != nil {
+		s.log.WithError(err).Errorf("Failed to fetch token %v", id)
+		return User{}, Token{}, ErrBadToken
+	}
+
+	if token == nil || !token.Verify(secret) {
+		return User{}, Token{}, ErrBadToken
+	}
+
+	user, err := s.db.User(token.User)
+	if err != nil {
+		s.log.WithError(err).Errorf("Failed to fetch user %q data", token.User)
+		return User{}, Token{}, ErrBadToken
+	}
+
+	if user == nil {
+		return User{}, Token{}, ErrBadToken
+	}
+
+	restricted, ok := token.Restrict(*user)
+	if !ok {
+		return User{}, Token{}, ErrBadToken
+	}
+
+	return restricted, *token, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, prefix)), true
+}
+
 func (s *UserManager) newSession(w http.ResponseWriter, session Session) error {
 	session.SID = uuid.New()
 
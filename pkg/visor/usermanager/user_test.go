@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
 )
 
 // nolint: funlen
@@ -84,3 +86,28 @@ func Test_checkPasswordFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestUser_Role(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	otherPK, _ := cipher.GenerateKeyPair()
+
+	legacy := User{Name: "admin"}
+	assert.Equal(t, RoleAdmin, legacy.UserRole())
+
+	operator := User{Name: "operator", Role: RoleOperator, Visors: []cipher.PubKey{pk}}
+	assert.True(t, operator.UserRole().Includes(RoleReadOnly))
+	assert.True(t, operator.UserRole().Includes(RoleOperator))
+	assert.False(t, operator.UserRole().Includes(RoleAdmin))
+	assert.True(t, operator.CanAccessVisor(pk))
+	assert.False(t, operator.CanAccessVisor(otherPK))
+
+	reader := User{Name: "reader", Role: RoleReadOnly}
+	assert.False(t, reader.UserRole().Includes(RoleOperator))
+	assert.True(t, reader.CanAccessVisor(otherPK))
+
+	// admins are never restricted
+	admin := User{Name: "root", Role: RoleAdmin, Visors: []cipher.PubKey{pk}}
+	assert.True(t, admin.CanAccessVisor(otherPK))
+
+	assert.False(t, Role("superuser").Valid())
+}
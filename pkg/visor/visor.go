@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io/fs"
 	"net/http"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -31,10 +32,14 @@ import (
 	"github.com/skycoin/skywire/pkg/transport"
 	"github.com/skycoin/skywire/pkg/transport/network"
 	"github.com/skycoin/skywire/pkg/transport/network/addrresolver"
+	"github.com/skycoin/skywire/pkg/transport/network/lan"
+	"github.com/skycoin/skywire/pkg/transport/network/stcp"
 	"github.com/skycoin/skywire/pkg/utclient"
+	"github.com/skycoin/skywire/pkg/visor/auditlog"
 	"github.com/skycoin/skywire/pkg/visor/dmsgtracker"
 	"github.com/skycoin/skywire/pkg/visor/logstore"
 	"github.com/skycoin/skywire/pkg/visor/visorconfig"
+	"github.com/skycoin/skywire/pkg/visor/visorevent"
 	"github.com/skycoin/skywire/pkg/visor/visorinit"
 )
 
@@ -49,6 +54,11 @@ var (
 	ErrTrpMangerNotAvailable = errors.New("no transport manager available")
 	// ErrAppLauncherNotAvailable represents error for unavailable app launcher
 	ErrAppLauncherNotAvailable = errors.New("no app launcher available")
+	// ErrLANDiscoveryNotAvailable represents error for disabled LAN discovery
+	ErrLANDiscoveryNotAvailable = errors.New("LAN discovery is not enabled")
+
+	// ErrEventsNotAvailable is returned when the visor has no event bus
+	ErrEventsNotAvailable = errors.New("no event bus available")
 )
 
 const (
@@ -91,6 +101,8 @@ type Visor struct {
 
 	tpM      *transport.Manager
 	arClient addrresolver.APIClient
+	lanTable stcp.DynamicPKTable // STCP PK table filled by LAN discovery
+	lanD     *lan.Discovery
 	router   router.Router
 	rfClient rfclient.Client
 
@@ -104,8 +116,10 @@ type Visor struct {
 	runtimeErrors chan error
 
 	isServicesHealthy    *internalHealthInfo
-	remoteVisors         map[cipher.PubKey]Conn // remote hypervisors the visor is attempting to connect to
-	connectedHypervisors map[cipher.PubKey]bool // remote hypervisors the visor is currently connected to
+	remoteVisors         map[cipher.PubKey]Conn   // remote hypervisors the visor is attempting to connect to
+	connectedHypervisors map[cipher.PubKey]bool   // remote hypervisors the visor is currently connected to
+	hypervisors          map[cipher.PubKey]func() // closers of the RPC served to the remote hypervisors
+	hypervisorsMx        *sync.Mutex
 	allowedPorts         map[int]bool
 	allowedMX            *sync.RWMutex
 
@@ -116,6 +130,9 @@ type Visor struct {
 
 	survey     visorconfig.Survey
 	surveyLock *sync.RWMutex
+
+	auditLog auditlog.Store  // audit log of the mutating RPC and hypervisor actions
+	events   *visorevent.Bus // events of the visor
 }
 
 // todo: consider moving module closing to the module system
@@ -138,19 +155,6 @@ func (v *Visor) MasterLogger() *logging.MasterLogger {
 	return v.conf.MasterLogger()
 }
 
-func reload(v *Visor) error {
-	if confPath == visorconfig.Stdin {
-		v.log.Error("Cannot reload visor ; config was piped via stdin")
-		return nil
-	}
-	if err := v.Close(); err != nil {
-		v.log.WithError(err).Error("Visor closed with error.")
-		return err
-	}
-	v = nil
-	return run(nil)
-}
-
 // RunVisor runs the visor
 func run(conf *visorconfig.V1) error {
 	store, hook := logstore.MakeStore(runtimeLogMaxEntries)
@@ -164,34 +168,7 @@ func run(conf *visorconfig.V1) error {
 	}
 
 	conf.MasterLogger().AddHook(hook)
-
-	if disableHypervisorPKs {
-		conf.Hypervisors = []cipher.PubKey{}
-	}
-
-	pubkey := cipher.PubKey{}
-	if remoteHypervisorPKs != "" {
-		hypervisorPKsSlice := strings.Split(remoteHypervisorPKs, ",")
-		for _, pubkeyString := range hypervisorPKsSlice {
-			if err := pubkey.Set(pubkeyString); err != nil {
-				mLog.Warnf("Cannot add %s PK as remote hypervisor PK due to: %s", pubkeyString, err)
-				continue
-			}
-			mLog.Infof("%s PK added as remote hypervisor PK", pubkeyString)
-			conf.Hypervisors = append(conf.Hypervisors, pubkey)
-		}
-	}
-
-	if logLvl != "" {
-		//validate & set log level
-		_, err := logging.LevelFromString(logLvl)
-		if err != nil {
-			mLog.WithError(err).Error("Invalid log level specified: ", logLvl)
-		} else {
-			conf.LogLevel = logLvl
-			mLog.Info("setting log level to: ", logLvl)
-		}
-	}
+	applyConfigFlags(conf)
 
 	if conf.Hypervisor != nil {
 		conf.Hypervisor.UIAssets = *uiAssets
@@ -230,6 +207,37 @@ func run(conf *visorconfig.V1) error {
 	return nil
 }
 
+// applyConfigFlags applies the command line overrides of the hypervisors and the log level to the config.
+func applyConfigFlags(conf *visorconfig.V1) {
+	if disableHypervisorPKs {
+		conf.Hypervisors = []cipher.PubKey{}
+	}
+
+	pubkey := cipher.PubKey{}
+	if remoteHypervisorPKs != "" {
+		hypervisorPKsSlice := strings.Split(remoteHypervisorPKs, ",")
+		for _, pubkeyString := range hypervisorPKsSlice {
+			if err := pubkey.Set(pubkeyString); err != nil {
+				mLog.Warnf("Cannot add %s PK as remote hypervisor PK due to: %s", pubkeyString, err)
+				continue
+			}
+			mLog.Infof("%s PK added as remote hypervisor PK", pubkeyString)
+			conf.Hypervisors = append(conf.Hypervisors, pubkey)
+		}
+	}
+
+	if logLvl != "" {
+		//validate & set log level
+		_, err := logging.LevelFromString(logLvl)
+		if err != nil {
+			mLog.WithError(err).Error("Invalid log level specified: ", logLvl)
+		} else {
+			conf.LogLevel = logLvl
+			mLog.Info("setting log level to: ", logLvl)
+		}
+	}
+}
+
 // NewVisor constructs new Visor.
 func NewVisor(ctx context.Context, conf *visorconfig.V1) (*Visor, bool) {
 	if conf == nil {
@@ -249,11 +257,15 @@ func NewVisor(ctx context.Context, conf *visorconfig.V1) (*Visor, bool) {
 		dtmReady:             make(chan struct{}),
 		stunReady:            make(chan struct{}),
 		connectedHypervisors: make(map[cipher.PubKey]bool),
+		hypervisors:          make(map[cipher.PubKey]func()),
+		hypervisorsMx:        new(sync.Mutex),
 		pingConns:            make(map[cipher.PubKey]ping),
 		pingConnMx:           new(sync.Mutex),
 		allowedPorts:         make(map[int]bool),
 		survey:               visorconfig.Survey{},
 		surveyLock:           new(sync.RWMutex),
+		auditLog:             newAuditLog(conf),
+		events:               visorevent.NewBus(conf.PK, visorevent.DefaultHistorySize),
 	}
 	v.isServicesHealthy.init()
 
@@ -320,6 +332,15 @@ func NewVisor(ctx context.Context, conf *visorconfig.V1) (*Visor, bool) {
 	return v, true
 }
 
+// newAuditLog returns the audit log of the visor, rotated as configured.
+func newAuditLog(conf *visorconfig.V1) auditlog.Store {
+	path := filepath.Join(conf.LocalPath, visorconfig.AuditLog)
+	if conf.AuditLog == nil {
+		return auditlog.NewFileStore(path, 0, 0)
+	}
+	return auditlog.NewFileStore(path, conf.AuditLog.MaxSize, conf.AuditLog.Backups)
+}
+
 func (v *Visor) processRuntimeErrs() bool {
 	ok := true
 	for {
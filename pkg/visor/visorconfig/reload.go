@@ -0,0 +1,102 @@
+// Package visorconfig pkg/visor/visorconfig/reload.go
+package visorconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Sections of the config which may be applied without restarting the visor.
+// The other sections are named after their JSON keys.
+const (
+	SectionApps                 = "launcher.apps"
+	SectionPersistentTransports = "persistent_transports"
+	SectionHypervisors          = "hypervisors"
+	SectionLogLevel             = "log_level"
+)
+
+// IsLiveSection returns true if the section of the config may be applied without restarting the visor.
+func IsLiveSection(section string) bool {
+	switch section {
+	case SectionApps, SectionPersistentTransports, SectionHypervisors, SectionLogLevel:
+		return true
+	}
+	return false
+}
+
+// Diff returns the sorted names of the top-level sections of the config which differ in `conf`.
+// The apps of the launcher are compared as a section on their own.
+func (v1 *V1) Diff(conf *V1) ([]string, error) {
+	old, err := v1.sections()
+	if err != nil {
+		return nil, err
+	}
+	sections, err := conf.sections()
+	if err != nil {
+		return nil, err
+	}
+
+	var diff []string
+	for name, raw := range sections {
+		if !bytes.Equal(old[name], raw) {
+			diff = append(diff, name)
+		}
+	}
+	for name := range old {
+		if _, ok := sections[name]; !ok {
+			diff = append(diff, name)
+		}
+	}
+	sort.Strings(diff)
+	return diff, nil
+}
+
+// sections returns the JSON encoded top-level sections of the config.
+func (v1 *V1) sections() (map[string]json.RawMessage, error) {
+	v1.mu.RLock()
+	raw, err := json.Marshal(v1)
+	v1.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &sections); err != nil {
+		return nil, err
+	}
+	if launcher, ok := sections["launcher"]; ok && !bytes.Equal(launcher, []byte("null")) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(launcher, &fields); err != nil {
+			return nil, err
+		}
+		sections[SectionApps] = fields["apps"]
+		delete(fields, "apps")
+		if sections["launcher"], err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	}
+	return sections, nil
+}
+
+// ReloadSections replaces the live sections of the config named in `sections` with the ones of `conf`.
+// The config is not flushed, as it is expected to have been read from its file.
+func (v1 *V1) ReloadSections(conf *V1, sections []string) {
+	v1.mu.Lock()
+	defer v1.mu.Unlock()
+
+	for _, section := range sections {
+		switch section {
+		case SectionApps:
+			if v1.Launcher != nil && conf.Launcher != nil {
+				v1.Launcher.Apps = conf.Launcher.Apps
+			}
+		case SectionPersistentTransports:
+			v1.PersistentTransports = conf.PersistentTransports
+		case SectionHypervisors:
+			v1.Hypervisors = conf.Hypervisors
+		case SectionLogLevel:
+			v1.LogLevel = conf.LogLevel
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package visorconfig pkg/visor/visorconfig/reload_test.go
+package visorconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/app/appserver"
+)
+
+func TestV1_Diff(t *testing.T) {
+	makeConf := func() *V1 {
+		conf := &V1{}
+		conf.Common = &Common{}
+		conf.LogLevel = "info"
+		conf.Launcher = &Launcher{
+			ServerAddr: "localhost:5505",
+			Apps:       []appserver.AppConfig{{Name: "skychat", Binary: "skychat", Port: 1}},
+		}
+		return conf
+	}
+
+	old, conf := makeConf(), makeConf()
+	diff, err := old.Diff(conf)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	pk, _ := cipher.GenerateKeyPair()
+	conf.LogLevel = "debug"
+	conf.Hypervisors = []cipher.PubKey{pk}
+	conf.Launcher.Apps[0].AutoStart = true
+	diff, err = old.Diff(conf)
+	require.NoError(t, err)
+	require.Equal(t, []string{SectionHypervisors, SectionApps, SectionLogLevel}, diff)
+	for _, section := range diff {
+		require.True(t, IsLiveSection(section))
+	}
+
+	conf.Launcher.ServerAddr = "localhost:5506"
+	diff, err = old.Diff(conf)
+	require.NoError(t, err)
+	require.Equal(t, []string{SectionHypervisors, "launcher", SectionApps, SectionLogLevel}, diff)
+	require.False(t, IsLiveSection("launcher"))
+	// the transports probed with the STUN servers are set up once
+	require.False(t, IsLiveSection("stun_servers"))
+
+	old.ReloadSections(conf, []string{SectionHypervisors, SectionApps, SectionLogLevel})
+	diff, err = old.Diff(conf)
+	require.NoError(t, err)
+	require.Equal(t, []string{"launcher"}, diff)
+}
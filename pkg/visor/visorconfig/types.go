@@ -11,6 +11,23 @@ import (
 const (
 	FileLogStore   = "file"
 	MemoryLogStore = "memory"
+	BBoltLogStore  = "bbolt"
+)
+
+// Routing table types.
+const (
+	MemoryRoutingTable     = "memory"
+	PersistentRoutingTable = "persistent"
+)
+
+// Route finder modes.
+const (
+	// FallbackRouteFinder finds the routes locally when the route finder service fails, it is the default.
+	FallbackRouteFinder = "fallback"
+	// RemoteRouteFinder only finds the routes with the route finder service.
+	RemoteRouteFinder = "remote"
+	// LocalRouteFinder finds the routes locally, falling back to the route finder service.
+	LocalRouteFinder = "local"
 )
 
 const (
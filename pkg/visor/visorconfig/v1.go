@@ -24,10 +24,12 @@ type V1 struct {
 	Dmsg          *dmsgc.DmsgConfig   `json:"dmsg"`
 	Dmsgpty       *Dmsgpty            `json:"dmsgpty,omitempty"`
 	STCP          *network.STCPConfig `json:"skywire-tcp,omitempty"`
+	WSS           *network.WSSConfig  `json:"wss,omitempty"`
 	Transport     *Transport          `json:"transport"`
 	Routing       *Routing            `json:"routing"`
 	UptimeTracker *UptimeTracker      `json:"uptime_tracker,omitempty"`
 	Launcher      *Launcher           `json:"launcher"`
+	Metrics       *Metrics            `json:"metrics,omitempty"`
 
 	SurveyWhitelist []cipher.PubKey `json:"survey_whitelist"`
 	Hypervisors     []cipher.PubKey `json:"hypervisors"`
@@ -40,6 +42,7 @@ type V1 struct {
 	ShutdownTimeout      Duration                         `json:"shutdown_timeout,omitempty"` // time value, examples: 10s, 1m, etc
 	IsPublic             bool                             `json:"is_public"`
 	PersistentTransports []transport.PersistentTransports `json:"persistent_transports"`
+	AuditLog             *AuditLogConfig                  `json:"audit_log,omitempty"`
 
 	Hypervisor *HypervisorConfig `json:"hypervisor,omitempty"`
 }
@@ -61,11 +64,34 @@ type Transport struct {
 	LogStore          *LogStore       `json:"log_store"`
 	StcprPort         int             `json:"stcpr_port"`
 	SudphPort         int             `json:"sudph_port"`
+	EnableQuic        bool            `json:"enable_quic,omitempty"`
+	Obfuscation       *Obfuscation    `json:"obfuscation,omitempty"`
+}
+
+// Obfuscation configures the obfuscation of the transports dialed by the visor.
+// Obfuscated transports are accepted regardless of this config.
+type Obfuscation struct {
+	Enabled bool `json:"enabled"`
+	// Required fails the transports dialed to visors which don't support obfuscation, instead of
+	// falling back to plain ones. Defaults to true.
+	Required *bool `json:"required,omitempty"`
+	// Padding is the largest number of random bytes added to every packet, up to 1024.
+	Padding int `json:"padding"`
+	// Jitter is the longest random delay before sending every packet, up to 1s.
+	Jitter Duration `json:"jitter"` // time value, examples: 10ms, 100ms etc
+}
+
+// AuditLogConfig configures the audit log of the mutating actions performed on the visor.
+type AuditLogConfig struct {
+	// MaxSize is the size in bytes the audit log is rotated at, defaults to 8MiB.
+	MaxSize int64 `json:"max_size,omitempty"`
+	// Backups is the number of rotated audit logs kept, the entries of the older ones are lost. Defaults to 4.
+	Backups int `json:"backups,omitempty"`
 }
 
 // LogStore configures a LogStore.
 type LogStore struct {
-	// Type defines the log store type. Valid values: file, memory.
+	// Type defines the log store type. Valid values: file, memory, bbolt.
 	Type             string   `json:"type"`
 	Location         string   `json:"location"`
 	RotationInterval Duration `json:"rotation_interval"` // time value, examples: 10s, 1m, 1h etc
@@ -77,6 +103,12 @@ type Routing struct {
 	RouteFinder        string          `json:"route_finder"`
 	RouteFinderTimeout Duration        `json:"route_finder_timeout,omitempty"`
 	MinHops            uint16          `json:"min_hops"`
+	// Table defines the routing table type. Valid values: memory, persistent.
+	// A persistent table keeps the rules of transit routes over visor restarts.
+	Table string `json:"table,omitempty"`
+	// RouteFinderMode defines how the routes are found. Valid values: fallback, remote, local.
+	// The local route finder searches the routes in the transports fetched from the transport discovery.
+	RouteFinderMode string `json:"route_finder_mode,omitempty"`
 }
 
 // UptimeTracker configures uptime tracker.
@@ -84,6 +116,13 @@ type UptimeTracker struct {
 	Addr string `json:"addr"`
 }
 
+// Metrics configures the prometheus metrics endpoint of the visor.
+type Metrics struct {
+	Enabled bool `json:"enabled"`
+	// Addr is the address the endpoint is served on, at the /metrics path.
+	Addr string `json:"addr"`
+}
+
 // Launcher configures the app
 type Launcher struct {
 	ServiceDisc   string                `json:"service_discovery"`
@@ -103,6 +103,10 @@ var (
 	TransportRPCTimeout = skyenv.TransportRPCTimeout // TransportRPCTimeout ...
 	UpdateRPCTimeout    = skyenv.UpdateRPCTimeout    // UpdateRPCTimeout ...
 
+	// Metrics constants
+
+	MetricsAddr = skyenv.MetricsAddr // MetricsAddr ...
+
 	// Default skywire app server and discovery constants
 
 	AppSrvAddr                = skyenv.AppSrvAddr                // AppSrvAddr ...
@@ -112,8 +116,9 @@ var (
 
 	// Routing constants
 
-	TpLogStore = skyenv.TpLogStore // TpLogStore ...
-	Custom     = skyenv.Custom     // Custom ...
+	TpLogStore   = skyenv.TpLogStore   // TpLogStore ...
+	Custom       = skyenv.Custom       // Custom ...
+	RoutingTable = skyenv.RoutingTable // RoutingTable ...
 
 	// Local constants
 
@@ -139,6 +144,9 @@ var (
 
 	// RewardFile is the name of the file containing skycoin reward address
 	RewardFile = skyenv.RewardFile
+
+	// AuditLog is the name of the audit log of the mutating actions performed on the visor
+	AuditLog = skyenv.AuditLog
 )
 
 // SkywireConfig returns the full path to the package config
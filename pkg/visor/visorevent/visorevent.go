@@ -0,0 +1,176 @@
+// Package visorevent pkg/visor/visorevent/visorevent.go
+package visorevent
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+// Event types.
+const (
+	TransportUp       = "transport_up"
+	TransportDown     = "transport_down"
+	RouteGroupCreated = "route_group_created"
+	RouteGroupClosed  = "route_group_closed"
+	AppStarted        = "app_started"
+	AppStopped        = "app_stopped"
+	AppCrashed        = "app_crashed"
+	DmsgSessionUp     = "dmsg_session_up"
+	DmsgSessionDown   = "dmsg_session_down"
+	ConfigReloaded    = "config_reloaded"
+)
+
+// DefaultHistorySize is the number of events kept by a Bus by default.
+const DefaultHistorySize = 256
+
+// AllTypes returns all event types.
+func AllTypes() map[string]bool {
+	return map[string]bool{
+		TransportUp:       true,
+		TransportDown:     true,
+		RouteGroupCreated: true,
+		RouteGroupClosed:  true,
+		AppStarted:        true,
+		AppStopped:        true,
+		AppCrashed:        true,
+		DmsgSessionUp:     true,
+		DmsgSessionDown:   true,
+		ConfigReloaded:    true,
+	}
+}
+
+// Event is an event of a visor.
+type Event struct {
+	// Seq is the sequence number of the event, it is reset when the visor restarts.
+	Seq   uint64          `json:"seq"`
+	Time  time.Time       `json:"time"`
+	Visor cipher.PubKey   `json:"visor"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// TransportData is the data of TransportUp and TransportDown events.
+type TransportData struct {
+	ID       uuid.UUID     `json:"id"`
+	RemotePK cipher.PubKey `json:"remote_pk"`
+	Type     string        `json:"type"`
+	Label    string        `json:"label,omitempty"`
+}
+
+// RouteGroupData is the data of RouteGroupCreated and RouteGroupClosed events.
+type RouteGroupData struct {
+	// Desc is the route descriptor of the route group.
+	Desc string `json:"desc"`
+}
+
+// AppData is the data of AppStarted, AppStopped and AppCrashed events.
+type AppData struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// DmsgSessionData is the data of DmsgSessionUp and DmsgSessionDown events.
+type DmsgSessionData struct {
+	Network string `json:"network"`
+	Addr    string `json:"addr"`
+}
+
+// ConfigReloadData is the data of ConfigReloaded events.
+type ConfigReloadData struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restart_required"`
+}
+
+// Bus publishes the events of a visor and keeps the most recent ones,
+// so that they can be followed by polling.
+type Bus struct {
+	pk  cipher.PubKey
+	cap int
+
+	mu      sync.Mutex
+	seq     uint64
+	history []Event
+	// notify is closed and replaced on every publish.
+	notify chan struct{}
+}
+
+// NewBus creates a Bus of the visor of `pk` keeping up to `historySize` events.
+func NewBus(pk cipher.PubKey, historySize int) *Bus {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &Bus{
+		pk:     pk,
+		cap:    historySize,
+		notify: make(chan struct{}),
+	}
+}
+
+// Publish publishes an event of type `typ` with `data`, which may be nil.
+func (b *Bus) Publish(typ string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	var raw json.RawMessage
+	if data != nil {
+		var err error
+		if raw, err = json.Marshal(data); err != nil {
+			raw = nil
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	b.history = append(b.history, Event{
+		Seq:   b.seq,
+		Time:  time.Now().UTC(),
+		Visor: b.pk,
+		Type:  typ,
+		Data:  raw,
+	})
+	if len(b.history) > b.cap {
+		b.history = append(b.history[:0], b.history[len(b.history)-b.cap:]...)
+	}
+
+	close(b.notify)
+	b.notify = make(chan struct{})
+}
+
+// Since returns the events published after the event of sequence number `seq`, waiting
+// until the context is done if there are none yet. All of the kept events are returned
+// if `seq` is ahead of the bus, as it is the case when the visor restarted.
+func (b *Bus) Since(ctx context.Context, seq uint64) []Event {
+	for {
+		b.mu.Lock()
+		if seq > b.seq {
+			seq = 0
+		}
+		events := make([]Event, 0)
+		for _, e := range b.history {
+			if e.Seq > seq {
+				events = append(events, e)
+			}
+		}
+		notify := b.notify
+		b.mu.Unlock()
+
+		if len(events) != 0 {
+			return events
+		}
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return events
+		}
+	}
+}
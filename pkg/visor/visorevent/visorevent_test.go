@@ -0,0 +1,62 @@
+// Package visorevent pkg/visor/visorevent/visorevent_test.go
+package visorevent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+)
+
+func TestBus(t *testing.T) {
+	pk, _ := cipher.GenerateKeyPair()
+	b := NewBus(pk, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Empty(t, b.Since(ctx, 0))
+
+	b.Publish(AppStarted, AppData{Name: "vpn-client"})
+	b.Publish(ConfigReloaded, nil)
+
+	events := b.Since(context.Background(), 0)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(1), events[0].Seq)
+	require.Equal(t, pk, events[0].Visor)
+	require.Equal(t, AppStarted, events[0].Type)
+	var data AppData
+	require.NoError(t, json.Unmarshal(events[0].Data, &data))
+	require.Equal(t, "vpn-client", data.Name)
+	require.Empty(t, events[1].Data)
+
+	// only the most recent events are kept
+	b.Publish(AppStopped, AppData{Name: "vpn-client"})
+	b.Publish(AppStarted, AppData{Name: "vpn-client"})
+	events = b.Since(context.Background(), 0)
+	require.Len(t, events, 3)
+	require.Equal(t, uint64(2), events[0].Seq)
+
+	// followers wait for the next event
+	got := make(chan []Event, 1)
+	go func() {
+		got <- b.Since(context.Background(), 4)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	b.Publish(TransportUp, TransportData{Type: "stcpr"})
+	select {
+	case events = <-got:
+		require.Len(t, events, 1)
+		require.Equal(t, uint64(5), events[0].Seq)
+		require.Equal(t, TransportUp, events[0].Type)
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+
+	// followers of a restarted visor get all of the kept events
+	events = b.Since(context.Background(), 100)
+	require.Len(t, events, 3)
+}
@@ -0,0 +1,92 @@
+// Package visormetrics pkg/visor/visormetrics/visormetrics.go
+package visormetrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/google/uuid"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/app/appserver"
+	"github.com/skycoin/skywire/pkg/router"
+)
+
+// appStatuses are the label values of the app statuses.
+var appStatuses = map[appserver.AppStatus]string{
+	appserver.AppStatusStopped:  "stopped",
+	appserver.AppStatusRunning:  "running",
+	appserver.AppStatusErrored:  "errored",
+	appserver.AppStatusStarting: "starting",
+}
+
+// Transport contains the traffic of a transport.
+type Transport struct {
+	ID        uuid.UUID
+	RemotePK  cipher.PubKey
+	Type      string
+	RecvBytes uint64
+	SentBytes uint64
+}
+
+// Snapshot is the state of a visor exported as metrics.
+type Snapshot struct {
+	Transports        []Transport
+	Routes            int
+	RouteGroups       []router.RouteGroupStats
+	Apps              []*appserver.AppState
+	DmsgSessions      int
+	HandshakeFailures uint64
+}
+
+// WritePrometheus writes the metrics of the snapshot to `w` in prometheus format.
+func (s Snapshot) WritePrometheus(w io.Writer) {
+	set := metrics.NewSet()
+	gauge := func(name string, v float64) {
+		set.GetOrCreateGauge(name, func() float64 { return v })
+	}
+
+	for _, tp := range s.Transports {
+		labels := fmt.Sprintf(`{id=%q,remote_pk=%q,type=%q}`, tp.ID, tp.RemotePK, tp.Type)
+		set.GetOrCreateCounter("skywire_transport_received_bytes_total" + labels).Set(tp.RecvBytes)
+		set.GetOrCreateCounter("skywire_transport_sent_bytes_total" + labels).Set(tp.SentBytes)
+	}
+	set.GetOrCreateCounter("skywire_transport_handshake_failures_total").Set(s.HandshakeFailures)
+
+	gauge("skywire_routing_rules", float64(s.Routes))
+	for _, rg := range s.RouteGroups {
+		src, dst := rg.Desc.Src(), rg.Desc.Dst()
+		labels := fmt.Sprintf(`{src_pk=%q,src_port="%d",dst_pk=%q,dst_port="%d"}`, src.PubKey, src.Port, dst.PubKey, dst.Port)
+		gauge("skywire_route_group_latency_seconds"+labels, rg.Latency.Seconds())
+		gauge("skywire_route_group_upload_bytes_per_second"+labels, float64(rg.UploadSpeed))
+		gauge("skywire_route_group_download_bytes_per_second"+labels, float64(rg.DownloadSpeed))
+		set.GetOrCreateCounter("skywire_route_group_sent_bytes_total" + labels).Set(rg.BandwidthSent)
+		set.GetOrCreateCounter("skywire_route_group_received_bytes_total" + labels).Set(rg.BandwidthReceived)
+	}
+
+	for _, app := range s.Apps {
+		for status, name := range appStatuses {
+			v := 0.0
+			if app.Status == status {
+				v = 1
+			}
+			gauge(fmt.Sprintf(`skywire_app_status{app=%q,status=%q}`, app.Name, name), v)
+		}
+	}
+
+	gauge("skywire_dmsg_sessions", float64(s.DmsgSessions))
+
+	set.WritePrometheus(w)
+}
+
+// Handler returns a handler serving the metrics of the snapshots taken by `snapshot`,
+// along with the metrics of the process.
+func Handler(snapshot func() Snapshot) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		snapshot().WritePrometheus(w)
+		metrics.WriteProcessMetrics(w)
+	})
+}
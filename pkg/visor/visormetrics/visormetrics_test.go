@@ -0,0 +1,64 @@
+// Package visormetrics pkg/visor/visormetrics/visormetrics_test.go
+package visormetrics
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skywire-utilities/pkg/cipher"
+	"github.com/skycoin/skywire/pkg/app/appserver"
+	"github.com/skycoin/skywire/pkg/router"
+	"github.com/skycoin/skywire/pkg/routing"
+)
+
+func TestSnapshot_WritePrometheus(t *testing.T) {
+	lPK, _ := cipher.GenerateKeyPair()
+	rPK, _ := cipher.GenerateKeyPair()
+	tpID := uuid.New()
+
+	s := Snapshot{
+		Transports: []Transport{{ID: tpID, RemotePK: rPK, Type: "stcpr", RecvBytes: 10, SentBytes: 20}},
+		Routes:     4,
+		RouteGroups: []router.RouteGroupStats{{
+			Desc:              routing.NewRouteDescriptor(lPK, rPK, 1, 2),
+			Latency:           1500 * time.Millisecond,
+			UploadSpeed:       100,
+			DownloadSpeed:     200,
+			BandwidthSent:     300,
+			BandwidthReceived: 400,
+		}},
+		Apps: []*appserver.AppState{
+			{AppConfig: appserver.AppConfig{Name: "skychat"}, Status: appserver.AppStatusRunning},
+		},
+		DmsgSessions:      2,
+		HandshakeFailures: 3,
+	}
+
+	var b bytes.Buffer
+	s.WritePrometheus(&b)
+	out := b.String()
+
+	tpLabels := fmt.Sprintf(`{id="%s",remote_pk="%s",type="stcpr"}`, tpID, rPK)
+	rgLabels := fmt.Sprintf(`{src_pk="%s",src_port="1",dst_pk="%s",dst_port="2"}`, lPK, rPK)
+	for _, line := range []string{
+		"skywire_transport_received_bytes_total" + tpLabels + " 10",
+		"skywire_transport_sent_bytes_total" + tpLabels + " 20",
+		"skywire_transport_handshake_failures_total 3",
+		"skywire_routing_rules 4",
+		"skywire_route_group_latency_seconds" + rgLabels + " 1.5",
+		"skywire_route_group_upload_bytes_per_second" + rgLabels + " 100",
+		"skywire_route_group_download_bytes_per_second" + rgLabels + " 200",
+		"skywire_route_group_sent_bytes_total" + rgLabels + " 300",
+		"skywire_route_group_received_bytes_total" + rgLabels + " 400",
+		`skywire_app_status{app="skychat",status="running"} 1`,
+		`skywire_app_status{app="skychat",status="stopped"} 0`,
+		"skywire_dmsg_sessions 2",
+	} {
+		require.Contains(t, out, line+"\n")
+	}
+}